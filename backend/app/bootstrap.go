@@ -0,0 +1,52 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"poker_score_backend/config"
+	"poker_score_backend/services"
+	"strings"
+)
+
+// bootstrapAdmin 启动时检查管理员账户
+// 仍在使用旧版默认密码的账户在生产环境拒绝启动，其他环境输出警告；
+// 还没有管理员时，优先使用 ADMIN_PHONE/ADMIN_PASSWORD 创建，否则生成一次性初始化令牌并打印到日志
+func bootstrapAdmin(cfg *config.Config, bootstrapService *services.BootstrapService) error {
+	defaults, err := bootstrapService.DefaultCredentialUsers()
+	if err != nil {
+		return fmt.Errorf("检查默认管理员密码失败: %w", err)
+	}
+	if len(defaults) > 0 {
+		phones := make([]string, 0, len(defaults))
+		for _, user := range defaults {
+			phones = append(phones, user.Phone)
+		}
+		if cfg.IsProduction() {
+			return fmt.Errorf("检测到仍在使用旧版默认密码 admin123 的账户（%s），请先执行 create-admin 子命令重置密码", strings.Join(phones, ", "))
+		}
+		log.Printf("警告: 以下账户仍在使用旧版默认密码 admin123，请尽快修改: %s", strings.Join(phones, ", "))
+	}
+
+	needsSetup, err := bootstrapService.NeedsSetup()
+	if err != nil {
+		return fmt.Errorf("检查管理员账户失败: %w", err)
+	}
+	if !needsSetup {
+		return nil
+	}
+
+	if cfg.Admin.Phone != "" && cfg.Admin.Password != "" {
+		if _, err := bootstrapService.CreateAdmin(cfg.Admin.Phone, cfg.Admin.Nickname, cfg.Admin.Password); err != nil {
+			return fmt.Errorf("根据环境变量创建管理员失败: %w", err)
+		}
+		log.Printf("已根据 ADMIN_PHONE/ADMIN_PASSWORD 创建管理员，可以从环境变量中移除密码")
+		return nil
+	}
+
+	token, err := bootstrapService.IssueSetupToken()
+	if err != nil {
+		return fmt.Errorf("生成初始化令牌失败: %w", err)
+	}
+	log.Printf("尚未创建管理员账户。请使用一次性初始化令牌调用 POST /api/setup/admin 创建管理员（重启后令牌失效）: %s", token)
+	return nil
+}
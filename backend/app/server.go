@@ -32,77 +32,229 @@ func NewServer(cfg *config.Config) (*gin.Engine, func() error, error) {
 		return models.CloseDatabase()
 	}
 
+	bootstrapService := services.NewBootstrapService()
+	if err := bootstrapAdmin(cfg, bootstrapService); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
 	hub := websocket.NewHub()
 	go hub.Run()
 
-	authService := services.NewAuthService(cfg.Session.MaxAge)
-	roomService := services.NewRoomService(hub)
+	authService := services.NewAuthService(cfg.Session.MaxAge, cfg.Session.IdleTimeout, cfg.Session.WSTicketTTL)
+	smsSender := services.NewLogSMSSender(cfg.SMS.LogFile)
+	passwordResetService := services.NewPasswordResetService(authService, smsSender, cfg.SMS.CodeTTL, cfg.SMS.MaxAttempts, cfg.SMS.ResendInterval)
+	phoneChangeService := services.NewPhoneChangeService(authService, smsSender, cfg.SMS.CodeTTL, cfg.SMS.MaxAttempts, cfg.SMS.ResendInterval)
+	apiTokenService := services.NewAPITokenService()
+	twoFactorService := services.NewTwoFactorService(authService, cfg.TwoFactor.Issuer, cfg.TwoFactor.ChallengeTTL)
+	accountService := services.NewAccountService(twoFactorService)
+	oidcService := services.NewOIDCService(services.OIDCOptions{
+		Issuer:               cfg.OIDC.Issuer,
+		ClientID:             cfg.OIDC.ClientID,
+		ClientSecret:         cfg.OIDC.ClientSecret,
+		RedirectURL:          cfg.OIDC.RedirectURL,
+		Scopes:               cfg.OIDC.Scopes,
+		PhoneClaim:           cfg.OIDC.PhoneClaim,
+		RequireVerifiedPhone: cfg.OIDC.RequireVerifiedPhone,
+		AllowSignup:          cfg.OIDC.AllowSignup,
+		StateTTL:             cfg.OIDC.StateTTL,
+	})
+	loginGuardService := services.NewLoginGuardService(cfg.RateLimit.LockoutThreshold, cfg.RateLimit.LockoutBase, cfg.RateLimit.LockoutMax, cfg.RateLimit.LockoutReset)
+	rateLimiter := services.NewRateLimiter(services.NewMemoryRateLimitStore(), map[string]services.RateLimitRule{
+		services.RateScopeLogin:         services.RateLimitRule(cfg.RateLimit.Login),
+		services.RateScopeRegister:      services.RateLimitRule(cfg.RateLimit.Register),
+		services.RateScopeJoin:          services.RateLimitRule(cfg.RateLimit.Join),
+		services.RateScopeMoney:         services.RateLimitRule(cfg.RateLimit.Money),
+		services.RateScopePasswordReset: services.RateLimitRule(cfg.RateLimit.PasswordReset),
+	})
+	presenceService := services.NewPresenceService(hub, cfg.Presence.AwayAfter, cfg.Presence.OfflineAfter)
+	hub.SetPresenceTracker(presenceService)
+	seasonService := services.NewSeasonService(cfg.Season.SnapshotHour)
+	cleanup = func() error {
+		presenceService.Stop()
+		seasonService.Stop()
+		return models.CloseDatabase()
+	}
+	roomService := services.NewRoomService(hub, presenceService)
 	operationService := services.NewOperationService(roomService)
 	settlementService := services.NewSettlementService(roomService)
 	recordService := services.NewRecordService()
-	adminService := services.NewAdminService()
+	chatService := services.NewChatService(roomService, cfg.Chat.RateLimit, cfg.Chat.RateWindow, cfg.Chat.MaxLength)
+	hub.SetMessageHandler(chatService)
+	clubService := services.NewClubService()
+	achievementService := services.NewAchievementService(roomService, recordService)
+	roomService.AddSettledListener(achievementService)
+	adminService := services.NewAdminService(achievementService)
+	analyticsService := services.NewAnalyticsService(recordService)
+	guestService := services.NewGuestService(roomService, achievementService)
+	recapService := services.NewRecapService(settlementService)
+	roomService.AddDissolvedListener(recapService)
 
-	authController := controllers.NewAuthController(authService, cfg)
+	authController := controllers.NewAuthController(authService, loginGuardService, twoFactorService, accountService, oidcService, cfg)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorService)
+	apiTokenController := controllers.NewAPITokenController(apiTokenService)
+	passwordResetController := controllers.NewPasswordResetController(passwordResetService)
+	phoneChangeController := controllers.NewPhoneChangeController(phoneChangeService)
+	lockoutController := controllers.NewLockoutController(loginGuardService)
+	setupController := controllers.NewSetupController(bootstrapService)
 	roomController := controllers.NewRoomController(roomService, settlementService)
-	operationController := controllers.NewOperationController(operationService)
+	operationController := controllers.NewOperationController(operationService, guestService)
 	settlementController := controllers.NewSettlementController(settlementService)
 	recordController := controllers.NewRecordController(recordService)
 	adminController := controllers.NewAdminController(adminService)
-	wsController := controllers.NewWebSocketController(hub)
+	chatController := controllers.NewChatController(chatService)
+	clubController := controllers.NewClubController(clubService)
+	seasonController := controllers.NewSeasonController(seasonService)
+	achievementController := controllers.NewAchievementController(achievementService)
+	analyticsController := controllers.NewAnalyticsController(analyticsService, recordService)
+	recapController := controllers.NewRecapController(recapService)
+	guestController := controllers.NewGuestController(guestService)
+	wsController := controllers.NewWebSocketController(hub, authService, cfg.Server.AllowedOrigins)
+
+	limitByIP := func(scope string) gin.HandlerFunc {
+		return middlewares.RateLimitMiddleware(rateLimiter, scope, middlewares.ClientIPKey)
+	}
+	limitByUser := func(scope string) gin.HandlerFunc {
+		return middlewares.RateLimitMiddleware(rateLimiter, scope, middlewares.UserIDKey)
+	}
 
 	engine := gin.Default()
+	// 客户端IP用于限流与登录记录，只信任配置的反向代理传来的 X-Forwarded-For
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("信任代理配置错误: %w", err)
+	}
 	engine.Use(middlewares.CORSMiddleware(cfg.Server.AllowedOrigins))
 
 	api := engine.Group("/api")
 	{
+		api.GET("/setup/status", setupController.GetStatus)
+		api.POST("/setup/admin", limitByIP(services.RateScopeLogin), setupController.CreateAdmin)
+
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", authController.Register)
-			auth.POST("/login", authController.Login)
-			auth.POST("/logout", middlewares.AuthMiddleware(cfg.Session.CookieName), authController.Logout)
-			auth.GET("/me", middlewares.AuthMiddleware(cfg.Session.CookieName), authController.GetMe)
-			auth.PUT("/nickname", middlewares.AuthMiddleware(cfg.Session.CookieName), authController.UpdateNickname)
-			auth.PUT("/password", middlewares.AuthMiddleware(cfg.Session.CookieName), authController.UpdatePassword)
+			auth.POST("/register", limitByIP(services.RateScopeRegister), authController.Register)
+			auth.POST("/login", limitByIP(services.RateScopeLogin), authController.Login)
+			auth.POST("/login/2fa", limitByIP(services.RateScopeLogin), authController.LoginTwoFactor)
+			auth.GET("/oidc", authController.GetOIDCStatus)
+			auth.GET("/oidc/login", limitByIP(services.RateScopeLogin), authController.OIDCLogin)
+			auth.GET("/oidc/callback", authController.OIDCCallback)
+			auth.POST("/oidc/link", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.OIDCLink)
+			auth.GET("/oidc/identities", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.GetOIDCIdentities)
+			auth.DELETE("/oidc/identities/:id", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.UnlinkOIDCIdentity)
+			auth.POST("/password/forgot", limitByIP(services.RateScopePasswordReset), passwordResetController.RequestCode)
+			auth.POST("/password/reset", limitByIP(services.RateScopePasswordReset), passwordResetController.ResetPassword)
+			auth.POST("/logout", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.Logout)
+			auth.GET("/me", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.GetMe)
+			auth.PUT("/nickname", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.UpdateNickname)
+			auth.PUT("/password", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.UpdatePassword)
+			auth.POST("/phone/code", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), phoneChangeController.RequestCode)
+			auth.PUT("/phone", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), phoneChangeController.ChangePhone)
+			auth.PUT("/preferences", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.UpdatePreferences)
+			auth.POST("/logout-all", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.LogoutAll)
+			auth.GET("/sessions", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.GetSessions)
+			auth.DELETE("/sessions/:id", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.RevokeSession)
+			auth.GET("/2fa", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), twoFactorController.GetStatus)
+			auth.POST("/2fa/setup", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), twoFactorController.Setup)
+			auth.POST("/2fa/enable", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), twoFactorController.Enable)
+			auth.POST("/2fa/disable", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), twoFactorController.Disable)
+			auth.POST("/2fa/recovery-codes", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), twoFactorController.RegenerateRecoveryCodes)
+			auth.GET("/export", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.ExportData)
+			auth.DELETE("/account", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), authController.DeleteAccount)
+			auth.GET("/tokens", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), apiTokenController.ListTokens)
+			auth.POST("/tokens", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), apiTokenController.CreateToken)
+			auth.DELETE("/tokens/:id", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), apiTokenController.RevokeToken)
 		}
 
-		rooms := api.Group("/rooms", middlewares.AuthMiddleware(cfg.Session.CookieName))
+		rooms := api.Group("/rooms", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService))
 		{
 			rooms.POST("", roomController.CreateRoom)
-			rooms.POST("/join", roomController.JoinRoom)
+			rooms.POST("/join", limitByUser(services.RateScopeJoin), roomController.JoinRoom)
 			rooms.GET("/last", roomController.GetLastRoom)
 			rooms.GET("/:room_id", roomController.GetRoomDetails)
 			rooms.POST("/:room_id/return", roomController.ReturnToRoom)
 			rooms.POST("/:room_id/leave", roomController.LeaveRoom)
 			rooms.POST("/:room_id/kick", roomController.KickUser)
 			rooms.POST("/:room_id/dissolve", roomController.DissolveRoom)
+			rooms.POST("/:room_id/guests", guestController.AddGuest)
 
-			rooms.POST("/:room_id/bet", operationController.Bet)
-			rooms.POST("/:room_id/withdraw", operationController.Withdraw)
-			rooms.POST("/:room_id/force-transfer", operationController.ForceTransfer)
-			rooms.POST("/:room_id/niuniu-bet", operationController.NiuniuBet)
+			rooms.POST("/:room_id/bet", limitByUser(services.RateScopeMoney), operationController.Bet)
+			rooms.POST("/:room_id/withdraw", limitByUser(services.RateScopeMoney), operationController.Withdraw)
+			rooms.POST("/:room_id/force-transfer", limitByUser(services.RateScopeMoney), operationController.ForceTransfer)
+			rooms.POST("/:room_id/niuniu-bet", limitByUser(services.RateScopeMoney), operationController.NiuniuBet)
 			rooms.GET("/:room_id/operations", operationController.GetOperations)
 			rooms.GET("/:room_id/history-amounts", operationController.GetHistoryAmounts)
+			rooms.GET("/:room_id/timeline", operationController.GetTimeline)
+			rooms.GET("/:room_id/recap", recapController.GetRecap)
+			rooms.GET("/:room_id/events", wsController.HandleEventStream)
+
+			rooms.GET("/:room_id/messages", chatController.GetMessages)
+			rooms.POST("/:room_id/messages", chatController.SendMessage)
+			rooms.DELETE("/:room_id/messages/:message_id", chatController.DeleteMessage)
+			rooms.POST("/:room_id/operations/:operation_id/reactions", chatController.React)
 
 			rooms.POST("/:room_id/settlement/initiate", settlementController.InitiateSettlement)
 			rooms.POST("/:room_id/settlement/confirm", settlementController.ConfirmSettlement)
 		}
 
-		records := api.Group("/records", middlewares.AuthMiddleware(cfg.Session.CookieName))
+		guests := api.Group("/guests", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService))
+		{
+			guests.GET("", guestController.ListGuests)
+			guests.POST("/claim", limitByUser(services.RateScopeJoin), guestController.ClaimGuest)
+			guests.POST("/:guest_id/claim-code", guestController.CreateClaimCode)
+		}
+
+		records := api.Group("/records", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService))
 		{
 			records.GET("/tonight", recordController.GetTonightRecords)
+			records.GET("/tonight/timeline", recordController.GetNightTimeline)
+			records.GET("/me/stats", recordController.GetMyStats)
+			records.GET("/me/analytics", analyticsController.GetMyAnalytics)
+			records.GET("/head-to-head", recordController.GetHeadToHead)
 		}
 
-		admin := api.Group("/admin", middlewares.AuthMiddleware(cfg.Session.CookieName), middlewares.AdminMiddleware())
+		clubs := api.Group("/clubs", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService))
+		{
+			clubs.POST("", clubController.CreateClub)
+			clubs.GET("", clubController.GetMyClubs)
+			clubs.POST("/join", clubController.JoinClub)
+			clubs.GET("/:club_id", clubController.GetClubDetails)
+			clubs.POST("/:club_id/leave", clubController.LeaveClub)
+			clubs.DELETE("/:club_id/members/:user_id", clubController.RemoveMember)
+			clubs.PUT("/:club_id/members/:user_id/role", clubController.SetMemberRole)
+			clubs.POST("/:club_id/invite-code", clubController.RegenerateInviteCode)
+			clubs.PUT("/:club_id/settings", clubController.UpdateSettings)
+			clubs.GET("/:club_id/rooms", clubController.GetClubRooms)
+			clubs.GET("/:club_id/leaderboard", clubController.GetLeaderboard)
+			clubs.GET("/:club_id/ledger", clubController.GetLedger)
+			clubs.POST("/:club_id/ledger/payments", clubController.RecordPayment)
+		}
+
+		seasons := api.Group("/seasons", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService))
+		{
+			seasons.POST("", seasonController.CreateSeason)
+			seasons.GET("", seasonController.ListSeasons)
+			seasons.GET("/:season_id/leaderboard", seasonController.GetLeaderboard)
+		}
+
+		api.GET("/achievements", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), achievementController.GetAchievements)
+
+		admin := api.Group("/admin", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), middlewares.AdminMiddleware(cfg.TwoFactor.RequireForAdmins))
 		{
 			admin.GET("/users", adminController.GetUsers)
 			admin.PUT("/users/:user_id", adminController.UpdateUser)
+			admin.POST("/users/merge", adminController.MergeUsers)
 			admin.GET("/rooms", adminController.GetRooms)
 			admin.GET("/rooms/:room_id", adminController.GetRoomDetails)
 			admin.GET("/users/:user_id/settlements", adminController.GetUserSettlements)
 			admin.GET("/room-member-history", adminController.GetRoomMemberHistory)
+			admin.GET("/lockouts", lockoutController.GetLockouts)
+			admin.DELETE("/lockouts/:phone", lockoutController.Unlock)
 		}
 
-		api.GET("/ws/room/:room_id", middlewares.AuthMiddleware(cfg.Session.CookieName), wsController.HandleWebSocket)
+		api.GET("/ws/schema", wsController.GetSchema)
+		api.POST("/ws/ticket", middlewares.AuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), wsController.IssueTicket)
+		api.GET("/ws/room/:room_id", middlewares.WebSocketAuthMiddleware(cfg.Session.CookieName, authService, apiTokenService), wsController.HandleWebSocket)
 	}
 
 	engine.GET("/ping", func(c *gin.Context) {
@@ -0,0 +1,29 @@
+// wsschema 生成房间事件消息的JSON Schema文档，供前端生成类型与校验使用
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	ws "poker_score_backend/websocket"
+)
+
+func main() {
+	output := flag.String("o", "", "输出文件路径（为空时输出到标准输出）")
+	flag.Parse()
+
+	data, err := ws.JSONSchemaDocument()
+	if err != nil {
+		log.Fatalf("生成JSON Schema失败: %v", err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		log.Fatalf("写入JSON Schema失败: %v", err)
+	}
+}
@@ -9,9 +9,18 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Session  SessionConfig
+	Env       string // 运行环境，production 表示生产环境
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Session   SessionConfig
+	Presence  PresenceConfig
+	Chat      ChatConfig
+	Season    SeasonConfig
+	SMS       SMSConfig
+	RateLimit RateLimitConfig
+	TwoFactor TwoFactorConfig
+	Admin     AdminConfig
+	OIDC      OIDCConfig
 }
 
 // ServerConfig 服务器配置
@@ -23,6 +32,7 @@ type ServerConfig struct {
 	CookieDomain   string        // Cookie域名
 	CookieSecure   bool          // Cookie是否只通过HTTPS传输
 	CookieSameSite string        // Cookie的SameSite属性
+	TrustedProxies []string      // 信任其 X-Forwarded-For 的反向代理地址或网段，为空时只使用连接的对端地址
 }
 
 // DatabaseConfig 数据库配置
@@ -35,8 +45,90 @@ type DatabaseConfig struct {
 
 // SessionConfig Session配置
 type SessionConfig struct {
-	CookieName string        // Session Cookie名称
-	MaxAge     time.Duration // Session有效期
+	CookieName  string        // Session Cookie名称
+	MaxAge      time.Duration // Session最长有效期（从登录起算）
+	IdleTimeout time.Duration // Session闲置超时，每次使用后顺延，0表示不限制
+	WSTicketTTL time.Duration // WebSocket连接票据有效期
+}
+
+// PresenceConfig 在线状态配置
+type PresenceConfig struct {
+	AwayAfter    time.Duration // 所有连接断开多久后标记为暂离
+	OfflineAfter time.Duration // 所有连接断开多久后标记为离线
+}
+
+// ChatConfig 房间聊天配置
+type ChatConfig struct {
+	RateLimit  int           // 每个用户在窗口期内最多发送的消息数
+	RateWindow time.Duration // 限流窗口
+	MaxLength  int           // 单条文本消息的最大字符数
+}
+
+// SeasonConfig 赛季配置
+type SeasonConfig struct {
+	SnapshotHour int // 每天保存赛季排名快照的时刻（服务器时区，0-23点）
+}
+
+// SMSConfig 短信验证码配置
+type SMSConfig struct {
+	CodeTTL        time.Duration // 验证码有效期
+	MaxAttempts    int           // 每个验证码允许验证失败的次数
+	ResendInterval time.Duration // 同一手机号两次发送验证码的最小间隔
+	LogFile        string        // 默认短信发送器写入验证码的日志文件，为空时输出到控制台
+}
+
+// RateLimit 令牌桶规则：桶容量为 Burst，每个 Period 匀速补满；Burst 为0表示不限流
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// RateLimitConfig 限流与登录保护配置
+type RateLimitConfig struct {
+	Login            RateLimit     // 登录，按IP
+	Register         RateLimit     // 注册，按IP
+	Join             RateLimit     // 通过房间号加入房间，按用户
+	Money            RateLimit     // 下注、收回、强制转移等积分操作，按用户
+	PasswordReset    RateLimit     // 发送找回密码验证码与重置密码，按IP
+	LockoutThreshold int           // 同一手机号连续登录失败多少次后锁定，0表示不锁定
+	LockoutBase      time.Duration // 首次锁定时长，之后每次锁定翻倍
+	LockoutMax       time.Duration // 最长锁定时长
+	LockoutReset     time.Duration // 距最近一次失败超过该时长后重新计算失败次数与锁定等级
+}
+
+// TwoFactorConfig 两步验证配置
+type TwoFactorConfig struct {
+	Issuer           string        // 验证器应用中显示的服务名称
+	RequireForAdmins bool          // 管理员必须通过两步验证登录才能访问后台接口
+	ChallengeTTL     time.Duration // 密码校验通过后完成第二步的时限
+}
+
+// AdminConfig 首次启动时创建管理员账户的配置
+// 手机号与密码都设置且系统中还没有管理员时自动创建，之后可以从环境中移除
+type AdminConfig struct {
+	Phone    string // 管理员手机号
+	Nickname string // 管理员昵称
+	Password string // 管理员初始密码
+}
+
+// OIDCConfig OpenID Connect 单点登录配置，Issuer 与 ClientID 都设置时启用
+type OIDCConfig struct {
+	Issuer               string        // 身份提供方地址，从 <Issuer>/.well-known/openid-configuration 读取端点
+	ClientID             string        // 在身份提供方登记的客户端ID
+	ClientSecret         string        // 客户端密钥，公开客户端可以为空（仅使用PKCE）
+	RedirectURL          string        // 回调地址，需与身份提供方登记的一致，指向 /api/auth/oidc/callback
+	Scopes               []string      // 申请的 scope，始终包含 openid
+	ProviderName         string        // 登录按钮上显示的名称
+	PhoneClaim           string        // 用于匹配已有账户手机号的 claim
+	RequireVerifiedPhone bool          // 只有 <PhoneClaim>_verified 为 true 时才按手机号匹配或创建账户
+	AllowSignup          bool          // 没有匹配的账户时自动创建新账户
+	FrontendURL          string        // 登录完成后跳转的前端地址，跳转路径拼接在其后
+	StateTTL             time.Duration // 跳转到身份提供方后完成登录的时限
+}
+
+// IsProduction 是否为生产环境
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
 }
 
 // GetConfig 获取配置
@@ -59,6 +151,7 @@ func GetConfig() *Config {
 	port := normalizePort(getEnv("SERVER_PORT", ":8080"))
 
 	return &Config{
+		Env: env,
 		Server: ServerConfig{
 			Port:           port,
 			ReadTimeout:    getEnvAsDuration("SERVER_READ_TIMEOUT", 10*time.Second),
@@ -67,6 +160,7 @@ func GetConfig() *Config {
 			CookieDomain:   cookieDomain,
 			CookieSecure:   cookieSecure,
 			CookieSameSite: cookieSameSite,
+			TrustedProxies: getEnvAsList("SERVER_TRUSTED_PROXIES", nil),
 		},
 		Database: DatabaseConfig{
 			Path:            getEnv("DATABASE_PATH", "./database.db"),
@@ -75,8 +169,62 @@ func GetConfig() *Config {
 			ConnMaxLifetime: getEnvAsDuration("DATABASE_CONN_MAX_LIFETIME", time.Hour),
 		},
 		Session: SessionConfig{
-			CookieName: getEnv("SESSION_COOKIE_NAME", "poker_session"),
-			MaxAge:     getEnvAsDuration("SESSION_MAX_AGE", 3650*24*time.Hour),
+			CookieName:  getEnv("SESSION_COOKIE_NAME", "poker_session"),
+			MaxAge:      getEnvAsDuration("SESSION_MAX_AGE", 3650*24*time.Hour),
+			IdleTimeout: getEnvAsDuration("SESSION_IDLE_TIMEOUT", 30*24*time.Hour),
+			WSTicketTTL: getEnvAsDuration("SESSION_WS_TICKET_TTL", 30*time.Second),
+		},
+		Presence: PresenceConfig{
+			AwayAfter:    getEnvAsDuration("PRESENCE_AWAY_AFTER", 5*time.Second),
+			OfflineAfter: getEnvAsDuration("PRESENCE_OFFLINE_AFTER", time.Minute),
+		},
+		Chat: ChatConfig{
+			RateLimit:  getEnvAsInt("CHAT_RATE_LIMIT", 5),
+			RateWindow: getEnvAsDuration("CHAT_RATE_WINDOW", 10*time.Second),
+			MaxLength:  getEnvAsInt("CHAT_MAX_LENGTH", 500),
+		},
+		Season: SeasonConfig{
+			SnapshotHour: normalizeHour(getEnvAsInt("SEASON_SNAPSHOT_HOUR", 7), 7),
+		},
+		SMS: SMSConfig{
+			CodeTTL:        getEnvAsDuration("SMS_CODE_TTL", 10*time.Minute),
+			MaxAttempts:    getEnvAsInt("SMS_CODE_MAX_ATTEMPTS", 5),
+			ResendInterval: getEnvAsDuration("SMS_RESEND_INTERVAL", time.Minute),
+			LogFile:        getEnv("SMS_LOG_FILE", ""),
+		},
+		RateLimit: RateLimitConfig{
+			Login:            getEnvAsRateLimit("RATE_LIMIT_LOGIN", RateLimit{Burst: 10, Period: time.Minute}),
+			Register:         getEnvAsRateLimit("RATE_LIMIT_REGISTER", RateLimit{Burst: 5, Period: time.Hour}),
+			Join:             getEnvAsRateLimit("RATE_LIMIT_JOIN", RateLimit{Burst: 20, Period: time.Minute}),
+			Money:            getEnvAsRateLimit("RATE_LIMIT_MONEY", RateLimit{Burst: 60, Period: time.Minute}),
+			PasswordReset:    getEnvAsRateLimit("RATE_LIMIT_PASSWORD_RESET", RateLimit{Burst: 10, Period: time.Hour}),
+			LockoutThreshold: getEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutBase:      getEnvAsDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+			LockoutMax:       getEnvAsDuration("LOGIN_LOCKOUT_MAX", 24*time.Hour),
+			LockoutReset:     getEnvAsDuration("LOGIN_LOCKOUT_RESET", 24*time.Hour),
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:           getEnv("TWO_FACTOR_ISSUER", "PokerScore"),
+			RequireForAdmins: getEnvAsBool("TWO_FACTOR_REQUIRE_ADMIN", false),
+			ChallengeTTL:     getEnvAsDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		},
+		Admin: AdminConfig{
+			Phone:    getEnv("ADMIN_PHONE", ""),
+			Nickname: getEnv("ADMIN_NICKNAME", ""),
+			Password: getEnv("ADMIN_PASSWORD", ""),
+		},
+		OIDC: OIDCConfig{
+			Issuer:               strings.TrimRight(getEnv("OIDC_ISSUER", ""), "/"),
+			ClientID:             getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:         getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:          getEnv("OIDC_REDIRECT_URL", ""),
+			Scopes:               getEnvAsList("OIDC_SCOPES", []string{"openid", "profile", "email", "phone"}),
+			ProviderName:         getEnv("OIDC_PROVIDER_NAME", "企业账号"),
+			PhoneClaim:           getEnv("OIDC_PHONE_CLAIM", "phone_number"),
+			RequireVerifiedPhone: getEnvAsBool("OIDC_REQUIRE_VERIFIED_PHONE", true),
+			AllowSignup:          getEnvAsBool("OIDC_ALLOW_SIGNUP", false),
+			FrontendURL:          strings.TrimRight(getEnv("OIDC_FRONTEND_URL", ""), "/"),
+			StateTTL:             getEnvAsDuration("OIDC_STATE_TTL", 10*time.Minute),
 		},
 	}
 }
@@ -118,6 +266,31 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// getEnvAsRateLimit 解析“次数/时长”格式的限流规则，例如 10/1m；设为 0 或 off 表示不限流
+func getEnvAsRateLimit(key string, defaultValue RateLimit) RateLimit {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+	if value == "0" || strings.EqualFold(value, "off") {
+		return RateLimit{}
+	}
+
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return defaultValue
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || burst < 0 {
+		return defaultValue
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return defaultValue
+	}
+	return RateLimit{Burst: burst, Period: period}
+}
+
 func getEnvAsList(key string, defaultValues []string) []string {
 	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
 		parts := strings.Split(value, ",")
@@ -157,3 +330,11 @@ func normalizeSameSite(value string) string {
 		return "Lax"
 	}
 }
+
+// normalizeHour 校验小时配置，超出0-23时使用默认值
+func normalizeHour(hour, defaultValue int) int {
+	if hour < 0 || hour > 23 {
+		return defaultValue
+	}
+	return hour
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportData 下载服务器保存的个人数据
+// format 可选 json（默认，单个 JSON 文件）或 csv（每张表一个 CSV 文件的 zip 压缩包）
+func (ctrl *AuthController) ExportData(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		utils.BadRequest(c, "format 必须是 json 或 csv")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	export, err := ctrl.accountService.ExportUserData(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "导出个人数据失败")
+		return
+	}
+
+	var (
+		content     []byte
+		contentType string
+		extension   string
+	)
+	if format == "csv" {
+		content, err = services.RenderExportCSVArchive(export)
+		contentType, extension = "application/zip", "zip"
+	} else {
+		content, err = services.RenderExportJSON(export)
+		contentType, extension = "application/json; charset=utf-8", "json"
+	}
+	if err != nil {
+		utils.InternalServerError(c, "导出个人数据失败")
+		return
+	}
+
+	filename := fmt.Sprintf("poker-score-export-%d-%s.%s", export.UserID, export.ExportedAt.Format("20060102"), extension)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// DeleteAccountRequest 注销账户请求
+type DeleteAccountRequest struct {
+	Password      string `json:"password" binding:"required"`
+	TwoFactorCode string `json:"two_factor_code"` // 开启两步验证时必填，验证码或恢复码
+}
+
+// DeleteAccount 注销当前账户，成功后所有设备退出登录
+func (ctrl *AuthController) DeleteAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.accountService.DeleteAccount(userID.(uint), req.Password, req.TwoFactorCode); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	ctrl.clearSessionCookie(c)
+
+	utils.SuccessWithMessage(c, "账户已注销", nil)
+}
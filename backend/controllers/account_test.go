@@ -0,0 +1,98 @@
+package controllers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccount_ExportAndDelete(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	user := registerUser(t, testutil.NewAPIClient(engine), "导出用户")
+	roomID, _ := createRoom(t, user, "texas")
+
+	resp, err := user.Client.Do(http.MethodGet, "/api/auth/export", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Header().Get("Content-Disposition"), ".json")
+
+	var document struct {
+		Profile struct {
+			Phone    string `json:"phone"`
+			Nickname string `json:"nickname"`
+		} `json:"profile"`
+		Rooms    []json.RawMessage `json:"rooms"`
+		Sessions []json.RawMessage `json:"sessions"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &document))
+	require.Equal(t, user.Phone, document.Profile.Phone)
+	require.Len(t, document.Rooms, 1)
+	require.NotEmpty(t, document.Sessions)
+
+	resp, err = user.Client.Do(http.MethodGet, "/api/auth/export?format=csv", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Equal(t, "application/zip", resp.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(resp.Body.Bytes()), int64(resp.Body.Len()))
+	require.NoError(t, err)
+	names := make([]string, 0, len(archive.File))
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	require.Contains(t, names, "profile.csv")
+	require.Contains(t, names, "settlements.csv")
+
+	resp, err = user.Client.Do(http.MethodGet, "/api/auth/export?format=xml", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// 房间还在进行中，不能注销
+	resp, err = user.Client.Do(http.MethodDelete, "/api/auth/account", map[string]string{"password": testUserPassword})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "您还在进行中的房间里，请在房间解散后再注销账户", body.Message)
+
+	resp, err = user.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/dissolve", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp, err = user.Client.Do(http.MethodDelete, "/api/auth/account", map[string]string{"password": testUserPassword})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "账户已注销", body.Message)
+
+	resp, err = user.Client.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp, err = testutil.NewAPIClient(engine).Do(http.MethodPost, "/api/auth/login", map[string]string{
+		"phone": user.Phone, "password": testUserPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "手机号或密码错误", body.Message)
+
+	// 注销后手机号可以重新注册
+	resp, err = testutil.NewAPIClient(engine).Do(http.MethodPost, "/api/auth/register", map[string]string{
+		"phone": user.Phone, "nickname": "新用户", "password": testUserPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotContains(t, resp.Body.String(), "已注销用户")
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AchievementController 成就控制器
+type AchievementController struct {
+	achievementService *services.AchievementService
+}
+
+// NewAchievementController 创建成就控制器
+func NewAchievementController(achievementService *services.AchievementService) *AchievementController {
+	return &AchievementController{
+		achievementService: achievementService,
+	}
+}
+
+// GetAchievements 获取用户的成就徽章，user_id 默认当前用户，可用于查看他人主页
+func (ctrl *AchievementController) GetAchievements(c *gin.Context) {
+	currentUserID, _ := c.Get("user_id")
+	userID := currentUserID.(uint)
+
+	if value := c.Query("user_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "用户ID格式错误")
+			return
+		}
+		userID = uint(parsed)
+	}
+
+	achievements, err := ctrl.achievementService.GetUserAchievements(userID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, achievements)
+}
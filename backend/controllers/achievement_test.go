@@ -0,0 +1,78 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAchievements_AnnouncedAfterSettlement(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "成就房主")
+	member := registerUser(t, testutil.NewAPIClient(engine), "成就成员")
+
+	roomID, roomCode := createRoom(t, owner, "texas")
+	resp, err := member.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	conn, _, err := dialRoom(server, roomID, "ticket="+issueWSTicket(t, member, roomID), "")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	readUntil(t, conn, "hello")
+
+	resp, err = member.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 100})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/withdraw", roomID), map[string]int{"amount": 0})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/settlement/initiate", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/settlement/confirm", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 房主赢了第一场，房间内播报“首胜”
+	earned := readUntil(t, conn, "achievement_earned")
+	require.EqualValues(t, owner.UserID, earned.Data["user_id"])
+	require.Equal(t, "first_win", earned.Data["code"])
+	require.Equal(t, "首胜", earned.Data["name"])
+
+	resp, err = member.Client.Do(http.MethodGet, fmt.Sprintf("/api/achievements?user_id=%d", owner.UserID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var profile struct {
+		Data struct {
+			EarnedCount  int `json:"earned_count"`
+			Achievements []struct {
+				Code   string `json:"code"`
+				Earned bool   `json:"earned"`
+				RoomID uint   `json:"room_id"`
+			} `json:"achievements"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &profile)
+	require.Equal(t, 1, profile.Data.EarnedCount)
+	require.Equal(t, "first_win", profile.Data.Achievements[0].Code)
+	require.True(t, profile.Data.Achievements[0].Earned)
+	require.Equal(t, roomID, profile.Data.Achievements[0].RoomID)
+	require.False(t, profile.Data.Achievements[1].Earned)
+
+	resp, err = member.Client.Do(http.MethodGet, "/api/achievements", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	decodeResponse(t, resp, &profile)
+	require.Equal(t, 0, profile.Data.EarnedCount)
+}
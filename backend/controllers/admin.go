@@ -111,6 +111,42 @@ func (ctrl *AdminController) UpdateUser(c *gin.Context) {
 	})
 }
 
+// MergeUsersRequest 合并账户请求体
+type MergeUsersRequest struct {
+	SourceUserID uint `json:"source_user_id" binding:"required"` // 被合并并删除的账户
+	TargetUserID uint `json:"target_user_id" binding:"required"` // 保留的账户
+}
+
+// MergeUsers 把一个账户的全部历史记录合并到另一个账户
+func (ctrl *AdminController) MergeUsers(c *gin.Context) {
+	var req MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请求参数错误")
+		return
+	}
+
+	result, err := ctrl.adminService.MergeUsers(req.SourceUserID, req.TargetUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.NotFound(c, "用户不存在")
+		case errors.Is(err, services.ErrMergeSameUser):
+			utils.BadRequest(c, "不能把账户合并到自身")
+		case errors.Is(err, services.ErrMergeAdmin):
+			utils.BadRequest(c, "不能合并管理员账户，请先取消其管理员角色")
+		case errors.Is(err, services.ErrMergeGuestTarget):
+			utils.BadRequest(c, "不能合并到游客账户")
+		case errors.Is(err, services.ErrMergeActiveRoom):
+			utils.Conflict(c, "被合并的账户仍在进行中的房间里，请在房间解散后再合并")
+		default:
+			utils.InternalServerError(c, "合并账户失败")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "账户合并成功", result)
+}
+
 // GetRooms 获取房间列表
 func (ctrl *AdminController) GetRooms(c *gin.Context) {
 	// 获取参数
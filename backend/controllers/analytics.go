@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsController 风险分析控制器
+type AnalyticsController struct {
+	analyticsService *services.AnalyticsService
+	recordService    *services.RecordService
+}
+
+// NewAnalyticsController 创建风险分析控制器
+func NewAnalyticsController(analyticsService *services.AnalyticsService, recordService *services.RecordService) *AnalyticsController {
+	return &AnalyticsController{
+		analyticsService: analyticsService,
+		recordService:    recordService,
+	}
+}
+
+// GetMyAnalytics 获取当前用户的波动与风险分析，筛选参数与生涯战绩相同
+func (ctrl *AnalyticsController) GetMyAnalytics(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	filter, ok := parseStatsFilter(c, ctrl.recordService.GetDayBoundary(userID.(uint)))
+	if !ok {
+		return
+	}
+
+	analytics, err := ctrl.analyticsService.GetPlayerAnalytics(userID.(uint), filter)
+	if err != nil {
+		utils.InternalServerError(c, "查询风险分析失败")
+		return
+	}
+
+	utils.Success(c, analytics)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPITokenValidDays 令牌有效期上限（天）
+const maxAPITokenValidDays = 3650
+
+// APITokenController 个人API令牌控制器
+type APITokenController struct {
+	apiTokenService *services.APITokenService
+}
+
+// NewAPITokenController 创建API令牌控制器
+func NewAPITokenController(apiTokenService *services.APITokenService) *APITokenController {
+	return &APITokenController{
+		apiTokenService: apiTokenService,
+	}
+}
+
+// ListTokens 列出当前用户的API令牌
+func (ctrl *APITokenController) ListTokens(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	tokens, err := ctrl.apiTokenService.List(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "获取令牌列表失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"tokens":           tokens,
+		"available_scopes": services.APITokenScopes,
+	})
+}
+
+// CreateAPITokenRequest 创建API令牌请求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=50"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"` // 0 表示长期有效
+}
+
+// CreateToken 创建API令牌，明文令牌只在本次响应中返回
+func (ctrl *APITokenController) CreateToken(c *gin.Context) {
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if req.ExpiresInDays > maxAPITokenValidDays {
+		utils.BadRequest(c, "有效期最长为"+strconv.Itoa(maxAPITokenValidDays)+"天")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	raw, token, err := ctrl.apiTokenService.Create(userID.(uint), req.Name, req.Scopes, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "令牌已创建，请立即保存，关闭后将无法再次查看", gin.H{
+		"token":     raw,
+		"api_token": token,
+	})
+}
+
+// RevokeToken 撤销API令牌
+func (ctrl *APITokenController) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "令牌ID格式错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.apiTokenService.Revoke(userID.(uint), uint(id)); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "令牌已撤销", nil)
+}
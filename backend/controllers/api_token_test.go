@@ -0,0 +1,144 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func createAPIToken(t *testing.T, client *testutil.APIClient, name string, scopes []string) (uint, string) {
+	t.Helper()
+
+	resp, err := client.Do(http.MethodPost, "/api/auth/tokens", map[string]interface{}{
+		"name":   name,
+		"scopes": scopes,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var body struct {
+		Data struct {
+			Token    string `json:"token"`
+			APIToken struct {
+				ID     uint     `json:"id"`
+				Prefix string   `json:"prefix"`
+				Scopes []string `json:"scopes"`
+			} `json:"api_token"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Regexp(t, `^pst_[0-9a-f]{48}$`, body.Data.Token)
+	require.Equal(t, body.Data.Token[:8], body.Data.APIToken.Prefix)
+	require.ElementsMatch(t, scopes, body.Data.APIToken.Scopes)
+	return body.Data.APIToken.ID, body.Data.Token
+}
+
+func TestAPIToken_ScopesListAndRevoke(t *testing.T) {
+	engine, client := newTestEnv(t)
+	owner := registerUser(t, client, "Owner")
+	roomID, _ := createRoom(t, owner, "texas")
+
+	resp, err := owner.Client.Do(http.MethodPost, "/api/auth/tokens", map[string]interface{}{
+		"name":   "bad",
+		"scopes": []string{"admin"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	readID, readToken := createAPIToken(t, owner.Client, "results-bot", []string{"records:read", "rooms:read"})
+	_, operateToken := createAPIToken(t, owner.Client, "bet-bot", []string{"rooms:operate"})
+
+	bot := testutil.NewAPIClient(engine)
+	bot.SetAuthorization("Bearer " + readToken)
+	requireStatus(t, bot, http.MethodGet, "/api/auth/me", http.StatusOK)
+	requireStatus(t, bot, http.MethodGet, "/api/records/me/stats", http.StatusOK)
+	requireStatus(t, bot, http.MethodGet, fmt.Sprintf("/api/rooms/%d", roomID), http.StatusOK)
+
+	// 权限范围之外的操作以及只接受Session的接口都会被拒绝
+	resp, err = bot.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 100})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	var denied struct {
+		Data struct {
+			RequiredScope string `json:"required_scope"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &denied)
+	require.Equal(t, "rooms:operate", denied.Data.RequiredScope)
+
+	requireStatus(t, bot, http.MethodGet, "/api/auth/tokens", http.StatusForbidden)
+	requireStatus(t, bot, http.MethodPost, "/api/rooms", http.StatusForbidden)
+
+	operator := testutil.NewAPIClient(engine)
+	operator.SetAuthorization("Bearer " + operateToken)
+	resp, err = operator.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 100})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	requireStatus(t, operator, http.MethodGet, "/api/records/me/stats", http.StatusForbidden)
+
+	// 列表不包含明文，记录了最近使用时间
+	resp, err = owner.Client.Do(http.MethodGet, "/api/auth/tokens", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NotContains(t, resp.Body.String(), readToken)
+	var list struct {
+		Data struct {
+			Tokens []struct {
+				ID         uint    `json:"id"`
+				Name       string  `json:"name"`
+				LastUsedAt *string `json:"last_used_at"`
+			} `json:"tokens"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &list)
+	require.Len(t, list.Data.Tokens, 2)
+	require.Equal(t, "results-bot", list.Data.Tokens[1].Name)
+	require.NotNil(t, list.Data.Tokens[1].LastUsedAt)
+
+	resp, err = owner.Client.Do(http.MethodDelete, fmt.Sprintf("/api/auth/tokens/%d", readID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	requireStatus(t, bot, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+
+	resp, err = owner.Client.Do(http.MethodDelete, fmt.Sprintf("/api/auth/tokens/%d", readID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestAPIToken_WebSocketRequiresOperateScope(t *testing.T) {
+	engine, client := newTestEnv(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	owner := registerUser(t, client, "Owner")
+	roomID, _ := createRoom(t, owner, "texas")
+	_, readToken := createAPIToken(t, owner.Client, "watch-bot", []string{"rooms:read"})
+	_, operateToken := createAPIToken(t, owner.Client, "chat-bot", []string{"rooms:operate"})
+
+	wsURL := fmt.Sprintf("ws%s/api/ws/room/%d", strings.TrimPrefix(server.URL, "http"), roomID)
+
+	// 只读令牌无法建立WebSocket连接，也就无法通过连接发送聊天
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + readToken}})
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + operateToken}})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	readUntil(t, conn, "hello")
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"type": "chat_message",
+		"data": map[string]string{"content": "机器人播报"},
+	}))
+	chat := readUntil(t, conn, "chat_message")
+	require.Equal(t, "机器人播报", chat.Data["content"])
+}
@@ -1,8 +1,13 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,15 +21,23 @@ import (
 
 // AuthController 认证控制器
 type AuthController struct {
-	authService *services.AuthService
-	config      *config.Config
+	authService       *services.AuthService
+	loginGuardService *services.LoginGuardService
+	twoFactorService  *services.TwoFactorService
+	accountService    *services.AccountService
+	oidcService       *services.OIDCService
+	config            *config.Config
 }
 
 // NewAuthController 创建认证控制器
-func NewAuthController(authService *services.AuthService, cfg *config.Config) *AuthController {
+func NewAuthController(authService *services.AuthService, loginGuardService *services.LoginGuardService, twoFactorService *services.TwoFactorService, accountService *services.AccountService, oidcService *services.OIDCService, cfg *config.Config) *AuthController {
 	return &AuthController{
-		authService: authService,
-		config:      cfg,
+		authService:       authService,
+		loginGuardService: loginGuardService,
+		twoFactorService:  twoFactorService,
+		accountService:    accountService,
+		oidcService:       oidcService,
+		config:            cfg,
 	}
 }
 
@@ -44,7 +57,7 @@ func (ctrl *AuthController) Register(c *gin.Context) {
 	}
 
 	// 调用服务层注册
-	user, session, err := ctrl.authService.Register(req.Phone, req.Nickname, req.Password)
+	user, session, err := ctrl.authService.Register(req.Phone, req.Nickname, req.Password, sessionClient(c))
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
@@ -80,13 +93,118 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	// 调用服务层登录
-	user, session, err := ctrl.authService.Login(req.Phone, req.Password)
+	// 手机号被锁定时不再校验密码
+	lockedFor, err := ctrl.loginGuardService.LockedFor(req.Phone)
 	if err != nil {
+		utils.InternalServerError(c, "登录失败")
+		return
+	}
+	if lockedFor > 0 {
+		utils.TooManyRequests(c, lockoutMessage(lockedFor), lockedFor)
+		return
+	}
+
+	// 校验密码
+	client := sessionClient(c)
+	user, err := ctrl.authService.Authenticate(req.Phone, req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			if lockedFor, _ := ctrl.loginGuardService.RecordFailure(req.Phone, client.IP); lockedFor > 0 {
+				utils.TooManyRequests(c, lockoutMessage(lockedFor), lockedFor)
+				return
+			}
+		}
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
+	if err := ctrl.loginGuardService.RecordSuccess(req.Phone); err != nil {
+		log.Printf("清除登录失败记录失败: Phone=%s, %v", req.Phone, err)
+	}
+
+	// 开启了两步验证：先不创建Session，返回第二步使用的令牌
+	enabled, err := ctrl.twoFactorService.IsEnabled(user.ID)
+	if err != nil {
+		utils.InternalServerError(c, "登录失败")
+		return
+	}
+	if enabled {
+		challenge, err := ctrl.twoFactorService.CreateChallenge(user.ID)
+		if err != nil {
+			utils.InternalServerError(c, "登录失败")
+			return
+		}
+		utils.SuccessWithMessage(c, "请输入两步验证码", gin.H{
+			"two_factor_required": true,
+			"challenge":           challenge.Token,
+			"expires_at":          challenge.ExpiresAt,
+		})
+		return
+	}
+
+	session, err := ctrl.authService.CreateSession(user.ID, client)
+	if err != nil {
+		utils.InternalServerError(c, "登录失败")
+		return
+	}
+
+	log.Printf("用户登录成功: ID=%d, Phone=%s, Nickname=%s", user.ID, user.Phone, user.Nickname)
+	ctrl.respondLoggedIn(c, user, session)
+}
+
+// LoginTwoFactorRequest 登录第二步请求
+type LoginTwoFactorRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"` // 验证器中的6位验证码或恢复码
+}
+
+// LoginTwoFactor 提交两步验证码完成登录
+func (ctrl *AuthController) LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	pending, err := ctrl.twoFactorService.ChallengeUser(req.Challenge)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	// 验证码错误与密码错误一起计入手机号的失败次数
+	lockedFor, err := ctrl.loginGuardService.LockedFor(pending.Phone)
+	if err != nil {
+		utils.InternalServerError(c, "登录失败")
+		return
+	}
+	if lockedFor > 0 {
+		utils.TooManyRequests(c, lockoutMessage(lockedFor), lockedFor)
+		return
+	}
+
+	client := sessionClient(c)
+	user, session, err := ctrl.twoFactorService.CompleteLogin(req.Challenge, req.Code, client)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) || errors.Is(err, services.ErrTwoFactorTooManyAttempts) {
+			if lockedFor, _ := ctrl.loginGuardService.RecordFailure(pending.Phone, client.IP); lockedFor > 0 {
+				utils.TooManyRequests(c, lockoutMessage(lockedFor), lockedFor)
+				return
+			}
+		}
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := ctrl.loginGuardService.RecordSuccess(user.Phone); err != nil {
+		log.Printf("清除登录失败记录失败: Phone=%s, %v", user.Phone, err)
+	}
+
+	ctrl.respondLoggedIn(c, user, session)
+}
+
+// respondLoggedIn 设置Session Cookie并返回登录成功响应
+func (ctrl *AuthController) respondLoggedIn(c *gin.Context, user *models.User, session *models.Session) {
 	// 设置Session Cookie
 	ctrl.setSessionCookie(c, session.SessionID)
 
@@ -105,33 +223,64 @@ func (ctrl *AuthController) Login(c *gin.Context) {
 
 // Logout 用户登出
 func (ctrl *AuthController) Logout(c *gin.Context) {
-	// 获取Session ID
-	sessionID, err := c.Cookie(ctrl.config.Session.CookieName)
-	if err == nil {
-		// 删除Session
+	// 删除当前请求使用的Session（Cookie或Authorization Header）
+	if sessionID := c.GetString("session_id"); sessionID != "" {
 		ctrl.authService.Logout(sessionID)
 	}
 
-	expiredCookie := &http.Cookie{
-		Name:     ctrl.config.Session.CookieName,
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-		HttpOnly: true,
+	ctrl.clearSessionCookie(c)
+
+	utils.SuccessWithMessage(c, "登出成功", nil)
+}
+
+// LogoutAll 退出所有设备（包括当前设备）
+func (ctrl *AuthController) LogoutAll(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	revoked, err := ctrl.authService.LogoutAll(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "退出登录失败")
+		return
 	}
 
-	secure, sameSite := ctrl.resolveCookieSecurity(c)
-	expiredCookie.Secure = secure
-	expiredCookie.SameSite = sameSite
+	ctrl.clearSessionCookie(c)
 
-	if domain := ctrl.resolveCookieDomain(c); domain != "" {
-		expiredCookie.Domain = domain
+	utils.SuccessWithMessage(c, "已退出所有设备", gin.H{
+		"revoked": revoked,
+	})
+}
+
+// GetSessions 获取当前用户的登录设备列表
+func (ctrl *AuthController) GetSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	sessions, err := ctrl.authService.ListSessions(userID.(uint), c.GetString("session_id"))
+	if err != nil {
+		utils.InternalServerError(c, "获取登录设备失败")
+		return
 	}
 
-	http.SetCookie(c.Writer, expiredCookie)
+	utils.Success(c, gin.H{
+		"sessions": sessions,
+	})
+}
 
-	utils.SuccessWithMessage(c, "登出成功", nil)
+// RevokeSession 注销某一台设备的登录
+func (ctrl *AuthController) RevokeSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "会话ID格式错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.authService.RevokeSession(userID.(uint), uint(id)); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已退出该设备", nil)
 }
 
 // GetMe 获取当前用户信息
@@ -141,11 +290,13 @@ func (ctrl *AuthController) GetMe(c *gin.Context) {
 	user := userInterface.(models.User)
 
 	utils.Success(c, gin.H{
-		"id":         user.ID,
-		"phone":      user.Phone,
-		"nickname":   user.Nickname,
-		"role":       user.Role,
-		"created_at": user.CreatedAt,
+		"id":              user.ID,
+		"phone":           user.Phone,
+		"nickname":        user.Nickname,
+		"role":            user.Role,
+		"timezone":        user.Timezone,
+		"day_cutoff_hour": user.DayCutoffHour,
+		"created_at":      user.CreatedAt,
 	})
 }
 
@@ -177,6 +328,35 @@ func (ctrl *AuthController) UpdateNickname(c *gin.Context) {
 	})
 }
 
+// UpdatePreferencesRequest 修改偏好设置请求
+type UpdatePreferencesRequest struct {
+	Timezone      string `json:"timezone"`
+	DayCutoffHour *int   `json:"day_cutoff_hour" binding:"required"`
+}
+
+// UpdatePreferences 修改统计战绩使用的时区与“一晚”分界时刻
+func (ctrl *AuthController) UpdatePreferences(c *gin.Context) {
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	// 获取用户ID
+	userID, _ := c.Get("user_id")
+
+	err := ctrl.authService.UpdatePreferences(userID.(uint), req.Timezone, *req.DayCutoffHour)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "修改成功", gin.H{
+		"timezone":        req.Timezone,
+		"day_cutoff_hour": *req.DayCutoffHour,
+	})
+}
+
 // UpdatePasswordRequest 修改密码请求
 type UpdatePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
@@ -195,7 +375,8 @@ func (ctrl *AuthController) UpdatePassword(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	// 调用服务层修改密码
-	err := ctrl.authService.UpdatePassword(userID.(uint), req.OldPassword, req.NewPassword)
+	// 修改成功后其他设备的登录会失效，当前设备保持登录
+	err := ctrl.authService.UpdatePassword(userID.(uint), c.GetString("session_id"), req.OldPassword, req.NewPassword)
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
@@ -233,6 +414,45 @@ func (ctrl *AuthController) setSessionCookie(c *gin.Context, sessionID string) {
 	http.SetCookie(c.Writer, cookie)
 }
 
+// clearSessionCookie 清除Session Cookie
+func (ctrl *AuthController) clearSessionCookie(c *gin.Context) {
+	expiredCookie := &http.Cookie{
+		Name:     ctrl.config.Session.CookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+	}
+
+	secure, sameSite := ctrl.resolveCookieSecurity(c)
+	expiredCookie.Secure = secure
+	expiredCookie.SameSite = sameSite
+
+	if domain := ctrl.resolveCookieDomain(c); domain != "" {
+		expiredCookie.Domain = domain
+	}
+
+	http.SetCookie(c.Writer, expiredCookie)
+}
+
+// lockoutMessage 登录锁定的提示，等待时间不足1分钟按秒显示，否则向上取整到分钟
+func lockoutMessage(lockedFor time.Duration) string {
+	wait := fmt.Sprintf("%d分钟", int(math.Ceil(lockedFor.Minutes())))
+	if lockedFor < time.Minute {
+		wait = fmt.Sprintf("%d秒", int(math.Ceil(lockedFor.Seconds())))
+	}
+	return "登录失败次数过多，请" + wait + "后再试"
+}
+
+// sessionClient 提取创建Session时记录的客户端信息
+func sessionClient(c *gin.Context) services.SessionClient {
+	return services.SessionClient{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
 func (ctrl *AuthController) resolveCookieSecurity(c *gin.Context) (bool, http.SameSite) {
 	secure := ctrl.config.Server.CookieSecure
 	if !secure {
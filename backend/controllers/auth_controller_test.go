@@ -318,3 +318,61 @@ func TestGetMe_Unauthorized(t *testing.T) {
 	require.Equal(t, 401, response.Code)
 	require.NotEmpty(t, response.Message)
 }
+
+func TestUpdatePreferences_Success(t *testing.T) {
+	_, client := newTestEnv(t)
+
+	resp, err := client.Do(http.MethodPost, "/api/auth/register", map[string]string{
+		"phone":    uniquePhone(),
+		"nickname": "夜猫子",
+		"password": "123456",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = client.Do(http.MethodPut, "/api/auth/preferences", map[string]interface{}{
+		"timezone":        "Mars/Olympus",
+		"day_cutoff_hour": 6,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = client.Do(http.MethodPut, "/api/auth/preferences", map[string]interface{}{
+		"timezone":        "Asia/Tokyo",
+		"day_cutoff_hour": 0,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = client.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var meResp struct {
+		Data struct {
+			Timezone      string `json:"timezone"`
+			DayCutoffHour int    `json:"day_cutoff_hour"`
+		} `json:"data"`
+	}
+
+	decodeResponse(t, resp, &meResp)
+	require.Equal(t, "Asia/Tokyo", meResp.Data.Timezone)
+	require.Equal(t, 0, meResp.Data.DayCutoffHour)
+
+	resp, err = client.Do(http.MethodGet, "/api/records/tonight", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var tonightResp struct {
+		Data struct {
+			TimeRange struct {
+				Timezone      string `json:"timezone"`
+				DayCutoffHour int    `json:"day_cutoff_hour"`
+			} `json:"time_range"`
+		} `json:"data"`
+	}
+
+	decodeResponse(t, resp, &tonightResp)
+	require.Equal(t, "Asia/Tokyo", tonightResp.Data.TimeRange.Timezone)
+	require.Equal(t, 0, tonightResp.Data.TimeRange.DayCutoffHour)
+}
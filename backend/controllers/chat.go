@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChatController 房间聊天控制器
+type ChatController struct {
+	chatService *services.ChatService
+}
+
+// NewChatController 创建房间聊天控制器
+func NewChatController(chatService *services.ChatService) *ChatController {
+	return &ChatController{
+		chatService: chatService,
+	}
+}
+
+// SendMessageRequest 发送聊天消息请求
+type SendMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ReactRequest 表情回应请求
+type ReactRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// GetMessages 分页获取聊天消息
+func (ctrl *ChatController) GetMessages(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	beforeID, _ := strconv.ParseUint(c.DefaultQuery("before_id", "0"), 10, 32)
+
+	userID, _ := c.Get("user_id")
+
+	messages, hasMore, err := ctrl.chatService.GetMessages(uint(roomID), userID.(uint), uint(beforeID), limit)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"messages": messages,
+		"has_more": hasMore,
+	})
+}
+
+// SendMessage 发送聊天消息（供无法使用WebSocket上行的客户端）
+func (ctrl *ChatController) SendMessage(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	var req SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	message, err := ctrl.chatService.SendMessage(uint(roomID), userID.(uint), req.Content)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "发送成功", message)
+}
+
+// React 对操作记录发送表情回应
+func (ctrl *ChatController) React(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	operationID, err := strconv.ParseUint(c.Param("operation_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "操作记录ID格式错误")
+		return
+	}
+
+	var req ReactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	message, err := ctrl.chatService.React(uint(roomID), userID.(uint), uint(operationID), req.Emoji)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "发送成功", message)
+}
+
+// DeleteMessage 删除聊天消息
+func (ctrl *ChatController) DeleteMessage(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	messageID, err := strconv.ParseUint(c.Param("message_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "消息ID格式错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.chatService.DeleteMessage(uint(roomID), uint(messageID), userID.(uint)); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
@@ -0,0 +1,105 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"poker_score_backend/testutil"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+type wsEnvelope struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
+
+func readUntil(t *testing.T, conn *websocket.Conn, eventType string) wsEnvelope {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	for {
+		_, frame, err := conn.ReadMessage()
+		require.NoError(t, err)
+
+		// 服务端会把排队的多条消息用换行拼在同一帧里发送
+		for _, line := range bytes.Split(frame, []byte{'\n'}) {
+			var msg wsEnvelope
+			require.NoError(t, json.Unmarshal(line, &msg))
+			if msg.Type == eventType {
+				return msg
+			}
+		}
+	}
+}
+
+func TestRoomChat_WebSocketAndREST(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "聊天房主")
+	member := registerUser(t, testutil.NewAPIClient(engine), "聊天成员")
+
+	roomID, roomCode := createRoom(t, owner, "texas")
+	resp, err := member.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	conn, _, err := dialRoom(server, roomID, "ticket="+issueWSTicket(t, member, roomID), "")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	readUntil(t, conn, "hello")
+
+	// 通过WebSocket发送消息，房间内所有连接都会收到广播
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"type": "chat_message",
+		"data": map[string]string{"content": "谁还没转账？"},
+	}))
+	chat := readUntil(t, conn, "chat_message")
+	require.Equal(t, "谁还没转账？", chat.Data["content"])
+	require.Equal(t, "text", chat.Data["message_type"])
+	require.EqualValues(t, member.UserID, chat.Data["user_id"])
+
+	// 处理失败时只向发送者回复error事件
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"type": "reaction",
+		"data": map[string]interface{}{"operation_id": 99999, "emoji": "👍"},
+	}))
+	failure := readUntil(t, conn, "error")
+	require.Equal(t, "reaction", failure.Data["request_type"])
+	require.Equal(t, "操作记录不存在", failure.Data["message"])
+
+	resp, err = member.Client.Do(http.MethodGet, fmt.Sprintf("/api/rooms/%d/messages?limit=10", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var list struct {
+		Code int `json:"code"`
+		Data struct {
+			Messages []struct {
+				ID      uint   `json:"id"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			HasMore bool `json:"has_more"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &list)
+	require.Len(t, list.Data.Messages, 1)
+	require.False(t, list.Data.HasMore)
+
+	// 房主删除消息后广播删除事件
+	resp, err = owner.Client.Do(http.MethodDelete, fmt.Sprintf("/api/rooms/%d/messages/%d", roomID, list.Data.Messages[0].ID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	deleted := readUntil(t, conn, "chat_message_deleted")
+	require.EqualValues(t, list.Data.Messages[0].ID, deleted.Data["message_id"])
+	require.EqualValues(t, owner.UserID, deleted.Data["deleted_by"])
+}
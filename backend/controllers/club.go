@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClubController 俱乐部控制器
+type ClubController struct {
+	clubService *services.ClubService
+}
+
+// NewClubController 创建俱乐部控制器
+func NewClubController(clubService *services.ClubService) *ClubController {
+	return &ClubController{
+		clubService: clubService,
+	}
+}
+
+// CreateClubRequest 创建俱乐部请求
+type CreateClubRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// JoinClubRequest 加入俱乐部请求
+type JoinClubRequest struct {
+	InviteCode string `json:"invite_code" binding:"required"`
+}
+
+// SetClubRoleRequest 设置俱乐部角色请求
+type SetClubRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+// UpdateClubSettingsRequest 修改俱乐部设置请求
+type UpdateClubSettingsRequest struct {
+	Timezone      string `json:"timezone"`
+	DayCutoffHour *int   `json:"day_cutoff_hour" binding:"required"`
+}
+
+// RecordClubPaymentRequest 登记还款请求
+type RecordClubPaymentRequest struct {
+	FromUserID uint    `json:"from_user_id" binding:"required"`
+	ToUserID   uint    `json:"to_user_id" binding:"required"`
+	RmbAmount  float64 `json:"rmb_amount" binding:"required"`
+	Note       string  `json:"note"`
+}
+
+// CreateClub 创建俱乐部
+func (ctrl *ClubController) CreateClub(c *gin.Context) {
+	var req CreateClubRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	club, err := ctrl.clubService.CreateClub(userID.(uint), req.Name, req.Description)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "俱乐部创建成功", gin.H{
+		"club_id":     club.ID,
+		"name":        club.Name,
+		"description": club.Description,
+		"invite_code": club.InviteCode,
+		"created_at":  club.CreatedAt,
+	})
+}
+
+// JoinClub 通过邀请码加入俱乐部
+func (ctrl *ClubController) JoinClub(c *gin.Context) {
+	var req JoinClubRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	club, err := ctrl.clubService.JoinClub(userID.(uint), req.InviteCode)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "加入俱乐部成功", gin.H{
+		"club_id": club.ID,
+		"name":    club.Name,
+	})
+}
+
+// GetMyClubs 获取我加入的俱乐部
+func (ctrl *ClubController) GetMyClubs(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	clubs, err := ctrl.clubService.GetMyClubs(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "查询俱乐部失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"clubs": clubs,
+	})
+}
+
+// GetClubDetails 获取俱乐部详情
+func (ctrl *ClubController) GetClubDetails(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	details, err := ctrl.clubService.GetClubDetails(clubID, userID.(uint))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, details)
+}
+
+// LeaveClub 退出俱乐部
+func (ctrl *ClubController) LeaveClub(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.clubService.LeaveClub(clubID, userID.(uint)); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已退出俱乐部", nil)
+}
+
+// RemoveMember 移除俱乐部成员
+func (ctrl *ClubController) RemoveMember(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "用户ID格式错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.clubService.RemoveMember(clubID, userID.(uint), uint(targetUserID)); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "成员已移除", nil)
+}
+
+// SetMemberRole 设置俱乐部成员角色
+func (ctrl *ClubController) SetMemberRole(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "用户ID格式错误")
+		return
+	}
+
+	var req SetClubRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.clubService.SetMemberRole(clubID, userID.(uint), uint(targetUserID), req.Role); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "角色设置成功", gin.H{
+		"user_id": targetUserID,
+		"role":    req.Role,
+	})
+}
+
+// UpdateSettings 修改俱乐部统计使用的时区与“一晚”分界
+func (ctrl *ClubController) UpdateSettings(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateClubSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.clubService.UpdateSettings(clubID, userID.(uint), req.Timezone, *req.DayCutoffHour); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "设置已保存", gin.H{
+		"timezone":        req.Timezone,
+		"day_cutoff_hour": *req.DayCutoffHour,
+	})
+}
+
+// RegenerateInviteCode 重置俱乐部邀请码
+func (ctrl *ClubController) RegenerateInviteCode(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	inviteCode, err := ctrl.clubService.RegenerateInviteCode(clubID, userID.(uint))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"invite_code": inviteCode,
+	})
+}
+
+// GetClubRooms 获取俱乐部下的房间
+func (ctrl *ClubController) GetClubRooms(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	rooms, err := ctrl.clubService.GetClubRooms(clubID, userID.(uint))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"rooms": rooms,
+	})
+}
+
+// GetLeaderboard 获取俱乐部排行榜，支持与生涯战绩相同的筛选参数
+func (ctrl *ClubController) GetLeaderboard(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	filter, ok := parseStatsFilter(c, ctrl.clubService.GetDayBoundary(clubID))
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	leaderboard, err := ctrl.clubService.GetLeaderboard(clubID, userID.(uint), filter)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"leaderboard": leaderboard,
+	})
+}
+
+// GetLedger 获取俱乐部账本
+func (ctrl *ClubController) GetLedger(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	ledger, err := ctrl.clubService.GetLedger(clubID, userID.(uint))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, ledger)
+}
+
+// RecordPayment 登记成员之间的还款
+func (ctrl *ClubController) RecordPayment(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+
+	var req RecordClubPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	payment, err := ctrl.clubService.RecordPayment(clubID, userID.(uint), req.FromUserID, req.ToUserID, req.RmbAmount, req.Note)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "还款登记成功", payment)
+}
+
+// parseClubID 解析路径中的俱乐部ID，出错时直接写入响应
+func parseClubID(c *gin.Context) (uint, bool) {
+	clubID, err := strconv.ParseUint(c.Param("club_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "俱乐部ID格式错误")
+		return 0, false
+	}
+	return uint(clubID), true
+}
@@ -0,0 +1,104 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClub_InviteAndClubRooms(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "俱乐部创建者")
+	member := registerUser(t, testutil.NewAPIClient(engine), "俱乐部成员")
+	outsider := registerUser(t, testutil.NewAPIClient(engine), "路人")
+
+	resp, err := owner.Client.Do(http.MethodPost, "/api/clubs", map[string]string{"name": "周五德州"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var created struct {
+		Code int `json:"code"`
+		Data struct {
+			ClubID     uint   `json:"club_id"`
+			InviteCode string `json:"invite_code"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &created)
+	require.Equal(t, 0, created.Code)
+	require.NotEmpty(t, created.Data.InviteCode)
+
+	resp, err = member.Client.Do(http.MethodPost, "/api/clubs/join", map[string]string{"invite_code": created.Data.InviteCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// 非成员不能在俱乐部下建房，也看不到俱乐部详情
+	resp, err = outsider.Client.Do(http.MethodPost, "/api/rooms", map[string]interface{}{
+		"room_type": "texas",
+		"chip_rate": "20:1",
+		"club_id":   created.Data.ClubID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = outsider.Client.Do(http.MethodGet, fmt.Sprintf("/api/clubs/%d", created.Data.ClubID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = member.Client.Do(http.MethodPost, "/api/rooms", map[string]interface{}{
+		"room_type": "texas",
+		"chip_rate": "20:1",
+		"club_id":   created.Data.ClubID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var room struct {
+		Data struct {
+			RoomID   uint   `json:"room_id"`
+			RoomCode string `json:"room_code"`
+			ClubID   *uint  `json:"club_id"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &room)
+	require.NotNil(t, room.Data.ClubID)
+	require.Equal(t, created.Data.ClubID, *room.Data.ClubID)
+
+	resp, err = outsider.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": room.Data.RoomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = owner.Client.Do(http.MethodGet, fmt.Sprintf("/api/clubs/%d/rooms", created.Data.ClubID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var rooms struct {
+		Data struct {
+			Rooms []struct {
+				RoomID uint `json:"room_id"`
+			} `json:"rooms"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &rooms)
+	require.Len(t, rooms.Data.Rooms, 1)
+	require.Equal(t, room.Data.RoomID, rooms.Data.Rooms[0].RoomID)
+
+	resp, err = member.Client.Do(http.MethodGet, fmt.Sprintf("/api/clubs/%d/leaderboard", created.Data.ClubID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var board struct {
+		Data struct {
+			Leaderboard []struct {
+				UserID   uint `json:"user_id"`
+				IsMember bool `json:"is_member"`
+			} `json:"leaderboard"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &board)
+	require.Len(t, board.Data.Leaderboard, 2)
+}
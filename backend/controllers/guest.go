@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GuestController 游客控制器
+type GuestController struct {
+	guestService *services.GuestService
+}
+
+// NewGuestController 创建游客控制器
+func NewGuestController(guestService *services.GuestService) *GuestController {
+	return &GuestController{
+		guestService: guestService,
+	}
+}
+
+// AddGuestRequest 添加游客请求，nickname 与 guest_id 二选一
+type AddGuestRequest struct {
+	Nickname string `json:"nickname" binding:"max=50"` // 新游客的昵称
+	GuestID  uint   `json:"guest_id"`                  // 之前添加过的游客ID
+}
+
+// AddGuest 房主向房间添加游客
+func (ctrl *GuestController) AddGuest(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("room_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	var req AddGuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	guest, err := ctrl.guestService.AddGuest(uint(roomID), userID.(uint), req.Nickname, req.GuestID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "游客已加入房间", gin.H{
+		"guest": gin.H{
+			"id":       guest.ID,
+			"nickname": guest.Nickname,
+		},
+	})
+}
+
+// ListGuests 当前用户添加过的游客
+func (ctrl *GuestController) ListGuests(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	guests, err := ctrl.guestService.ListGuests(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "获取游客列表失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"guests": guests,
+	})
+}
+
+// CreateClaimCode 房主为游客生成认领码
+func (ctrl *GuestController) CreateClaimCode(c *gin.Context) {
+	guestID, err := strconv.ParseUint(c.Param("guest_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "游客ID格式错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	claim, err := ctrl.guestService.CreateClaimCode(userID.(uint), uint(guestID))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"guest_id":   claim.GuestID,
+		"code":       claim.Code,
+		"expires_at": claim.ExpiresAt,
+	})
+}
+
+// ClaimGuestRequest 认领游客请求
+type ClaimGuestRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ClaimGuest 凭认领码把游客的战绩合并到当前账户
+func (ctrl *GuestController) ClaimGuest(c *gin.Context) {
+	var req ClaimGuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	guest, err := ctrl.guestService.Claim(userID.(uint), req.Code)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "游客战绩已合并到您的账户", gin.H{
+		"guest_id":       guest.ID,
+		"guest_nickname": guest.Nickname,
+	})
+}
@@ -0,0 +1,129 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuest_HostOperatesForGuestAndGuestIsClaimed(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	host := registerUser(t, testutil.NewAPIClient(engine), "Host")
+	member := registerUser(t, testutil.NewAPIClient(engine), "Member")
+	roomID, roomCode := createRoom(t, host, "texas")
+
+	resp, err := member.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = host.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/guests", roomID), map[string]string{"nickname": "Uncle"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var added struct {
+		Data struct {
+			Guest struct {
+				ID uint `json:"id"`
+			} `json:"guest"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &added)
+	guestID := added.Data.Guest.ID
+	require.NotZero(t, guestID)
+
+	// 只有房主可以代游客操作
+	resp, err = member.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]interface{}{"amount": 50, "guest_id": guestID})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+
+	resp, err = host.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]interface{}{"amount": 50, "guest_id": guestID})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var bet struct {
+		Data struct {
+			UserID    uint `json:"user_id"`
+			MyBalance int  `json:"my_balance"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &bet)
+	require.Equal(t, guestID, bet.Data.UserID)
+	require.Equal(t, -50, bet.Data.MyBalance)
+
+	resp, err = host.Client.Do(http.MethodGet, fmt.Sprintf("/api/rooms/%d", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var details struct {
+		Data struct {
+			MyBalance int `json:"my_balance"`
+			Members   []struct {
+				UserID  uint `json:"user_id"`
+				IsGuest bool `json:"is_guest"`
+				Balance int  `json:"balance"`
+			} `json:"members"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &details)
+	require.Zero(t, details.Data.MyBalance)
+	require.Len(t, details.Data.Members, 3)
+	for _, m := range details.Data.Members {
+		require.Equal(t, m.UserID == guestID, m.IsGuest)
+		if m.IsGuest {
+			require.Equal(t, -50, m.Balance)
+		}
+	}
+
+	resp, err = member.Client.Do(http.MethodPost, fmt.Sprintf("/api/guests/%d/claim-code", guestID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = host.Client.Do(http.MethodPost, fmt.Sprintf("/api/guests/%d/claim-code", guestID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var claim struct {
+		Data struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &claim)
+
+	// 房间还在进行中时不能认领
+	uncle := registerUser(t, testutil.NewAPIClient(engine), "Uncle")
+	resp, err = uncle.Client.Do(http.MethodPost, "/api/guests/claim", map[string]string{"code": claim.Data.Code})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+
+	resp, err = host.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/withdraw", roomID), map[string]int{"amount": 50})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	requireStatus(t, host.Client, http.MethodPost, fmt.Sprintf("/api/rooms/%d/dissolve", roomID), http.StatusOK)
+
+	resp, err = uncle.Client.Do(http.MethodPost, "/api/guests/claim", map[string]string{"code": claim.Data.Code})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	// 认领后房间成员中的游客变成了正式账户
+	resp, err = uncle.Client.Do(http.MethodGet, fmt.Sprintf("/api/rooms/%d", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	decodeResponse(t, resp, &details)
+	memberIDs := make([]uint, 0, len(details.Data.Members))
+	for _, m := range details.Data.Members {
+		require.False(t, m.IsGuest)
+		memberIDs = append(memberIDs, m.UserID)
+	}
+	require.ElementsMatch(t, []uint{host.UserID, member.UserID, uncle.UserID}, memberIDs)
+
+	resp, err = host.Client.Do(http.MethodGet, "/api/guests", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var list struct {
+		Data struct {
+			Guests []interface{} `json:"guests"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &list)
+	require.Empty(t, list.Data.Guests)
+}
@@ -0,0 +1,36 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadToHead_RequiresSharedRoomOrClub(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	alice := registerUser(t, testutil.NewAPIClient(engine), "Alice")
+	bob := registerUser(t, testutil.NewAPIClient(engine), "Bob")
+	carol := registerUser(t, testutil.NewAPIClient(engine), "Carol")
+
+	_, roomCode := createRoom(t, alice, "texas")
+	resp, err := bob.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	// 查看自己与任何人的交手记录
+	requireStatus(t, alice.Client, http.MethodGet, fmt.Sprintf("/api/records/head-to-head?opponent_id=%d", bob.UserID), http.StatusOK)
+	requireStatus(t, carol.Client, http.MethodGet, fmt.Sprintf("/api/records/head-to-head?opponent_id=%d", alice.UserID), http.StatusOK)
+
+	// 与两人都没有关系时不能查看他们之间的交手记录
+	othersPath := fmt.Sprintf("/api/records/head-to-head?user_id=%d&opponent_id=%d", alice.UserID, bob.UserID)
+	requireStatus(t, carol.Client, http.MethodGet, othersPath, http.StatusForbidden)
+
+	resp, err = carol.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	requireStatus(t, carol.Client, http.MethodGet, othersPath, http.StatusOK)
+}
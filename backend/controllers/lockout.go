@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutController 登录锁定管理控制器（管理员）
+type LockoutController struct {
+	loginGuardService *services.LoginGuardService
+}
+
+// NewLockoutController 创建登录锁定管理控制器
+func NewLockoutController(loginGuardService *services.LoginGuardService) *LockoutController {
+	return &LockoutController{
+		loginGuardService: loginGuardService,
+	}
+}
+
+// GetLockouts 查看登录失败记录，默认只返回锁定中的手机号，all=true 时包含未达到锁定阈值的记录
+func (ctrl *LockoutController) GetLockouts(c *gin.Context) {
+	lockouts, err := ctrl.loginGuardService.ListLockouts(c.Query("all") != "true")
+	if err != nil {
+		utils.InternalServerError(c, "查询登录锁定失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"lockouts": lockouts,
+	})
+}
+
+// Unlock 解除手机号的登录锁定
+func (ctrl *LockoutController) Unlock(c *gin.Context) {
+	if err := ctrl.loginGuardService.Unlock(c.Param("phone")); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已解除锁定", nil)
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookieSuffix 保存单点登录 state 的 Cookie 名后缀，回调时校验发起登录的是同一个浏览器
+const oidcStateCookieSuffix = "_oidc_state"
+
+// GetOIDCStatus 返回是否启用单点登录以及登录按钮上显示的名称
+func (ctrl *AuthController) GetOIDCStatus(c *gin.Context) {
+	utils.Success(c, gin.H{
+		"enabled":       ctrl.oidcService.Enabled(),
+		"provider_name": ctrl.config.OIDC.ProviderName,
+	})
+}
+
+// OIDCLogin 跳转到身份提供方登录，redirect 为登录完成后跳转的前端路径
+func (ctrl *AuthController) OIDCLogin(c *gin.Context) {
+	authorizationURL, state, err := ctrl.oidcService.BeginLogin(0, c.Query("redirect"))
+	if err != nil {
+		ctrl.respondOIDCError(c, err)
+		return
+	}
+
+	ctrl.setOIDCStateCookie(c, state)
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// OIDCLinkRequest 绑定单点登录身份请求
+type OIDCLinkRequest struct {
+	Redirect string `json:"redirect"` // 绑定完成后跳转的前端路径
+}
+
+// OIDCLink 为当前用户绑定单点登录身份，返回需要跳转的授权地址
+func (ctrl *AuthController) OIDCLink(c *gin.Context) {
+	var req OIDCLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(c, "参数错误: "+err.Error())
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+
+	authorizationURL, state, err := ctrl.oidcService.BeginLogin(userID.(uint), req.Redirect)
+	if err != nil {
+		ctrl.respondOIDCError(c, err)
+		return
+	}
+
+	ctrl.setOIDCStateCookie(c, state)
+	utils.Success(c, gin.H{
+		"authorization_url": authorizationURL,
+	})
+}
+
+// OIDCCallback 身份提供方登录完成后的回调
+// 结果通过跳转回前端告知：成功时设置Session Cookie；开启两步验证时附带 two_factor_challenge；
+// 绑定成功时附带 oidc=linked；失败时附带 oidc_error
+func (ctrl *AuthController) OIDCCallback(c *gin.Context) {
+	stateCookie, _ := c.Cookie(ctrl.config.Session.CookieName + oidcStateCookieSuffix)
+	ctrl.clearOIDCStateCookie(c)
+
+	state := c.Query("state")
+	record, err := ctrl.oidcService.ConsumeState(state)
+	if err != nil {
+		ctrl.redirectToFrontend(c, "/", "oidc_error", err.Error())
+		return
+	}
+	if stateCookie == "" || stateCookie != state {
+		ctrl.redirectToFrontend(c, record.RedirectPath, "oidc_error", services.ErrOIDCStateInvalid.Error())
+		return
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		log.Printf("身份提供方返回错误: %s %s", providerError, c.Query("error_description"))
+		ctrl.redirectToFrontend(c, record.RedirectPath, "oidc_error", "单点登录未完成，请重新登录")
+		return
+	}
+
+	result, err := ctrl.oidcService.CompleteLogin(record, c.Query("code"))
+	if err != nil {
+		ctrl.redirectToFrontend(c, record.RedirectPath, "oidc_error", err.Error())
+		return
+	}
+
+	if result.Linked {
+		ctrl.redirectToFrontend(c, result.RedirectPath, "oidc", "linked")
+		return
+	}
+
+	// 身份提供方代替了密码校验，开启了两步验证的用户仍需完成第二步
+	enabled, err := ctrl.twoFactorService.IsEnabled(result.User.ID)
+	if err != nil {
+		ctrl.redirectToFrontend(c, result.RedirectPath, "oidc_error", "登录失败")
+		return
+	}
+	if enabled {
+		challenge, err := ctrl.twoFactorService.CreateChallenge(result.User.ID)
+		if err != nil {
+			ctrl.redirectToFrontend(c, result.RedirectPath, "oidc_error", "登录失败")
+			return
+		}
+		ctrl.redirectToFrontend(c, result.RedirectPath, "two_factor_challenge", challenge.Token)
+		return
+	}
+
+	session, err := ctrl.authService.CreateSession(result.User.ID, sessionClient(c))
+	if err != nil {
+		ctrl.redirectToFrontend(c, result.RedirectPath, "oidc_error", "登录失败")
+		return
+	}
+
+	log.Printf("用户通过单点登录登录: ID=%d, Phone=%s, Created=%v", result.User.ID, result.User.Phone, result.Created)
+	ctrl.setSessionCookie(c, session.SessionID)
+	ctrl.redirectToFrontend(c, result.RedirectPath, "", "")
+}
+
+// GetOIDCIdentities 获取当前用户绑定的单点登录身份
+func (ctrl *AuthController) GetOIDCIdentities(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	identities, err := ctrl.oidcService.ListIdentities(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "获取绑定信息失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"identities": identities,
+	})
+}
+
+// UnlinkOIDCIdentity 解除单点登录绑定
+func (ctrl *AuthController) UnlinkOIDCIdentity(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "绑定ID格式错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.oidcService.Unlink(userID.(uint), uint(id)); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "已解除绑定", nil)
+}
+
+func (ctrl *AuthController) respondOIDCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOIDCDisabled):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrOIDCProvider):
+		utils.Error(c, http.StatusBadGateway, 502, err.Error())
+	default:
+		utils.InternalServerError(c, "发起单点登录失败")
+	}
+}
+
+// redirectToFrontend 跳转回前端地址下的 path，key 不为空时附带查询参数
+func (ctrl *AuthController) redirectToFrontend(c *gin.Context, path, key, value string) {
+	location := ctrl.config.OIDC.FrontendURL + path
+	if key != "" {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		location += separator + url.Values{key: {value}}.Encode()
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// setOIDCStateCookie 保存 state，跳转回来时是跨站的顶级导航，SameSite 不能为 Strict
+func (ctrl *AuthController) setOIDCStateCookie(c *gin.Context, state string) {
+	secure, sameSite := ctrl.resolveCookieSecurity(c)
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     ctrl.config.Session.CookieName + oidcStateCookieSuffix,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(ctrl.config.OIDC.StateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: sameSite,
+	})
+}
+
+func (ctrl *AuthController) clearOIDCStateCookie(c *gin.Context) {
+	secure, sameSite := ctrl.resolveCookieSecurity(c)
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     ctrl.config.Session.CookieName + oidcStateCookieSuffix,
+		Value:    "",
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: sameSite,
+	})
+}
@@ -0,0 +1,240 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"poker_score_backend/services"
+	"poker_score_backend/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCTestEnv(t *testing.T) (*gin.Engine, *testutil.MockOIDCIssuer) {
+	t.Helper()
+
+	issuer, err := testutil.NewMockOIDCIssuer("poker-score", "client-secret")
+	require.NoError(t, err)
+	t.Cleanup(issuer.Close)
+
+	cfg := testutil.TestConfig()
+	cfg.OIDC.Issuer = issuer.URL
+	cfg.OIDC.ClientID = issuer.ClientID
+	cfg.OIDC.ClientSecret = issuer.ClientSecret
+	cfg.OIDC.RedirectURL = "http://localhost/api/auth/oidc/callback"
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+	return engine, issuer
+}
+
+// completeOIDCLogin 在身份提供方完成授权后请求回调，返回回调跳转的前端地址
+func completeOIDCLogin(t *testing.T, client *testutil.APIClient, issuer *testutil.MockOIDCIssuer, authorizationURL string) *url.URL {
+	t.Helper()
+
+	callback, err := issuer.Authorize(authorizationURL)
+	require.NoError(t, err)
+
+	resp, err := client.Do(http.MethodGet, callback.RequestURI(), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.Code, resp.Body.String())
+
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	return location
+}
+
+func beginOIDCLogin(t *testing.T, client *testutil.APIClient, redirect string) string {
+	t.Helper()
+
+	resp, err := client.Do(http.MethodGet, "/api/auth/oidc/login?redirect="+url.QueryEscape(redirect), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.Code, resp.Body.String())
+	return resp.Header().Get("Location")
+}
+
+func TestOIDC_DisabledByDefault(t *testing.T) {
+	_, client := newTestEnv(t)
+
+	resp, err := client.Do(http.MethodGet, "/api/auth/oidc", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Data struct {
+			Enabled bool `json:"enabled"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	require.False(t, body.Data.Enabled)
+
+	resp, err = client.Do(http.MethodGet, "/api/auth/oidc/login", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestOIDC_LoginMatchesVerifiedPhone(t *testing.T) {
+	engine, issuer := newOIDCTestEnv(t)
+	user := registerUser(t, testutil.NewAPIClient(engine), "单点登录")
+
+	issuer.SetIdentity(map[string]interface{}{
+		"sub":                   "employee-1",
+		"name":                  "张三",
+		"phone_number":          "+86 " + user.Phone,
+		"phone_number_verified": true,
+	})
+
+	browser := testutil.NewAPIClient(engine)
+	authorizationURL := beginOIDCLogin(t, browser, "/rooms?tab=mine")
+	authorization, err := url.Parse(authorizationURL)
+	require.NoError(t, err)
+	require.Equal(t, "S256", authorization.Query().Get("code_challenge_method"))
+	require.Contains(t, authorization.Query().Get("scope"), "openid")
+
+	callback, err := issuer.Authorize(authorizationURL)
+	require.NoError(t, err)
+
+	resp, err := browser.Do(http.MethodGet, callback.RequestURI(), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.Code, resp.Body.String())
+	require.Equal(t, "/rooms?tab=mine", resp.Header().Get("Location"))
+
+	var me struct {
+		Data struct {
+			ID uint `json:"id"`
+		} `json:"data"`
+	}
+	resp, err = browser.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &me)
+	require.Equal(t, user.UserID, me.Data.ID)
+
+	// 回调地址只能使用一次
+	resp, err = testutil.NewAPIClient(engine).Do(http.MethodGet, callback.RequestURI(), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFound, resp.Code)
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, services.ErrOIDCStateInvalid.Error(), location.Query().Get("oidc_error"))
+
+	var identities struct {
+		Data struct {
+			Identities []struct {
+				ID          uint   `json:"id"`
+				Subject     string `json:"subject"`
+				DisplayName string `json:"display_name"`
+			} `json:"identities"`
+		} `json:"data"`
+	}
+	resp, err = browser.Do(http.MethodGet, "/api/auth/oidc/identities", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &identities)
+	require.Len(t, identities.Data.Identities, 1)
+	require.Equal(t, "employee-1", identities.Data.Identities[0].Subject)
+	require.Equal(t, "张三", identities.Data.Identities[0].DisplayName)
+
+	// 之后按 sub 识别，手机号变化不影响登录
+	issuer.SetIdentity(map[string]interface{}{"sub": "employee-1"})
+	other := testutil.NewAPIClient(engine)
+	location = completeOIDCLogin(t, other, issuer, beginOIDCLogin(t, other, "/"))
+	require.Empty(t, location.Query().Get("oidc_error"))
+	resp, err = other.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = browser.Do(http.MethodDelete, fmt.Sprintf("/api/auth/oidc/identities/%d", identities.Data.Identities[0].ID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestOIDC_UnknownIdentityAndStateBinding(t *testing.T) {
+	engine, issuer := newOIDCTestEnv(t)
+
+	// 没有手机号的身份不会匹配到账户，默认也不自动注册
+	issuer.SetIdentity(map[string]interface{}{"sub": "stranger"})
+	browser := testutil.NewAPIClient(engine)
+	location := completeOIDCLogin(t, browser, issuer, beginOIDCLogin(t, browser, "/"))
+	require.Equal(t, services.ErrOIDCNotLinked.Error(), location.Query().Get("oidc_error"))
+
+	resp, err := browser.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// 未验证的手机号不能用来匹配账户
+	user := registerUser(t, testutil.NewAPIClient(engine), "未验证")
+	issuer.SetIdentity(map[string]interface{}{"sub": "unverified", "phone_number": user.Phone})
+	location = completeOIDCLogin(t, browser, issuer, beginOIDCLogin(t, browser, "/"))
+	require.Equal(t, services.ErrOIDCNotLinked.Error(), location.Query().Get("oidc_error"))
+
+	// 在另一个浏览器中打开回调地址（state Cookie 不一致）会被拒绝
+	issuer.SetIdentity(map[string]interface{}{"sub": "victim", "phone_number": user.Phone, "phone_number_verified": true})
+	attacker := testutil.NewAPIClient(engine)
+	location = completeOIDCLogin(t, browser, issuer, beginOIDCLogin(t, attacker, "/"))
+	require.Equal(t, services.ErrOIDCStateInvalid.Error(), location.Query().Get("oidc_error"))
+
+	resp, err = browser.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// 只允许跳转到本站路径
+	location = completeOIDCLogin(t, browser, issuer, beginOIDCLogin(t, browser, "https://evil.example.com/"))
+	require.Equal(t, "/", location.Path)
+	require.Empty(t, location.Host)
+}
+
+func TestOIDC_LinkIdentity(t *testing.T) {
+	engine, issuer := newOIDCTestEnv(t)
+	user := registerUser(t, testutil.NewAPIClient(engine), "绑定用户")
+	other := registerUser(t, testutil.NewAPIClient(engine), "其他用户")
+
+	issuer.SetIdentity(map[string]interface{}{"sub": "employee-2", "email": "e2@example.com"})
+
+	var link struct {
+		Data struct {
+			AuthorizationURL string `json:"authorization_url"`
+		} `json:"data"`
+	}
+	resp, err := user.Client.Do(http.MethodPost, "/api/auth/oidc/link", map[string]string{"redirect": "/profile"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &link)
+
+	location := completeOIDCLogin(t, user.Client, issuer, link.Data.AuthorizationURL)
+	require.Equal(t, "/profile", location.Path)
+	require.Equal(t, "linked", location.Query().Get("oidc"))
+
+	// 绑定后可以直接通过单点登录登录
+	browser := testutil.NewAPIClient(engine)
+	completeOIDCLogin(t, browser, issuer, beginOIDCLogin(t, browser, "/"))
+	var me struct {
+		Data struct {
+			ID uint `json:"id"`
+		} `json:"data"`
+	}
+	resp, err = browser.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &me)
+	require.Equal(t, user.UserID, me.Data.ID)
+
+	// 同一个身份不能再绑定到其他账户
+	resp, err = other.Client.Do(http.MethodPost, "/api/auth/oidc/link", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &link)
+	location = completeOIDCLogin(t, other.Client, issuer, link.Data.AuthorizationURL)
+	require.Equal(t, services.ErrOIDCLinkedToOther.Error(), location.Query().Get("oidc_error"))
+
+	// 未登录不能发起绑定
+	resp, err = testutil.NewAPIClient(engine).Do(http.MethodPost, "/api/auth/oidc/link", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
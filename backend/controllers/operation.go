@@ -11,18 +11,33 @@ import (
 // OperationController 房间操作控制器
 type OperationController struct {
 	operationService *services.OperationService
+	guestService     *services.GuestService
 }
 
 // NewOperationController 创建房间操作控制器
-func NewOperationController(operationService *services.OperationService) *OperationController {
+func NewOperationController(operationService *services.OperationService, guestService *services.GuestService) *OperationController {
 	return &OperationController{
 		operationService: operationService,
+		guestService:     guestService,
 	}
 }
 
+// resolveActor 确定积分操作的实际用户，房主可以通过 guest_id 代房间内的游客操作
+func (ctrl *OperationController) resolveActor(c *gin.Context, roomID, guestID uint) (uint, bool) {
+	userID, _ := c.Get("user_id")
+
+	actorID, err := ctrl.guestService.ResolveActor(roomID, userID.(uint), guestID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return 0, false
+	}
+	return actorID, true
+}
+
 // BetRequest 下注请求
 type BetRequest struct {
-	Amount int `json:"amount" binding:"required,gt=0"`
+	Amount  int  `json:"amount" binding:"required,gt=0"`
+	GuestID uint `json:"guest_id"` // 房主代游客下注时传入游客ID
 }
 
 // Bet 下注/支出
@@ -41,17 +56,20 @@ func (ctrl *OperationController) Bet(c *gin.Context) {
 		return
 	}
 
-	// 获取用户ID
-	userID, _ := c.Get("user_id")
+	actorID, ok := ctrl.resolveActor(c, uint(roomID), req.GuestID)
+	if !ok {
+		return
+	}
 
 	// 下注
-	myBalance, tableBalance, err := ctrl.operationService.Bet(uint(roomID), userID.(uint), req.Amount)
+	myBalance, tableBalance, err := ctrl.operationService.Bet(uint(roomID), actorID, req.Amount)
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
 	utils.SuccessWithMessage(c, "下注成功", gin.H{
+		"user_id":       actorID,
 		"my_balance":    myBalance,
 		"table_balance": tableBalance,
 	})
@@ -59,7 +77,8 @@ func (ctrl *OperationController) Bet(c *gin.Context) {
 
 // WithdrawRequest 收回请求
 type WithdrawRequest struct {
-	Amount int `json:"amount"` // 0或负数表示全收
+	Amount  int  `json:"amount"`   // 0或负数表示全收
+	GuestID uint `json:"guest_id"` // 房主代游客收回时传入游客ID
 }
 
 // ForceTransferRequest 积分强制转移请求
@@ -83,17 +102,20 @@ func (ctrl *OperationController) Withdraw(c *gin.Context) {
 		return
 	}
 
-	// 获取用户ID
-	userID, _ := c.Get("user_id")
+	actorID, ok := ctrl.resolveActor(c, uint(roomID), req.GuestID)
+	if !ok {
+		return
+	}
 
 	// 收回
-	myBalance, tableBalance, actualAmount, err := ctrl.operationService.Withdraw(uint(roomID), userID.(uint), req.Amount)
+	myBalance, tableBalance, actualAmount, err := ctrl.operationService.Withdraw(uint(roomID), actorID, req.Amount)
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
 	utils.SuccessWithMessage(c, "收回成功", gin.H{
+		"user_id":       actorID,
 		"my_balance":    myBalance,
 		"table_balance": tableBalance,
 		"actual_amount": actualAmount,
@@ -137,7 +159,8 @@ func (ctrl *OperationController) ForceTransfer(c *gin.Context) {
 
 // NiuniuBetRequest 牛牛下注请求
 type NiuniuBetRequest struct {
-	Bets []services.NiuniuBetItem `json:"bets" binding:"required"`
+	Bets    []services.NiuniuBetItem `json:"bets" binding:"required"`
+	GuestID uint                     `json:"guest_id"` // 房主代游客下注时传入游客ID
 }
 
 // NiuniuBet 牛牛下注
@@ -172,17 +195,20 @@ func (ctrl *OperationController) NiuniuBet(c *gin.Context) {
 		}
 	}
 
-	// 获取用户ID
-	userID, _ := c.Get("user_id")
+	actorID, ok := ctrl.resolveActor(c, uint(roomID), req.GuestID)
+	if !ok {
+		return
+	}
 
 	// 牛牛下注
-	myBalance, totalAmount, err := ctrl.operationService.NiuniuBet(uint(roomID), userID.(uint), req.Bets)
+	myBalance, totalAmount, err := ctrl.operationService.NiuniuBet(uint(roomID), actorID, req.Bets)
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
 	utils.SuccessWithMessage(c, "下注成功", gin.H{
+		"user_id":      actorID,
 		"my_balance":   myBalance,
 		"total_amount": totalAmount,
 	})
@@ -223,6 +249,33 @@ func (ctrl *OperationController) GetOperations(c *gin.Context) {
 	})
 }
 
+// GetTimeline 获取房间积分走势，max_points 控制返回的最大点数
+func (ctrl *OperationController) GetTimeline(c *gin.Context) {
+	// 获取房间ID
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	maxPoints, ok := parseMaxPoints(c)
+	if !ok {
+		return
+	}
+
+	// 获取用户ID
+	userID, _ := c.Get("user_id")
+
+	timeline, err := ctrl.operationService.GetRoomTimeline(uint(roomID), userID.(uint), maxPoints)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, timeline)
+}
+
 // GetHistoryAmounts 获取用户历史操作金额
 func (ctrl *OperationController) GetHistoryAmounts(c *gin.Context) {
 	// 获取房间ID
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetController 找回密码控制器
+type PasswordResetController struct {
+	passwordResetService *services.PasswordResetService
+}
+
+// NewPasswordResetController 创建找回密码控制器
+func NewPasswordResetController(passwordResetService *services.PasswordResetService) *PasswordResetController {
+	return &PasswordResetController{
+		passwordResetService: passwordResetService,
+	}
+}
+
+// ForgotPasswordRequest 获取找回密码验证码请求
+type ForgotPasswordRequest struct {
+	Phone string `json:"phone" binding:"required,len=11"`
+}
+
+// RequestCode 发送找回密码验证码
+func (ctrl *PasswordResetController) RequestCode(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := ctrl.passwordResetService.RequestCode(req.Phone); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	// 无论手机号是否注册都返回相同的提示
+	utils.SuccessWithMessage(c, "如果该手机号已注册，验证码将发送到该手机", nil)
+}
+
+// ResetPasswordRequest 通过验证码重置密码请求
+type ResetPasswordRequest struct {
+	Phone       string `json:"phone" binding:"required,len=11"`
+	Code        string `json:"code" binding:"required,len=6,numeric"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ResetPassword 校验验证码并设置新密码，成功后所有设备需要重新登录
+func (ctrl *PasswordResetController) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if err := ctrl.passwordResetService.ResetPassword(req.Phone, req.Code, req.NewPassword); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "密码已重置，请使用新密码登录", nil)
+}
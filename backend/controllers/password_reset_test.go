@@ -0,0 +1,101 @@
+package controllers_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readLoggedCode 从默认短信发送器的日志文件中读取发给手机号的最后一条验证码
+func readLoggedCode(t *testing.T, path, phone string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	code := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Split(line, "\t")
+		require.Len(t, fields, 4)
+		if fields[1] == phone {
+			code = fields[3]
+		}
+	}
+	require.NotEmpty(t, code)
+	return code
+}
+
+func TestPasswordReset_ForgotAndReset(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.SMS.LogFile = filepath.Join(t.TempDir(), "sms.log")
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	user := registerUser(t, testutil.NewAPIClient(engine), "忘记密码")
+	anonymous := testutil.NewAPIClient(engine)
+
+	// 未注册的手机号返回同样的提示，且不发送短信
+	resp, err := anonymous.Do(http.MethodPost, "/api/auth/password/forgot", map[string]string{"phone": uniquePhone()})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	_, err = os.Stat(cfg.SMS.LogFile)
+	require.True(t, os.IsNotExist(err))
+
+	resp, err = anonymous.Do(http.MethodPost, "/api/auth/password/forgot", map[string]string{"phone": user.Phone})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "如果该手机号已注册，验证码将发送到该手机", body.Message)
+
+	code := readLoggedCode(t, cfg.SMS.LogFile, user.Phone)
+
+	// 冷却期内重复获取与未注册的手机号返回同样的提示
+	resp, err = anonymous.Do(http.MethodPost, "/api/auth/password/forgot", map[string]string{"phone": user.Phone})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "如果该手机号已注册，验证码将发送到该手机", body.Message)
+	require.Equal(t, code, readLoggedCode(t, cfg.SMS.LogFile, user.Phone))
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	resp, err = anonymous.Do(http.MethodPost, "/api/auth/password/reset", map[string]string{
+		"phone": user.Phone, "code": wrong, "new_password": "newpass123",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "验证码错误，还可尝试4次", body.Message)
+
+	resp, err = anonymous.Do(http.MethodPost, "/api/auth/password/reset", map[string]string{
+		"phone": user.Phone, "code": code, "new_password": "newpass123",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	// 原有的登录全部失效，旧密码不能再登录
+	requireStatus(t, user.Client, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+
+	resp, err = anonymous.Do(http.MethodPost, "/api/auth/login", map[string]string{"phone": user.Phone, "password": testUserPassword})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	loginUser(t, testutil.NewAPIClient(engine), user.Phone, "newpass123")
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PhoneChangeController 修改手机号控制器
+type PhoneChangeController struct {
+	phoneChangeService *services.PhoneChangeService
+}
+
+// NewPhoneChangeController 创建修改手机号控制器
+func NewPhoneChangeController(phoneChangeService *services.PhoneChangeService) *PhoneChangeController {
+	return &PhoneChangeController{
+		phoneChangeService: phoneChangeService,
+	}
+}
+
+// PhoneCodeRequest 获取修改手机号验证码请求
+type PhoneCodeRequest struct {
+	NewPhone string `json:"new_phone" binding:"required,len=11,numeric"`
+}
+
+// RequestCode 向新手机号发送验证码
+func (ctrl *PhoneChangeController) RequestCode(c *gin.Context) {
+	var req PhoneCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := ctrl.phoneChangeService.RequestCode(userID.(uint), req.NewPhone); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "验证码已发送到新手机号", nil)
+}
+
+// ChangePhoneRequest 修改手机号请求
+type ChangePhoneRequest struct {
+	NewPhone string `json:"new_phone" binding:"required,len=11,numeric"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+	Password string `json:"password" binding:"required"` // 当前密码
+}
+
+// ChangePhone 校验验证码与当前密码后修改手机号，其他设备的登录会失效，当前设备保持登录
+func (ctrl *PhoneChangeController) ChangePhone(c *gin.Context) {
+	var req ChangePhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	user, err := ctrl.phoneChangeService.ChangePhone(userID.(uint), c.GetString("session_id"), req.NewPhone, req.Code, req.Password)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "手机号修改成功，请使用新手机号登录", gin.H{
+		"user": gin.H{
+			"id":       user.ID,
+			"phone":    user.Phone,
+			"nickname": user.Nickname,
+			"role":     user.Role,
+		},
+	})
+}
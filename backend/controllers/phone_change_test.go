@@ -0,0 +1,105 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"poker_score_backend/models"
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneChange_ThenAdminMergesOldAccount(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.SMS.LogFile = filepath.Join(t.TempDir(), "sms.log")
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	user := registerUser(t, testutil.NewAPIClient(engine), "换号用户")
+	other := registerUser(t, testutil.NewAPIClient(engine), "旧号用户")
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			User struct {
+				ID    uint   `json:"id"`
+				Phone string `json:"phone"`
+			} `json:"user"`
+			MovedRooms  int `json:"moved_rooms"`
+			SharedRooms int `json:"shared_rooms"`
+		} `json:"data"`
+	}
+
+	resp, err := user.Client.Do(http.MethodPost, "/api/auth/phone/code", map[string]string{"new_phone": other.Phone})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "该手机号已注册，如需合并两个账户请联系管理员", body.Message)
+
+	newPhone := uniquePhone()
+	resp, err = user.Client.Do(http.MethodPost, "/api/auth/phone/code", map[string]string{"new_phone": newPhone})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	code := readLoggedCode(t, cfg.SMS.LogFile, newPhone)
+	resp, err = user.Client.Do(http.MethodPut, "/api/auth/phone", map[string]string{
+		"new_phone": newPhone, "code": code, "password": testUserPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "手机号修改成功，请使用新手机号登录", body.Message)
+	require.Equal(t, newPhone, body.Data.User.Phone)
+
+	// 当前设备保持登录，新手机号可以登录
+	resp, err = user.Client.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	loginUser(t, testutil.NewAPIClient(engine), newPhone, testUserPassword)
+
+	// 管理员把另一个账户合并进来，进行中的房间需要先解散
+	roomID, _ := createRoom(t, other, "texas")
+
+	admin := registerUser(t, testutil.NewAPIClient(engine), "合并管理员")
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", admin.UserID).Update("role", "admin").Error)
+	loginUser(t, admin.Client, admin.Phone, testUserPassword)
+
+	merge := map[string]uint{"source_user_id": other.UserID, "target_user_id": user.UserID}
+	resp, err = user.Client.Do(http.MethodPost, "/api/admin/users/merge", merge)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.Code, resp.Body.String())
+
+	resp, err = admin.Client.Do(http.MethodPost, "/api/admin/users/merge", merge)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.Code, resp.Body.String())
+
+	resp, err = other.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/dissolve", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp, err = admin.Client.Do(http.MethodPost, "/api/admin/users/merge", merge)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "账户合并成功", body.Message)
+	require.Equal(t, user.UserID, body.Data.User.ID)
+	require.Equal(t, 1, body.Data.MovedRooms)
+	require.Zero(t, body.Data.SharedRooms)
+
+	var room models.Room
+	require.NoError(t, models.DB.First(&room, roomID).Error)
+	require.Equal(t, user.UserID, room.CreatedBy)
+
+	// 被合并账户的登录状态随之失效
+	resp, err = other.Client.Do(http.MethodGet, "/api/auth/me", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.Code, resp.Body.String())
+}
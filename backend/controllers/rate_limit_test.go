@@ -0,0 +1,211 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"poker_score_backend/config"
+	"poker_score_backend/models"
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLockout_ProgressiveAndAdminUnlock(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	user := registerUser(t, testutil.NewAPIClient(engine), "被锁用户")
+	attacker := testutil.NewAPIClient(engine)
+
+	login := func(password string) (int, string, string) {
+		resp, err := attacker.Do(http.MethodPost, "/api/auth/login", map[string]string{"phone": user.Phone, "password": password})
+		require.NoError(t, err)
+		var body struct {
+			Message string `json:"message"`
+		}
+		decodeResponse(t, resp, &body)
+		return resp.Code, body.Message, resp.Header().Get("Retry-After")
+	}
+
+	for i := 0; i < 4; i++ {
+		code, message, _ := login("wrong-password")
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Equal(t, "手机号或密码错误", message)
+	}
+
+	code, message, retryAfter := login("wrong-password")
+	require.Equal(t, http.StatusTooManyRequests, code)
+	require.Equal(t, "登录失败次数过多，请1分钟后再试", message)
+	require.Equal(t, "60", retryAfter)
+
+	// 锁定期间正确的密码也不能登录
+	code, _, retryAfter = login(testUserPassword)
+	require.Equal(t, http.StatusTooManyRequests, code)
+	require.NotEmpty(t, retryAfter)
+
+	admin := registerUser(t, testutil.NewAPIClient(engine), "管理员")
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", admin.UserID).Update("role", "admin").Error)
+
+	requireStatus(t, user.Client, http.MethodGet, "/api/admin/lockouts", http.StatusForbidden)
+
+	resp, err := admin.Client.Do(http.MethodGet, "/api/admin/lockouts", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var listResp struct {
+		Data struct {
+			Lockouts []struct {
+				Phone     string `json:"phone"`
+				UserID    uint   `json:"user_id"`
+				Nickname  string `json:"nickname"`
+				LockCount int    `json:"lock_count"`
+				Locked    bool   `json:"locked"`
+			} `json:"lockouts"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &listResp)
+	require.Len(t, listResp.Data.Lockouts, 1)
+	require.Equal(t, user.Phone, listResp.Data.Lockouts[0].Phone)
+	require.Equal(t, user.UserID, listResp.Data.Lockouts[0].UserID)
+	require.Equal(t, 1, listResp.Data.Lockouts[0].LockCount)
+	require.True(t, listResp.Data.Lockouts[0].Locked)
+
+	requireStatus(t, admin.Client, http.MethodDelete, "/api/admin/lockouts/"+user.Phone, http.StatusOK)
+	requireStatus(t, admin.Client, http.MethodDelete, "/api/admin/lockouts/"+user.Phone, http.StatusBadRequest)
+
+	code, _, _ = login(testUserPassword)
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestRateLimit_RegisterAndMoneyOperations(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.RateLimit.Register = config.RateLimit{Burst: 2, Period: time.Hour}
+	cfg.RateLimit.Money = config.RateLimit{Burst: 3, Period: time.Minute}
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "房主")
+	registerUser(t, testutil.NewAPIClient(engine), "第二人")
+
+	resp, err := testutil.NewAPIClient(engine).Do(http.MethodPost, "/api/auth/register", map[string]string{
+		"phone": uniquePhone(), "nickname": "第三人", "password": testUserPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	requireRetryAfter(t, resp, 1800)
+
+	var body struct {
+		Code int `json:"code"`
+		Data struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, 429, body.Code)
+	require.InDelta(t, 1800, body.Data.RetryAfter, 10)
+
+	// 伪造 X-Forwarded-For 不能换一个限流桶
+	spoofed := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(
+		fmt.Sprintf(`{"phone":"%s","nickname":"第四人","password":"%s"}`, uniquePhone(), testUserPassword)))
+	spoofed.Header.Set("Content-Type", "application/json")
+	spoofed.Header.Set("X-Forwarded-For", "203.0.113.7")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, spoofed)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code, recorder.Body.String())
+
+	roomID, _ := createRoom(t, owner, "texas")
+	for i := 0; i < 3; i++ {
+		resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 10})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	}
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 10})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	requireRetryAfter(t, resp, 20)
+
+	// 查询类接口不受影响
+	requireStatus(t, owner.Client, http.MethodGet, fmt.Sprintf("/api/rooms/%d/operations", roomID), http.StatusOK)
+}
+
+func TestRateLimit_PasswordReset(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.RateLimit.PasswordReset = config.RateLimit{Burst: 3, Period: time.Hour}
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	client := testutil.NewAPIClient(engine)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Do(http.MethodPost, "/api/auth/password/forgot", map[string]string{"phone": uniquePhone()})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	}
+	resp, err := client.Do(http.MethodPost, "/api/auth/password/reset", map[string]string{
+		"phone": uniquePhone(), "code": "000000", "new_password": "newpass123",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+
+	// 发送验证码与重置密码共用同一个限流桶
+	resp, err = client.Do(http.MethodPost, "/api/auth/password/reset", map[string]string{
+		"phone": uniquePhone(), "code": "000000", "new_password": "newpass123",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	requireRetryAfter(t, resp, 1200)
+
+	resp, err = client.Do(http.MethodPost, "/api/auth/password/forgot", map[string]string{"phone": uniquePhone()})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+}
+
+// requireRetryAfter 校验 Retry-After 头，允许测试运行较慢时少几秒
+func requireRetryAfter(t *testing.T, resp *httptest.ResponseRecorder, expected int) {
+	t.Helper()
+
+	retryAfter, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.LessOrEqual(t, retryAfter, expected)
+	require.Greater(t, retryAfter, expected-10)
+}
+
+func TestRateLimit_TrustedProxyForwardedFor(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.RateLimit.Register = config.RateLimit{Burst: 1, Period: time.Hour}
+	// httptest 请求的对端地址为 192.0.2.1，视为反向代理
+	cfg.Server.TrustedProxies = []string{"192.0.2.0/24"}
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	register := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(
+			fmt.Sprintf(`{"phone":"%s","nickname":"代理用户","password":"%s"}`, uniquePhone(), testUserPassword)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, register("198.51.100.1"))
+	require.Equal(t, http.StatusTooManyRequests, register("198.51.100.1"))
+	// 经可信代理转发时按真实客户端IP分别限流
+	require.Equal(t, http.StatusOK, register("198.51.100.2"))
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"net/http"
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecapController 房间复盘控制器
+type RecapController struct {
+	recapService *services.RecapService
+}
+
+// NewRecapController 创建房间复盘控制器
+func NewRecapController(recapService *services.RecapService) *RecapController {
+	return &RecapController{
+		recapService: recapService,
+	}
+}
+
+// GetRecap 获取已解散房间的复盘
+// format 可选 json（默认）、markdown、html，后两种直接返回可分享的文本
+func (ctrl *RecapController) GetRecap(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("room_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" && format != "html" {
+		utils.BadRequest(c, "format 必须是 json、markdown 或 html")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	recap, err := ctrl.recapService.GetRoomRecap(uint(roomID), userID.(uint))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	switch format {
+	case "markdown":
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(services.RenderRecapMarkdown(recap)))
+	case "html":
+		page, err := services.RenderRecapHTML(recap)
+		if err != nil {
+			utils.InternalServerError(c, "生成复盘页面失败")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	default:
+		utils.Success(c, recap)
+	}
+}
@@ -0,0 +1,93 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecap_GeneratedWhenRoomDissolved(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "复盘房主")
+	member := registerUser(t, testutil.NewAPIClient(engine), "复盘成员")
+	outsider := registerUser(t, testutil.NewAPIClient(engine), "复盘路人")
+
+	roomID, roomCode := createRoom(t, owner, "texas")
+	resp, err := member.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = member.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 100})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/withdraw", roomID), map[string]int{"amount": 100})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	recapPath := fmt.Sprintf("/api/rooms/%d/recap", roomID)
+	resp, err = member.Client.Do(http.MethodGet, recapPath, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "房间尚未解散")
+
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/dissolve", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp, err = member.Client.Do(http.MethodGet, recapPath, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var recap struct {
+		Data struct {
+			RoomCode    string `json:"room_code"`
+			Reason      string `json:"reason"`
+			DissolvedBy struct {
+				UserID uint `json:"user_id"`
+			} `json:"dissolved_by"`
+			TotalBet         int `json:"total_bet"`
+			MostActiveBettor struct {
+				UserID uint `json:"user_id"`
+			} `json:"most_active_bettor"`
+			BiggestPots []struct {
+				UserID uint `json:"user_id"`
+				Amount int  `json:"amount"`
+			} `json:"biggest_pots"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &recap)
+	require.Equal(t, roomCode, recap.Data.RoomCode)
+	require.Equal(t, "manual", recap.Data.Reason)
+	require.Equal(t, owner.UserID, recap.Data.DissolvedBy.UserID)
+	require.Equal(t, 100, recap.Data.TotalBet)
+	require.Equal(t, member.UserID, recap.Data.MostActiveBettor.UserID)
+	require.Len(t, recap.Data.BiggestPots, 1)
+	require.Equal(t, owner.UserID, recap.Data.BiggestPots[0].UserID)
+
+	resp, err = owner.Client.Do(http.MethodGet, recapPath+"?format=markdown", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.True(t, strings.HasPrefix(resp.Header().Get("Content-Type"), "text/markdown"))
+	require.True(t, strings.HasPrefix(resp.Body.String(), fmt.Sprintf("# 房间 %s 复盘", roomCode)))
+	require.Contains(t, resp.Body.String(), "由 复盘房主 手动解散")
+
+	resp, err = owner.Client.Do(http.MethodGet, recapPath+"?format=html", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.True(t, strings.HasPrefix(resp.Header().Get("Content-Type"), "text/html"))
+	require.Contains(t, resp.Body.String(), "<!DOCTYPE html>")
+
+	resp, err = owner.Client.Do(http.MethodGet, recapPath+"?format=pdf", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = outsider.Client.Do(http.MethodGet, recapPath, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
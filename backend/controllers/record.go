@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"poker_score_backend/services"
 	"poker_score_backend/utils"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -53,3 +56,152 @@ func (ctrl *RecordController) GetTonightRecords(c *gin.Context) {
 	utils.Success(c, records)
 }
 
+// GetNightTimeline 获取某一晚所有房间的积分走势，date 为空表示当前这一晚
+func (ctrl *RecordController) GetNightTimeline(c *gin.Context) {
+	maxPoints, ok := parseMaxPoints(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	timeline, err := ctrl.recordService.GetNightTimeline(userID.(uint), c.Query("date"), maxPoints)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, timeline)
+}
+
+// GetMyStats 获取当前用户的生涯战绩统计
+// 支持 start_date/end_date（RFC3339 或 YYYY-MM-DD，结束日期包含当天）与 room_type 筛选
+func (ctrl *RecordController) GetMyStats(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	filter, ok := parseStatsFilter(c, ctrl.recordService.GetDayBoundary(userID.(uint)))
+	if !ok {
+		return
+	}
+
+	stats, err := ctrl.recordService.GetLifetimeStats(userID.(uint), filter)
+	if err != nil {
+		utils.InternalServerError(c, "查询战绩统计失败")
+		return
+	}
+
+	utils.Success(c, stats)
+}
+
+// GetHeadToHead 获取两名用户的交手记录
+// opponent_id 必填；user_id 默认为当前用户，支持与生涯战绩相同的筛选参数
+// 查看他人之间的交手记录时，需要与两人都同过房间或在同一俱乐部
+func (ctrl *RecordController) GetHeadToHead(c *gin.Context) {
+	currentUserID, _ := c.Get("user_id")
+
+	opponentID, err := strconv.ParseUint(c.Query("opponent_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "对手ID格式错误")
+		return
+	}
+
+	userID := uint64(currentUserID.(uint))
+	if value := c.Query("user_id"); value != "" {
+		userID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "用户ID格式错误")
+			return
+		}
+	}
+
+	if err := ctrl.recordService.CheckHeadToHeadAccess(currentUserID.(uint), uint(userID), uint(opponentID)); err != nil {
+		if errors.Is(err, services.ErrHeadToHeadForbidden) {
+			utils.Forbidden(c, err.Error())
+			return
+		}
+		utils.InternalServerError(c, "获取交手记录失败")
+		return
+	}
+
+	filter, ok := parseStatsFilter(c, ctrl.recordService.GetDayBoundary(currentUserID.(uint)))
+	if !ok {
+		return
+	}
+
+	result, err := ctrl.recordService.GetHeadToHead(uint(userID), uint(opponentID), filter)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// parseStatsFilter 解析 start_date/end_date/room_type 筛选参数，出错时直接写入响应
+// 仅给出日期时按 boundary 的时区与分界时刻取那一晚
+func parseStatsFilter(c *gin.Context, boundary services.DayBoundary) (services.StatsFilter, bool) {
+	var filter services.StatsFilter
+	var err error
+
+	if filter.Start, err = parseDateParam(c.Query("start_date"), false, boundary); err != nil {
+		utils.BadRequest(c, "开始日期格式错误")
+		return filter, false
+	}
+	if filter.End, err = parseDateParam(c.Query("end_date"), true, boundary); err != nil {
+		utils.BadRequest(c, "结束日期格式错误")
+		return filter, false
+	}
+	if filter.Start != nil && filter.End != nil && filter.End.Before(*filter.Start) {
+		utils.BadRequest(c, "结束日期不能早于开始日期")
+		return filter, false
+	}
+
+	filter.RoomType = c.Query("room_type")
+	if filter.RoomType != "" && filter.RoomType != "texas" && filter.RoomType != "niuniu" {
+		utils.BadRequest(c, "房间类型只能是texas或niuniu")
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// parseMaxPoints 解析积分走势的 max_points 参数，出错时直接写入响应
+func parseMaxPoints(c *gin.Context) (int, bool) {
+	value := c.Query("max_points")
+	if value == "" {
+		return services.DefaultTimelinePoints, true
+	}
+
+	maxPoints, err := strconv.Atoi(value)
+	if err != nil || maxPoints < 2 || maxPoints > services.MaxTimelinePoints {
+		utils.BadRequest(c, fmt.Sprintf("max_points 必须在2-%d之间", services.MaxTimelinePoints))
+		return 0, false
+	}
+	return maxPoints, true
+}
+
+// parseDateParam 解析日期参数；仅给出日期时，开始日期取那一晚的开始，结束日期取那一晚的结束
+func parseDateParam(value string, endOfNight bool, boundary services.DayBoundary) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	// 数据库按字符串比较时间，统一换算到服务器时区
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.In(time.Local)
+		return &t, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("日期格式错误")
+	}
+
+	start, end := boundary.DateRange(date.Year(), date.Month(), date.Day())
+	start, end = start.In(time.Local), end.In(time.Local)
+	if endOfNight {
+		end = end.Add(-time.Nanosecond)
+		return &end, nil
+	}
+	return &start, nil
+}
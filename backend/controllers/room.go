@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"poker_score_backend/models"
 	"poker_score_backend/services"
 	"poker_score_backend/utils"
 	"strconv"
@@ -27,6 +28,7 @@ func NewRoomController(roomService *services.RoomService, settlementService *ser
 type CreateRoomRequest struct {
 	RoomType string `json:"room_type" binding:"required,oneof=texas niuniu"`
 	ChipRate string `json:"chip_rate" binding:"required"`
+	ClubID   *uint  `json:"club_id"` // 可选，在俱乐部下创建房间
 }
 
 // CreateRoom 创建房间
@@ -40,11 +42,21 @@ func (ctrl *RoomController) CreateRoom(c *gin.Context) {
 	// 获取用户ID
 	userID, _ := c.Get("user_id")
 
-	// 调用服务层创建房间
-	room, err := ctrl.roomService.CreateRoom(userID.(uint), req.RoomType, req.ChipRate)
-	if err != nil {
-		utils.InternalServerError(c, "创建房间失败")
-		return
+	// 调用服务层创建房间，指定俱乐部时校验俱乐部成员身份
+	var room *models.Room
+	var err error
+	if req.ClubID != nil {
+		room, err = ctrl.roomService.CreateClubRoom(userID.(uint), *req.ClubID, req.RoomType, req.ChipRate)
+		if err != nil {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+	} else {
+		room, err = ctrl.roomService.CreateRoom(userID.(uint), req.RoomType, req.ChipRate)
+		if err != nil {
+			utils.InternalServerError(c, "创建房间失败")
+			return
+		}
 	}
 
 	utils.SuccessWithMessage(c, "房间创建成功", gin.H{
@@ -52,6 +64,7 @@ func (ctrl *RoomController) CreateRoom(c *gin.Context) {
 		"room_code":  room.RoomCode,
 		"room_type":  room.RoomType,
 		"chip_rate":  room.ChipRate,
+		"club_id":    room.ClubID,
 		"created_at": room.CreatedAt,
 	})
 }
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeasonController 赛季控制器
+type SeasonController struct {
+	seasonService *services.SeasonService
+}
+
+// NewSeasonController 创建赛季控制器
+func NewSeasonController(seasonService *services.SeasonService) *SeasonController {
+	return &SeasonController{
+		seasonService: seasonService,
+	}
+}
+
+// CreateSeasonRequest 创建赛季请求
+type CreateSeasonRequest struct {
+	Name            string    `json:"name" binding:"required"`
+	ClubID          *uint     `json:"club_id"`
+	StartAt         time.Time `json:"start_at" binding:"required"`
+	EndAt           time.Time `json:"end_at" binding:"required"`
+	RmbWeight       *float64  `json:"rmb_weight"` // 默认为1
+	PlacementPoints []float64 `json:"placement_points"`
+	AttendanceBonus float64   `json:"attendance_bonus"`
+}
+
+// CreateSeason 创建赛季
+func (ctrl *SeasonController) CreateSeason(c *gin.Context) {
+	var req CreateSeasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	rmbWeight := 1.0
+	if req.RmbWeight != nil {
+		rmbWeight = *req.RmbWeight
+	}
+
+	userID, _ := c.Get("user_id")
+
+	season, err := ctrl.seasonService.CreateSeason(userID.(uint), services.SeasonInput{
+		Name:            req.Name,
+		ClubID:          req.ClubID,
+		StartAt:         req.StartAt,
+		EndAt:           req.EndAt,
+		RmbWeight:       rmbWeight,
+		PlacementPoints: req.PlacementPoints,
+		AttendanceBonus: req.AttendanceBonus,
+	})
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "赛季创建成功", season)
+}
+
+// ListSeasons 获取赛季列表，传 club_id 时返回俱乐部赛季，否则返回全服赛季
+func (ctrl *SeasonController) ListSeasons(c *gin.Context) {
+	var clubID *uint
+	if value := c.Query("club_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "俱乐部ID格式错误")
+			return
+		}
+		id := uint(parsed)
+		clubID = &id
+	}
+
+	userID, _ := c.Get("user_id")
+
+	seasons, err := ctrl.seasonService.ListSeasons(userID.(uint), clubID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"seasons": seasons,
+	})
+}
+
+// GetLeaderboard 获取赛季排行榜，可通过 date=YYYY-MM-DD 查看历史快照
+func (ctrl *SeasonController) GetLeaderboard(c *gin.Context) {
+	seasonID, err := strconv.ParseUint(c.Param("season_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "赛季ID格式错误")
+		return
+	}
+
+	date := c.Query("date")
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			utils.BadRequest(c, "日期格式错误")
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+
+	leaderboard, err := ctrl.seasonService.GetLeaderboard(uint(seasonID), userID.(uint), date)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, leaderboard)
+}
@@ -0,0 +1,138 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sessionView struct {
+	ID        uint   `json:"id"`
+	Device    string `json:"device"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	Current   bool   `json:"current"`
+}
+
+func listSessions(t *testing.T, client *testutil.APIClient) []sessionView {
+	t.Helper()
+
+	resp, err := client.Do(http.MethodGet, "/api/auth/sessions", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Data struct {
+			Sessions []sessionView `json:"sessions"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	return body.Data.Sessions
+}
+
+func requireStatus(t *testing.T, client *testutil.APIClient, method, path string, status int) {
+	t.Helper()
+
+	resp, err := client.Do(method, path, nil)
+	require.NoError(t, err)
+	require.Equal(t, status, resp.Code, resp.Body.String())
+}
+
+func TestSessions_ListRevokeAndLogoutAll(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	phoneClient := testutil.NewAPIClient(engine)
+	phoneClient.SetUserAgent("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 MicroMessenger/8.0.40")
+	user := registerUser(t, phoneClient, "多设备用户")
+
+	laptop := testutil.NewAPIClient(engine)
+	laptop.SetUserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36")
+	loginUser(t, laptop, user.Phone, testUserPassword)
+
+	tablet := testutil.NewAPIClient(engine)
+	loginUser(t, tablet, user.Phone, testUserPassword)
+
+	other := registerUser(t, testutil.NewAPIClient(engine), "其他用户")
+
+	sessions := listSessions(t, phoneClient)
+	require.Len(t, sessions, 3)
+
+	devices := make(map[string]sessionView, len(sessions))
+	for _, session := range sessions {
+		devices[session.Device] = session
+	}
+	require.True(t, devices["微信 · iPhone"].Current)
+	require.False(t, devices["Chrome · Windows"].Current)
+	require.Contains(t, devices, "未知设备")
+	require.NotEmpty(t, devices["Chrome · Windows"].IP)
+
+	// 注销笔记本上的登录
+	laptopSessionID := devices["Chrome · Windows"].ID
+	requireStatus(t, phoneClient, http.MethodDelete, fmt.Sprintf("/api/auth/sessions/%d", laptopSessionID), http.StatusOK)
+	requireStatus(t, laptop, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+	requireStatus(t, phoneClient, http.MethodDelete, fmt.Sprintf("/api/auth/sessions/%d", laptopSessionID), http.StatusBadRequest)
+	require.Len(t, listSessions(t, phoneClient), 2)
+
+	// 不能注销其他用户的会话
+	otherSessionID := listSessions(t, other.Client)[0].ID
+	requireStatus(t, phoneClient, http.MethodDelete, fmt.Sprintf("/api/auth/sessions/%d", otherSessionID), http.StatusBadRequest)
+	requireStatus(t, other.Client, http.MethodGet, "/api/auth/me", http.StatusOK)
+
+	// 退出所有设备，包括当前设备
+	resp, err := tablet.Do(http.MethodPost, "/api/auth/logout-all", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var logoutAll struct {
+		Message string `json:"message"`
+		Data    struct {
+			Revoked int `json:"revoked"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &logoutAll)
+	require.Equal(t, "已退出所有设备", logoutAll.Message)
+	require.Equal(t, 2, logoutAll.Data.Revoked)
+
+	requireStatus(t, tablet, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+	requireStatus(t, phoneClient, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+	requireStatus(t, other.Client, http.MethodGet, "/api/auth/me", http.StatusOK)
+}
+
+func TestUpdatePassword_RevokesOtherSessions(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	user := registerUser(t, testutil.NewAPIClient(engine), "改密用户")
+	otherDevice := testutil.NewAPIClient(engine)
+	loginUser(t, otherDevice, user.Phone, testUserPassword)
+
+	// Authorization Header 登录的设备同样会被注销
+	headerDevice := testutil.NewAPIClient(engine)
+	resp, err := headerDevice.Do(http.MethodPost, "/api/auth/login", map[string]string{"phone": user.Phone, "password": testUserPassword})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var login struct {
+		Data struct {
+			SessionID string `json:"session_id"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &login)
+	bearer := testutil.NewAPIClient(engine)
+	bearer.SetAuthorization("Bearer " + login.Data.SessionID)
+	requireStatus(t, bearer, http.MethodGet, "/api/auth/me", http.StatusOK)
+
+	resp, err = user.Client.Do(http.MethodPut, "/api/auth/password", map[string]string{
+		"old_password": testUserPassword,
+		"new_password": "new-password",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	requireStatus(t, user.Client, http.MethodGet, "/api/auth/me", http.StatusOK)
+	requireStatus(t, otherDevice, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+	requireStatus(t, bearer, http.MethodGet, "/api/auth/me", http.StatusUnauthorized)
+	require.Len(t, listSessions(t, user.Client), 1)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupController 首次运行初始化控制器
+type SetupController struct {
+	bootstrapService *services.BootstrapService
+}
+
+// NewSetupController 创建初始化控制器
+func NewSetupController(bootstrapService *services.BootstrapService) *SetupController {
+	return &SetupController{
+		bootstrapService: bootstrapService,
+	}
+}
+
+// GetStatus 查询系统是否还需要创建管理员
+func (ctrl *SetupController) GetStatus(c *gin.Context) {
+	needsSetup, err := ctrl.bootstrapService.NeedsSetup()
+	if err != nil {
+		utils.InternalServerError(c, "查询初始化状态失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"needs_setup": needsSetup,
+	})
+}
+
+// SetupAdminRequest 创建第一个管理员请求
+type SetupAdminRequest struct {
+	Token    string `json:"token" binding:"required"` // 启动日志中打印的一次性初始化令牌
+	Phone    string `json:"phone" binding:"required,len=11"`
+	Nickname string `json:"nickname" binding:"required,min=1,max=50"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// CreateAdmin 使用一次性初始化令牌创建第一个管理员
+func (ctrl *SetupController) CreateAdmin(c *gin.Context) {
+	var req SetupAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	user, err := ctrl.bootstrapService.CompleteSetup(req.Token, req.Phone, req.Nickname, req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrSetupCompleted) {
+			utils.Forbidden(c, err.Error())
+			return
+		}
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "管理员创建成功，请使用该账户登录", gin.H{
+		"user": gin.H{
+			"id":       user.ID,
+			"phone":    user.Phone,
+			"nickname": user.Nickname,
+			"role":     user.Role,
+		},
+	})
+}
@@ -0,0 +1,120 @@
+package controllers_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"testing"
+
+	"poker_score_backend/models"
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// legacyAdminHash 旧版内置默认管理员 admin123 的哈希
+const legacyAdminHash = "$2a$10$4wXVwkYAv50vhrkJs4acCO1VYMjvePZPySNDltOBHHGWr2AZ0nnIa"
+
+var setupTokenPattern = regexp.MustCompile(`初始化令牌.*: ([0-9a-f]{48})`)
+
+func setupStatus(t *testing.T, client *testutil.APIClient) bool {
+	t.Helper()
+
+	resp, err := client.Do(http.MethodGet, "/api/setup/status", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Data struct {
+			NeedsSetup bool `json:"needs_setup"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	return body.Data.NeedsSetup
+}
+
+func TestSetup_FirstVisitorCreatesAdminWithLoggedToken(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	engine, cleanup, err := testutil.NewTestServer(nil)
+	log.SetOutput(os.Stderr)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, cleanup()) })
+
+	match := setupTokenPattern.FindStringSubmatch(logs.String())
+	require.Len(t, match, 2)
+	token := match[1]
+
+	client := testutil.NewAPIClient(engine)
+	require.True(t, setupStatus(t, client))
+
+	phone := uniquePhone()
+	request := map[string]string{
+		"token":    "wrong",
+		"phone":    phone,
+		"nickname": "Admin",
+		"password": "secret1",
+	}
+	resp, err := client.Do(http.MethodPost, "/api/setup/admin", request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	request["token"] = token
+	resp, err = client.Do(http.MethodPost, "/api/setup/admin", request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.False(t, setupStatus(t, client))
+
+	loginUser(t, client, phone, "secret1")
+	requireStatus(t, client, http.MethodGet, "/api/admin/users", http.StatusOK)
+
+	// 令牌只能使用一次
+	request["phone"] = uniquePhone()
+	resp, err = client.Do(http.MethodPost, "/api/setup/admin", request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestSetup_AdminFromConfig(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.Admin.Phone = uniquePhone()
+	cfg.Admin.Password = "secret1"
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, cleanup()) })
+
+	client := testutil.NewAPIClient(engine)
+	require.False(t, setupStatus(t, client))
+
+	loginUser(t, client, cfg.Admin.Phone, "secret1")
+	requireStatus(t, client, http.MethodGet, "/api/admin/users", http.StatusOK)
+}
+
+func TestSetup_RefusesLegacyDefaultPasswordInProduction(t *testing.T) {
+	cfg := testutil.TestConfig()
+
+	// 先用同一个内存数据库写入旧版默认管理员，保持连接打开以免数据库被释放
+	require.NoError(t, models.InitDatabase(cfg.Database.Path, 1, 1, cfg.Database.ConnMaxLifetime))
+	seedDB, err := models.DB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { seedDB.Close() })
+	require.NoError(t, models.DB.Create(&models.User{
+		Phone:        "13800138000",
+		Nickname:     "系统管理员",
+		PasswordHash: legacyAdminHash,
+		Role:         "admin",
+	}).Error)
+
+	cfg.Env = "production"
+	_, _, err = testutil.NewTestServer(cfg)
+	require.ErrorContains(t, err, "旧版默认密码")
+
+	// 非生产环境只告警
+	cfg.Env = "development"
+	_, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	require.NoError(t, cleanup())
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorController 两步验证控制器
+type TwoFactorController struct {
+	twoFactorService *services.TwoFactorService
+}
+
+// NewTwoFactorController 创建两步验证控制器
+func NewTwoFactorController(twoFactorService *services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// TwoFactorCodeRequest 提交验证码的请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest 关闭两步验证请求
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // 验证码或恢复码
+}
+
+// GetStatus 获取两步验证状态
+func (ctrl *TwoFactorController) GetStatus(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	status, err := ctrl.twoFactorService.GetStatus(userID.(uint))
+	if err != nil {
+		utils.InternalServerError(c, "获取两步验证状态失败")
+		return
+	}
+
+	utils.Success(c, status)
+}
+
+// Setup 生成两步验证密钥与扫码地址
+func (ctrl *TwoFactorController) Setup(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	setup, err := ctrl.twoFactorService.BeginSetup(userID.(uint))
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, setup)
+}
+
+// Enable 校验验证码并启用两步验证，返回恢复码
+func (ctrl *TwoFactorController) Enable(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	codes, err := ctrl.twoFactorService.Enable(userID.(uint), c.GetString("session_id"), req.Code)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "两步验证已开启，请妥善保存恢复码", gin.H{
+		"recovery_codes": codes,
+	})
+}
+
+// Disable 关闭两步验证
+func (ctrl *TwoFactorController) Disable(c *gin.Context) {
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	if err := ctrl.twoFactorService.Disable(userID.(uint), req.Password, req.Code); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "两步验证已关闭", nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (ctrl *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	codes, err := ctrl.twoFactorService.RegenerateRecoveryCodes(userID.(uint), req.Code)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "恢复码已重新生成，旧的恢复码已失效", gin.H{
+		"recovery_codes": codes,
+	})
+}
@@ -0,0 +1,172 @@
+package controllers_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	"poker_score_backend/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// totpAt 模拟验证器应用计算 offset 个时间窗口之后的验证码
+func totpAt(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30+offset))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	index := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[index:index+4])&0x7fffffff)%1000000)
+}
+
+// enableTwoFactor 为已登录的客户端开启两步验证，返回密钥与恢复码
+func enableTwoFactor(t *testing.T, client *testutil.APIClient) (string, []string) {
+	t.Helper()
+
+	resp, err := client.Do(http.MethodPost, "/api/auth/2fa/setup", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var setup struct {
+		Data struct {
+			Secret          string `json:"secret"`
+			ProvisioningURI string `json:"provisioning_uri"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &setup)
+	require.Contains(t, setup.Data.ProvisioningURI, "otpauth://totp/PokerScoreTest:")
+
+	resp, err = client.Do(http.MethodPost, "/api/auth/2fa/enable", map[string]string{"code": totpAt(t, setup.Data.Secret, 0)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var enabled struct {
+		Data struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &enabled)
+	require.Len(t, enabled.Data.RecoveryCodes, 10)
+	return setup.Data.Secret, enabled.Data.RecoveryCodes
+}
+
+type loginStepResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		Challenge         string `json:"challenge"`
+		SessionID         string `json:"session_id"`
+	} `json:"data"`
+}
+
+func TestTwoFactor_LoginRequiresSecondStep(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	user := registerUser(t, testutil.NewAPIClient(engine), "两步验证")
+	secret, recoveryCodes := enableTwoFactor(t, user.Client)
+
+	device := testutil.NewAPIClient(engine)
+	resp, err := device.Do(http.MethodPost, "/api/auth/login", map[string]string{"phone": user.Phone, "password": testUserPassword})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var step loginStepResponse
+	decodeResponse(t, resp, &step)
+	require.Equal(t, "请输入两步验证码", step.Message)
+	require.True(t, step.Data.TwoFactorRequired)
+	require.NotEmpty(t, step.Data.Challenge)
+	require.Empty(t, step.Data.SessionID)
+	require.Nil(t, device.Cookie(testSessionCookieName))
+
+	resp, err = device.Do(http.MethodPost, "/api/auth/login/2fa", map[string]string{"challenge": step.Data.Challenge, "code": "000000"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = device.Do(http.MethodPost, "/api/auth/login/2fa", map[string]string{"challenge": step.Data.Challenge, "code": totpAt(t, secret, 1)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	decodeResponse(t, resp, &step)
+	require.Equal(t, "登录成功", step.Message)
+	require.NotEmpty(t, step.Data.SessionID)
+	requireStatus(t, device, http.MethodGet, "/api/auth/me", http.StatusOK)
+
+	// 令牌只能使用一次
+	resp, err = device.Do(http.MethodPost, "/api/auth/login/2fa", map[string]string{"challenge": step.Data.Challenge, "code": recoveryCodes[0]})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, err = user.Client.Do(http.MethodGet, "/api/auth/2fa", nil)
+	require.NoError(t, err)
+	var status struct {
+		Data struct {
+			Enabled                bool `json:"enabled"`
+			RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &status)
+	require.True(t, status.Data.Enabled)
+	require.Equal(t, 10, status.Data.RecoveryCodesRemaining)
+
+	resp, err = user.Client.Do(http.MethodPost, "/api/auth/2fa/disable", map[string]string{"password": testUserPassword, "code": recoveryCodes[0]})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	loginUser(t, testutil.NewAPIClient(engine), user.Phone, testUserPassword)
+}
+
+func TestTwoFactor_RequiredForAdmins(t *testing.T) {
+	cfg := testutil.TestConfig()
+	cfg.TwoFactor.RequireForAdmins = true
+
+	engine, cleanup, err := testutil.NewTestServer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, cleanup())
+	})
+
+	admin := registerUser(t, testutil.NewAPIClient(engine), "管理员")
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", admin.UserID).Update("role", "admin").Error)
+
+	resp, err := admin.Client.Do(http.MethodGet, "/api/admin/users", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	var body struct {
+		Message string `json:"message"`
+		Data    struct {
+			TwoFactorRequired bool `json:"two_factor_required"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, "管理员需开启两步验证并使用验证码登录", body.Message)
+	require.True(t, body.Data.TwoFactorRequired)
+
+	// 开启两步验证的Session视为已验证
+	secret, _ := enableTwoFactor(t, admin.Client)
+	requireStatus(t, admin.Client, http.MethodGet, "/api/admin/users", http.StatusOK)
+
+	// 其他设备必须完成第二步
+	device := testutil.NewAPIClient(engine)
+	resp, err = device.Do(http.MethodPost, "/api/auth/login", map[string]string{"phone": admin.Phone, "password": testUserPassword})
+	require.NoError(t, err)
+	var step loginStepResponse
+	decodeResponse(t, resp, &step)
+	require.True(t, step.Data.TwoFactorRequired)
+
+	resp, err = device.Do(http.MethodPost, "/api/auth/login/2fa", map[string]string{"challenge": step.Data.Challenge, "code": totpAt(t, secret, 1)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	requireStatus(t, device, http.MethodGet, "/api/admin/users", http.StatusOK)
+}
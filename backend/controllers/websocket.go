@@ -1,36 +1,102 @@
 package controllers
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"poker_score_backend/middlewares"
 	"poker_score_backend/models"
+	"poker_score_backend/services"
 	"poker_score_backend/utils"
 	ws "poker_score_backend/websocket"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	// 允许所有来源（生产环境应该限制）
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
+const (
+	// SSE心跳间隔，避免代理因空闲断开连接
+	sseHeartbeatPeriod = 25 * time.Second
+
+	// 建议客户端断线后的重连间隔（毫秒）
+	sseRetryMillis = 3000
+)
 
 // WebSocketController WebSocket控制器
 type WebSocketController struct {
-	hub *ws.Hub
+	hub            *ws.Hub
+	authService    *services.AuthService
+	allowedOrigins []string
+	upgrader       websocket.Upgrader
 }
 
 // NewWebSocketController 创建WebSocket控制器
-func NewWebSocketController(hub *ws.Hub) *WebSocketController {
-	return &WebSocketController{
-		hub: hub,
+func NewWebSocketController(hub *ws.Hub, authService *services.AuthService, allowedOrigins []string) *WebSocketController {
+	ctrl := &WebSocketController{
+		hub:            hub,
+		authService:    authService,
+		allowedOrigins: allowedOrigins,
+	}
+	ctrl.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     ctrl.checkOrigin,
 	}
+	return ctrl
+}
+
+// checkOrigin 校验WebSocket握手来源，防止跨站WebSocket劫持
+// 未携带Origin的请求来自非浏览器客户端，予以放行；同源请求和允许列表中的来源可以连接
+func (ctrl *WebSocketController) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if middlewares.IsOriginAllowed(origin, ctrl.allowedOrigins) {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+
+	log.Printf("拒绝WebSocket连接: 来源不在允许列表中, Origin=%s", origin)
+	return false
+}
+
+// IssueTicketRequest 签发WebSocket票据请求
+type IssueTicketRequest struct {
+	RoomID uint `json:"room_id" binding:"required"`
+}
+
+// IssueTicket 签发一次性WebSocket连接票据
+func (ctrl *WebSocketController) IssueTicket(c *gin.Context) {
+	var req IssueTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	ticket, err := ctrl.authService.IssueWSTicket(userID.(uint), req.RoomID)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"ticket":     ticket.Ticket,
+		"room_id":    ticket.RoomID,
+		"expires_at": ticket.ExpiresAt,
+		"expires_in": int(time.Until(ticket.ExpiresAt).Seconds()),
+	})
 }
 
 // HandleWebSocket 处理WebSocket连接
@@ -58,17 +124,157 @@ func (ctrl *WebSocketController) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// 协商协议版本：优先使用Sec-WebSocket-Protocol子协议，其次为查询参数
+	subprotocols := websocket.Subprotocols(c.Request)
+	version, err := negotiateProtocolVersion(c, subprotocols)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var responseHeader http.Header
+	if len(subprotocols) > 0 {
+		responseHeader = http.Header{"Sec-WebSocket-Protocol": {ws.Subprotocol(version)}}
+	}
+
 	// 升级为WebSocket连接
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := ctrl.upgrader.Upgrade(c.Writer, c.Request, responseHeader)
 	if err != nil {
 		log.Printf("WebSocket升级失败: %v", err)
 		return
 	}
 
 	// 创建WebSocket客户端并启动
-	ws.ServeWs(ctrl.hub, conn, userID.(uint), uint(roomID))
+	ws.ServeWs(ctrl.hub, conn, userID.(uint), uint(roomID), version)
+
+	log.Printf("WebSocket连接建立: RoomID=%d, UserID=%d, ProtocolVersion=%d", roomID, userID, version)
+}
+
+// HandleEventStream 通过Server-Sent Events推送房间事件（WebSocket不可用时的降级方案）
+// 消息内容与WebSocket广播完全一致，支持通过Last-Event-ID续传断线期间的事件
+func (ctrl *WebSocketController) HandleEventStream(c *gin.Context) {
+	roomIDStr := c.Param("room_id")
+	roomID, err := strconv.ParseUint(roomIDStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "房间ID格式错误")
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未登录")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var member models.RoomMember
+	err = models.DB.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error
+	if err != nil {
+		utils.BadRequest(c, "您不在该房间中")
+		return
+	}
+
+	// EventSource重连时会自动携带Last-Event-ID请求头；手动切换降级时可通过查询参数传入
+	lastEventIDStr := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if lastEventIDStr == "" {
+		lastEventIDStr = strings.TrimSpace(c.Query("last_event_id"))
+	}
+	var lastEventID uint64
+	if lastEventIDStr != "" {
+		lastEventID, err = strconv.ParseUint(lastEventIDStr, 10, 64)
+		if err != nil {
+			utils.BadRequest(c, "Last-Event-ID格式错误")
+			return
+		}
+	}
+
+	version, err := negotiateProtocolVersion(c, nil)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	sub, missed, resync := ctrl.hub.Subscribe(uint(roomID), userID, lastEventID)
+	defer ctrl.hub.Unsubscribe(sub)
+
+	ctrl.hub.MarkConnected(uint(roomID), userID)
+	defer func() {
+		ctrl.hub.MarkDisconnected(uint(roomID), userID)
+		log.Printf("SSE连接断开: RoomID=%d, UserID=%d", roomID, userID)
+	}()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // 关闭Nginx缓冲
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetryMillis)
+
+	if lastEventID == 0 || resync {
+		// 首次连接或无法续传时下发当前序号，客户端需重新拉取房间详情
+		var event ws.Event = ws.ConnectedEvent{RoomID: uint(roomID), ProtocolVersion: version}
+		if resync {
+			event = ws.ResyncEvent{RoomID: uint(roomID), ProtocolVersion: version}
+		}
+		payload, _ := ws.EncodeEvent(event)
+		writeSSEEvent(c.Writer, sub.StartID, payload)
+	}
+
+	for _, event := range missed {
+		writeSSEEvent(c.Writer, event.ID, event.Message)
+	}
+	c.Writer.Flush()
+
+	log.Printf("SSE连接建立: RoomID=%d, UserID=%d, LastEventID=%d, Missed=%d", roomID, userID, lastEventID, len(missed))
+
+	heartbeat := time.NewTicker(sseHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Hub因消费过慢关闭了订阅，客户端会携带Last-Event-ID自动重连
+				return
+			}
+			writeSSEEvent(c.Writer, event.ID, event.Message)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// negotiateProtocolVersion 根据子协议或 protocol_version 查询参数协商消息协议版本
+func negotiateProtocolVersion(c *gin.Context, subprotocols []string) (int, error) {
+	requested := subprotocols
+	if len(requested) == 0 {
+		if value := strings.TrimSpace(c.Query("protocol_version")); value != "" {
+			requested = strings.Split(value, ",")
+		}
+	}
+
+	return ws.NegotiateVersion(requested)
+}
+
+// writeSSEEvent 按SSE格式写出一条事件
+func writeSSEEvent(w http.ResponseWriter, id uint64, message []byte) {
+	fmt.Fprintf(w, "id: %d\n", id)
+	for _, line := range strings.Split(string(message), "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
 
-	log.Printf("WebSocket连接建立: RoomID=%d, UserID=%d", roomID, userID)
+// GetSchema 获取房间事件消息的JSON Schema
+func (ctrl *WebSocketController) GetSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, ws.JSONSchema())
 }
 
 // GetHub 获取Hub实例（供其他控制器使用）
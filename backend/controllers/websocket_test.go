@@ -0,0 +1,278 @@
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"poker_score_backend/testutil"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	ID   uint64
+	Type string
+	Data map[string]interface{}
+}
+
+func createRoom(t *testing.T, owner testUser, roomType string) (uint, string) {
+	t.Helper()
+
+	resp, err := owner.Client.Do(http.MethodPost, "/api/rooms", map[string]string{
+		"room_type": roomType,
+		"chip_rate": "20:1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Code int `json:"code"`
+		Data struct {
+			RoomID   uint   `json:"room_id"`
+			RoomCode string `json:"room_code"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, 0, body.Code)
+	return body.Data.RoomID, body.Data.RoomCode
+}
+
+func sessionIDOf(t *testing.T, user testUser) string {
+	t.Helper()
+
+	cookie := user.Client.Cookie(testSessionCookieName)
+	require.NotNil(t, cookie)
+	return cookie.Value
+}
+
+// openEventStream 建立SSE连接并返回逐条读取事件的函数
+func openEventStream(t *testing.T, server *httptest.Server, sessionID string, roomID uint, lastEventID uint64) func() sseEvent {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/rooms/%d/events", server.URL, roomID), nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	if lastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastEventID, 10))
+	}
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var current sseEvent
+		var data strings.Builder
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				current.ID, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+			case strings.HasPrefix(line, "data: "):
+				data.WriteString(strings.TrimPrefix(line, "data: "))
+			case line == "" && data.Len() > 0:
+				var msg struct {
+					Type string                 `json:"type"`
+					Data map[string]interface{} `json:"data"`
+				}
+				if err := json.Unmarshal([]byte(data.String()), &msg); err == nil {
+					current.Type = msg.Type
+					current.Data = msg.Data
+					events <- current
+				}
+				current = sseEvent{}
+				data.Reset()
+			}
+		}
+	}()
+
+	return func() sseEvent {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			require.True(t, ok, "SSE连接被意外关闭")
+			return event
+		case <-time.After(3 * time.Second):
+			t.Fatal("等待SSE事件超时")
+			return sseEvent{}
+		}
+	}
+}
+
+func TestRoomEventStream_StreamsAndResumes(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "SSE房主")
+	member := registerUser(t, testutil.NewAPIClient(engine), "SSE成员")
+
+	roomID, roomCode := createRoom(t, owner, "texas")
+	resp, err := member.Client.Do(http.MethodPost, "/api/rooms/join", map[string]string{"room_code": roomCode})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	next := openEventStream(t, server, sessionIDOf(t, member), roomID, 0)
+	connected := next()
+	require.Equal(t, "connected", connected.Type)
+	require.NotZero(t, connected.ID)
+
+	// 建立连接后成员变为在线
+	online := next()
+	require.Equal(t, "presence_changed", online.Type)
+	require.Equal(t, connected.ID+1, online.ID)
+	require.Equal(t, "online", online.Data["presence"])
+	require.EqualValues(t, member.UserID, online.Data["user_id"])
+
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/bet", roomID), map[string]int{"amount": 100})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	bet := next()
+	require.Equal(t, "bet", bet.Type)
+	require.Equal(t, online.ID+1, bet.ID)
+	require.EqualValues(t, 100, bet.Data["amount"])
+	require.EqualValues(t, owner.UserID, bet.Data["user_id"])
+
+	resp, err = owner.Client.Do(http.MethodPost, fmt.Sprintf("/api/rooms/%d/withdraw", roomID), map[string]int{"amount": 40})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "withdraw", next().Type)
+
+	// 携带Last-Event-ID重连时应补发断线后的事件
+	resumed := openEventStream(t, server, sessionIDOf(t, member), roomID, bet.ID)
+	replayed := resumed()
+	require.Equal(t, "withdraw", replayed.Type)
+	require.Equal(t, bet.ID+1, replayed.ID)
+	require.EqualValues(t, 40, replayed.Data["amount"])
+
+	// 序号不在历史中时要求客户端重新同步
+	stale := openEventStream(t, server, sessionIDOf(t, member), roomID, 1)
+	require.Equal(t, "resync", stale().Type)
+}
+
+func TestRoomEventStream_RejectsNonMember(t *testing.T) {
+	engine, _ := newTestEnv(t)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "SSE房主")
+	outsider := registerUser(t, testutil.NewAPIClient(engine), "SSE路人")
+	roomID, _ := createRoom(t, owner, "texas")
+
+	resp, err := outsider.Client.Do(http.MethodGet, fmt.Sprintf("/api/rooms/%d/events", roomID), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, 400, body.Code)
+	require.Equal(t, "您不在该房间中", body.Message)
+}
+
+func issueWSTicket(t *testing.T, user testUser, roomID uint) string {
+	t.Helper()
+
+	resp, err := user.Client.Do(http.MethodPost, "/api/ws/ticket", map[string]uint{"room_id": roomID})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Code int `json:"code"`
+		Data struct {
+			Ticket    string `json:"ticket"`
+			RoomID    uint   `json:"room_id"`
+			ExpiresIn int    `json:"expires_in"`
+		} `json:"data"`
+	}
+	decodeResponse(t, resp, &body)
+	require.Equal(t, 0, body.Code)
+	require.NotEmpty(t, body.Data.Ticket)
+	require.Equal(t, roomID, body.Data.RoomID)
+	require.Positive(t, body.Data.ExpiresIn)
+	return body.Data.Ticket
+}
+
+func dialRoom(server *httptest.Server, roomID uint, query, origin string) (*websocket.Conn, *http.Response, error) {
+	wsURL := fmt.Sprintf("ws%s/api/ws/room/%d?%s", strings.TrimPrefix(server.URL, "http"), roomID, query)
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	return websocket.DefaultDialer.Dial(wsURL, header)
+}
+
+func TestWebSocketTicket_SingleUse(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "票据房主")
+	roomID, _ := createRoom(t, owner, "texas")
+
+	ticket := issueWSTicket(t, owner, roomID)
+	conn, _, err := dialRoom(server, roomID, "ticket="+ticket, "http://localhost")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	var hello struct {
+		Type string `json:"type"`
+	}
+	require.NoError(t, conn.ReadJSON(&hello))
+	require.Equal(t, "hello", hello.Type)
+
+	// 票据只能使用一次
+	_, resp, err := dialRoom(server, roomID, "ticket="+ticket, "http://localhost")
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 票据与房间绑定
+	otherRoomID, _ := createRoom(t, owner, "niuniu")
+	_, resp, err = dialRoom(server, otherRoomID, "ticket="+issueWSTicket(t, owner, roomID), "")
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestWebSocket_RejectsForeignOriginAndQuerySession(t *testing.T) {
+	engine, _ := newTestEnv(t)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	owner := registerUser(t, testutil.NewAPIClient(engine), "来源房主")
+	outsider := registerUser(t, testutil.NewAPIClient(engine), "来源路人")
+	roomID, _ := createRoom(t, owner, "texas")
+
+	_, resp, err := dialRoom(server, roomID, "ticket="+issueWSTicket(t, owner, roomID), "https://evil.example")
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 长期有效的Session ID不再允许出现在URL中
+	_, resp, err = dialRoom(server, roomID, "session_id="+sessionIDOf(t, owner), "http://localhost")
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 非房间成员无法换取票据
+	resp2, err := outsider.Client.Do(http.MethodPost, "/api/ws/ticket", map[string]uint{"room_id": roomID})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp2.Code)
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
 	"poker_score_backend/app"
 	"poker_score_backend/config"
+	"poker_score_backend/models"
+	"poker_score_backend/services"
 )
 
 func main() {
 	// 加载配置
 	cfg := config.GetConfig()
 
+	// 子命令
+	if len(os.Args) > 1 && os.Args[1] == "create-admin" {
+		if err := runCreateAdmin(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("创建管理员失败: %v", err)
+		}
+		return
+	}
+
 	// 初始化服务器
 	engine, cleanup, err := app.NewServer(cfg)
 	if err != nil {
@@ -29,3 +42,36 @@ func main() {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
 }
+
+// runCreateAdmin 创建管理员，手机号已注册时提升为管理员并重置密码
+// 用法: ./server create-admin -phone 13800000000 -nickname 管理员 -password xxxxxx
+// 未传 -phone/-password 时读取 ADMIN_PHONE/ADMIN_PASSWORD，避免密码出现在进程列表中
+func runCreateAdmin(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	phone := flags.String("phone", cfg.Admin.Phone, "管理员手机号（默认读取 ADMIN_PHONE）")
+	nickname := flags.String("nickname", cfg.Admin.Nickname, "管理员昵称（默认读取 ADMIN_NICKNAME）")
+	password := flags.String("password", cfg.Admin.Password, "管理员密码（默认读取 ADMIN_PASSWORD）")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *phone == "" || *password == "" {
+		return fmt.Errorf("必须指定手机号和密码")
+	}
+
+	if err := models.InitDatabase(
+		cfg.Database.Path,
+		cfg.Database.MaxIdleConns,
+		cfg.Database.MaxOpenConns,
+		cfg.Database.ConnMaxLifetime,
+	); err != nil {
+		return fmt.Errorf("初始化数据库失败: %w", err)
+	}
+	defer models.CloseDatabase()
+
+	user, err := services.NewBootstrapService().CreateAdmin(*phone, *nickname, *password)
+	if err != nil {
+		return err
+	}
+	log.Printf("管理员账户已就绪: ID=%d, 手机号=%s, 昵称=%s", user.ID, user.Phone, user.Nickname)
+	return nil
+}
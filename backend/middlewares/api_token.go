@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"net/http"
+	"poker_score_backend/models"
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenAnyScope 任意权限范围的令牌都可以访问
+const apiTokenAnyScope = "*"
+
+// apiTokenRoutes 允许使用个人API令牌访问的接口及所需权限范围，键为“方法 路由”
+// 未列出的接口（账户设置、令牌管理、后台等）只接受Session
+var apiTokenRoutes = map[string]string{
+	"GET /api/auth/me": apiTokenAnyScope,
+
+	"GET /api/records/tonight":          models.APIScopeRecordsRead,
+	"GET /api/records/tonight/timeline": models.APIScopeRecordsRead,
+	"GET /api/records/me/stats":         models.APIScopeRecordsRead,
+	"GET /api/records/me/analytics":     models.APIScopeRecordsRead,
+	"GET /api/records/head-to-head":     models.APIScopeRecordsRead,
+	"GET /api/achievements":             models.APIScopeRecordsRead,
+
+	"GET /api/rooms/last":                     models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id":                 models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id/operations":      models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id/history-amounts": models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id/timeline":        models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id/recap":           models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id/messages":        models.APIScopeRoomsRead,
+	"GET /api/rooms/:room_id/events":          models.APIScopeRoomsRead,
+	"POST /api/rooms/:room_id/bet":            models.APIScopeRoomsOperate,
+	"POST /api/rooms/:room_id/withdraw":       models.APIScopeRoomsOperate,
+	"POST /api/rooms/:room_id/force-transfer": models.APIScopeRoomsOperate,
+	"POST /api/rooms/:room_id/niuniu-bet":     models.APIScopeRoomsOperate,
+	"GET /api/ws/room/:room_id":               models.APIScopeRoomsOperate, // WebSocket连接可以上行发送聊天和表情，不属于只读权限
+}
+
+// authenticateAPIToken 校验个人API令牌以及令牌对当前接口的权限，失败时写入响应并返回 false
+func authenticateAPIToken(c *gin.Context, apiTokenService *services.APITokenService, raw string) bool {
+	required, ok := apiTokenRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		utils.Forbidden(c, "该接口不支持使用API令牌访问")
+		return false
+	}
+
+	token, user, err := apiTokenService.Authenticate(raw, c.ClientIP())
+	if err != nil {
+		utils.Unauthorized(c, err.Error())
+		return false
+	}
+
+	if required != apiTokenAnyScope && !token.HasScope(required) {
+		utils.ErrorWithData(c, http.StatusForbidden, 403, "API令牌没有访问该接口的权限", gin.H{
+			"required_scope": required,
+		})
+		return false
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("user", *user)
+	c.Set("api_token_id", token.ID)
+	c.Set("two_factor", false)
+	return true
+}
@@ -1,15 +1,22 @@
 package middlewares
 
 import (
+	"net/http"
 	"poker_score_backend/models"
+	"poker_score_backend/services"
 	"poker_score_backend/utils"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AuthMiddleware 认证中间件
-// 支持从Cookie、Authorization Header或Query参数中获取Session ID
-func AuthMiddleware(sessionCookieName string) gin.HandlerFunc {
+// 支持从Cookie或Authorization Header中获取Session ID；
+// Session ID长期有效，不接受通过Query参数传递，以免被代理记录到访问日志中。
+// 校验通过后会记录Session的使用时间与IP，并顺延闲置过期时间。
+// Authorization Header 中以 pst_ 开头的是个人API令牌，只能访问令牌权限范围内的接口
+func AuthMiddleware(sessionCookieName string, authService *services.AuthService, apiTokenService *services.APITokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var sessionID string
 		var err error
@@ -25,12 +32,16 @@ func AuthMiddleware(sessionCookieName string) gin.HandlerFunc {
 			}
 		}
 
-		// 2. 如果Header中没有，尝试从Query参数获取（用于WebSocket）
-		if sessionID == "" {
-			sessionID = c.Query("session_id")
+		if apiTokenService != nil && strings.HasPrefix(sessionID, services.APITokenPrefix) {
+			if !authenticateAPIToken(c, apiTokenService, sessionID) {
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
 		}
 
-		// 3. 如果Query参数中没有，尝试从Cookie获取
+		// 2. 如果Header中没有，尝试从Cookie获取
 		if sessionID == "" {
 			sessionID, err = c.Cookie(sessionCookieName)
 			if err != nil || sessionID == "" {
@@ -40,43 +51,63 @@ func AuthMiddleware(sessionCookieName string) gin.HandlerFunc {
 			}
 		}
 
-		// 查询Session
-		var session models.Session
-		err = models.DB.Where("session_id = ?", sessionID).First(&session).Error
+		session, user, err := authService.AuthenticateSession(sessionID, services.SessionClient{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
 		if err != nil {
-			utils.Unauthorized(c, "未登录或Session已过期，请重新登录")
+			utils.Unauthorized(c, err.Error())
 			c.Abort()
 			return
 		}
 
-		// 检查Session是否过期
-		if session.IsExpired() {
-			// 删除过期的Session
-			models.DB.Delete(&session)
-			utils.Unauthorized(c, "Session已过期，请重新登录")
+		// 将用户信息存入上下文
+		c.Set("user_id", user.ID)
+		c.Set("user", *user)
+		c.Set("session_id", session.SessionID)
+		c.Set("two_factor", session.TwoFactor)
+
+		c.Next()
+	}
+}
+
+// WebSocketAuthMiddleware WebSocket认证中间件
+// 浏览器无法为WebSocket握手设置Header，可先调用 POST /api/ws/ticket 换取一次性票据，
+// 再通过 ?ticket= 传入；未携带票据时与AuthMiddleware相同
+func WebSocketAuthMiddleware(sessionCookieName string, authService *services.AuthService, apiTokenService *services.APITokenService) gin.HandlerFunc {
+	sessionAuth := AuthMiddleware(sessionCookieName, authService, apiTokenService)
+
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			sessionAuth(c)
+			return
+		}
+
+		roomID, err := strconv.ParseUint(c.Param("room_id"), 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "房间ID格式错误")
 			c.Abort()
 			return
 		}
 
-		// 查询用户信息
-		var user models.User
-		err = models.DB.First(&user, session.UserID).Error
+		user, err := authService.ConsumeWSTicket(ticket, uint(roomID))
 		if err != nil {
-			utils.Unauthorized(c, "用户不存在")
+			utils.Unauthorized(c, err.Error())
 			c.Abort()
 			return
 		}
 
-		// 将用户信息存入上下文
 		c.Set("user_id", user.ID)
-		c.Set("user", user)
+		c.Set("user", *user)
 
 		c.Next()
 	}
 }
 
 // AdminMiddleware 管理员权限中间件
-func AdminMiddleware() gin.HandlerFunc {
+// requireTwoFactor 为 true 时，管理员还必须使用通过两步验证登录的Session
+func AdminMiddleware(requireTwoFactor bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户信息
 		userInterface, exists := c.Get("user")
@@ -100,6 +131,14 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if requireTwoFactor && !c.GetBool("two_factor") {
+			utils.ErrorWithData(c, http.StatusForbidden, 403, "管理员需开启两步验证并使用验证码登录", gin.H{
+				"two_factor_required": true,
+			})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -9,27 +9,7 @@ func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
 
-		allowAll := false
-		for _, allowedOrigin := range allowedOrigins {
-			if allowedOrigin == "*" {
-				allowAll = true
-				break
-			}
-		}
-
-		allowed := false
-		if allowAll && origin != "" {
-			allowed = true
-		} else {
-			for _, allowedOrigin := range allowedOrigins {
-				if origin == allowedOrigin {
-					allowed = true
-					break
-				}
-			}
-		}
-
-		if allowed {
+		if IsOriginAllowed(origin, allowedOrigins) {
 			if origin != "" {
 				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 			}
@@ -46,3 +26,16 @@ func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// IsOriginAllowed 判断来源是否在允许列表中，列表包含 "*" 时允许任意非空来源
+func IsOriginAllowed(origin string, allowedOrigins []string) bool {
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == "*" && origin != "" {
+			return true
+		}
+		if origin == allowedOrigin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"fmt"
+	"poker_score_backend/services"
+	"poker_score_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc 从请求中提取限流使用的key
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// ClientIPKey 按客户端IP限流，用于登录、注册等无需登录的接口
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// UserIDKey 按当前登录用户限流，需放在 AuthMiddleware 之后；未登录时退化为按IP
+func UserIDKey(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return c.ClientIP()
+}
+
+// RateLimitMiddleware 令牌桶限流中间件，超出频率时返回429并通过 Retry-After 告知需要等待的秒数
+func RateLimitMiddleware(limiter *services.RateLimiter, scope string, key RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if allowed, retryAfter := limiter.Allow(scope, key(c)); !allowed {
+			utils.TooManyRequests(c, "请求过于频繁，请稍后再试", retryAfter)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
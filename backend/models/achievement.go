@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// UserAchievement 用户获得的成就徽章，每个成就每人只获得一次
+type UserAchievement struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_achievement" json:"user_id"`      // 用户ID
+	Code      string    `gorm:"size:50;not null;uniqueIndex:idx_user_achievement" json:"code"` // 成就代码
+	RoomID    *uint     `json:"room_id,omitempty"`                                             // 达成时所在的房间
+	EarnedAt  time.Time `gorm:"not null;index" json:"earned_at"`                               // 达成时间（历史记录中首次满足条件的时间）
+	CreatedAt time.Time `json:"created_at"`                                                    // 授予时间
+}
+
+// TableName 指定表名
+func (UserAchievement) TableName() string {
+	return "user_achievements"
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API令牌权限范围
+const (
+	APIScopeRecordsRead  = "records:read"  // 读取战绩、统计与成就
+	APIScopeRoomsRead    = "rooms:read"    // 读取房间详情、操作记录与聊天
+	APIScopeRoomsOperate = "rooms:operate" // 在房间内下注、收回、强制转移
+)
+
+// APIToken 用户为脚本、机器人生成的个人API令牌
+// 数据库只保存令牌的哈希，明文只在创建时返回一次
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`         // 所属用户ID
+	Name       string     `gorm:"size:50;not null" json:"name"`          // 令牌用途说明
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`        // 令牌开头的几个字符，便于用户辨认
+	TokenHash  string     `gorm:"uniqueIndex;size:64;not null" json:"-"` // 令牌哈希（SHA-256）
+	Scopes     string     `gorm:"size:255;not null" json:"scopes"`       // 权限范围，逗号分隔
+	LastUsedAt *time.Time `json:"last_used_at"`                          // 最近一次使用时间
+	LastUsedIP string     `gorm:"size:64" json:"last_used_ip"`           // 最近一次使用时的IP
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`               // 过期时间，为空表示长期有效
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// ScopeList 令牌的权限范围列表
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope 令牌是否具有指定权限
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired 判断令牌是否过期
+func (t *APIToken) IsExpired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ChatMessage 房间聊天消息模型
+type ChatMessage struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	RoomID      uint           `gorm:"not null;index:idx_room_chat" json:"room_id"` // 房间ID
+	UserID      uint           `gorm:"not null;index" json:"user_id"`               // 发送者用户ID
+	MessageType string         `gorm:"size:20;not null" json:"message_type"`        // 消息类型：text/reaction
+	Content     string         `gorm:"type:text;not null" json:"content"`           // 文本内容或表情
+	OperationID *uint          `gorm:"index" json:"operation_id,omitempty"`         // 表情回应的操作记录ID
+	CreatedAt   time.Time      `gorm:"index:idx_room_chat" json:"created_at"`       // 发送时间
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`                              // 删除时间（软删除）
+	DeletedBy   *uint          `json:"deleted_by,omitempty"`                        // 删除者用户ID
+}
+
+// TableName 指定表名
+func (ChatMessage) TableName() string {
+	return "chat_messages"
+}
+
+// 聊天消息类型常量
+const (
+	ChatTypeText     = "text"     // 文本消息
+	ChatTypeReaction = "reaction" // 对操作记录的表情回应
+)
+
+// migrateChatReactionIndex 为表情回应建立唯一索引，同一用户对同一操作记录的同一表情只能保留一条
+// 建索引前先删除已有的重复回应（保留最早的一条）；软删除的回应不参与，撤回后可以重新发送
+func migrateChatReactionIndex() error {
+	if err := DB.Exec(`DELETE FROM chat_messages
+		WHERE message_type = ? AND deleted_at IS NULL AND id NOT IN (
+			SELECT MIN(id) FROM chat_messages
+			WHERE message_type = ? AND deleted_at IS NULL
+			GROUP BY room_id, user_id, operation_id, content
+		)`, ChatTypeReaction, ChatTypeReaction).Error; err != nil {
+		return err
+	}
+
+	return DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_reaction_unique
+		ON chat_messages(room_id, user_id, operation_id, content)
+		WHERE message_type = '` + ChatTypeReaction + `' AND deleted_at IS NULL`).Error
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// Club 俱乐部模型，固定牌友组成的长期群组
+type Club struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Name          string    `gorm:"size:50;not null" json:"name"`                   // 俱乐部名称
+	Description   string    `gorm:"size:200" json:"description"`                    // 俱乐部简介
+	InviteCode    string    `gorm:"size:8;not null;uniqueIndex" json:"invite_code"` // 邀请码
+	CreatedBy     uint      `gorm:"not null;index" json:"created_by"`               // 创建者用户ID
+	Timezone      string    `gorm:"size:64;not null;default:''" json:"timezone"`    // 俱乐部统计使用的IANA时区，为空时使用服务器时区
+	DayCutoffHour int       `gorm:"not null;default:7" json:"day_cutoff_hour"`      // 俱乐部“一晚”的分界时刻（0-23点）
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Club) TableName() string {
+	return "clubs"
+}
+
+// ClubMember 俱乐部成员模型
+type ClubMember struct {
+	ID       uint      `gorm:"primaryKey" json:"id"`
+	ClubID   uint      `gorm:"not null;uniqueIndex:idx_club_user" json:"club_id"`       // 俱乐部ID
+	UserID   uint      `gorm:"not null;uniqueIndex:idx_club_user;index" json:"user_id"` // 用户ID
+	Role     string    `gorm:"size:20;not null;default:'member'" json:"role"`           // 俱乐部角色：owner/admin/member
+	JoinedAt time.Time `gorm:"not null" json:"joined_at"`                               // 加入时间
+}
+
+// TableName 指定表名
+func (ClubMember) TableName() string {
+	return "club_members"
+}
+
+// IsAdmin 俱乐部管理员（含创建者），与全局管理员角色相互独立
+func (m *ClubMember) IsAdmin() bool {
+	return m.Role == ClubRoleOwner || m.Role == ClubRoleAdmin
+}
+
+// ClubPayment 俱乐部成员之间的线下还款记录，用于冲抵跨场次累计的账目
+type ClubPayment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ClubID     uint      `gorm:"not null;index" json:"club_id"`                 // 俱乐部ID
+	FromUserID uint      `gorm:"not null;index" json:"from_user_id"`            // 付款人
+	ToUserID   uint      `gorm:"not null;index" json:"to_user_id"`              // 收款人
+	RmbAmount  float64   `gorm:"type:decimal(10,2);not null" json:"rmb_amount"` // 金额（人民币）
+	Note       string    `gorm:"size:200" json:"note"`                          // 备注
+	RecordedBy uint      `gorm:"not null" json:"recorded_by"`                   // 登记人
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ClubPayment) TableName() string {
+	return "club_payments"
+}
+
+// 俱乐部角色常量
+const (
+	ClubRoleOwner  = "owner"  // 创建者
+	ClubRoleAdmin  = "admin"  // 管理员
+	ClubRoleMember = "member" // 普通成员
+)
@@ -44,64 +44,45 @@ func InitDatabase(dbPath string, maxIdleConns, maxOpenConns int, connMaxLifetime
 
 	log.Println("数据库表迁移成功")
 
-	// 初始化默认管理员账户
-	err = initAdminUser()
-	if err != nil {
-		return err
-	}
-
 	log.Println("数据库初始化完成")
 	return nil
 }
 
 // autoMigrate 自动迁移所有表
 func autoMigrate() error {
-	return DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&User{},
 		&Session{},
+		&WSTicket{},
 		&Room{},
 		&RoomMember{},
 		&UserBalance{},
 		&RoomOperation{},
 		&Settlement{},
 		&BetRecord{},
+		&ChatMessage{},
+		&Club{},
+		&ClubMember{},
+		&ClubPayment{},
+		&Season{},
+		&SeasonStanding{},
+		&UserAchievement{},
+		&RoomRecap{},
+		&VerificationCode{},
+		&LoginLockout{},
+		&TwoFactor{},
+		&RecoveryCode{},
+		&LoginChallenge{},
+		&APIToken{},
+		&GuestClaim{},
+		&OIDCIdentity{},
+		&OIDCLoginState{},
 	)
-}
-
-// initAdminUser 初始化默认管理员账户
-func initAdminUser() error {
-	// 检查管理员账户是否已存在
-	var count int64
-	err := DB.Model(&User{}).Where("role = ?", "admin").Count(&count).Error
-	if err != nil {
-		return err
-	}
-
-	// 如果已存在管理员，则不创建
-	if count > 0 {
-		log.Println("管理员账户已存在，跳过创建")
-		return nil
-	}
-
-	// 导入密码加密工具
-	// 这里直接使用bcrypt加密，避免循环依赖
-	passwordHash := "$2a$10$4wXVwkYAv50vhrkJs4acCO1VYMjvePZPySNDltOBHHGWr2AZ0nnIa" // admin123
-
-	// 创建默认管理员账户
-	admin := User{
-		Phone:        "13800138000",
-		Nickname:     "系统管理员",
-		PasswordHash: passwordHash,
-		Role:         "admin",
-	}
-
-	err = DB.Create(&admin).Error
 	if err != nil {
 		return err
 	}
 
-	log.Println("默认管理员账户创建成功: 手机号 13800138000, 密码 admin123")
-	return nil
+	return migrateChatReactionIndex()
 }
 
 // CloseDatabase 关闭数据库连接
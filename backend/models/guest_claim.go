@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// GuestClaim 游客认领码，房主生成后交给游客本人，注册后凭认领码把游客的战绩合并到自己的账户
+type GuestClaim struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	GuestID   uint      `gorm:"uniqueIndex;not null" json:"guest_id"`     // 游客用户ID，每个游客同时只有一个有效认领码
+	Code      string    `gorm:"uniqueIndex;size:16;not null" json:"code"` // 认领码
+	CreatedBy uint      `gorm:"not null" json:"created_by"`               // 生成认领码的房主
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`         // 过期时间
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (GuestClaim) TableName() string {
+	return "guest_claims"
+}
+
+// IsExpired 判断认领码是否过期
+func (c *GuestClaim) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginLockout 手机号登录失败记录与锁定状态
+// 连续失败达到阈值后锁定一段时间，多次锁定时长逐次翻倍；登录成功后删除记录
+type LoginLockout struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Phone          string     `gorm:"uniqueIndex;size:11;not null" json:"phone"` // 登录使用的手机号（可能未注册）
+	FailedAttempts int        `gorm:"not null;default:0" json:"failed_attempts"` // 本轮锁定前连续失败的次数
+	LockCount      int        `gorm:"not null;default:0" json:"lock_count"`      // 已被锁定的次数，决定下次锁定时长
+	LockedUntil    *time.Time `gorm:"index" json:"locked_until"`                 // 锁定截止时间，为空表示未锁定
+	LastFailedAt   time.Time  `gorm:"not null" json:"last_failed_at"`            // 最近一次失败的时间
+	LastFailedIP   string     `gorm:"size:64" json:"last_failed_ip"`             // 最近一次失败的IP
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LoginLockout) TableName() string {
+	return "login_lockouts"
+}
+
+// IsLocked 判断当前是否处于锁定中
+func (l *LoginLockout) IsLocked() bool {
+	return l.LockedUntil != nil && time.Now().Before(*l.LockedUntil)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCIdentity 绑定到用户的单点登录身份，同一身份提供方的同一账号（iss + sub）只能绑定一个用户
+type OIDCIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`                                  // 用户ID
+	Issuer      string     `gorm:"size:255;not null;uniqueIndex:idx_oidc_identity" json:"issuer"`  // 身份提供方（ID Token 的 iss）
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_oidc_identity" json:"subject"` // 身份提供方中的账号（ID Token 的 sub）
+	Email       string     `gorm:"size:255;not null;default:''" json:"email"`                      // 最近一次登录时的邮箱，仅用于展示
+	DisplayName string     `gorm:"size:100;not null;default:''" json:"display_name"`               // 最近一次登录时的名称，仅用于展示
+	LastLoginAt *time.Time `json:"last_login_at"`                                                  // 最近一次通过该身份登录的时间
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OIDCIdentity) TableName() string {
+	return "oidc_identities"
+}
+
+// OIDCLoginState 跳转到身份提供方期间保存的登录状态，回调时按 state 取出并删除
+type OIDCLoginState struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	State        string    `gorm:"uniqueIndex;size:64;not null" json:"-"`        // 回调时校验的 state 参数
+	Nonce        string    `gorm:"size:64;not null" json:"-"`                    // 写入 ID Token 的 nonce，防止重放
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`                   // PKCE code_verifier
+	UserID       uint      `gorm:"not null;default:0;index" json:"user_id"`      // 绑定身份时为发起绑定的用户，登录时为0
+	RedirectPath string    `gorm:"size:255;not null;default:''" json:"redirect"` // 完成后跳转的前端路径
+	ExpiresAt    time.Time `gorm:"index;not null" json:"expires_at"`             // 过期时间
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+// IsExpired 判断登录状态是否过期
+func (s *OIDCLoginState) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
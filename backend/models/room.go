@@ -12,6 +12,7 @@ type Room struct {
 	ChipRate    string     `gorm:"size:20;not null" json:"chip_rate"`                     // 积分与人民币比例（如"20:1"）
 	Status      string     `gorm:"size:20;not null;default:'active';index" json:"status"` // 房间状态：active/dissolved
 	CreatedBy   uint       `gorm:"not null;index" json:"created_by"`                      // 创建者用户ID
+	ClubID      *uint      `gorm:"index" json:"club_id,omitempty"`                        // 所属俱乐部ID（可为空）
 	CreatedAt   time.Time  `gorm:"index:idx_room_code" json:"created_at"`
 	DissolvedAt *time.Time `json:"dissolved_at,omitempty"` // 解散时间
 }
@@ -11,6 +11,8 @@ type RoomMember struct {
 	UserID   uint      `gorm:"not null;index:idx_room_user" json:"user_id"`           // 用户ID
 	JoinedAt time.Time `gorm:"not null;index" json:"joined_at"`                       // 加入时间
 	Status   string    `gorm:"size:20;not null;default:'online';index" json:"status"` // 状态：online/offline
+
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"` // 最近一次实时连接活跃时间
 }
 
 // TableName 指定表名
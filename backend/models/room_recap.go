@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RoomRecap 房间解散时生成的复盘报告，每个房间只生成一份
+type RoomRecap struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RoomID      uint      `gorm:"not null;uniqueIndex" json:"room_id"` // 房间ID
+	Content     string    `gorm:"type:text;not null" json:"content"`   // 复盘内容（JSON格式）
+	GeneratedAt time.Time `gorm:"not null" json:"generated_at"`        // 生成时间
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RoomRecap) TableName() string {
+	return "room_recaps"
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Season 赛季模型，ClubID 为空表示全服赛季
+type Season struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	Name            string     `gorm:"size:50;not null" json:"name"`                      // 赛季名称
+	ClubID          *uint      `gorm:"index" json:"club_id,omitempty"`                    // 所属俱乐部ID（为空表示全服）
+	StartAt         time.Time  `gorm:"not null;index" json:"start_at"`                    // 开始时间（含）
+	EndAt           time.Time  `gorm:"not null;index" json:"end_at"`                      // 结束时间（不含）
+	RmbWeight       float64    `gorm:"not null" json:"rmb_weight"`                        // 每1元净盈亏折算的积分
+	PlacementPoints []float64  `gorm:"serializer:json;type:text" json:"placement_points"` // 单场名次积分，依次为第1、2、3…名
+	AttendanceBonus float64    `gorm:"not null" json:"attendance_bonus"`                  // 每参加一场的出勤积分
+	CreatedBy       uint       `gorm:"not null" json:"created_by"`                        // 创建者用户ID
+	CreatedAt       time.Time  `json:"created_at"`
+	FinalizedAt     *time.Time `json:"finalized_at,omitempty"` // 最终排名固化时间，之后不再重新计算
+}
+
+// TableName 指定表名
+func (Season) TableName() string {
+	return "seasons"
+}
+
+// SeasonStanding 赛季排名快照，每晚定时写入一次
+type SeasonStanding struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	SeasonID         uint      `gorm:"not null;uniqueIndex:idx_season_snapshot_user" json:"season_id"`             // 赛季ID
+	SnapshotDate     string    `gorm:"size:10;not null;uniqueIndex:idx_season_snapshot_user" json:"snapshot_date"` // 快照日期（YYYY-MM-DD）
+	UserID           uint      `gorm:"not null;uniqueIndex:idx_season_snapshot_user" json:"user_id"`               // 用户ID
+	Nickname         string    `gorm:"size:50;not null" json:"nickname"`                                           // 快照时的昵称
+	Rank             int       `gorm:"not null" json:"rank"`                                                       // 名次
+	Points           float64   `gorm:"not null" json:"points"`                                                     // 总积分
+	RmbPoints        float64   `gorm:"not null" json:"rmb_points"`                                                 // 净盈亏积分
+	PlacementPoints  float64   `gorm:"not null" json:"placement_points"`                                           // 名次积分
+	AttendancePoints float64   `gorm:"not null" json:"attendance_points"`                                          // 出勤积分
+	Sessions         int       `gorm:"not null" json:"sessions"`                                                   // 参加场次
+	Wins             int       `gorm:"not null" json:"wins"`                                                       // 单场第一名次数
+	NetChip          int       `gorm:"not null" json:"net_chip"`                                                   // 积分净盈亏
+	NetRmb           float64   `gorm:"type:decimal(10,2);not null" json:"net_rmb"`                                 // 人民币净盈亏
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SeasonStanding) TableName() string {
+	return "season_standings"
+}
@@ -6,11 +6,15 @@ import (
 
 // Session Session模型
 type Session struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	SessionID string    `gorm:"uniqueIndex;size:255;not null" json:"session_id"` // Session标识符（UUID）
-	UserID    uint      `gorm:"not null;index" json:"user_id"`                    // 用户ID
-	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"` // 过期时间
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SessionID  string    `gorm:"uniqueIndex;size:255;not null" json:"session_id"` // Session标识符（UUID）
+	UserID     uint      `gorm:"not null;index" json:"user_id"`                   // 用户ID
+	UserAgent  string    `gorm:"size:500" json:"user_agent"`                      // 登录时的User-Agent
+	IP         string    `gorm:"size:64" json:"ip"`                               // 最近一次使用时的IP
+	LastUsedAt time.Time `json:"last_used_at"`                                    // 最近一次使用时间
+	TwoFactor  bool      `gorm:"not null;default:false" json:"two_factor"`        // 登录时是否通过了两步验证
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `gorm:"index;not null" json:"expires_at"` // 过期时间（随使用滑动延长，不超过最长有效期）
 }
 
 // TableName 指定表名
@@ -22,4 +26,3 @@ func (Session) TableName() string {
 func (s *Session) IsExpired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
-
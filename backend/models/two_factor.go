@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// TwoFactor 用户的TOTP两步验证设置
+// 开始绑定时生成密钥（Enabled 为 false），用户输入验证器中的验证码确认后才启用
+type TwoFactor struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"uniqueIndex;not null" json:"user_id"`   // 用户ID
+	Secret       string     `gorm:"size:64;not null" json:"-"`             // TOTP密钥（Base32）
+	Enabled      bool       `gorm:"not null;default:false" json:"enabled"` // 是否已启用
+	EnabledAt    *time.Time `json:"enabled_at"`                            // 启用时间
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"`           // 最近一次验证通过的时间窗口，防止验证码重放
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TwoFactor) TableName() string {
+	return "two_factors"
+}
+
+// RecoveryCode 两步验证恢复码，丢失验证器时代替验证码使用，每个只能使用一次
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"` // 用户ID
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`     // 恢复码哈希
+	UsedAt    *time.Time `json:"used_at"`                       // 使用时间，未使用为空
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// LoginChallenge 开启两步验证的用户通过密码校验后待完成的登录
+type LoginChallenge struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Token     string    `gorm:"uniqueIndex;size:64;not null" json:"token"` // 第二步提交时携带的令牌（UUID）
+	UserID    uint      `gorm:"not null;index" json:"user_id"`             // 用户ID
+	Attempts  int       `gorm:"not null;default:0" json:"attempts"`        // 验证码错误次数
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`          // 过期时间
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (LoginChallenge) TableName() string {
+	return "login_challenges"
+}
+
+// IsExpired 判断登录挑战是否过期
+func (c *LoginChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
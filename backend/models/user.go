@@ -6,17 +6,35 @@ import (
 
 // User 用户模型
 type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Phone        string    `gorm:"uniqueIndex;size:11;not null" json:"phone"`         // 手机号
-	Nickname     string    `gorm:"size:50;not null" json:"nickname"`                  // 昵称
-	PasswordHash string    `gorm:"size:255;not null" json:"-"`                        // 密码哈希（不返回给前端）
-	Role         string    `gorm:"size:20;not null;default:'user'" json:"role"`       // 用户角色：admin/user
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Phone         string    `gorm:"uniqueIndex;size:11;not null" json:"phone"`   // 手机号
+	Nickname      string    `gorm:"size:50;not null" json:"nickname"`            // 昵称
+	PasswordHash  string    `gorm:"size:255;not null" json:"-"`                  // 密码哈希（不返回给前端）
+	Role          string    `gorm:"size:20;not null;default:'user'" json:"role"` // 用户角色：admin/user/guest/deleted
+	Timezone      string    `gorm:"size:64;not null;default:''" json:"timezone"` // IANA时区（如Asia/Shanghai），为空时使用服务器时区
+	DayCutoffHour int       `gorm:"not null;default:7" json:"day_cutoff_hour"`   // “一晚”的分界时刻（0-23点）
+	GuestOf       *uint     `gorm:"index" json:"guest_of,omitempty"`             // 游客由哪位房主添加，普通用户为空
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// RoleGuest 房主代为记分的游客，没有手机号和密码，不能登录
+const RoleGuest = "guest"
+
+// RoleDeleted 已注销并匿名化的账户，仅保留房间账目，不能登录
+const RoleDeleted = "deleted"
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
 }
 
+// IsGuest 是否为游客
+func (u *User) IsGuest() bool {
+	return u.Role == RoleGuest
+}
+
+// IsDeleted 是否为已注销的账户
+func (u *User) IsDeleted() bool {
+	return u.Role == RoleDeleted
+}
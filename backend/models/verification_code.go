@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// 验证码用途
+const (
+	VerificationPurposeResetPassword = "reset_password" // 找回密码
+	VerificationPurposeChangePhone   = "change_phone"   // 修改手机号（发送到新手机号）
+)
+
+// VerificationCode 短信验证码模型
+// 只保存验证码的哈希值；验证失败次数达到上限或过期后作废，验证通过后立即标记为已使用
+type VerificationCode struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Phone      string     `gorm:"size:11;not null;index:idx_verification_phone_purpose" json:"phone"`   // 接收验证码的手机号
+	Purpose    string     `gorm:"size:32;not null;index:idx_verification_phone_purpose" json:"purpose"` // 用途
+	UserID     uint       `gorm:"not null;default:0" json:"user_id"`                                    // 申请验证码的用户（修改手机号时使用，找回密码时为0）
+	CodeHash   string     `gorm:"size:64;not null" json:"-"`                                            // 验证码哈希
+	Attempts   int        `gorm:"not null;default:0" json:"attempts"`                                   // 已验证失败的次数
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`                                           // 过期时间
+	ConsumedAt *time.Time `json:"consumed_at"`                                                          // 验证通过（使用）的时间
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (VerificationCode) TableName() string {
+	return "verification_codes"
+}
+
+// IsExpired 判断验证码是否过期
+func (v *VerificationCode) IsExpired() bool {
+	return time.Now().After(v.ExpiresAt)
+}
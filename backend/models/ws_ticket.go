@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// WSTicket WebSocket连接票据模型
+// 由已登录用户换取，仅能使用一次且有效期很短，避免在URL中携带长期有效的Session ID
+type WSTicket struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Ticket    string    `gorm:"uniqueIndex;size:64;not null" json:"ticket"` // 票据（UUID）
+	UserID    uint      `gorm:"not null;index" json:"user_id"`              // 用户ID
+	RoomID    uint      `gorm:"not null" json:"room_id"`                    // 票据适用的房间ID
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"` // 过期时间
+}
+
+// TableName 指定表名
+func (WSTicket) TableName() string {
+	return "ws_tickets"
+}
+
+// IsExpired 判断票据是否过期
+func (t *WSTicket) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
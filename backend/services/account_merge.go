@@ -0,0 +1,430 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"poker_score_backend/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMergeSameUser    = errors.New("cannot merge user into itself")
+	ErrMergeAdmin       = errors.New("cannot merge admin account")
+	ErrMergeGuestTarget = errors.New("cannot merge into guest")
+	ErrMergeActiveRoom  = errors.New("source user is in an active room")
+)
+
+// MergeUsersResult 账户合并结果
+type MergeUsersResult struct {
+	User        *models.User `json:"user"`         // 保留的账户
+	MovedRooms  int          `json:"moved_rooms"`  // 转入的房间数（含两个账户共同的房间）
+	SharedRooms int          `json:"shared_rooms"` // 两个账户都在的房间数，这些房间的成员、积分与结算记录已合并为一条
+}
+
+// clubRoleRank 合并俱乐部成员身份时保留较高的角色
+var clubRoleRank = map[string]int{
+	models.ClubRoleMember: 1,
+	models.ClubRoleAdmin:  2,
+	models.ClubRoleOwner:  3,
+}
+
+// MergeUsers 把 sourceID 的全部历史记录合并到 targetID 名下并删除 sourceID，整个过程在一个事务中完成
+// 用于用户换手机号后重复注册的情况；被合并账户仍在进行中的房间时拒绝合并
+func (s *AdminService) MergeUsers(sourceID, targetID uint) (*MergeUsersResult, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSameUser
+	}
+
+	result := &MergeUsersResult{}
+	var source, target models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		if err := tx.Where("id IN ?", []uint{sourceID, targetID}).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			if user.ID == sourceID {
+				source = user
+			} else {
+				target = user
+			}
+		}
+		if source.ID == 0 || target.ID == 0 || target.IsDeleted() {
+			return ErrUserNotFound
+		}
+		if source.Role == "admin" {
+			return ErrMergeAdmin
+		}
+		if target.IsGuest() {
+			return ErrMergeGuestTarget
+		}
+
+		var activeRooms int64
+		if err := tx.Model(&models.RoomMember{}).
+			Joins("JOIN rooms ON rooms.id = room_members.room_id").
+			Where("room_members.user_id = ? AND rooms.status = ?", sourceID, "active").
+			Count(&activeRooms).Error; err != nil {
+			return err
+		}
+		if activeRooms > 0 {
+			return ErrMergeActiveRoom
+		}
+
+		var roomIDs []uint
+		if err := tx.Model(&models.RoomMember{}).Where("user_id = ?", sourceID).
+			Distinct().Pluck("room_id", &roomIDs).Error; err != nil {
+			return err
+		}
+		result.MovedRooms = len(roomIDs)
+
+		// 共同房间的成员记录会被 mergeSharedRooms 删除，需先按合并前的房间列表清除复盘
+		if len(roomIDs) > 0 {
+			if err := tx.Where("room_id IN ?", roomIDs).Delete(&models.RoomRecap{}).Error; err != nil {
+				return err
+			}
+		}
+
+		shared, err := mergeSharedRooms(tx, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		result.SharedRooms = shared
+
+		if err := moveUserHistory(tx, sourceID, targetID); err != nil {
+			return err
+		}
+		if err := mergeClubMemberships(tx, sourceID, targetID); err != nil {
+			return err
+		}
+		if err := moveUserOwnership(tx, sourceID, targetID); err != nil {
+			return err
+		}
+		if err := deleteUserCredentials(tx, &source); err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, sourceID).Error
+	})
+	if err != nil {
+		log.Printf("合并账户失败: SourceID=%d, TargetID=%d, %v", sourceID, targetID, err)
+		return nil, err
+	}
+
+	log.Printf("账户已合并: SourceID=%d(%s), TargetID=%d(%s), Rooms=%d, SharedRooms=%d",
+		source.ID, source.Phone, target.ID, target.Phone, result.MovedRooms, result.SharedRooms)
+
+	if s.achievementService != nil {
+		if _, err := s.achievementService.EvaluateUser(targetID); err != nil {
+			log.Printf("合并账户后评估成就失败: UserID=%d, %v", targetID, err)
+		}
+	}
+
+	result.User = &target
+	return result, nil
+}
+
+// mergeSharedRooms 处理两个账户都在的房间：成员记录保留最早的加入时间，
+// 积分余额相加，同一批次的结算记录合并为一条；其余记录由 moveUserHistory 转移
+func mergeSharedRooms(tx *gorm.DB, fromID, toID uint) (int, error) {
+	var roomIDs []uint
+	if err := tx.Model(&models.RoomMember{}).
+		Where("user_id = ? AND room_id IN (?)", fromID,
+			tx.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", toID)).
+		Distinct().Pluck("room_id", &roomIDs).Error; err != nil {
+		return 0, err
+	}
+
+	for _, roomID := range roomIDs {
+		if err := mergeRoomMember(tx, roomID, fromID, toID); err != nil {
+			return 0, err
+		}
+		if err := mergeRoomBalance(tx, roomID, fromID, toID); err != nil {
+			return 0, err
+		}
+		if err := mergeRoomSettlements(tx, roomID, fromID, toID); err != nil {
+			return 0, err
+		}
+	}
+	return len(roomIDs), nil
+}
+
+func mergeRoomMember(tx *gorm.DB, roomID, fromID, toID uint) error {
+	var members []models.RoomMember
+	if err := tx.Where("room_id = ? AND user_id IN ?", roomID, []uint{fromID, toID}).
+		Order("joined_at ASC, id ASC").Find(&members).Error; err != nil {
+		return err
+	}
+
+	var kept *models.RoomMember
+	for i := range members {
+		if members[i].UserID == toID {
+			kept = &members[i]
+			break
+		}
+	}
+	if kept == nil {
+		return nil
+	}
+
+	if members[0].JoinedAt.Before(kept.JoinedAt) {
+		if err := tx.Model(&models.RoomMember{}).Where("id = ?", kept.ID).
+			Update("joined_at", members[0].JoinedAt).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("room_id = ? AND user_id = ?", roomID, fromID).Delete(&models.RoomMember{}).Error
+}
+
+func mergeRoomBalance(tx *gorm.DB, roomID, fromID, toID uint) error {
+	var balances []models.UserBalance
+	if err := tx.Where("room_id = ? AND user_id IN ?", roomID, []uint{fromID, toID}).Find(&balances).Error; err != nil {
+		return err
+	}
+	if len(balances) < 2 {
+		return nil
+	}
+
+	var from models.UserBalance
+	for _, balance := range balances {
+		if balance.UserID == fromID {
+			from = balance
+		}
+	}
+	if err := tx.Model(&models.UserBalance{}).Where("room_id = ? AND user_id = ?", roomID, toID).
+		Update("balance", gorm.Expr("balance + ?", from.Balance)).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.UserBalance{}, from.ID).Error
+}
+
+func mergeRoomSettlements(tx *gorm.DB, roomID, fromID, toID uint) error {
+	var settlements []models.Settlement
+	if err := tx.Where("room_id = ? AND user_id IN ?", roomID, []uint{fromID, toID}).
+		Order("id ASC").Find(&settlements).Error; err != nil {
+		return err
+	}
+
+	kept := make(map[string]*models.Settlement)
+	for i := range settlements {
+		if settlements[i].UserID == toID {
+			kept[settlements[i].SettlementBatch] = &settlements[i]
+		}
+	}
+
+	for _, settlement := range settlements {
+		if settlement.UserID != fromID {
+			continue
+		}
+		target, ok := kept[settlement.SettlementBatch]
+		if !ok {
+			continue
+		}
+
+		target.ChipAmount += settlement.ChipAmount
+		target.RmbAmount = math.Round((target.RmbAmount+settlement.RmbAmount)*100) / 100
+		if err := tx.Model(&models.Settlement{}).Where("id = ?", target.ID).Updates(map[string]interface{}{
+			"chip_amount": target.ChipAmount,
+			"rmb_amount":  target.RmbAmount,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Settlement{}, settlement.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeClubMemberships 转移俱乐部成员身份，两个账户都在的俱乐部保留较高的角色
+func mergeClubMemberships(tx *gorm.DB, fromID, toID uint) error {
+	var memberships []models.ClubMember
+	if err := tx.Where("user_id = ?", fromID).Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		var existing []models.ClubMember
+		if err := tx.Where("club_id = ? AND user_id = ?", membership.ClubID, toID).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			if err := tx.Model(&models.ClubMember{}).Where("id = ?", membership.ID).Update("user_id", toID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		updates := map[string]interface{}{}
+		if clubRoleRank[membership.Role] > clubRoleRank[existing[0].Role] {
+			updates["role"] = membership.Role
+		}
+		if membership.JoinedAt.Before(existing[0].JoinedAt) {
+			updates["joined_at"] = membership.JoinedAt
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&models.ClubMember{}).Where("id = ?", existing[0].ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&models.ClubMember{}, membership.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveUserOwnership 转移创建者、登记人与单点登录身份等归属字段
+func moveUserOwnership(tx *gorm.DB, fromID, toID uint) error {
+	updates := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.Room{}, "created_by"},
+		{&models.Club{}, "created_by"},
+		{&models.ClubPayment{}, "recorded_by"},
+		{&models.Season{}, "created_by"},
+		{&models.GuestClaim{}, "created_by"},
+		{&models.User{}, "guest_of"},
+		{&models.OIDCIdentity{}, "user_id"},
+	}
+	for _, update := range updates {
+		if err := tx.Model(update.model).Where(update.column+" = ?", fromID).Update(update.column, toID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteUserCredentials 删除账户的登录状态、令牌、两步验证与验证码等不随历史转移的数据
+func deleteUserCredentials(tx *gorm.DB, user *models.User) error {
+	for _, model := range []interface{}{
+		&models.Session{},
+		&models.WSTicket{},
+		&models.APIToken{},
+		&models.TwoFactor{},
+		&models.RecoveryCode{},
+		&models.LoginChallenge{},
+		&models.VerificationCode{},
+		&models.OIDCIdentity{},
+		&models.OIDCLoginState{},
+	} {
+		if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("guest_id = ?", user.ID).Delete(&models.GuestClaim{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("phone = ?", user.Phone).Delete(&models.LoginLockout{}).Error
+}
+
+// moveUserHistory 把 fromID 的房间、积分、结算、下注、聊天、俱乐部记账记录与赛季快照转到 toID 名下
+// 两个账户共同的房间需先由 mergeSharedRooms 处理；涉及房间的复盘会在下次查看时重新生成，
+// fromID 的成就直接删除，由 toID 重新评估
+func moveUserHistory(tx *gorm.DB, fromID, toID uint) error {
+	if err := tx.Where("room_id IN (?)",
+		tx.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", fromID)).
+		Delete(&models.RoomRecap{}).Error; err != nil {
+		return err
+	}
+
+	// 两个账户对同一操作记录发送过相同表情时只保留 toID 的那条，避免违反表情回应的唯一索引
+	if err := tx.Where("user_id = ? AND message_type = ? AND EXISTS (?)", fromID, models.ChatTypeReaction,
+		tx.Table("chat_messages AS kept").Select("1").
+			Where("kept.user_id = ? AND kept.message_type = ? AND kept.deleted_at IS NULL", toID, models.ChatTypeReaction).
+			Where("kept.room_id = chat_messages.room_id AND kept.operation_id = chat_messages.operation_id AND kept.content = chat_messages.content")).
+		Delete(&models.ChatMessage{}).Error; err != nil {
+		return err
+	}
+
+	updates := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.RoomMember{}, "user_id"},
+		{&models.UserBalance{}, "user_id"},
+		{&models.RoomOperation{}, "user_id"},
+		{&models.RoomOperation{}, "target_user_id"},
+		{&models.Settlement{}, "user_id"},
+		{&models.BetRecord{}, "from_user_id"},
+		{&models.BetRecord{}, "to_user_id"},
+		{&models.ChatMessage{}, "user_id"},
+		{&models.ChatMessage{}, "deleted_by"},
+		{&models.ClubPayment{}, "from_user_id"},
+		{&models.ClubPayment{}, "to_user_id"},
+	}
+	for _, update := range updates {
+		// 包含已软删除的聊天消息
+		if err := tx.Unscoped().Model(update.model).Where(update.column+" = ?", fromID).Update(update.column, toID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("user_id = ?", fromID).Delete(&models.UserAchievement{}).Error; err != nil {
+		return err
+	}
+	return mergeSeasonStandings(tx, fromID, toID)
+}
+
+// mergeSeasonStandings 把 fromID 的赛季快照转到 toID 名下，已固化的赛季快照不会重新计算，不能直接删除
+// toID 在同一份快照中没有记录时直接改为 toID；已有记录时把两条记录的积分与场次相加，并重新排列这份快照的名次
+func mergeSeasonStandings(tx *gorm.DB, fromID, toID uint) error {
+	var sources []models.SeasonStanding
+	if err := tx.Where("user_id = ?", fromID).Find(&sources).Error; err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		var targets []models.SeasonStanding
+		if err := tx.Where("season_id = ? AND snapshot_date = ? AND user_id = ?", source.SeasonID, source.SnapshotDate, toID).
+			Limit(1).Find(&targets).Error; err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			if err := tx.Model(&models.SeasonStanding{}).Where("id = ?", source.ID).Update("user_id", toID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		target := targets[0]
+		if err := tx.Model(&models.SeasonStanding{}).Where("id = ?", target.ID).Updates(map[string]interface{}{
+			"points":            roundTo(target.Points+source.Points, 2),
+			"rmb_points":        roundTo(target.RmbPoints+source.RmbPoints, 2),
+			"placement_points":  roundTo(target.PlacementPoints+source.PlacementPoints, 2),
+			"attendance_points": roundTo(target.AttendancePoints+source.AttendancePoints, 2),
+			"sessions":          target.Sessions + source.Sessions,
+			"wins":              target.Wins + source.Wins,
+			"net_chip":          target.NetChip + source.NetChip,
+			"net_rmb":           roundTo(target.NetRmb+source.NetRmb, 2),
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.SeasonStanding{}, source.ID).Error; err != nil {
+			return err
+		}
+		if err := rerankSnapshot(tx, source.SeasonID, source.SnapshotDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rerankSnapshot 按排行榜的排序规则重新排列一份快照的名次
+func rerankSnapshot(tx *gorm.DB, seasonID uint, snapshotDate string) error {
+	var standings []models.SeasonStanding
+	if err := tx.Where("season_id = ? AND snapshot_date = ?", seasonID, snapshotDate).Find(&standings).Error; err != nil {
+		return err
+	}
+	sortStandings(standings)
+	for i, standing := range standings {
+		if standing.Rank == i+1 {
+			continue
+		}
+		if err := tx.Model(&models.SeasonStanding{}).Where("id = ?", standing.ID).Update("rank", i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeUsers_MovesHistoryAndMergesSharedRooms(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Old", "New", "Carol"})
+	old, current, carol := users[0], users[1], users[2]
+
+	roomService := NewRoomService(nil, nil)
+	operationService := NewOperationService(roomService)
+	settlementService := NewSettlementService(roomService)
+	service := NewAdminService(nil)
+
+	// 两个账户都在的房间
+	shared, err := roomService.CreateRoom(carol.ID, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(old.ID, shared.ID)
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(current.ID, shared.ID)
+	require.NoError(t, err)
+	_, _, err = operationService.Bet(shared.ID, old.ID, 100)
+	require.NoError(t, err)
+	_, _, err = operationService.Bet(shared.ID, current.ID, 50)
+	require.NoError(t, err)
+	_, _, _, err = operationService.Withdraw(shared.ID, carol.ID, 0)
+	require.NoError(t, err)
+	_, _, err = settlementService.ConfirmSettlement(shared.ID, carol.ID)
+	require.NoError(t, err)
+
+	// 只有旧账户在的房间
+	own, err := roomService.CreateRoom(old.ID, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(carol.ID, own.ID)
+	require.NoError(t, err)
+	_, _, err = operationService.Bet(own.ID, old.ID, 30)
+	require.NoError(t, err)
+	_, _, _, err = operationService.Withdraw(own.ID, carol.ID, 0)
+	require.NoError(t, err)
+	_, _, err = settlementService.ConfirmSettlement(own.ID, old.ID)
+	require.NoError(t, err)
+
+	club := models.Club{Name: "Club", InviteCode: "CLUB0001", CreatedBy: old.ID}
+	require.NoError(t, models.DB.Create(&club).Error)
+	require.NoError(t, models.DB.Create(&[]models.ClubMember{
+		{ClubID: club.ID, UserID: old.ID, Role: models.ClubRoleOwner, JoinedAt: time.Now().Add(-time.Hour)},
+		{ClubID: club.ID, UserID: current.ID, Role: models.ClubRoleMember, JoinedAt: time.Now()},
+	}).Error)
+
+	_, err = NewAuthService(time.Hour, time.Hour, time.Minute).CreateSession(old.ID, SessionClient{})
+	require.NoError(t, err)
+
+	_, err = service.MergeUsers(old.ID, old.ID)
+	require.ErrorIs(t, err, ErrMergeSameUser)
+	_, err = service.MergeUsers(old.ID, 9999)
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	// 房间还在进行中时不能合并
+	_, err = service.MergeUsers(old.ID, current.ID)
+	require.ErrorIs(t, err, ErrMergeActiveRoom)
+
+	_, err = roomService.ManualDissolveRoom(shared.ID, carol.ID)
+	require.NoError(t, err)
+	_, err = roomService.ManualDissolveRoom(own.ID, old.ID)
+	require.NoError(t, err)
+
+	result, err := service.MergeUsers(old.ID, current.ID)
+	require.NoError(t, err)
+	require.Equal(t, current.ID, result.User.ID)
+	require.Equal(t, 2, result.MovedRooms)
+	require.Equal(t, 1, result.SharedRooms)
+
+	// 共同房间：成员、积分与同批次结算各只剩一条，盈亏相加
+	var memberCount int64
+	models.DB.Model(&models.RoomMember{}).Where("room_id = ? AND user_id = ?", shared.ID, current.ID).Count(&memberCount)
+	require.Equal(t, int64(1), memberCount)
+
+	var balanceCount int64
+	models.DB.Model(&models.UserBalance{}).Where("room_id = ? AND user_id = ?", shared.ID, current.ID).Count(&balanceCount)
+	require.Equal(t, int64(1), balanceCount)
+
+	var settlements []models.Settlement
+	require.NoError(t, models.DB.Where("room_id = ?", shared.ID).Order("chip_amount ASC").Find(&settlements).Error)
+	require.Len(t, settlements, 2)
+	require.Equal(t, current.ID, settlements[0].UserID)
+	require.Equal(t, -150, settlements[0].ChipAmount)
+	require.InDelta(t, -7.5, settlements[0].RmbAmount, 0.001)
+	require.Equal(t, carol.ID, settlements[1].UserID)
+	require.Equal(t, 150, settlements[1].ChipAmount)
+
+	// 旧账户单独的房间整体转移
+	var moved models.Room
+	require.NoError(t, models.DB.First(&moved, own.ID).Error)
+	require.Equal(t, current.ID, moved.CreatedBy)
+	require.NoError(t, models.DB.Where("room_id = ?", own.ID).Order("chip_amount ASC").Find(&settlements).Error)
+	require.Equal(t, current.ID, settlements[0].UserID)
+	require.Equal(t, -30, settlements[0].ChipAmount)
+
+	var bets int64
+	models.DB.Model(&models.RoomOperation{}).Where("user_id = ? AND operation_type = ?", current.ID, models.OpTypeBet).Count(&bets)
+	require.Equal(t, int64(3), bets)
+
+	// 俱乐部保留较高的角色
+	var memberships []models.ClubMember
+	require.NoError(t, models.DB.Where("club_id = ?", club.ID).Find(&memberships).Error)
+	require.Len(t, memberships, 1)
+	require.Equal(t, current.ID, memberships[0].UserID)
+	require.Equal(t, models.ClubRoleOwner, memberships[0].Role)
+
+	var remaining int64
+	models.DB.Model(&models.User{}).Where("id = ?", old.ID).Count(&remaining)
+	require.Zero(t, remaining)
+	models.DB.Model(&models.Session{}).Where("user_id = ?", old.ID).Count(&remaining)
+	require.Zero(t, remaining)
+	models.DB.Model(&models.RoomMember{}).Where("user_id = ?", old.ID).Count(&remaining)
+	require.Zero(t, remaining)
+}
+
+func TestMergeUsers_InvalidatesSharedRecapAndKeepsStandings(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Old", "New", "Carol"})
+	old, current, carol := users[0], users[1], users[2]
+
+	joined := time.Now().Add(-2 * time.Hour)
+	shared := seedSettledRoom(t, "texas", joined, joined.Add(time.Hour), map[uint]int{old.ID: 30, current.ID: 20, carol.ID: -50})
+	require.NoError(t, models.DB.Create(&models.RoomRecap{RoomID: shared.ID, Content: "{}", GeneratedAt: time.Now()}).Error)
+
+	// 已固化的赛季快照：两个账户都有记录的一天，以及只有旧账户的一天
+	finalizedAt := time.Now()
+	season := models.Season{Name: "S1", StartAt: joined.Add(-48 * time.Hour), EndAt: joined.Add(-time.Hour), RmbWeight: 1, CreatedBy: carol.ID, FinalizedAt: &finalizedAt}
+	require.NoError(t, models.DB.Create(&season).Error)
+	require.NoError(t, models.DB.Create(&[]models.SeasonStanding{
+		{SeasonID: season.ID, SnapshotDate: "2025-11-01", UserID: old.ID, Nickname: "Old", Rank: 1, Points: 10, Sessions: 2, Wins: 1, NetChip: 200, NetRmb: 10},
+		{SeasonID: season.ID, SnapshotDate: "2025-11-01", UserID: carol.ID, Nickname: "Carol", Rank: 2, Points: 5, Sessions: 1, NetChip: 100, NetRmb: 5},
+		{SeasonID: season.ID, SnapshotDate: "2025-11-01", UserID: current.ID, Nickname: "New", Rank: 3, Points: 3, Sessions: 1, NetChip: 60, NetRmb: 3},
+		{SeasonID: season.ID, SnapshotDate: "2025-11-02", UserID: old.ID, Nickname: "Old", Rank: 1, Points: 8, Sessions: 1, NetChip: 160, NetRmb: 8},
+	}).Error)
+
+	result, err := NewAdminService(nil).MergeUsers(old.ID, current.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.SharedRooms)
+
+	// 共同房间的分数已合并，旧的复盘需要重新生成
+	var count int64
+	models.DB.Model(&models.RoomRecap{}).Where("room_id = ?", shared.ID).Count(&count)
+	require.Zero(t, count)
+
+	models.DB.Model(&models.SeasonStanding{}).Where("user_id = ?", old.ID).Count(&count)
+	require.Zero(t, count)
+
+	var first []models.SeasonStanding
+	require.NoError(t, models.DB.Where("season_id = ? AND snapshot_date = ?", season.ID, "2025-11-01").Order("rank ASC").Find(&first).Error)
+	require.Len(t, first, 2)
+	require.Equal(t, current.ID, first[0].UserID)
+	require.Equal(t, 1, first[0].Rank)
+	require.Equal(t, 13.0, first[0].Points)
+	require.Equal(t, 3, first[0].Sessions)
+	require.Equal(t, 260, first[0].NetChip)
+	require.Equal(t, carol.ID, first[1].UserID)
+	require.Equal(t, 2, first[1].Rank)
+
+	var second []models.SeasonStanding
+	require.NoError(t, models.DB.Where("season_id = ? AND snapshot_date = ?", season.ID, "2025-11-02").Find(&second).Error)
+	require.Len(t, second, 1)
+	require.Equal(t, current.ID, second[0].UserID)
+	require.Equal(t, 8.0, second[0].Points)
+}
+
+// tonightFriendIDs 返回“今晚一起玩过的好友”中的用户ID
+func tonightFriendIDs(t *testing.T, service *RecordService, userID uint, start, end time.Time) []uint {
+	t.Helper()
+
+	records, err := service.GetTonightRecords(userID, &start, &end)
+	require.NoError(t, err)
+	ids := make([]uint, 0)
+	for _, record := range records["friends_records"].([]map[string]interface{}) {
+		ids = append(ids, record["user_id"].(uint))
+	}
+	return ids
+}
+
+func TestMergeUsers_RefreshesCoPlayGraph(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Old", "New", "Carol"})
+	alice, old, current, carol := users[0], users[1], users[2], users[3]
+
+	joined := time.Now().Add(-2 * time.Hour)
+	seedSettledRoom(t, "texas", joined, joined.Add(time.Hour), map[uint]int{alice.ID: 10, old.ID: -10})
+	seedSettledRoom(t, "texas", joined.Add(time.Minute), joined.Add(time.Hour), map[uint]int{current.ID: 20, carol.ID: -20})
+
+	recordService := NewRecordService()
+	start, end := joined.Add(-time.Hour), time.Now()
+	require.ElementsMatch(t, []uint{alice.ID, old.ID}, tonightFriendIDs(t, recordService, alice.ID, start, end))
+
+	// 合并只改写成员记录的 user_id，数量与最大ID都不变，缓存的关系图也要重新构建
+	_, err := NewAdminService(nil).MergeUsers(old.ID, current.ID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []uint{alice.ID, current.ID, carol.ID}, tonightFriendIDs(t, recordService, alice.ID, start, end))
+}
+
+func TestMergeUsers_RejectsAdminAndGuestTarget(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Admin", "User"})
+	admin, user := users[0], users[1]
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", admin.ID).Update("role", "admin").Error)
+
+	guest := models.User{Phone: "g0000000001", Nickname: "Guest", Role: models.RoleGuest, GuestOf: &user.ID}
+	require.NoError(t, models.DB.Create(&guest).Error)
+
+	service := NewAdminService(nil)
+
+	_, err := service.MergeUsers(admin.ID, user.ID)
+	require.ErrorIs(t, err, ErrMergeAdmin)
+	_, err = service.MergeUsers(user.ID, guest.ID)
+	require.ErrorIs(t, err, ErrMergeGuestTarget)
+
+	// 游客可以由管理员合并到正式账户
+	result, err := service.MergeUsers(guest.ID, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, result.MovedRooms)
+}
+
+func TestMergeUsers_DropsDuplicateReactions(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Old", "New", "Carol"})
+	old, current, carol := users[0], users[1], users[2]
+
+	joined := time.Now().Add(-2 * time.Hour)
+	shared := seedSettledRoom(t, "texas", joined, joined.Add(time.Hour), map[uint]int{old.ID: 30, current.ID: 20, carol.ID: -50})
+	operation := models.RoomOperation{RoomID: shared.ID, UserID: carol.ID, OperationType: models.OpTypeBet}
+	require.NoError(t, models.DB.Create(&operation).Error)
+
+	// 两个账户对同一操作发送了相同的表情，旧账户还发送了另一个表情
+	opID := operation.ID
+	for _, reaction := range []models.ChatMessage{
+		{RoomID: shared.ID, UserID: old.ID, Content: "👍"},
+		{RoomID: shared.ID, UserID: old.ID, Content: "🔥"},
+		{RoomID: shared.ID, UserID: current.ID, Content: "👍"},
+	} {
+		reaction.MessageType = models.ChatTypeReaction
+		reaction.OperationID = &opID
+		require.NoError(t, models.DB.Create(&reaction).Error)
+	}
+
+	_, err := NewAdminService(nil).MergeUsers(old.ID, current.ID)
+	require.NoError(t, err)
+
+	var contents []string
+	require.NoError(t, models.DB.Model(&models.ChatMessage{}).Where("user_id = ?", current.ID).
+		Order("content").Pluck("content", &contents).Error)
+	require.ElementsMatch(t, []string{"👍", "🔥"}, contents)
+}
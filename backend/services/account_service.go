@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	ws "poker_score_backend/websocket"
+
+	"gorm.io/gorm"
+)
+
+// DeletedUserNickname 注销后的账户在其他玩家的房间记录中显示的昵称
+const DeletedUserNickname = "已注销用户"
+
+// AccountService 个人数据导出与账户注销
+type AccountService struct {
+	twoFactorService *TwoFactorService
+}
+
+// NewAccountService 创建账户服务
+func NewAccountService(twoFactorService *TwoFactorService) *AccountService {
+	return &AccountService{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// DeleteAccount 校验密码（开启两步验证时还需验证码）后注销账户
+// 账户不会被物理删除：房间成员、积分、操作、结算与下注记录保留，保证其他玩家的账目仍然平衡；
+// 昵称与手机号被替换，登录凭据、聊天内容、成就等个人数据被删除
+func (s *AccountService) DeleteAccount(userID uint, password, twoFactorCode string) error {
+	var users []models.User
+	if err := models.DB.Where("id = ?", userID).Limit(1).Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 || users[0].IsGuest() || users[0].IsDeleted() {
+		return errors.New("用户不存在")
+	}
+	user := users[0]
+
+	if user.Role == "admin" {
+		return errors.New("管理员账户不能注销，请先取消管理员角色")
+	}
+	if !utils.CheckPassword(password, user.PasswordHash) {
+		return errors.New("密码错误")
+	}
+
+	enabled, err := s.twoFactorService.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		if twoFactorCode == "" {
+			return errors.New("已开启两步验证，请输入验证码或恢复码")
+		}
+		if err := s.twoFactorService.verify(userID, twoFactorCode); err != nil {
+			return err
+		}
+	}
+
+	var activeRooms int64
+	if err := models.DB.Model(&models.RoomMember{}).
+		Joins("JOIN rooms ON rooms.id = room_members.room_id").
+		Where("room_members.user_id = ? AND rooms.status = ?", userID, "active").
+		Count(&activeRooms).Error; err != nil {
+		return err
+	}
+	if activeRooms > 0 {
+		return errors.New("您还在进行中的房间里，请在房间解散后再注销账户")
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := handOverClubs(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.ClubMember{}).Error; err != nil {
+			return err
+		}
+		if err := deleteUserCredentials(tx, &user); err != nil {
+			return err
+		}
+		if err := tx.Where("phone = ?", user.Phone).Delete(&models.VerificationCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("created_by = ?", userID).Delete(&models.GuestClaim{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserAchievement{}).Error; err != nil {
+			return err
+		}
+
+		// 聊天内容属于个人数据，清空后按已删除处理
+		if err := tx.Unscoped().Model(&models.ChatMessage{}).Where("user_id = ?", userID).
+			Update("content", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ChatMessage{}).Where("user_id = ?", userID).
+			Update("deleted_by", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.ChatMessage{}).Error; err != nil {
+			return err
+		}
+
+		// 操作描述与赛季快照中保存了昵称
+		if err := anonymizeOperationDescriptions(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SeasonStanding{}).Where("user_id = ?", userID).
+			Update("nickname", DeletedUserNickname).Error; err != nil {
+			return err
+		}
+		// 复盘中保存了昵称，下次查看时重新生成
+		if err := tx.Where("room_id IN (?)",
+			tx.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", userID)).
+			Delete(&models.RoomRecap{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"phone":           deletedUserPhone(userID),
+			"nickname":        DeletedUserNickname,
+			"password_hash":   "",
+			"role":            models.RoleDeleted,
+			"timezone":        "",
+			"day_cutoff_hour": 7,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("注销账户失败: UserID=%d, %v", userID, err)
+		return err
+	}
+
+	log.Printf("账户已注销并匿名化: UserID=%d", userID)
+	return nil
+}
+
+// handOverClubs 把用户创建的俱乐部转交给最早加入的管理员，没有管理员时转交给最早加入的成员；
+// 没有其他成员的俱乐部保持原样
+func handOverClubs(tx *gorm.DB, userID uint) error {
+	var owned []models.ClubMember
+	if err := tx.Where("user_id = ? AND role = ?", userID, models.ClubRoleOwner).Find(&owned).Error; err != nil {
+		return err
+	}
+
+	for _, membership := range owned {
+		var candidates []models.ClubMember
+		if err := tx.Where("club_id = ? AND user_id <> ?", membership.ClubID, userID).
+			Order("joined_at ASC, id ASC").Find(&candidates).Error; err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		successor := candidates[0]
+		for _, candidate := range candidates {
+			if candidate.Role == models.ClubRoleAdmin {
+				successor = candidate
+				break
+			}
+		}
+
+		if err := tx.Model(&models.ClubMember{}).Where("id = ?", successor.ID).
+			Update("role", models.ClubRoleOwner).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Club{}).Where("id = ?", membership.ClubID).
+			Update("created_by", successor.UserID).Error; err != nil {
+			return err
+		}
+		log.Printf("注销账户转交俱乐部: ClubID=%d, FromUserID=%d, ToUserID=%d", membership.ClubID, userID, successor.UserID)
+	}
+	return nil
+}
+
+// anonymizeOperationDescriptions 按操作类型重写描述中该用户的昵称
+// 踢出与强制转移的描述由操作字段重新生成；牛牛下注与确认结算的描述是JSON，只替换属于该用户的条目，
+// 不做字符串替换，避免昵称恰好是其他文字的一部分时误改
+func anonymizeOperationDescriptions(tx *gorm.DB, userID uint) error {
+	var targeted []models.RoomOperation
+	if err := tx.Where("target_user_id = ? AND operation_type IN ?", userID,
+		[]string{models.OpTypeKick, models.OpTypeForceTransfer}).Find(&targeted).Error; err != nil {
+		return err
+	}
+	for _, op := range targeted {
+		description := "踢出了用户" + DeletedUserNickname
+		if op.OperationType == models.OpTypeForceTransfer {
+			amount := 0
+			if op.Amount != nil {
+				amount = *op.Amount
+			}
+			description = fmt.Sprintf("将桌面%d积分转移给%s", amount, DeletedUserNickname)
+		}
+		if err := tx.Model(&models.RoomOperation{}).Where("id = ?", op.ID).Update("description", description).Error; err != nil {
+			return err
+		}
+	}
+
+	var ops []models.RoomOperation
+	if err := tx.Where("operation_type IN ? AND room_id IN (?)",
+		[]string{models.OpTypeNiuniuBet, models.OpTypeSettlementConfirmed},
+		tx.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", userID)).
+		Find(&ops).Error; err != nil {
+		return err
+	}
+	for _, op := range ops {
+		var description []byte
+		var err error
+		changed := false
+		if op.OperationType == models.OpTypeNiuniuBet {
+			var details []NiuniuBetDetail
+			if json.Unmarshal([]byte(op.Description), &details) != nil {
+				continue
+			}
+			for i := range details {
+				if details[i].ToUserID == userID && details[i].ToNickname != "" {
+					details[i].ToNickname = DeletedUserNickname
+					changed = true
+				}
+			}
+			description, err = json.Marshal(details)
+		} else {
+			var summary ws.SettlementSummary
+			if json.Unmarshal([]byte(op.Description), &summary) != nil {
+				continue
+			}
+			for i := range summary.Details {
+				if summary.Details[i].UserID == userID {
+					summary.Details[i].Nickname = DeletedUserNickname
+					changed = true
+				}
+			}
+			description, err = json.Marshal(summary)
+		}
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+		if err := tx.Model(&models.RoomOperation{}).Where("id = ?", op.ID).Update("description", string(description)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletedUserPhone 注销账户的占位手机号（d 开头，不是合法手机号），按用户ID生成保证唯一
+func deletedUserPhone(userID uint) string {
+	return fmt.Sprintf("d%010d", userID)
+}
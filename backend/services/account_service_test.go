@@ -0,0 +1,236 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	ws "poker_score_backend/websocket"
+
+	"github.com/stretchr/testify/require"
+)
+
+// playSettledRoom 创建一个 Alice 输给 Bob 100 积分并已解散的房间
+func playSettledRoom(t *testing.T, alice, bob models.User) *models.Room {
+	t.Helper()
+
+	roomService := NewRoomService(nil, nil)
+	operationService := NewOperationService(roomService)
+
+	room, err := roomService.CreateRoom(bob.ID, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(alice.ID, room.ID)
+	require.NoError(t, err)
+	_, _, err = operationService.Bet(room.ID, alice.ID, 100)
+	require.NoError(t, err)
+	_, _, _, err = operationService.Withdraw(room.ID, bob.ID, 0)
+	require.NoError(t, err)
+	_, _, err = NewSettlementService(roomService).ConfirmSettlement(room.ID, bob.ID)
+	require.NoError(t, err)
+	_, err = roomService.ManualDissolveRoom(room.ID, bob.ID)
+	require.NoError(t, err)
+	return room
+}
+
+func TestAccount_ExportUserData(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0], users[1]
+	room := playSettledRoom(t, alice, bob)
+
+	service := NewAccountService(NewTwoFactorService(NewAuthService(time.Hour, time.Hour, time.Minute), "test", time.Minute))
+	export, err := service.ExportUserData(alice.ID)
+	require.NoError(t, err)
+
+	tables := make(map[string]ExportTable)
+	for _, table := range export.Tables {
+		tables[table.Name] = table
+	}
+	require.Len(t, tables["profile"].Rows, 1)
+	require.Len(t, tables["rooms"].Rows, 1)
+	require.Equal(t, room.ID, tables["rooms"].Rows[0][0])
+	require.Len(t, tables["settlements"].Rows, 1)
+	require.Equal(t, -100, tables["settlements"].Rows[0][2])
+	require.NotEmpty(t, tables["operations"].Rows)
+
+	content, err := RenderExportJSON(export)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "password")
+
+	var document struct {
+		Profile struct {
+			Phone    string `json:"phone"`
+			Nickname string `json:"nickname"`
+		} `json:"profile"`
+		Settlements []struct {
+			ChipAmount int     `json:"chip_amount"`
+			RmbAmount  float64 `json:"rmb_amount"`
+		} `json:"settlements"`
+		BetRecords []json.RawMessage `json:"bet_records"`
+	}
+	require.NoError(t, json.Unmarshal(content, &document))
+	require.Equal(t, alice.Phone, document.Profile.Phone)
+	require.Equal(t, -100, document.Settlements[0].ChipAmount)
+	require.InDelta(t, -5, document.Settlements[0].RmbAmount, 0.001)
+	require.NotNil(t, document.BetRecords)
+
+	archive, err := RenderExportCSVArchive(export)
+	require.NoError(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	require.Len(t, reader.File, len(export.Tables))
+
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[file.Name] = strings.TrimPrefix(string(data), utf8BOM)
+	}
+	lines := strings.Split(strings.TrimSpace(files["settlements.csv"]), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, "id,room_id,chip_amount,rmb_amount,settlement_batch,settled_at", lines[0])
+	require.Contains(t, lines[1], ",-100,-5.00,")
+}
+
+func TestAccount_DeleteAnonymizesAndKeepsLedger(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol"})
+	alice, bob, carol := users[0], users[1], users[2]
+
+	passwordHash, err := utils.HashPassword("secret123")
+	require.NoError(t, err)
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", alice.ID).Update("password_hash", passwordHash).Error)
+
+	// 一个字的昵称会出现在其他描述的数字里，不能按字符串替换
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", alice.ID).Update("nickname", "1").Error)
+
+	room := playSettledRoom(t, alice, bob)
+
+	// 牛牛房间：Bob 向 Alice 下注，再把桌面积分强制转移给 Alice，然后结算
+	roomService := NewRoomService(nil, nil)
+	operationService := NewOperationService(roomService)
+	niuniu, err := roomService.CreateRoom(bob.ID, "niuniu", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(alice.ID, niuniu.ID)
+	require.NoError(t, err)
+	_, _, err = operationService.NiuniuBet(niuniu.ID, bob.ID, []NiuniuBetItem{{ToUserID: alice.ID, Amount: 100}})
+	require.NoError(t, err)
+	_, _, _, _, err = operationService.ForceTransfer(niuniu.ID, bob.ID, alice.ID)
+	require.NoError(t, err)
+	_, _, err = NewSettlementService(roomService).ConfirmSettlement(niuniu.ID, bob.ID)
+	require.NoError(t, err)
+	_, err = roomService.ManualDissolveRoom(niuniu.ID, bob.ID)
+	require.NoError(t, err)
+
+	chat := models.ChatMessage{RoomID: room.ID, UserID: alice.ID, MessageType: models.ChatTypeText, Content: "我的手机号是多少"}
+	require.NoError(t, models.DB.Create(&chat).Error)
+
+	club := models.Club{Name: "Club", InviteCode: "CLUB0001", CreatedBy: alice.ID}
+	require.NoError(t, models.DB.Create(&club).Error)
+	require.NoError(t, models.DB.Create(&[]models.ClubMember{
+		{ClubID: club.ID, UserID: alice.ID, Role: models.ClubRoleOwner, JoinedAt: time.Now().Add(-2 * time.Hour)},
+		{ClubID: club.ID, UserID: bob.ID, Role: models.ClubRoleMember, JoinedAt: time.Now().Add(-time.Hour)},
+		{ClubID: club.ID, UserID: carol.ID, Role: models.ClubRoleAdmin, JoinedAt: time.Now()},
+	}).Error)
+
+	authService := NewAuthService(time.Hour, time.Hour, time.Minute)
+	_, err = authService.CreateSession(alice.ID, SessionClient{})
+	require.NoError(t, err)
+
+	service := NewAccountService(NewTwoFactorService(authService, "test", time.Minute))
+
+	require.EqualError(t, service.DeleteAccount(alice.ID, "wrong", ""), "密码错误")
+
+	// 进行中的房间需要先解散
+	active, err := NewRoomService(nil, nil).CreateRoom(alice.ID, "texas", "20:1")
+	require.NoError(t, err)
+	require.EqualError(t, service.DeleteAccount(alice.ID, "secret123", ""), "您还在进行中的房间里，请在房间解散后再注销账户")
+	_, err = NewRoomService(nil, nil).ManualDissolveRoom(active.ID, alice.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, service.DeleteAccount(alice.ID, "secret123", ""))
+
+	var deleted models.User
+	require.NoError(t, models.DB.First(&deleted, alice.ID).Error)
+	require.True(t, deleted.IsDeleted())
+	require.Equal(t, DeletedUserNickname, deleted.Nickname)
+	require.NotEqual(t, alice.Phone, deleted.Phone)
+	require.Empty(t, deleted.PasswordHash)
+
+	_, err = authService.Authenticate(alice.Phone, "secret123")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	var count int64
+	models.DB.Model(&models.Session{}).Where("user_id = ?", alice.ID).Count(&count)
+	require.Zero(t, count)
+	models.DB.Model(&models.ClubMember{}).Where("user_id = ?", alice.ID).Count(&count)
+	require.Zero(t, count)
+	models.DB.Model(&models.ChatMessage{}).Where("user_id = ?", alice.ID).Count(&count)
+	require.Zero(t, count)
+
+	var stored models.ChatMessage
+	require.NoError(t, models.DB.Unscoped().First(&stored, chat.ID).Error)
+	require.Empty(t, stored.Content)
+
+	// 俱乐部转交给管理员
+	var updatedClub models.Club
+	require.NoError(t, models.DB.First(&updatedClub, club.ID).Error)
+	require.Equal(t, carol.ID, updatedClub.CreatedBy)
+	owner, err := getClubMember(club.ID, carol.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ClubRoleOwner, owner.Role)
+
+	// 房间账目保持平衡
+	var total int64
+	require.NoError(t, models.DB.Model(&models.Settlement{}).Where("room_id = ?", room.ID).
+		Select("COALESCE(SUM(chip_amount), 0)").Scan(&total).Error)
+	require.Zero(t, total)
+	models.DB.Model(&models.Settlement{}).Where("room_id = ? AND user_id = ?", room.ID, alice.ID).Count(&count)
+	require.Equal(t, int64(1), count)
+
+	members, err := NewRoomService(nil, nil).GetRoomMembers(room.ID)
+	require.NoError(t, err)
+	nicknames := make([]string, 0, len(members))
+	for _, member := range members {
+		nicknames = append(nicknames, member["nickname"].(string))
+	}
+	require.ElementsMatch(t, []string{"Bob", DeletedUserNickname}, nicknames)
+
+	// 操作描述按结构替换昵称，其他文字保持不变
+	descriptions := make(map[string][]string)
+	var ops []models.RoomOperation
+	require.NoError(t, models.DB.Where("room_id IN ?", []uint{room.ID, niuniu.ID}).Order("id ASC").Find(&ops).Error)
+	for _, op := range ops {
+		descriptions[op.OperationType] = append(descriptions[op.OperationType], op.Description)
+	}
+	require.Equal(t, []string{"下注了100积分"}, descriptions[models.OpTypeBet])
+	require.Equal(t, []string{"将桌面100积分转移给" + DeletedUserNickname}, descriptions[models.OpTypeForceTransfer])
+	require.Len(t, descriptions[models.OpTypeNiuniuBet], 1)
+	var bets []NiuniuBetDetail
+	require.NoError(t, json.Unmarshal([]byte(descriptions[models.OpTypeNiuniuBet][0]), &bets))
+	require.Equal(t, []NiuniuBetDetail{{ToUserID: alice.ID, ToNickname: DeletedUserNickname, Amount: 100}}, bets)
+	require.Len(t, descriptions[models.OpTypeSettlementConfirmed], 2)
+	for _, description := range descriptions[models.OpTypeSettlementConfirmed] {
+		var summary ws.SettlementSummary
+		require.NoError(t, json.Unmarshal([]byte(description), &summary))
+		require.Len(t, summary.Details, 2)
+		for _, detail := range summary.Details {
+			if detail.UserID == alice.ID {
+				require.Equal(t, DeletedUserNickname, detail.Nickname)
+			} else {
+				require.Equal(t, "Bob", detail.Nickname)
+			}
+		}
+	}
+
+	require.EqualError(t, service.DeleteAccount(alice.ID, "secret123", ""), "用户不存在")
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	ws "poker_score_backend/websocket"
+	"sort"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// bigPotThreshold 单次收回达到该积分算作“大锅”
+	bigPotThreshold = 1000
+	// bigNightRmb 单晚盈利达到该金额（元）算作“大丰收”
+	bigNightRmb = 200
+	// nightWinStreak 连续盈利的晚数
+	nightWinStreak = 5
+	// niuniuBetCount 牛牛累计下注次数
+	niuniuBetCount = 100
+)
+
+// AchievementDefinition 成就定义
+type AchievementDefinition struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// achievementRule 成就规则：在历史记录中查找首次满足条件的时间与房间
+type achievementRule struct {
+	AchievementDefinition
+	evaluate func(h *achievementHistory) (*achievementHit, error)
+}
+
+// achievementHit 规则首次满足的位置
+type achievementHit struct {
+	At     time.Time
+	RoomID *uint
+}
+
+// achievementRules 所有成就规则，顺序即展示顺序
+var achievementRules = []achievementRule{
+	{
+		AchievementDefinition{Code: "first_win", Name: "首胜", Description: "第一次在一场对局中盈利"},
+		func(h *achievementHistory) (*achievementHit, error) {
+			sessions, err := h.sessions()
+			if err != nil {
+				return nil, err
+			}
+			for _, session := range sessions {
+				if session.ChipAmount > 0 {
+					return sessionHit(session), nil
+				}
+			}
+			return nil, nil
+		},
+	},
+	{
+		AchievementDefinition{Code: "sessions_10", Name: "常客", Description: "累计参加10场对局"},
+		nthSessionRule(10),
+	},
+	{
+		AchievementDefinition{Code: "sessions_100", Name: "百场老将", Description: "累计参加100场对局"},
+		nthSessionRule(100),
+	},
+	{
+		AchievementDefinition{Code: "night_streak_5", Name: "五连红", Description: "连续5晚盈利"},
+		func(h *achievementHistory) (*achievementHit, error) {
+			nights, err := h.nights()
+			if err != nil {
+				return nil, err
+			}
+			streak := 0
+			for _, night := range nights {
+				if night.RmbAmount > 0 {
+					streak++
+				} else {
+					streak = 0
+				}
+				if streak == nightWinStreak {
+					return &achievementHit{At: night.EndedAt, RoomID: &night.LastRoomID}, nil
+				}
+			}
+			return nil, nil
+		},
+	},
+	{
+		AchievementDefinition{Code: "big_night", Name: "大丰收", Description: "一晚盈利至少200元"},
+		func(h *achievementHistory) (*achievementHit, error) {
+			nights, err := h.nights()
+			if err != nil {
+				return nil, err
+			}
+			for _, night := range nights {
+				if night.RmbAmount >= bigNightRmb {
+					return &achievementHit{At: night.EndedAt, RoomID: &night.LastRoomID}, nil
+				}
+			}
+			return nil, nil
+		},
+	},
+	{
+		AchievementDefinition{Code: "big_pot", Name: "大锅", Description: "一次收回至少1000积分"},
+		func(h *achievementHistory) (*achievementHit, error) {
+			var ops []models.RoomOperation
+			if err := models.DB.Where("((operation_type = ? AND user_id = ?) OR (operation_type = ? AND target_user_id = ?)) AND amount >= ?",
+				models.OpTypeWithdraw, h.userID, models.OpTypeForceTransfer, h.userID, bigPotThreshold).
+				Order("created_at ASC, id ASC").
+				Limit(1).
+				Find(&ops).Error; err != nil {
+				return nil, err
+			}
+			if len(ops) == 0 {
+				return nil, nil
+			}
+			return &achievementHit{At: ops[0].CreatedAt, RoomID: &ops[0].RoomID}, nil
+		},
+	},
+	{
+		AchievementDefinition{Code: "niuniu_100", Name: "牛牛狂热", Description: "牛牛累计下注100次"},
+		func(h *achievementHistory) (*achievementHit, error) {
+			var bets []models.BetRecord
+			if err := models.DB.Where("from_user_id = ?", h.userID).
+				Order("created_at ASC, id ASC").
+				Offset(niuniuBetCount - 1).
+				Limit(1).
+				Find(&bets).Error; err != nil {
+				return nil, err
+			}
+			if len(bets) == 0 {
+				return nil, nil
+			}
+			return &achievementHit{At: bets[0].CreatedAt, RoomID: &bets[0].RoomID}, nil
+		},
+	},
+}
+
+// nthSessionRule 第 n 场对局结束时达成
+func nthSessionRule(n int) func(h *achievementHistory) (*achievementHit, error) {
+	return func(h *achievementHistory) (*achievementHit, error) {
+		sessions, err := h.sessions()
+		if err != nil {
+			return nil, err
+		}
+		if len(sessions) < n {
+			return nil, nil
+		}
+		return sessionHit(sessions[n-1]), nil
+	}
+}
+
+func sessionHit(session playerSession) *achievementHit {
+	roomID := session.RoomID
+	return &achievementHit{At: session.EndedAt, RoomID: &roomID}
+}
+
+// achievementHistory 评估成就时按需加载、复用的历史数据
+type achievementHistory struct {
+	userID        uint
+	recordService *RecordService
+
+	loadedSessions []playerSession
+	loadedNights   []nightResult
+}
+
+func (h *achievementHistory) sessions() ([]playerSession, error) {
+	if h.loadedSessions == nil {
+		sessions, err := h.recordService.loadPlayerSessions(h.userID, StatsFilter{})
+		if err != nil {
+			return nil, err
+		}
+		h.loadedSessions = sessions
+	}
+	return h.loadedSessions, nil
+}
+
+// nights 按用户的时区与分界把对局归到各晚
+func (h *achievementHistory) nights() ([]nightResult, error) {
+	if h.loadedNights == nil {
+		sessions, err := h.sessions()
+		if err != nil {
+			return nil, err
+		}
+		h.loadedNights = groupNights(sessions, userDayBoundary(h.userID))
+	}
+	return h.loadedNights, nil
+}
+
+// AchievementService 成就服务
+type AchievementService struct {
+	roomService   *RoomService
+	recordService *RecordService
+}
+
+// NewAchievementService 创建成就服务
+func NewAchievementService(roomService *RoomService, recordService *RecordService) *AchievementService {
+	return &AchievementService{
+		roomService:   roomService,
+		recordService: recordService,
+	}
+}
+
+// EvaluateUser 评估用户尚未获得的成就，返回本次新获得的成就
+func (s *AchievementService) EvaluateUser(userID uint) ([]models.UserAchievement, error) {
+	var earned []models.UserAchievement
+	if err := models.DB.Where("user_id = ?", userID).Find(&earned).Error; err != nil {
+		return nil, err
+	}
+
+	earnedCodes := make(map[string]struct{}, len(earned))
+	for _, achievement := range earned {
+		earnedCodes[achievement.Code] = struct{}{}
+	}
+
+	history := &achievementHistory{userID: userID, recordService: s.recordService}
+	awarded := make([]models.UserAchievement, 0)
+	for _, rule := range achievementRules {
+		if _, ok := earnedCodes[rule.Code]; ok {
+			continue
+		}
+
+		hit, err := rule.evaluate(history)
+		if err != nil {
+			return awarded, err
+		}
+		if hit == nil {
+			continue
+		}
+
+		achievement := models.UserAchievement{
+			UserID:   userID,
+			Code:     rule.Code,
+			RoomID:   hit.RoomID,
+			EarnedAt: hit.At,
+		}
+
+		// 并发评估时由唯一索引保证只授予一次
+		res := models.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&achievement)
+		if res.Error != nil {
+			return awarded, res.Error
+		}
+		if res.RowsAffected > 0 {
+			awarded = append(awarded, achievement)
+		}
+	}
+
+	return awarded, nil
+}
+
+// OnRoomSettled 房间结算后评估所有成员的成就，并在房间内播报新获得的成就
+func (s *AchievementService) OnRoomSettled(roomID uint) {
+	var memberIDs []uint
+	if err := models.DB.Model(&models.RoomMember{}).
+		Where("room_id = ?", roomID).
+		Distinct("user_id").
+		Pluck("user_id", &memberIDs).Error; err != nil {
+		log.Printf("评估成就时查询房间成员失败: RoomID=%d, %v", roomID, err)
+		return
+	}
+
+	for _, userID := range memberIDs {
+		awarded, err := s.EvaluateUser(userID)
+		if err != nil {
+			log.Printf("评估成就失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
+		}
+		for _, achievement := range awarded {
+			log.Printf("获得成就: RoomID=%d, UserID=%d, Code=%s", roomID, userID, achievement.Code)
+			s.broadcastAchievementEarned(roomID, achievement)
+		}
+	}
+}
+
+// GetUserAchievements 获取用户的成就列表（包含未获得的），查询前会补评一次历史记录
+func (s *AchievementService) GetUserAchievements(userID uint) (map[string]interface{}, error) {
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	if _, err := s.EvaluateUser(userID); err != nil {
+		return nil, err
+	}
+
+	var earned []models.UserAchievement
+	if err := models.DB.Where("user_id = ?", userID).Find(&earned).Error; err != nil {
+		return nil, err
+	}
+
+	earnedByCode := make(map[string]models.UserAchievement, len(earned))
+	for _, achievement := range earned {
+		earnedByCode[achievement.Code] = achievement
+	}
+
+	achievements := make([]map[string]interface{}, 0, len(achievementRules))
+	for _, rule := range achievementRules {
+		view := map[string]interface{}{
+			"code":        rule.Code,
+			"name":        rule.Name,
+			"description": rule.Description,
+			"earned":      false,
+		}
+		if achievement, ok := earnedByCode[rule.Code]; ok {
+			view["earned"] = true
+			view["earned_at"] = achievement.EarnedAt
+			if achievement.RoomID != nil {
+				view["room_id"] = *achievement.RoomID
+			}
+		}
+		achievements = append(achievements, view)
+	}
+
+	// 已获得的按达成时间倒序排在前面
+	sort.SliceStable(achievements, func(i, j int) bool {
+		ei, ej := achievements[i]["earned"].(bool), achievements[j]["earned"].(bool)
+		if ei != ej {
+			return ei
+		}
+		if !ei {
+			return false
+		}
+		return achievements[i]["earned_at"].(time.Time).After(achievements[j]["earned_at"].(time.Time))
+	})
+
+	return map[string]interface{}{
+		"user_id":      user.ID,
+		"nickname":     user.Nickname,
+		"earned_count": len(earned),
+		"achievements": achievements,
+	}, nil
+}
+
+func (s *AchievementService) broadcastAchievementEarned(roomID uint, achievement models.UserAchievement) {
+	if s.roomService == nil || s.roomService.hub == nil {
+		return
+	}
+
+	definition, ok := findAchievementDefinition(achievement.Code)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	models.DB.First(&user, achievement.UserID)
+
+	if err := s.roomService.publishEvent(roomID, ws.AchievementEarnedEvent{
+		UserID:      achievement.UserID,
+		Nickname:    user.Nickname,
+		Code:        definition.Code,
+		Name:        definition.Name,
+		Description: definition.Description,
+		EarnedAt:    achievement.EarnedAt,
+	}); err != nil {
+		log.Printf("播报成就失败: RoomID=%d, UserID=%d, Code=%s, %v", roomID, achievement.UserID, achievement.Code, err)
+	}
+}
+
+func findAchievementDefinition(code string) (AchievementDefinition, bool) {
+	for _, rule := range achievementRules {
+		if rule.Code == code {
+			return rule.AchievementDefinition, true
+		}
+	}
+	return AchievementDefinition{}, false
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAchievementService_EvaluatesHistory(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	// Alice 先输一晚，再连赢5晚，其中第5晚赢了1000积分（50元），一共6场
+	base := time.Date(2025, 11, 1, 20, 0, 0, 0, time.Local)
+	seedSettledRoom(t, "texas", base, base.Add(time.Hour), map[uint]int{alice: -100, bob: 100})
+	var rooms []models.Room
+	for i := 1; i <= 5; i++ {
+		night := base.AddDate(0, 0, i)
+		chip := 100
+		if i == 5 {
+			chip = 4000
+		}
+		rooms = append(rooms, seedSettledRoom(t, "texas", night, night.Add(time.Hour), map[uint]int{alice: chip, bob: -chip}))
+	}
+
+	amount := 1200
+	require.NoError(t, models.DB.Create(&models.RoomOperation{
+		RoomID: rooms[2].ID, UserID: alice, OperationType: models.OpTypeWithdraw, Amount: &amount, CreatedAt: base.AddDate(0, 0, 3).Add(30 * time.Minute),
+	}).Error)
+
+	service := NewAchievementService(&RoomService{}, NewRecordService())
+	awarded, err := service.EvaluateUser(alice)
+	require.NoError(t, err)
+
+	byCode := make(map[string]models.UserAchievement)
+	for _, achievement := range awarded {
+		byCode[achievement.Code] = achievement
+	}
+	require.Len(t, byCode, 4)
+
+	require.Equal(t, rooms[0].ID, *byCode["first_win"].RoomID)
+	require.Equal(t, rooms[2].ID, *byCode["big_pot"].RoomID)
+	require.Equal(t, rooms[4].ID, *byCode["night_streak_5"].RoomID)
+	require.True(t, base.AddDate(0, 0, 5).Add(time.Hour).Equal(byCode["night_streak_5"].EarnedAt))
+	require.Equal(t, rooms[4].ID, *byCode["big_night"].RoomID)
+	require.NotContains(t, byCode, "sessions_10")
+
+	// 已获得的成就不会重复授予
+	awarded, err = service.EvaluateUser(alice)
+	require.NoError(t, err)
+	require.Empty(t, awarded)
+
+	// 再打4场达到10场
+	for i := 6; i <= 9; i++ {
+		night := base.AddDate(0, 0, i)
+		seedSettledRoom(t, "niuniu", night, night.Add(time.Hour), map[uint]int{alice: -10, bob: 10})
+	}
+	service.OnRoomSettled(rooms[0].ID)
+
+	profile, err := service.GetUserAchievements(alice)
+	require.NoError(t, err)
+	require.Equal(t, 5, profile["earned_count"])
+	achievements := profile["achievements"].([]map[string]interface{})
+	require.Equal(t, "sessions_10", achievements[0]["code"], "最新获得的排在最前")
+	require.Equal(t, false, achievements[len(achievements)-1]["earned"])
+
+	// Bob 赢了第一晚，也拿到首胜
+	bobProfile, err := service.GetUserAchievements(bob)
+	require.NoError(t, err)
+	require.Equal(t, 2, bobProfile["earned_count"])
+
+	_, err = service.GetUserAchievements(999)
+	require.EqualError(t, err, "用户不存在")
+}
@@ -13,11 +13,15 @@ import (
 )
 
 // AdminService 后台管理服务
-type AdminService struct{}
+type AdminService struct {
+	achievementService *AchievementService // 合并账户后重新评估成就
+}
 
 // NewAdminService 创建后台管理服务
-func NewAdminService() *AdminService {
-	return &AdminService{}
+func NewAdminService(achievementService *AchievementService) *AdminService {
+	return &AdminService{
+		achievementService: achievementService,
+	}
 }
 
 var (
@@ -300,10 +304,10 @@ func (s *AdminService) GetUserSettlements(userID uint, startTime, endTime *time.
 	query := models.DB.Where("user_id = ?", userID)
 
 	if startTime != nil {
-		query = query.Where("settled_at >= ?", *startTime)
+		query = query.Where("settled_at >= ?", storageTime(*startTime))
 	}
 	if endTime != nil {
-		query = query.Where("settled_at <= ?", *endTime)
+		query = query.Where("settled_at <= ?", storageTime(*endTime))
 	}
 
 	var settlements []models.Settlement
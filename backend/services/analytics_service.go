@@ -0,0 +1,184 @@
+package services
+
+import (
+	"poker_score_backend/models"
+)
+
+// AnalyticsService 玩家风险与波动分析服务
+type AnalyticsService struct {
+	recordService *RecordService
+}
+
+// NewAnalyticsService 创建风险分析服务
+func NewAnalyticsService(recordService *RecordService) *AnalyticsService {
+	return &AnalyticsService{
+		recordService: recordService,
+	}
+}
+
+// GetPlayerAnalytics 基于结算记录与入座时长计算用户的波动、回撤、资金曲线、投入回报率与时薪置信区间
+// 金额均以人民币（元）计；本系统没有锦标赛，投入回报率以每场对局中本人下注的积分总和作为买入
+func (s *AnalyticsService) GetPlayerAnalytics(userID uint, filter StatsFilter) (map[string]interface{}, error) {
+	sessions, err := s.recordService.loadPlayerSessions(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	boundary := userDayBoundary(userID)
+	nights := groupNights(sessions, boundary)
+
+	nightly := make([]float64, len(nights))
+	nightViews := make([]map[string]interface{}, 0, len(nights))
+	for i, night := range nights {
+		nightly[i] = night.RmbAmount
+		nightViews = append(nightViews, map[string]interface{}{
+			"date":       night.Date,
+			"sessions":   night.Sessions,
+			"rmb_amount": night.RmbAmount,
+		})
+	}
+
+	results := make([]float64, len(sessions))
+	hours := make([]float64, len(sessions))
+	for i, session := range sessions {
+		results[i] = session.RmbAmount
+		hours[i] = session.EndedAt.Sub(session.StartedAt).Hours()
+	}
+
+	curve := cumulativeCurve(results)
+	bankroll := make([]map[string]interface{}, 0, len(sessions))
+	for i, session := range sessions {
+		bankroll = append(bankroll, map[string]interface{}{
+			"at":             session.EndedAt,
+			"room_id":        session.RoomID,
+			"room_code":      session.RoomCode,
+			"rmb_amount":     session.RmbAmount,
+			"cumulative_rmb": roundTo(curve[i+1], 2),
+		})
+	}
+
+	peak := 0.0
+	for _, v := range curve {
+		if v > peak {
+			peak = v
+		}
+	}
+
+	roi, err := s.buyInReturn(userID, sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	var hourly interface{}
+	if rate, ok := estimateHourlyRate(results, hours, confidenceZ95); ok {
+		hourly = map[string]interface{}{
+			"rate":          roundTo(rate.Rate, 2),
+			"stddev":        roundTo(rate.StdDev, 2),
+			"std_error":     roundTo(rate.StdError, 2),
+			"ci_lower":      roundTo(rate.Lower, 2),
+			"ci_upper":      roundTo(rate.Upper, 2),
+			"confidence":    0.95,
+			"prob_positive": roundTo(rate.ProbPositive, 4),
+			"hours":         roundTo(rate.Hours, 2),
+			"sessions":      rate.Sessions,
+			"significant":   rate.Lower > 0 || rate.Upper < 0,
+		}
+	}
+
+	return map[string]interface{}{
+		"filter": map[string]interface{}{
+			"start":     filter.Start,
+			"end":       filter.End,
+			"room_type": filter.RoomType,
+		},
+		"total_sessions": len(sessions),
+		"total_nights":   len(nights),
+		"net_rmb":        roundTo(curve[len(curve)-1], 2),
+		"nightly": map[string]interface{}{
+			"mean":    roundTo(mean(nightly), 2),
+			"stddev":  roundTo(sampleStdDev(nightly), 2),
+			"results": nightViews,
+		},
+		"session_stddev":     roundTo(sampleStdDev(results), 2),
+		"bankroll":           bankroll,
+		"max_downswing":      swingView(maxDrawdown(curve), sessions),
+		"max_upswing":        swingView(maxRunup(curve), sessions),
+		"current_drawdown":   roundTo(curve[len(curve)-1]-peak, 2),
+		"longest_underwater": longestUnderwater(curve),
+		"roi":                roi,
+		"hourly":             hourly,
+	}, nil
+}
+
+// buyInReturn 按每场对局中本人下注（含牛牛下注）的积分总和计算投入回报率
+func (s *AnalyticsService) buyInReturn(userID uint, sessions []playerSession) (map[string]interface{}, error) {
+	roomIDs := make([]uint, 0, len(sessions))
+	for _, session := range sessions {
+		roomIDs = append(roomIDs, session.RoomID)
+	}
+
+	buyIns := make(map[uint]int, len(roomIDs))
+	if len(roomIDs) > 0 {
+		type buyInRow struct {
+			RoomID uint
+			Total  int
+		}
+		var rows []buyInRow
+		if err := models.DB.Model(&models.RoomOperation{}).
+			Select("room_id, SUM(amount) AS total").
+			Where("user_id = ? AND room_id IN ? AND operation_type IN ?", userID, roomIDs,
+				[]string{models.OpTypeBet, models.OpTypeNiuniuBet}).
+			Group("room_id").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			buyIns[row.RoomID] = row.Total
+		}
+	}
+
+	totalBuyIn, totalChip := 0, 0
+	perSession := make([]float64, 0, len(sessions))
+	for _, session := range sessions {
+		buyIn := buyIns[session.RoomID]
+		if buyIn <= 0 {
+			continue
+		}
+		totalBuyIn += buyIn
+		totalChip += session.ChipAmount
+		perSession = append(perSession, float64(session.ChipAmount)/float64(buyIn))
+	}
+
+	roi := 0.0
+	if totalBuyIn > 0 {
+		roi = float64(totalChip) / float64(totalBuyIn)
+	}
+
+	return map[string]interface{}{
+		"sessions":     len(perSession),
+		"total_buy_in": totalBuyIn,
+		"net_chip":     totalChip,
+		"roi":          roundTo(roi, 4),
+		"average_roi":  roundTo(mean(perSession), 4),
+		"roi_stddev":   roundTo(sampleStdDev(perSession), 4),
+	}, nil
+}
+
+// swingView 把资金曲线下标换算成对局，曲线第 i 个点对应第 i 场结束后（第0个点为起点）
+func swingView(s swing, sessions []playerSession) map[string]interface{} {
+	if s.Amount == 0 {
+		return nil
+	}
+
+	view := map[string]interface{}{
+		"rmb_amount": roundTo(s.Amount, 2),
+		"sessions":   s.Length(),
+		"end_at":     sessions[s.End-1].EndedAt,
+	}
+	if s.Start > 0 {
+		view["start_at"] = sessions[s.Start-1].EndedAt
+	} else {
+		view["start_at"] = sessions[0].StartedAt
+	}
+	return view
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"math"
+)
+
+// confidenceZ95 95%置信区间对应的正态分位数
+const confidenceZ95 = 1.959964
+
+// swing 资金曲线上的一段下跌（或上涨）：Start 为起点（峰值或谷底）下标，End 为终点下标
+type swing struct {
+	Amount float64
+	Start  int
+	End    int
+}
+
+// Length 这段走势经过的场次数
+func (s swing) Length() int {
+	return s.End - s.Start
+}
+
+// hourlyRate 时薪及其置信区间
+type hourlyRate struct {
+	Rate         float64 // 每小时盈亏
+	StdDev       float64 // 每小时标准差（按时长加权估计）
+	StdError     float64 // 时薪的标准误
+	Lower        float64 // 置信区间下限
+	Upper        float64 // 置信区间上限
+	ProbPositive float64 // 真实时薪大于0的概率（正态近似）
+	Hours        float64
+	Sessions     int
+}
+
+// mean 算术平均值，空切片返回0
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// sampleStdDev 样本标准差（n-1），少于2个值时返回0
+func sampleStdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// cumulativeCurve 由每场结果生成资金曲线，第一个点为起始的0
+func cumulativeCurve(results []float64) []float64 {
+	curve := make([]float64, len(results)+1)
+	for i, v := range results {
+		curve[i+1] = curve[i] + v
+	}
+	return curve
+}
+
+// maxDrawdown 资金曲线上从峰值到之后谷底的最大回撤，Amount 为负数；没有回撤时为零值
+func maxDrawdown(curve []float64) swing {
+	var worst swing
+	peak := 0
+	for i := 1; i < len(curve); i++ {
+		if curve[i] > curve[peak] {
+			peak = i
+			continue
+		}
+		if drop := curve[i] - curve[peak]; drop < worst.Amount {
+			worst = swing{Amount: drop, Start: peak, End: i}
+		}
+	}
+	return worst
+}
+
+// maxRunup 资金曲线上从谷底到之后峰值的最大上涨，Amount 为正数；没有上涨时为零值
+func maxRunup(curve []float64) swing {
+	inverted := make([]float64, len(curve))
+	for i, v := range curve {
+		inverted[i] = -v
+	}
+	run := maxDrawdown(inverted)
+	run.Amount = -run.Amount
+	return run
+}
+
+// longestUnderwater 资金曲线低于此前最高点持续的最多场次
+func longestUnderwater(curve []float64) int {
+	longest, current := 0, 0
+	peak := math.Inf(-1)
+	for _, v := range curve {
+		if v >= peak {
+			peak = v
+			current = 0
+			continue
+		}
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+	return longest
+}
+
+// estimateHourlyRate 估计时薪及置信区间
+// 把每场结果看作均值与方差都和时长成正比的独立样本：时薪 = 总盈亏 / 总时长，
+// 每小时方差 = Σ(x_i - w·h_i)² / h_i / (n-1)，时薪的标准误 = 每小时标准差 / √总时长
+func estimateHourlyRate(results, hours []float64, z float64) (hourlyRate, bool) {
+	var rate hourlyRate
+	total := 0.0
+	for i := range results {
+		if hours[i] <= 0 {
+			continue
+		}
+		rate.Sessions++
+		rate.Hours += hours[i]
+		total += results[i]
+	}
+	if rate.Sessions < 2 {
+		return rate, false
+	}
+
+	rate.Rate = total / rate.Hours
+
+	variance := 0.0
+	for i := range results {
+		if hours[i] <= 0 {
+			continue
+		}
+		deviation := results[i] - rate.Rate*hours[i]
+		variance += deviation * deviation / hours[i]
+	}
+	variance /= float64(rate.Sessions - 1)
+
+	rate.StdDev = math.Sqrt(variance)
+	rate.StdError = rate.StdDev / math.Sqrt(rate.Hours)
+	rate.Lower = rate.Rate - z*rate.StdError
+	rate.Upper = rate.Rate + z*rate.StdError
+
+	switch {
+	case rate.StdError > 0:
+		rate.ProbPositive = normalCDF(rate.Rate / rate.StdError)
+	case rate.Rate > 0:
+		rate.ProbPositive = 1
+	}
+
+	return rate, true
+}
+
+// normalCDF 标准正态分布的累积分布函数
+func normalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeanAndSampleStdDev(t *testing.T) {
+	t.Parallel()
+
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	require.Equal(t, 5.0, mean(values))
+	require.InDelta(t, math.Sqrt(32.0/7), sampleStdDev(values), 1e-9)
+
+	require.Equal(t, 0.0, mean(nil))
+	require.Equal(t, 0.0, sampleStdDev([]float64{42}))
+}
+
+func TestSwingsOnBankrollCurve(t *testing.T) {
+	t.Parallel()
+
+	curve := cumulativeCurve([]float64{100, -50, -80, 30, 200, -10})
+	require.Equal(t, []float64{0, 100, 50, -30, 0, 200, 190}, curve)
+
+	down := maxDrawdown(curve)
+	require.Equal(t, swing{Amount: -130, Start: 1, End: 3}, down)
+	require.Equal(t, 2, down.Length())
+
+	up := maxRunup(curve)
+	require.Equal(t, swing{Amount: 230, Start: 3, End: 5}, up)
+
+	require.Equal(t, 3, longestUnderwater(curve))
+
+	// 一路上涨没有回撤
+	rising := cumulativeCurve([]float64{10, 20, 30})
+	require.Equal(t, swing{}, maxDrawdown(rising))
+	require.Equal(t, 0, longestUnderwater(rising))
+}
+
+func TestEstimateHourlyRate(t *testing.T) {
+	t.Parallel()
+
+	// 0小时的对局不参与估计
+	rate, ok := estimateHourlyRate([]float64{30, -10, 40, 99}, []float64{2, 1, 3, 0}, confidenceZ95)
+	require.True(t, ok)
+	require.Equal(t, 3, rate.Sessions)
+	require.Equal(t, 6.0, rate.Hours)
+	require.InDelta(t, 10, rate.Rate, 1e-9)
+
+	// 每小时方差 = (10²/2 + 20²/1 + 10²/3) / 2
+	stdDev := math.Sqrt((50 + 400 + 100.0/3) / 2)
+	require.InDelta(t, stdDev, rate.StdDev, 1e-9)
+	require.InDelta(t, stdDev/math.Sqrt(6), rate.StdError, 1e-9)
+	require.InDelta(t, 10-confidenceZ95*rate.StdError, rate.Lower, 1e-9)
+	require.InDelta(t, 10+confidenceZ95*rate.StdError, rate.Upper, 1e-9)
+	require.InDelta(t, 0.9424, rate.ProbPositive, 1e-3)
+
+	_, ok = estimateHourlyRate([]float64{30}, []float64{2}, confidenceZ95)
+	require.False(t, ok, "少于两场无法估计方差")
+}
+
+func TestNormalCDF(t *testing.T) {
+	t.Parallel()
+
+	require.InDelta(t, 0.5, normalCDF(0), 1e-12)
+	require.InDelta(t, 0.975, normalCDF(confidenceZ95), 1e-6)
+	require.InDelta(t, 0.025, normalCDF(-confidenceZ95), 1e-6)
+}
+
+func TestAnalyticsService_GetPlayerAnalytics(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	bet := func(room models.Room, userID uint, amount int, at time.Time) {
+		require.NoError(t, models.DB.Create(&models.RoomOperation{
+			RoomID: room.ID, UserID: userID, OperationType: models.OpTypeBet, Amount: &amount, CreatedAt: at,
+		}).Error)
+	}
+
+	// 三晚：+100元（2小时）、-150元（1小时）、+50元（3小时），比例 20:1
+	night1 := time.Date(2025, 11, 3, 20, 0, 0, 0, time.Local)
+	room1 := seedSettledRoom(t, "texas", night1, night1.Add(2*time.Hour), map[uint]int{alice: 2000, bob: -2000})
+	bet(room1, alice, 1000, night1.Add(time.Hour))
+
+	night2 := night1.AddDate(0, 0, 1)
+	room2 := seedSettledRoom(t, "texas", night2, night2.Add(time.Hour), map[uint]int{alice: -3000, bob: 3000})
+	bet(room2, alice, 4000, night2.Add(30*time.Minute))
+
+	night3 := night1.AddDate(0, 0, 2)
+	seedSettledRoom(t, "niuniu", night3, night3.Add(3*time.Hour), map[uint]int{alice: 1000, bob: -1000})
+
+	service := NewAnalyticsService(NewRecordService())
+	analytics, err := service.GetPlayerAnalytics(alice, StatsFilter{})
+	require.NoError(t, err)
+
+	require.Equal(t, 3, analytics["total_sessions"])
+	require.Equal(t, 3, analytics["total_nights"])
+	require.Equal(t, 0.0, analytics["net_rmb"])
+
+	nightly := analytics["nightly"].(map[string]interface{})
+	require.Equal(t, 0.0, nightly["mean"])
+	require.Equal(t, roundTo(math.Sqrt(17500), 2), nightly["stddev"])
+
+	bankroll := analytics["bankroll"].([]map[string]interface{})
+	require.Len(t, bankroll, 3)
+	require.Equal(t, []float64{100, -50, 0}, []float64{
+		bankroll[0]["cumulative_rmb"].(float64), bankroll[1]["cumulative_rmb"].(float64), bankroll[2]["cumulative_rmb"].(float64),
+	})
+
+	down := analytics["max_downswing"].(map[string]interface{})
+	require.Equal(t, -150.0, down["rmb_amount"])
+	require.Equal(t, 1, down["sessions"])
+	up := analytics["max_upswing"].(map[string]interface{})
+	require.Equal(t, 100.0, up["rmb_amount"])
+	require.Equal(t, -100.0, analytics["current_drawdown"])
+	require.Equal(t, 2, analytics["longest_underwater"])
+
+	// 第三场没有下注记录，不计入投入回报率
+	roi := analytics["roi"].(map[string]interface{})
+	require.Equal(t, 2, roi["sessions"])
+	require.Equal(t, 5000, roi["total_buy_in"])
+	require.Equal(t, -0.2, roi["roi"])
+	require.Equal(t, 0.625, roi["average_roi"])
+
+	hourly := analytics["hourly"].(map[string]interface{})
+	require.Equal(t, 0.0, hourly["rate"])
+	require.Equal(t, 6.0, hourly["hours"])
+	require.Equal(t, false, hourly["significant"])
+	require.Less(t, hourly["ci_lower"].(float64), 0.0)
+	require.Greater(t, hourly["ci_upper"].(float64), 0.0)
+
+	// 按游戏类型筛选后只剩一场，无法估计时薪区间
+	filtered, err := service.GetPlayerAnalytics(alice, StatsFilter{RoomType: "niuniu"})
+	require.NoError(t, err)
+	require.Equal(t, 1, filtered["total_sessions"])
+	require.Nil(t, filtered["hourly"])
+	require.Nil(t, filtered["max_downswing"])
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"poker_score_backend/models"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix 个人API令牌的固定前缀，用于与Session ID区分
+const APITokenPrefix = "pst_"
+
+const (
+	apiTokenBytes       = 24 // 令牌随机部分的字节数
+	apiTokenShownPrefix = 8  // 列表中展示的令牌字符数（含前缀）
+	maxAPITokensPerUser = 20
+)
+
+// apiTokenTouchInterval 两次记录令牌使用时间的最小间隔，避免脚本频繁调用时每个请求都写数据库
+const apiTokenTouchInterval = time.Minute
+
+// APITokenScopes 所有可申请的权限范围
+var APITokenScopes = []string{
+	models.APIScopeRecordsRead,
+	models.APIScopeRoomsRead,
+	models.APIScopeRoomsOperate,
+}
+
+// APITokenService 个人API令牌服务
+type APITokenService struct{}
+
+// NewAPITokenService 创建API令牌服务
+func NewAPITokenService() *APITokenService {
+	return &APITokenService{}
+}
+
+// Create 为用户创建API令牌，返回只展示一次的明文令牌
+// validFor 为0表示长期有效
+func (s *APITokenService) Create(userID uint, name string, scopes []string, validFor time.Duration) (string, map[string]interface{}, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("令牌名称不能为空")
+	}
+	normalized, err := normalizeAPITokenScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if validFor < 0 {
+		return "", nil, errors.New("有效期不能为负数")
+	}
+
+	var count int64
+	if err := models.DB.Model(&models.APIToken{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return "", nil, err
+	}
+	if count >= maxAPITokensPerUser {
+		return "", nil, fmt.Errorf("每个用户最多创建%d个令牌，请先删除不再使用的令牌", maxAPITokensPerUser)
+	}
+
+	buf := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	raw := APITokenPrefix + hex.EncodeToString(buf)
+
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:apiTokenShownPrefix],
+		TokenHash: hashAPIToken(raw),
+		Scopes:    strings.Join(normalized, ","),
+	}
+	if validFor > 0 {
+		expiresAt := time.Now().Add(validFor)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := models.DB.Create(&token).Error; err != nil {
+		log.Printf("创建API令牌失败: UserID=%d, %v", userID, err)
+		return "", nil, err
+	}
+
+	log.Printf("创建API令牌: UserID=%d, ID=%d, Scopes=%s", userID, token.ID, token.Scopes)
+	return raw, apiTokenView(&token), nil
+}
+
+// List 列出用户的所有API令牌（不含明文）
+func (s *APITokenService) List(userID uint) ([]map[string]interface{}, error) {
+	var tokens []models.APIToken
+	if err := models.DB.Where("user_id = ?", userID).Order("id DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(tokens))
+	for i := range tokens {
+		result = append(result, apiTokenView(&tokens[i]))
+	}
+	return result, nil
+}
+
+// Revoke 删除用户的某个API令牌，立即失效
+func (s *APITokenService) Revoke(userID, id uint) error {
+	result := models.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIToken{})
+	if result.Error != nil {
+		log.Printf("删除API令牌失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("令牌不存在")
+	}
+
+	log.Printf("API令牌已撤销: UserID=%d, ID=%d", userID, id)
+	return nil
+}
+
+// Authenticate 校验API令牌并返回所属用户，同时记录使用时间与IP
+func (s *APITokenService) Authenticate(raw, ip string) (*models.APIToken, *models.User, error) {
+	var tokens []models.APIToken
+	if err := models.DB.Where("token_hash = ?", hashAPIToken(raw)).Limit(1).Find(&tokens).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("API令牌无效或已撤销")
+	}
+	token := tokens[0]
+	if token.IsExpired() {
+		return nil, nil, errors.New("API令牌已过期")
+	}
+
+	var users []models.User
+	if err := models.DB.Where("id = ?", token.UserID).Limit(1).Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil, errors.New("用户不存在")
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval || token.LastUsedIP != ip {
+		token.LastUsedAt = &now
+		token.LastUsedIP = ip
+		if err := models.DB.Model(&models.APIToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{
+			"last_used_at": token.LastUsedAt,
+			"last_used_ip": token.LastUsedIP,
+		}).Error; err != nil {
+			log.Printf("更新API令牌使用记录失败: ID=%d, %v", token.ID, err)
+		}
+	}
+
+	return &token, &users[0], nil
+}
+
+// normalizeAPITokenScopes 校验并去重权限范围，按 APITokenScopes 的顺序返回
+func normalizeAPITokenScopes(scopes []string) ([]string, error) {
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		valid := false
+		for _, known := range APITokenScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("未知的权限范围: %s", scope)
+		}
+		requested[scope] = true
+	}
+	if len(requested) == 0 {
+		return nil, errors.New("请至少选择一个权限范围")
+	}
+
+	result := make([]string, 0, len(requested))
+	for _, known := range APITokenScopes {
+		if requested[known] {
+			result = append(result, known)
+		}
+	}
+	return result, nil
+}
+
+// hashAPIToken 计算令牌哈希；令牌本身为高熵随机串，无需加盐
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiTokenView(token *models.APIToken) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           token.ID,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.ScopeList(),
+		"last_used_at": token.LastUsedAt,
+		"last_used_ip": token.LastUsedIP,
+		"expires_at":   token.ExpiresAt,
+		"created_at":   token.CreatedAt,
+	}
+}
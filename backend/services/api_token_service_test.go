@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIToken_CreateAuthenticateAndRevoke(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	service := NewAPITokenService()
+
+	_, _, err := service.Create(users[0].ID, "bot", []string{"rooms:write"}, 0)
+	require.EqualError(t, err, "未知的权限范围: rooms:write")
+	_, _, err = service.Create(users[0].ID, "bot", nil, 0)
+	require.EqualError(t, err, "请至少选择一个权限范围")
+
+	raw, view, err := service.Create(users[0].ID, " bot ", []string{models.APIScopeRoomsOperate, models.APIScopeRecordsRead, models.APIScopeRecordsRead}, 0)
+	require.NoError(t, err)
+	require.Equal(t, "bot", view["name"])
+	require.Equal(t, []string{models.APIScopeRecordsRead, models.APIScopeRoomsOperate}, view["scopes"])
+
+	var stored models.APIToken
+	require.NoError(t, models.DB.First(&stored, view["id"]).Error)
+	require.NotContains(t, stored.TokenHash, raw[len(APITokenPrefix):])
+	require.Nil(t, stored.LastUsedAt)
+
+	token, user, err := service.Authenticate(raw, "10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, users[0].ID, user.ID)
+	require.True(t, token.HasScope(models.APIScopeRoomsOperate))
+	require.False(t, token.HasScope(models.APIScopeRoomsRead))
+
+	var touched models.APIToken
+	require.NoError(t, models.DB.First(&touched, stored.ID).Error)
+	require.NotNil(t, touched.LastUsedAt)
+	require.Equal(t, "10.0.0.1", touched.LastUsedIP)
+
+	_, _, err = service.Authenticate(raw+"0", "10.0.0.1")
+	require.EqualError(t, err, "API令牌无效或已撤销")
+
+	// 只能撤销自己的令牌
+	require.EqualError(t, service.Revoke(users[1].ID, stored.ID), "令牌不存在")
+	require.NoError(t, service.Revoke(users[0].ID, stored.ID))
+	_, _, err = service.Authenticate(raw, "10.0.0.1")
+	require.EqualError(t, err, "API令牌无效或已撤销")
+}
+
+func TestAPIToken_Expiry(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Alice"})[0]
+	service := NewAPITokenService()
+
+	raw, view, err := service.Create(user.ID, "short", []string{models.APIScopeRoomsRead}, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, view["expires_at"])
+
+	_, _, err = service.Authenticate(raw, "")
+	require.NoError(t, err)
+
+	require.NoError(t, models.DB.Model(&models.APIToken{}).Where("id = ?", view["id"]).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, _, err = service.Authenticate(raw, "")
+	require.EqualError(t, err, "API令牌已过期")
+
+	tokens, err := service.List(user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+}
@@ -5,25 +5,39 @@ import (
 	"log"
 	"poker_score_backend/models"
 	"poker_score_backend/utils"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// sessionTouchInterval 两次记录Session使用时间的最小间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
 // AuthService 认证服务
 type AuthService struct {
-	sessionMaxAge time.Duration
+	sessionMaxAge      time.Duration
+	sessionIdleTimeout time.Duration
+	wsTicketTTL        time.Duration
+}
+
+// SessionClient 创建或使用Session的客户端信息
+type SessionClient struct {
+	IP        string
+	UserAgent string
 }
 
 // NewAuthService 创建认证服务
-func NewAuthService(sessionMaxAge time.Duration) *AuthService {
+func NewAuthService(sessionMaxAge, sessionIdleTimeout, wsTicketTTL time.Duration) *AuthService {
 	return &AuthService{
-		sessionMaxAge: sessionMaxAge,
+		sessionMaxAge:      sessionMaxAge,
+		sessionIdleTimeout: sessionIdleTimeout,
+		wsTicketTTL:        wsTicketTTL,
 	}
 }
 
 // Register 用户注册
-func (s *AuthService) Register(phone, nickname, password string) (*models.User, *models.Session, error) {
+func (s *AuthService) Register(phone, nickname, password string, client SessionClient) (*models.User, *models.Session, error) {
 	// 检查手机号是否已注册
 	var count int64
 	err := models.DB.Model(&models.User{}).Where("phone = ?", phone).Count(&count).Error
@@ -60,7 +74,7 @@ func (s *AuthService) Register(phone, nickname, password string) (*models.User,
 	log.Printf("用户注册成功: ID=%d, Phone=%s, Nickname=%s", user.ID, user.Phone, user.Nickname)
 
 	// 自动登录，创建Session
-	session, err := s.CreateSession(user.ID)
+	session, err := s.CreateSession(user.ID, client)
 	if err != nil {
 		return &user, nil, err
 	}
@@ -68,43 +82,68 @@ func (s *AuthService) Register(phone, nickname, password string) (*models.User,
 	return &user, session, nil
 }
 
+// ErrInvalidCredentials 手机号或密码错误，手机号未注册时返回同样的错误
+var ErrInvalidCredentials = errors.New("手机号或密码错误")
+
 // Login 用户登录
-func (s *AuthService) Login(phone, password string) (*models.User, *models.Session, error) {
+func (s *AuthService) Login(phone, password string, client SessionClient) (*models.User, *models.Session, error) {
+	user, err := s.Authenticate(phone, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 创建Session
+	session, err := s.CreateSession(user.ID, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("用户登录成功: ID=%d, Phone=%s, Nickname=%s", user.ID, user.Phone, user.Nickname)
+
+	return user, session, nil
+}
+
+// Authenticate 校验手机号与密码，不创建Session（开启两步验证的用户还需完成第二步）
+func (s *AuthService) Authenticate(phone, password string) (*models.User, error) {
 	// 查询用户
 	var user models.User
-	err := models.DB.Where("phone = ?", phone).First(&user).Error
+	// 游客与已注销的账户没有密码，不能登录
+	err := models.DB.Where("phone = ? AND role NOT IN ?", phone, []string{models.RoleGuest, models.RoleDeleted}).First(&user).Error
 	if err != nil {
 		log.Printf("用户不存在: Phone=%s", phone)
-		return nil, nil, errors.New("手机号或密码错误")
+		return nil, ErrInvalidCredentials
 	}
 
 	// 验证密码
 	if !utils.CheckPassword(password, user.PasswordHash) {
 		log.Printf("密码错误: UserID=%d", user.ID)
-		return nil, nil, errors.New("手机号或密码错误")
-	}
-
-	// 创建Session
-	session, err := s.CreateSession(user.ID)
-	if err != nil {
-		return nil, nil, err
+		return nil, ErrInvalidCredentials
 	}
 
-	log.Printf("用户登录成功: ID=%d, Phone=%s, Nickname=%s", user.ID, user.Phone, user.Nickname)
+	return &user, nil
+}
 
-	return &user, session, nil
+// CreateSession 创建Session，记录登录设备与IP
+func (s *AuthService) CreateSession(userID uint, client SessionClient) (*models.Session, error) {
+	return s.createSession(userID, client, false)
 }
 
-// CreateSession 创建Session
-func (s *AuthService) CreateSession(userID uint) (*models.Session, error) {
+// createSession 创建Session，twoFactor 表示本次登录是否通过了两步验证
+func (s *AuthService) createSession(userID uint, client SessionClient, twoFactor bool) (*models.Session, error) {
 	// 生成Session ID
 	sessionID := uuid.New().String()
 
 	// 创建Session
+	now := time.Now()
 	session := models.Session{
-		SessionID: sessionID,
-		UserID:    userID,
-		ExpiresAt: time.Now().Add(s.sessionMaxAge),
+		SessionID:  sessionID,
+		UserID:     userID,
+		UserAgent:  truncateString(client.UserAgent, 500),
+		IP:         client.IP,
+		LastUsedAt: now,
+		TwoFactor:  twoFactor,
+		CreatedAt:  now,
+		ExpiresAt:  s.sessionExpiry(now, now),
 	}
 
 	err := models.DB.Create(&session).Error
@@ -118,6 +157,119 @@ func (s *AuthService) CreateSession(userID uint) (*models.Session, error) {
 	return &session, nil
 }
 
+// sessionExpiry 计算Session在 lastUsedAt 使用后的过期时间：闲置超时随使用顺延，但不超过从登录起算的最长有效期
+func (s *AuthService) sessionExpiry(createdAt, lastUsedAt time.Time) time.Time {
+	expiresAt := createdAt.Add(s.sessionMaxAge)
+	if s.sessionIdleTimeout > 0 {
+		if idle := lastUsedAt.Add(s.sessionIdleTimeout); idle.Before(expiresAt) {
+			expiresAt = idle
+		}
+	}
+	return expiresAt
+}
+
+// AuthenticateSession 校验Session并返回所属用户，同时记录使用时间与IP、顺延过期时间
+func (s *AuthService) AuthenticateSession(sessionID string, client SessionClient) (*models.Session, *models.User, error) {
+	var session models.Session
+	if err := models.DB.Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, nil, errors.New("未登录或Session已过期，请重新登录")
+	}
+
+	now := time.Now()
+	lastUsedAt := session.LastUsedAt
+	if lastUsedAt.IsZero() {
+		// 增加使用记录之前创建的Session，从登录时间起算闲置时长
+		lastUsedAt = session.CreatedAt
+	}
+	if session.IsExpired() || now.After(s.sessionExpiry(session.CreatedAt, lastUsedAt)) {
+		// 删除过期的Session
+		models.DB.Delete(&session)
+		return nil, nil, errors.New("Session已过期，请重新登录")
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, session.UserID).Error; err != nil {
+		return nil, nil, errors.New("用户不存在")
+	}
+
+	if now.Sub(session.LastUsedAt) >= sessionTouchInterval || session.IP != client.IP {
+		session.LastUsedAt = now
+		session.IP = client.IP
+		session.ExpiresAt = s.sessionExpiry(session.CreatedAt, now)
+		if err := models.DB.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"last_used_at": session.LastUsedAt,
+			"ip":           session.IP,
+			"expires_at":   session.ExpiresAt,
+		}).Error; err != nil {
+			log.Printf("更新Session使用记录失败: SessionID=%d, %v", session.ID, err)
+		}
+	}
+
+	return &session, &user, nil
+}
+
+// ListSessions 列出用户所有未过期的Session，currentSessionID 对应的一条标记为当前设备
+func (s *AuthService) ListSessions(userID uint, currentSessionID string) ([]map[string]interface{}, error) {
+	var sessions []models.Session
+	if err := models.DB.Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC, id DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, map[string]interface{}{
+			"id":           session.ID,
+			"device":       describeDevice(session.UserAgent),
+			"user_agent":   session.UserAgent,
+			"ip":           session.IP,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.SessionID == currentSessionID,
+		})
+	}
+	return result, nil
+}
+
+// RevokeSession 注销用户的某一个Session（按记录ID）
+func (s *AuthService) RevokeSession(userID, id uint) error {
+	result := models.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Session{})
+	if result.Error != nil {
+		log.Printf("删除Session失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("会话不存在")
+	}
+
+	log.Printf("Session已注销: UserID=%d, ID=%d", userID, id)
+	return nil
+}
+
+// LogoutAll 注销用户的所有Session（包括当前设备），返回注销的数量
+func (s *AuthService) LogoutAll(userID uint) (int64, error) {
+	return s.revokeSessions(userID, "")
+}
+
+// revokeSessions 删除用户除 keepSessionID 以外的所有Session，keepSessionID 为空时全部删除
+func (s *AuthService) revokeSessions(userID uint, keepSessionID string) (int64, error) {
+	query := models.DB.Where("user_id = ?", userID)
+	if keepSessionID != "" {
+		query = query.Where("session_id <> ?", keepSessionID)
+	}
+
+	result := query.Delete(&models.Session{})
+	if result.Error != nil {
+		log.Printf("批量删除Session失败: UserID=%d, %v", userID, result.Error)
+		return 0, result.Error
+	}
+
+	log.Printf("已注销用户的Session: UserID=%d, 数量=%d", userID, result.RowsAffected)
+	return result.RowsAffected, nil
+}
+
 // Logout 用户登出
 func (s *AuthService) Logout(sessionID string) error {
 	// 删除Session
@@ -131,6 +283,68 @@ func (s *AuthService) Logout(sessionID string) error {
 	return nil
 }
 
+// IssueWSTicket 为房间成员签发一次性WebSocket连接票据
+func (s *AuthService) IssueWSTicket(userID, roomID uint) (*models.WSTicket, error) {
+	var count int64
+	err := models.DB.Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("您不在该房间中")
+	}
+
+	ticket := models.WSTicket{
+		Ticket:    uuid.New().String(),
+		UserID:    userID,
+		RoomID:    roomID,
+		ExpiresAt: time.Now().Add(s.wsTicketTTL),
+	}
+
+	if err := models.DB.Create(&ticket).Error; err != nil {
+		log.Printf("创建WebSocket票据失败: %v", err)
+		return nil, err
+	}
+
+	log.Printf("WebSocket票据签发成功: UserID=%d, RoomID=%d", userID, roomID)
+	return &ticket, nil
+}
+
+// ConsumeWSTicket 校验并作废WebSocket连接票据，返回票据所属用户
+// 票据无论校验成功与否都只能使用一次
+func (s *AuthService) ConsumeWSTicket(ticketValue string, roomID uint) (*models.User, error) {
+	var ticket models.WSTicket
+	err := models.DB.Where("ticket = ?", ticketValue).First(&ticket).Error
+	if err != nil {
+		return nil, errors.New("票据无效")
+	}
+
+	// 通过删除结果判断是否抢到了票据，避免并发重复使用
+	result := models.DB.Where("id = ?", ticket.ID).Delete(&models.WSTicket{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("票据无效")
+	}
+
+	if ticket.IsExpired() {
+		return nil, errors.New("票据已过期")
+	}
+	if ticket.RoomID != roomID {
+		return nil, errors.New("票据与房间不匹配")
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, ticket.UserID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	return &user, nil
+}
+
 // GetUserByID 根据ID获取用户信息
 func (s *AuthService) GetUserByID(userID uint) (*models.User, error) {
 	var user models.User
@@ -153,8 +367,27 @@ func (s *AuthService) UpdateNickname(userID uint, nickname string) error {
 	return nil
 }
 
-// UpdatePassword 修改密码
-func (s *AuthService) UpdatePassword(userID uint, oldPassword, newPassword string) error {
+// UpdatePreferences 修改统计战绩使用的时区与“一晚”分界时刻，timezone 为空表示使用服务器时区
+func (s *AuthService) UpdatePreferences(userID uint, timezone string, cutoffHour int) error {
+	if _, err := NewDayBoundary(timezone, cutoffHour); err != nil {
+		return err
+	}
+
+	err := models.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"timezone":        strings.TrimSpace(timezone),
+		"day_cutoff_hour": cutoffHour,
+	}).Error
+	if err != nil {
+		log.Printf("修改偏好设置失败: UserID=%d, %v", userID, err)
+		return err
+	}
+
+	log.Printf("偏好设置修改成功: UserID=%d, Timezone=%s, CutoffHour=%d", userID, timezone, cutoffHour)
+	return nil
+}
+
+// UpdatePassword 修改密码，成功后注销除当前Session以外的所有Session
+func (s *AuthService) UpdatePassword(userID uint, currentSessionID, oldPassword, newPassword string) error {
 	// 查询用户
 	var user models.User
 	err := models.DB.First(&user, userID).Error
@@ -182,6 +415,10 @@ func (s *AuthService) UpdatePassword(userID uint, oldPassword, newPassword strin
 	}
 
 	log.Printf("密码修改成功: UserID=%d", userID)
+
+	if _, err := s.revokeSessions(userID, currentSessionID); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -196,6 +433,15 @@ func (s *AuthService) CleanExpiredSessions() error {
 		log.Printf("清理过期Session: 删除%d条记录", result.RowsAffected)
 	}
 
+	result = models.DB.Where("expires_at < ?", time.Now()).Delete(&models.WSTicket{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("清理过期WebSocket票据: 删除%d条记录", result.RowsAffected)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_SlidingSessionExpiry(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Alice"})[0]
+
+	service := NewAuthService(24*time.Hour, time.Hour, time.Minute)
+	client := SessionClient{IP: "10.0.0.1", UserAgent: "test"}
+
+	session, err := service.CreateSession(user.ID, client)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Second)
+
+	// 闲置半小时后使用：顺延到一小时后，并记录新的IP
+	lastUsed := time.Now().Add(-30 * time.Minute)
+	require.NoError(t, models.DB.Model(session).Updates(map[string]interface{}{"last_used_at": lastUsed, "expires_at": lastUsed.Add(time.Hour)}).Error)
+
+	touched, authed, err := service.AuthenticateSession(session.SessionID, SessionClient{IP: "10.0.0.2"})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, authed.ID)
+	require.WithinDuration(t, time.Now().Add(time.Hour), touched.ExpiresAt, time.Second)
+
+	var stored models.Session
+	require.NoError(t, models.DB.First(&stored, session.ID).Error)
+	require.Equal(t, "10.0.0.2", stored.IP)
+	require.WithinDuration(t, time.Now(), stored.LastUsedAt, time.Second)
+
+	// 顺延不超过从登录起算的最长有效期
+	createdAt := time.Now().Add(-23*time.Hour - 30*time.Minute)
+	require.NoError(t, models.DB.Model(&stored).Updates(map[string]interface{}{"created_at": createdAt, "last_used_at": time.Now().Add(-2 * time.Minute)}).Error)
+	touched, _, err = service.AuthenticateSession(session.SessionID, client)
+	require.NoError(t, err)
+	require.WithinDuration(t, createdAt.Add(24*time.Hour), touched.ExpiresAt, time.Second)
+
+	// 闲置超过一小时：过期并删除
+	idle, err := service.CreateSession(user.ID, client)
+	require.NoError(t, err)
+	require.NoError(t, models.DB.Model(idle).Update("last_used_at", time.Now().Add(-61*time.Minute)).Error)
+
+	_, _, err = service.AuthenticateSession(idle.SessionID, client)
+	require.EqualError(t, err, "Session已过期，请重新登录")
+
+	var count int64
+	require.NoError(t, models.DB.Model(&models.Session{}).Where("id = ?", idle.ID).Count(&count).Error)
+	require.Zero(t, count)
+}
+
+func TestDescribeDevice(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "微信 · iPhone", describeDevice("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148 MicroMessenger/8.0.40"))
+	require.Equal(t, "Safari · Mac", describeDevice("Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Version/17.0 Safari/605.1.15"))
+	require.Equal(t, "Chrome · Android", describeDevice("Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"))
+	require.Equal(t, "未知设备", describeDevice(""))
+}
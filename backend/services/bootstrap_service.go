@@ -0,0 +1,193 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 早期版本在空数据库中自动创建的默认管理员（13800138000 / admin123）
+const (
+	legacyDefaultAdminPhone    = "13800138000"
+	legacyDefaultAdminPassword = "admin123"
+	legacyDefaultAdminHash     = "$2a$10$4wXVwkYAv50vhrkJs4acCO1VYMjvePZPySNDltOBHHGWr2AZ0nnIa"
+)
+
+// DefaultAdminNickname 初始化管理员未指定昵称时使用的昵称
+const DefaultAdminNickname = "系统管理员"
+
+// setupTokenBytes 一次性初始化令牌的随机字节数
+const setupTokenBytes = 24
+
+// ErrSetupCompleted 系统中已存在管理员，不再接受初始化请求
+var ErrSetupCompleted = errors.New("系统已完成初始化")
+
+// BootstrapService 首次运行时创建管理员账户
+// 管理员可以通过命令行、环境变量或启动日志中打印的一次性令牌创建，不再内置默认账户
+type BootstrapService struct {
+	mu         sync.Mutex
+	setupToken string // 仅保存在内存中，重启后重新生成
+}
+
+// NewBootstrapService 创建初始化服务
+func NewBootstrapService() *BootstrapService {
+	return &BootstrapService{}
+}
+
+// NeedsSetup 系统中是否还没有管理员
+func (s *BootstrapService) NeedsSetup() (bool, error) {
+	var count int64
+	if err := models.DB.Model(&models.User{}).Where("role = ?", "admin").Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// CreateAdmin 创建管理员账户；手机号已注册时将该用户提升为管理员并重置密码
+// 供命令行和环境变量使用，调用方已具备服务器权限，因此不检查是否已有管理员
+func (s *BootstrapService) CreateAdmin(phone, nickname, password string) (*models.User, error) {
+	if err := validateAdminCredentials(phone, password); err != nil {
+		return nil, err
+	}
+	if nickname == "" {
+		nickname = DefaultAdminNickname
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("密码加密失败: %v", err)
+		return nil, err
+	}
+
+	var user models.User
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		if err := tx.Where("phone = ?", phone).Limit(1).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			user = models.User{
+				Phone:        phone,
+				Nickname:     nickname,
+				PasswordHash: passwordHash,
+				Role:         "admin",
+			}
+			return tx.Create(&user).Error
+		}
+
+		user = users[0]
+		user.Role = "admin"
+		user.PasswordHash = passwordHash
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"role":          user.Role,
+			"password_hash": user.PasswordHash,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("创建管理员失败: Phone=%s, %v", phone, err)
+		return nil, err
+	}
+
+	log.Printf("管理员账户已就绪: ID=%d, Phone=%s", user.ID, user.Phone)
+	return &user, nil
+}
+
+// IssueSetupToken 生成一次性初始化令牌，持有令牌的访问者可以创建第一个管理员
+// 已存在管理员时返回 ErrSetupCompleted
+func (s *BootstrapService) IssueSetupToken() (string, error) {
+	needsSetup, err := s.NeedsSetup()
+	if err != nil {
+		return "", err
+	}
+	if !needsSetup {
+		return "", ErrSetupCompleted
+	}
+
+	buf := make([]byte, setupTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	s.setupToken = token
+	s.mu.Unlock()
+	return token, nil
+}
+
+// CompleteSetup 使用一次性令牌创建第一个管理员，成功后令牌作废
+func (s *BootstrapService) CompleteSetup(token, phone, nickname, password string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	needsSetup, err := s.NeedsSetup()
+	if err != nil {
+		return nil, err
+	}
+	if !needsSetup {
+		s.setupToken = ""
+		return nil, ErrSetupCompleted
+	}
+	if s.setupToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.setupToken)) != 1 {
+		return nil, errors.New("初始化令牌无效")
+	}
+
+	var count int64
+	if err := models.DB.Model(&models.User{}).Where("phone = ?", phone).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("手机号已注册")
+	}
+
+	user, err := s.CreateAdmin(phone, nickname, password)
+	if err != nil {
+		return nil, err
+	}
+	s.setupToken = ""
+	log.Printf("通过初始化令牌创建管理员: ID=%d", user.ID)
+	return user, nil
+}
+
+// DefaultCredentialUsers 查找仍在使用早期内置默认密码的账户
+func (s *BootstrapService) DefaultCredentialUsers() ([]models.User, error) {
+	var users []models.User
+	if err := models.DB.Where("password_hash = ? OR phone = ?", legacyDefaultAdminHash, legacyDefaultAdminPhone).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]models.User, 0, len(users))
+	for _, user := range users {
+		if user.PasswordHash == legacyDefaultAdminHash || utils.CheckPassword(legacyDefaultAdminPassword, user.PasswordHash) {
+			result = append(result, user)
+		}
+	}
+	return result, nil
+}
+
+// validateAdminCredentials 校验管理员手机号与密码，规则与注册接口一致
+func validateAdminCredentials(phone, password string) error {
+	if len(phone) != 11 {
+		return errors.New("手机号必须为11位")
+	}
+	for _, ch := range phone {
+		if ch < '0' || ch > '9' {
+			return errors.New("手机号必须为11位")
+		}
+	}
+	if len(password) < 6 {
+		return errors.New("密码至少6位")
+	}
+	if password == legacyDefaultAdminPassword {
+		return errors.New("不能使用旧版默认密码")
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrap_SetupTokenCreatesFirstAdminOnce(t *testing.T) {
+	setupSettlementTestDB(t)
+	service := NewBootstrapService()
+
+	needsSetup, err := service.NeedsSetup()
+	require.NoError(t, err)
+	require.True(t, needsSetup)
+
+	_, err = service.CompleteSetup("", "13900000001", "Admin", "secret1")
+	require.EqualError(t, err, "初始化令牌无效")
+
+	token, err := service.IssueSetupToken()
+	require.NoError(t, err)
+	require.Len(t, token, setupTokenBytes*2)
+
+	_, err = service.CompleteSetup("wrong", "13900000001", "Admin", "secret1")
+	require.EqualError(t, err, "初始化令牌无效")
+
+	_, err = service.CompleteSetup(token, "13900000001", "Admin", legacyDefaultAdminPassword)
+	require.EqualError(t, err, "不能使用旧版默认密码")
+
+	admin, err := service.CompleteSetup(token, "13900000001", "Admin", "secret1")
+	require.NoError(t, err)
+	require.Equal(t, "admin", admin.Role)
+	require.True(t, utils.CheckPassword("secret1", admin.PasswordHash))
+
+	needsSetup, err = service.NeedsSetup()
+	require.NoError(t, err)
+	require.False(t, needsSetup)
+
+	// 令牌只能使用一次，已有管理员后也不能再生成
+	_, err = service.CompleteSetup(token, "13900000002", "Other", "secret2")
+	require.ErrorIs(t, err, ErrSetupCompleted)
+	_, err = service.IssueSetupToken()
+	require.ErrorIs(t, err, ErrSetupCompleted)
+}
+
+func TestBootstrap_CreateAdminPromotesExistingUser(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Alice"})[0]
+	service := NewBootstrapService()
+
+	_, err := service.CreateAdmin("1390000000x", "", "secret1")
+	require.EqualError(t, err, "手机号必须为11位")
+
+	admin, err := service.CreateAdmin(user.Phone, "", "secret1")
+	require.NoError(t, err)
+	require.Equal(t, user.ID, admin.ID)
+
+	var stored models.User
+	require.NoError(t, models.DB.First(&stored, user.ID).Error)
+	require.Equal(t, "admin", stored.Role)
+	require.Equal(t, "Alice", stored.Nickname)
+	require.True(t, utils.CheckPassword("secret1", stored.PasswordHash))
+
+	created, err := service.CreateAdmin("13900000099", "", "secret2")
+	require.NoError(t, err)
+	require.Equal(t, DefaultAdminNickname, created.Nickname)
+}
+
+func TestBootstrap_DetectsLegacyDefaultPassword(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	service := NewBootstrapService()
+
+	found, err := service.DefaultCredentialUsers()
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	require.NoError(t, models.DB.Model(&users[1]).Update("password_hash", legacyDefaultAdminHash).Error)
+	legacy := models.User{Phone: legacyDefaultAdminPhone, Nickname: "系统管理员", PasswordHash: "hash", Role: "admin"}
+	require.NoError(t, models.DB.Create(&legacy).Error)
+
+	found, err = service.DefaultCredentialUsers()
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, users[1].ID, found[0].ID)
+
+	// 旧版默认管理员修改密码后不再告警
+	_, err = service.CreateAdmin(users[1].Phone, "", "secret1")
+	require.NoError(t, err)
+	found, err = service.DefaultCredentialUsers()
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	ws "poker_score_backend/websocket"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AllowedReactions 可用于回应操作记录的快捷表情
+var AllowedReactions = []string{"👍", "👎", "😂", "😮", "😭", "🔥", "💰", "🎉"}
+
+// ChatService 房间聊天服务
+type ChatService struct {
+	roomService *RoomService
+	rateLimit   int
+	rateWindow  time.Duration
+	maxLength   int
+
+	mu        sync.Mutex
+	recent    map[chatRateKey][]time.Time
+	lastSweep time.Time
+}
+
+type chatRateKey struct {
+	RoomID uint
+	UserID uint
+}
+
+// chatMessagePayload 通过WebSocket发送的文本消息
+type chatMessagePayload struct {
+	Content string `json:"content"`
+}
+
+// chatReactionPayload 通过WebSocket发送的表情回应
+type chatReactionPayload struct {
+	OperationID uint   `json:"operation_id"`
+	Emoji       string `json:"emoji"`
+}
+
+// NewChatService 创建房间聊天服务
+func NewChatService(roomService *RoomService, rateLimit int, rateWindow time.Duration, maxLength int) *ChatService {
+	return &ChatService{
+		roomService: roomService,
+		rateLimit:   rateLimit,
+		rateWindow:  rateWindow,
+		maxLength:   maxLength,
+		recent:      make(map[chatRateKey][]time.Time),
+	}
+}
+
+// HandleClientMessage 处理客户端通过WebSocket发送的聊天消息，实现 ws.MessageHandler
+func (s *ChatService) HandleClientMessage(roomID, userID uint, msgType string, data json.RawMessage) error {
+	switch msgType {
+	case ws.ClientMessageChat:
+		var payload chatMessagePayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return errors.New("消息格式错误")
+		}
+		_, err := s.SendMessage(roomID, userID, payload.Content)
+		return err
+	case ws.ClientMessageReaction:
+		var payload chatReactionPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return errors.New("消息格式错误")
+		}
+		_, err := s.React(roomID, userID, payload.OperationID, payload.Emoji)
+		return err
+	default:
+		return errors.New("不支持的消息类型")
+	}
+}
+
+// SendMessage 发送文本消息
+func (s *ChatService) SendMessage(roomID, userID uint, content string) (map[string]interface{}, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errors.New("消息内容不能为空")
+	}
+	if utf8.RuneCountInString(content) > s.maxLength {
+		return nil, errors.New("消息内容过长")
+	}
+
+	if err := s.checkMember(roomID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.allow(roomID, userID); err != nil {
+		return nil, err
+	}
+
+	message := models.ChatMessage{
+		RoomID:      roomID,
+		UserID:      userID,
+		MessageType: models.ChatTypeText,
+		Content:     content,
+	}
+	if err := models.DB.Create(&message).Error; err != nil {
+		log.Printf("保存聊天消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
+		return nil, err
+	}
+
+	log.Printf("聊天消息发送成功: RoomID=%d, UserID=%d, MessageID=%d", roomID, userID, message.ID)
+
+	return s.publishMessage(message), nil
+}
+
+// React 对房间内的某条操作记录发送表情回应
+func (s *ChatService) React(roomID, userID, operationID uint, emoji string) (map[string]interface{}, error) {
+	if !isAllowedReaction(emoji) {
+		return nil, errors.New("不支持的表情")
+	}
+
+	if err := s.checkMember(roomID, userID); err != nil {
+		return nil, err
+	}
+
+	var operation models.RoomOperation
+	if err := models.DB.Where("id = ? AND room_id = ?", operationID, roomID).First(&operation).Error; err != nil {
+		return nil, errors.New("操作记录不存在")
+	}
+
+	var count int64
+	err := models.DB.Model(&models.ChatMessage{}).
+		Where("room_id = ? AND user_id = ? AND message_type = ? AND operation_id = ? AND content = ?",
+			roomID, userID, models.ChatTypeReaction, operationID, emoji).
+		Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("已经发送过该表情")
+	}
+
+	if err := s.allow(roomID, userID); err != nil {
+		return nil, err
+	}
+
+	opID := operationID
+	message := models.ChatMessage{
+		RoomID:      roomID,
+		UserID:      userID,
+		MessageType: models.ChatTypeReaction,
+		Content:     emoji,
+		OperationID: &opID,
+	}
+	// 并发发送时由唯一索引保证同一表情只保存一次
+	res := models.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&message)
+	if res.Error != nil {
+		log.Printf("保存表情回应失败: RoomID=%d, UserID=%d, %v", roomID, userID, res.Error)
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New("已经发送过该表情")
+	}
+
+	log.Printf("表情回应发送成功: RoomID=%d, UserID=%d, OperationID=%d", roomID, userID, operationID)
+
+	return s.publishMessage(message), nil
+}
+
+// DeleteMessage 删除聊天消息，房主可以删除任意消息，其他成员只能删除自己的消息
+func (s *ChatService) DeleteMessage(roomID, messageID, userID uint) error {
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return errors.New("房间不存在")
+	}
+
+	var message models.ChatMessage
+	if err := models.DB.Where("id = ? AND room_id = ?", messageID, roomID).First(&message).Error; err != nil {
+		return errors.New("消息不存在")
+	}
+
+	if room.CreatedBy != userID && message.UserID != userID {
+		return errors.New("只有房主可以删除他人的消息")
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&message).Update("deleted_by", userID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&message).Error
+	})
+	if err != nil {
+		log.Printf("删除聊天消息失败: RoomID=%d, MessageID=%d, %v", roomID, messageID, err)
+		return err
+	}
+
+	log.Printf("聊天消息已删除: RoomID=%d, MessageID=%d, DeletedBy=%d", roomID, messageID, userID)
+
+	s.broadcastMessageDeleted(roomID, messageID, userID)
+	return nil
+}
+
+// GetMessages 分页获取聊天消息（按时间倒序）
+// beforeID 为上一页最早一条消息的ID，0表示从最新消息开始
+func (s *ChatService) GetMessages(roomID, userID, beforeID uint, limit int) ([]map[string]interface{}, bool, error) {
+	var member models.RoomMember
+	if err := models.DB.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
+		return nil, false, errors.New("您不在该房间中")
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	query := models.DB.Where("room_id = ?", roomID)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var messages []models.ChatMessage
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	nicknames := make(map[uint]string)
+	result := make([]map[string]interface{}, 0, len(messages))
+	for _, message := range messages {
+		nickname, ok := nicknames[message.UserID]
+		if !ok {
+			var user models.User
+			if err := models.DB.First(&user, message.UserID).Error; err == nil {
+				nickname = user.Nickname
+			}
+			nicknames[message.UserID] = nickname
+		}
+		result = append(result, chatMessageView(message, nickname))
+	}
+
+	return result, hasMore, nil
+}
+
+// checkMember 只有仍在房间中的成员才能在进行中的房间发言
+func (s *ChatService) checkMember(roomID, userID uint) error {
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return errors.New("房间不存在")
+	}
+	if room.Status != "active" {
+		return errors.New("房间已解散")
+	}
+
+	var member models.RoomMember
+	if err := models.DB.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
+		return errors.New("您不在该房间中")
+	}
+	if member.Status != "online" {
+		return errors.New("您已离开该房间")
+	}
+
+	return nil
+}
+
+// allow 按滑动窗口限制发送频率
+func (s *ChatService) allow(roomID, userID uint) error {
+	key := chatRateKey{RoomID: roomID, UserID: userID}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	kept := s.recent[key][:0]
+	for _, sentAt := range s.recent[key] {
+		if now.Sub(sentAt) < s.rateWindow {
+			kept = append(kept, sentAt)
+		}
+	}
+
+	if len(kept) >= s.rateLimit {
+		s.recent[key] = kept
+		return errors.New("发送过于频繁，请稍后再试")
+	}
+
+	s.recent[key] = append(kept, now)
+	return nil
+}
+
+// sweep 每个窗口期删除一次窗口内没有发送记录的用户，避免长时间运行后占用过多内存
+func (s *ChatService) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.rateWindow {
+		return
+	}
+	s.lastSweep = now
+
+	for key, sent := range s.recent {
+		if len(sent) == 0 || now.Sub(sent[len(sent)-1]) >= s.rateWindow {
+			delete(s.recent, key)
+		}
+	}
+}
+
+func (s *ChatService) publishMessage(message models.ChatMessage) map[string]interface{} {
+	var user models.User
+	models.DB.First(&user, message.UserID)
+
+	view := chatMessageView(message, user.Nickname)
+
+	if s.roomService.hub != nil {
+		err := s.roomService.publishEvent(message.RoomID, ws.ChatMessageEvent{
+			MessageID:   message.ID,
+			UserID:      message.UserID,
+			Nickname:    user.Nickname,
+			MessageType: message.MessageType,
+			Content:     message.Content,
+			OperationID: message.OperationID,
+			CreatedAt:   message.CreatedAt,
+		})
+		if err != nil {
+			log.Printf("广播聊天消息失败: RoomID=%d, MessageID=%d, %v", message.RoomID, message.ID, err)
+		}
+	}
+
+	return view
+}
+
+func (s *ChatService) broadcastMessageDeleted(roomID, messageID, deletedBy uint) {
+	if s.roomService.hub == nil {
+		return
+	}
+
+	var user models.User
+	models.DB.First(&user, deletedBy)
+
+	err := s.roomService.publishEvent(roomID, ws.ChatMessageDeletedEvent{
+		MessageID:         messageID,
+		DeletedBy:         deletedBy,
+		DeletedByNickname: user.Nickname,
+		DeletedAt:         time.Now(),
+	})
+	if err != nil {
+		log.Printf("广播删除聊天消息失败: RoomID=%d, MessageID=%d, %v", roomID, messageID, err)
+	}
+}
+
+func chatMessageView(message models.ChatMessage, nickname string) map[string]interface{} {
+	view := map[string]interface{}{
+		"id":           message.ID,
+		"user_id":      message.UserID,
+		"nickname":     nickname,
+		"message_type": message.MessageType,
+		"content":      message.Content,
+		"created_at":   message.CreatedAt,
+	}
+	if message.OperationID != nil {
+		view["operation_id"] = *message.OperationID
+	}
+	return view
+}
+
+func isAllowedReaction(emoji string) bool {
+	for _, allowed := range AllowedReactions {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
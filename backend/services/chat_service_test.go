@@ -0,0 +1,170 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatService_RateLimitAndModeration(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol"})
+	alice, bob, carol := users[0].ID, users[1].ID, users[2].ID
+
+	roomService := NewRoomService(nil, nil)
+	chatService := NewChatService(roomService, 2, time.Minute, 10)
+
+	room, err := roomService.CreateRoom(alice, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+
+	_, err = chatService.SendMessage(room.ID, carol, "你好")
+	require.EqualError(t, err, "您不在该房间中")
+	_, err = chatService.SendMessage(room.ID, bob, "   ")
+	require.EqualError(t, err, "消息内容不能为空")
+	_, err = chatService.SendMessage(room.ID, bob, "这条消息超过了十个字符的长度")
+	require.EqualError(t, err, "消息内容过长")
+
+	first, err := chatService.SendMessage(room.ID, bob, "第一条")
+	require.NoError(t, err)
+	_, err = chatService.SendMessage(room.ID, bob, "第二条")
+	require.NoError(t, err)
+	_, err = chatService.SendMessage(room.ID, bob, "第三条")
+	require.EqualError(t, err, "发送过于频繁，请稍后再试")
+
+	// 限流按用户统计，不影响其他成员
+	_, err = chatService.SendMessage(room.ID, alice, "房主发言")
+	require.NoError(t, err)
+
+	// 普通成员不能删除他人消息，房主可以
+	firstID := first["id"].(uint)
+	require.EqualError(t, chatService.DeleteMessage(room.ID, firstID+2, bob), "只有房主可以删除他人的消息")
+	require.NoError(t, chatService.DeleteMessage(room.ID, firstID, alice))
+
+	var deleted models.ChatMessage
+	require.NoError(t, models.DB.Unscoped().First(&deleted, firstID).Error)
+	require.NotNil(t, deleted.DeletedBy)
+	require.Equal(t, alice, *deleted.DeletedBy)
+
+	messages, hasMore, err := chatService.GetMessages(room.ID, bob, 0, 1)
+	require.NoError(t, err)
+	require.True(t, hasMore)
+	require.Len(t, messages, 1)
+	require.Equal(t, "房主发言", messages[0]["content"])
+
+	messages, hasMore, err = chatService.GetMessages(room.ID, bob, messages[0]["id"].(uint), 10)
+	require.NoError(t, err)
+	require.False(t, hasMore)
+	require.Len(t, messages, 1, "已删除的消息不应返回")
+	require.Equal(t, "第二条", messages[0]["content"])
+}
+
+func TestChatService_ReactToOperation(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	roomService := NewRoomService(nil, nil)
+	operationService := NewOperationService(roomService)
+	chatService := NewChatService(roomService, 10, time.Minute, 500)
+
+	room, err := roomService.CreateRoom(alice, "texas", "20:1")
+	require.NoError(t, err)
+	otherRoom, err := roomService.CreateRoom(bob, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+
+	_, _, err = operationService.Bet(room.ID, alice, 500)
+	require.NoError(t, err)
+
+	var bet models.RoomOperation
+	require.NoError(t, models.DB.Where("room_id = ? AND operation_type = ?", room.ID, models.OpTypeBet).First(&bet).Error)
+
+	reaction, err := chatService.React(room.ID, bob, bet.ID, "🔥")
+	require.NoError(t, err)
+	require.Equal(t, models.ChatTypeReaction, reaction["message_type"])
+	require.Equal(t, bet.ID, reaction["operation_id"])
+
+	_, err = chatService.React(room.ID, bob, bet.ID, "🔥")
+	require.EqualError(t, err, "已经发送过该表情")
+	_, err = chatService.React(room.ID, bob, bet.ID, "abc")
+	require.EqualError(t, err, "不支持的表情")
+	_, err = chatService.React(otherRoom.ID, bob, bet.ID, "👍")
+	require.EqualError(t, err, "操作记录不存在")
+}
+
+func TestChatService_ConcurrentReactionsStoredOnce(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	roomService := NewRoomService(nil, nil)
+	chatService := NewChatService(roomService, 20, time.Minute, 500)
+
+	room, err := roomService.CreateRoom(alice, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+	_, _, err = NewOperationService(roomService).Bet(room.ID, alice, 500)
+	require.NoError(t, err)
+
+	var bet models.RoomOperation
+	require.NoError(t, models.DB.Where("room_id = ? AND operation_type = ?", room.ID, models.OpTypeBet).First(&bet).Error)
+
+	const attempts = 8
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = chatService.React(room.ID, bob, bet.ID, "🎉")
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.EqualError(t, err, "已经发送过该表情")
+	}
+	require.Equal(t, 1, succeeded)
+
+	var stored []models.ChatMessage
+	require.NoError(t, models.DB.Where("room_id = ? AND message_type = ?", room.ID, models.ChatTypeReaction).Find(&stored).Error)
+	require.Len(t, stored, 1)
+
+	// 唯一索引同样拦截绕过检查的直接写入
+	opID := bet.ID
+	duplicate := models.ChatMessage{RoomID: room.ID, UserID: bob, MessageType: models.ChatTypeReaction, Content: "🎉", OperationID: &opID}
+	require.Error(t, models.DB.Create(&duplicate).Error)
+
+	// 撤回后可以重新发送
+	require.NoError(t, chatService.DeleteMessage(room.ID, stored[0].ID, bob))
+	_, err = chatService.React(room.ID, bob, bet.ID, "🎉")
+	require.NoError(t, err)
+}
+
+func TestChatService_SweepsIdleRateLimitEntries(t *testing.T) {
+	chatService := NewChatService(nil, 2, 20*time.Millisecond, 10)
+
+	for userID := uint(1); userID <= 3; userID++ {
+		require.NoError(t, chatService.allow(1, userID))
+	}
+	require.Len(t, chatService.recent, 3)
+
+	// 窗口期过后，下一次发送时清理不再发言的用户
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, chatService.allow(1, 4))
+	require.Len(t, chatService.recent, 1)
+	require.Contains(t, chatService.recent, chatRateKey{RoomID: 1, UserID: 4})
+}
@@ -0,0 +1,726 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ClubService 俱乐部服务
+type ClubService struct{}
+
+// NewClubService 创建俱乐部服务
+func NewClubService() *ClubService {
+	return &ClubService{}
+}
+
+// clubRoomResult 用户在某个俱乐部房间内的结算汇总
+type clubRoomResult struct {
+	RoomID        uint
+	RoomType      string
+	UserID        uint
+	ChipAmount    int
+	RmbAmount     float64
+	LastSettledAt string
+}
+
+// CreateClub 创建俱乐部，创建者自动成为俱乐部的 owner
+func (s *ClubService) CreateClub(userID uint, name, description string) (*models.Club, error) {
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	if name == "" {
+		return nil, errors.New("俱乐部名称不能为空")
+	}
+	if utf8.RuneCountInString(name) > 50 {
+		return nil, errors.New("俱乐部名称不能超过50个字符")
+	}
+	if utf8.RuneCountInString(description) > 200 {
+		return nil, errors.New("俱乐部简介不能超过200个字符")
+	}
+
+	inviteCode, err := s.generateInviteCode(models.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	club := models.Club{
+		Name:        name,
+		Description: description,
+		InviteCode:  inviteCode,
+		CreatedBy:   userID,
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&club).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ClubMember{
+			ClubID:   club.ID,
+			UserID:   userID,
+			Role:     models.ClubRoleOwner,
+			JoinedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		log.Printf("创建俱乐部失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("俱乐部创建成功: ClubID=%d, Name=%s, CreatedBy=%d", club.ID, club.Name, userID)
+	return &club, nil
+}
+
+// JoinClub 通过邀请码加入俱乐部，已是成员时直接返回
+func (s *ClubService) JoinClub(userID uint, inviteCode string) (*models.Club, error) {
+	var club models.Club
+	err := models.DB.Where("invite_code = ?", strings.ToUpper(strings.TrimSpace(inviteCode))).First(&club).Error
+	if err != nil {
+		return nil, errors.New("邀请码无效")
+	}
+
+	if isClubMember(club.ID, userID) {
+		return &club, nil
+	}
+
+	member := models.ClubMember{
+		ClubID:   club.ID,
+		UserID:   userID,
+		Role:     models.ClubRoleMember,
+		JoinedAt: time.Now(),
+	}
+	if err := models.DB.Create(&member).Error; err != nil {
+		log.Printf("加入俱乐部失败: ClubID=%d, UserID=%d, %v", club.ID, userID, err)
+		return nil, err
+	}
+
+	log.Printf("用户加入俱乐部: ClubID=%d, UserID=%d", club.ID, userID)
+	return &club, nil
+}
+
+// GetMyClubs 获取用户加入的所有俱乐部
+func (s *ClubService) GetMyClubs(userID uint) ([]map[string]interface{}, error) {
+	var memberships []models.ClubMember
+	if err := models.DB.Where("user_id = ?", userID).Order("joined_at DESC").Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(memberships))
+	for _, membership := range memberships {
+		var club models.Club
+		if err := models.DB.First(&club, membership.ClubID).Error; err != nil {
+			continue
+		}
+
+		var memberCount int64
+		models.DB.Model(&models.ClubMember{}).Where("club_id = ?", club.ID).Count(&memberCount)
+
+		result = append(result, map[string]interface{}{
+			"club_id":      club.ID,
+			"name":         club.Name,
+			"description":  club.Description,
+			"my_role":      membership.Role,
+			"member_count": memberCount,
+			"joined_at":    membership.JoinedAt,
+			"created_at":   club.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// GetClubDetails 获取俱乐部详情与成员列表，邀请码仅对俱乐部管理员可见
+func (s *ClubService) GetClubDetails(clubID, userID uint) (map[string]interface{}, error) {
+	club, membership, err := s.loadClubForMember(clubID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []models.ClubMember
+	if err := models.DB.Where("club_id = ?", clubID).Order("joined_at ASC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	nicknames, err := loadNicknames(clubMemberIDs(members))
+	if err != nil {
+		return nil, err
+	}
+
+	memberList := make([]map[string]interface{}, 0, len(members))
+	for _, member := range members {
+		memberList = append(memberList, map[string]interface{}{
+			"user_id":   member.UserID,
+			"nickname":  nicknames[member.UserID],
+			"role":      member.Role,
+			"joined_at": member.JoinedAt,
+		})
+	}
+
+	result := map[string]interface{}{
+		"club_id":         club.ID,
+		"name":            club.Name,
+		"description":     club.Description,
+		"created_by":      club.CreatedBy,
+		"created_at":      club.CreatedAt,
+		"timezone":        club.Timezone,
+		"day_cutoff_hour": club.DayCutoffHour,
+		"my_role":         membership.Role,
+		"members":         memberList,
+	}
+	if membership.IsAdmin() {
+		result["invite_code"] = club.InviteCode
+	}
+
+	return result, nil
+}
+
+// LeaveClub 退出俱乐部，创建者不能退出
+func (s *ClubService) LeaveClub(clubID, userID uint) error {
+	_, membership, err := s.loadClubForMember(clubID, userID)
+	if err != nil {
+		return err
+	}
+	if membership.Role == models.ClubRoleOwner {
+		return errors.New("创建者不能退出俱乐部")
+	}
+
+	if err := models.DB.Delete(membership).Error; err != nil {
+		return err
+	}
+
+	log.Printf("用户退出俱乐部: ClubID=%d, UserID=%d", clubID, userID)
+	return nil
+}
+
+// RemoveMember 移除俱乐部成员；管理员可以移除普通成员，只有创建者可以移除管理员
+func (s *ClubService) RemoveMember(clubID, operatorID, targetUserID uint) error {
+	_, operator, err := s.loadClubForMember(clubID, operatorID)
+	if err != nil {
+		return err
+	}
+	if !operator.IsAdmin() {
+		return errors.New("只有俱乐部管理员可以移除成员")
+	}
+	if operatorID == targetUserID {
+		return errors.New("不能移除自己，请使用退出俱乐部")
+	}
+
+	target, err := getClubMember(clubID, targetUserID)
+	if err != nil {
+		return errors.New("该用户不是俱乐部成员")
+	}
+	if target.Role == models.ClubRoleOwner {
+		return errors.New("不能移除俱乐部创建者")
+	}
+	if target.Role == models.ClubRoleAdmin && operator.Role != models.ClubRoleOwner {
+		return errors.New("只有创建者可以移除管理员")
+	}
+
+	if err := models.DB.Delete(target).Error; err != nil {
+		return err
+	}
+
+	log.Printf("俱乐部成员被移除: ClubID=%d, UserID=%d, RemovedBy=%d", clubID, targetUserID, operatorID)
+	return nil
+}
+
+// SetMemberRole 设置成员的俱乐部角色，只有创建者可以任免管理员
+func (s *ClubService) SetMemberRole(clubID, operatorID, targetUserID uint, role string) error {
+	if role != models.ClubRoleAdmin && role != models.ClubRoleMember {
+		return errors.New("角色只能是admin或member")
+	}
+
+	_, operator, err := s.loadClubForMember(clubID, operatorID)
+	if err != nil {
+		return err
+	}
+	if operator.Role != models.ClubRoleOwner {
+		return errors.New("只有俱乐部创建者可以设置管理员")
+	}
+
+	target, err := getClubMember(clubID, targetUserID)
+	if err != nil {
+		return errors.New("该用户不是俱乐部成员")
+	}
+	if target.Role == models.ClubRoleOwner {
+		return errors.New("不能修改创建者的角色")
+	}
+
+	if err := models.DB.Model(target).Update("role", role).Error; err != nil {
+		return err
+	}
+
+	log.Printf("俱乐部成员角色变更: ClubID=%d, UserID=%d, Role=%s", clubID, targetUserID, role)
+	return nil
+}
+
+// RegenerateInviteCode 重新生成邀请码，旧邀请码立即失效
+func (s *ClubService) RegenerateInviteCode(clubID, operatorID uint) (string, error) {
+	club, operator, err := s.loadClubForMember(clubID, operatorID)
+	if err != nil {
+		return "", err
+	}
+	if !operator.IsAdmin() {
+		return "", errors.New("只有俱乐部管理员可以重置邀请码")
+	}
+
+	inviteCode, err := s.generateInviteCode(models.DB)
+	if err != nil {
+		return "", err
+	}
+	if err := models.DB.Model(club).Update("invite_code", inviteCode).Error; err != nil {
+		return "", err
+	}
+
+	return inviteCode, nil
+}
+
+// UpdateSettings 修改俱乐部统计使用的时区与“一晚”分界，只有俱乐部管理员可以修改
+func (s *ClubService) UpdateSettings(clubID, operatorID uint, timezone string, cutoffHour int) error {
+	if _, err := NewDayBoundary(timezone, cutoffHour); err != nil {
+		return err
+	}
+
+	club, operator, err := s.loadClubForMember(clubID, operatorID)
+	if err != nil {
+		return err
+	}
+	if !operator.IsAdmin() {
+		return errors.New("只有俱乐部管理员可以修改设置")
+	}
+
+	err = models.DB.Model(club).Updates(map[string]interface{}{
+		"timezone":        strings.TrimSpace(timezone),
+		"day_cutoff_hour": cutoffHour,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	log.Printf("俱乐部设置已修改: ClubID=%d, Timezone=%s, CutoffHour=%d", clubID, timezone, cutoffHour)
+	return nil
+}
+
+// GetDayBoundary 获取俱乐部统计使用的时区与“一晚”分界
+func (s *ClubService) GetDayBoundary(clubID uint) DayBoundary {
+	return clubDayBoundary(clubID)
+}
+
+// GetClubRooms 获取俱乐部下的房间列表（按创建时间倒序）
+func (s *ClubService) GetClubRooms(clubID, userID uint) ([]map[string]interface{}, error) {
+	if _, _, err := s.loadClubForMember(clubID, userID); err != nil {
+		return nil, err
+	}
+
+	var rooms []models.Room
+	if err := models.DB.Where("club_id = ?", clubID).Order("created_at DESC").Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(rooms))
+	for _, room := range rooms {
+		var memberCount int64
+		models.DB.Model(&models.RoomMember{}).Where("room_id = ?", room.ID).Count(&memberCount)
+
+		result = append(result, map[string]interface{}{
+			"room_id":      room.ID,
+			"room_code":    room.RoomCode,
+			"room_type":    room.RoomType,
+			"chip_rate":    room.ChipRate,
+			"status":       room.Status,
+			"created_by":   room.CreatedBy,
+			"created_at":   room.CreatedAt,
+			"dissolved_at": room.DissolvedAt,
+			"member_count": memberCount,
+		})
+	}
+
+	return result, nil
+}
+
+// GetLeaderboard 俱乐部排行榜：汇总俱乐部房间内的结算记录，按人民币净盈亏排序
+// 当前成员即使没有战绩也会出现在榜单中；已退出的成员保留历史战绩
+func (s *ClubService) GetLeaderboard(clubID, userID uint, filter StatsFilter) ([]map[string]interface{}, error) {
+	if _, _, err := s.loadClubForMember(clubID, userID); err != nil {
+		return nil, err
+	}
+
+	results, err := s.loadClubRoomResults(clubID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type leaderboardEntry struct {
+		UserID   uint
+		IsMember bool
+		Sessions int
+		Wins     int
+		NetChip  int
+		NetRmb   float64
+	}
+
+	entries := make(map[uint]*leaderboardEntry)
+	ensure := func(id uint) *leaderboardEntry {
+		entry, ok := entries[id]
+		if !ok {
+			entry = &leaderboardEntry{UserID: id}
+			entries[id] = entry
+		}
+		return entry
+	}
+
+	var members []models.ClubMember
+	if err := models.DB.Where("club_id = ?", clubID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		ensure(member.UserID).IsMember = true
+	}
+
+	for _, result := range results {
+		entry := ensure(result.UserID)
+		entry.Sessions++
+		entry.NetChip += result.ChipAmount
+		entry.NetRmb += result.RmbAmount
+		if result.ChipAmount > 0 {
+			entry.Wins++
+		}
+	}
+
+	sorted := make([]*leaderboardEntry, 0, len(entries))
+	userIDs := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+		userIDs = append(userIDs, entry.UserID)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if roundTo(a.NetRmb, 2) != roundTo(b.NetRmb, 2) {
+			return a.NetRmb > b.NetRmb
+		}
+		if a.NetChip != b.NetChip {
+			return a.NetChip > b.NetChip
+		}
+		return a.UserID < b.UserID
+	})
+
+	nicknames, err := loadNicknames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboard := make([]map[string]interface{}, 0, len(sorted))
+	for i, entry := range sorted {
+		winRate := 0.0
+		if entry.Sessions > 0 {
+			winRate = roundTo(float64(entry.Wins)/float64(entry.Sessions), 4)
+		}
+		leaderboard = append(leaderboard, map[string]interface{}{
+			"rank":      i + 1,
+			"user_id":   entry.UserID,
+			"nickname":  nicknames[entry.UserID],
+			"is_member": entry.IsMember,
+			"sessions":  entry.Sessions,
+			"wins":      entry.Wins,
+			"win_rate":  winRate,
+			"net_chip":  entry.NetChip,
+			"net_rmb":   roundTo(entry.NetRmb, 2),
+		})
+	}
+
+	return leaderboard, nil
+}
+
+// GetLedger 俱乐部账本：按晚（俱乐部的时区与分界时刻）汇总俱乐部房间的结算结果并累计到每位成员，
+// 再用成员间登记的还款冲抵，得到每人当前的未结清余额（正数表示应收，负数表示应付）
+func (s *ClubService) GetLedger(clubID, userID uint) (map[string]interface{}, error) {
+	if _, _, err := s.loadClubForMember(clubID, userID); err != nil {
+		return nil, err
+	}
+
+	results, err := s.loadClubRoomResults(clubID, StatsFilter{})
+	if err != nil {
+		return nil, err
+	}
+	boundary := clubDayBoundary(clubID)
+
+	var payments []models.ClubPayment
+	if err := models.DB.Where("club_id = ?", clubID).Order("created_at ASC, id ASC").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	type balanceEntry struct {
+		SettledRmb  float64
+		PaidRmb     float64
+		ReceivedRmb float64
+	}
+
+	balances := make(map[uint]*balanceEntry)
+	ensure := func(id uint) *balanceEntry {
+		entry, ok := balances[id]
+		if !ok {
+			entry = &balanceEntry{}
+			balances[id] = entry
+		}
+		return entry
+	}
+
+	// 按晚分组
+	type nightSummary struct {
+		Date    string
+		Rooms   map[uint]bool
+		Amounts map[uint]float64
+	}
+	nights := make([]*nightSummary, 0)
+	nightIndex := make(map[string]*nightSummary)
+	for _, result := range results {
+		settledAt, _ := parseDBTime(result.LastSettledAt)
+		date := boundary.NightDate(settledAt)
+		night, ok := nightIndex[date]
+		if !ok {
+			night = &nightSummary{Date: date, Rooms: make(map[uint]bool), Amounts: make(map[uint]float64)}
+			nightIndex[date] = night
+			nights = append(nights, night)
+		}
+		night.Rooms[result.RoomID] = true
+		night.Amounts[result.UserID] += result.RmbAmount
+	}
+	sort.Slice(nights, func(i, j int) bool { return nights[i].Date < nights[j].Date })
+
+	for _, payment := range payments {
+		ensure(payment.FromUserID).PaidRmb += payment.RmbAmount
+		ensure(payment.ToUserID).ReceivedRmb += payment.RmbAmount
+	}
+
+	userIDs := make([]uint, 0)
+	for _, night := range nights {
+		for id := range night.Amounts {
+			ensure(id)
+		}
+	}
+	for id := range balances {
+		userIDs = append(userIDs, id)
+	}
+	nicknames, err := loadNicknames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	running := make(map[uint]float64)
+	nightList := make([]map[string]interface{}, 0, len(nights))
+	for _, night := range nights {
+		ids := make([]uint, 0, len(night.Amounts))
+		for id := range night.Amounts {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		entries := make([]map[string]interface{}, 0, len(ids))
+		for _, id := range ids {
+			amount := night.Amounts[id]
+			running[id] += amount
+			ensure(id).SettledRmb += amount
+			entries = append(entries, map[string]interface{}{
+				"user_id":        id,
+				"nickname":       nicknames[id],
+				"rmb_amount":     roundTo(amount, 2),
+				"cumulative_rmb": roundTo(running[id], 2),
+			})
+		}
+
+		nightList = append(nightList, map[string]interface{}{
+			"date":       night.Date,
+			"room_count": len(night.Rooms),
+			"entries":    entries,
+		})
+	}
+
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	balanceList := make([]map[string]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		entry := balances[id]
+		balanceList = append(balanceList, map[string]interface{}{
+			"user_id":      id,
+			"nickname":     nicknames[id],
+			"settled_rmb":  roundTo(entry.SettledRmb, 2),
+			"paid_rmb":     roundTo(entry.PaidRmb, 2),
+			"received_rmb": roundTo(entry.ReceivedRmb, 2),
+			"balance":      roundTo(entry.SettledRmb+entry.PaidRmb-entry.ReceivedRmb, 2),
+		})
+	}
+
+	paymentList := make([]map[string]interface{}, 0, len(payments))
+	for _, payment := range payments {
+		paymentList = append(paymentList, clubPaymentView(payment, nicknames))
+	}
+
+	return map[string]interface{}{
+		"balances": balanceList,
+		"nights":   nightList,
+		"payments": paymentList,
+	}, nil
+}
+
+// RecordPayment 登记成员之间的线下还款，俱乐部管理员或收款人本人可以登记
+func (s *ClubService) RecordPayment(clubID, operatorID, fromUserID, toUserID uint, amount float64, note string) (map[string]interface{}, error) {
+	if amount <= 0 {
+		return nil, errors.New("还款金额必须大于0")
+	}
+	if fromUserID == toUserID {
+		return nil, errors.New("付款人和收款人不能相同")
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > 200 {
+		return nil, errors.New("备注不能超过200个字符")
+	}
+
+	_, operator, err := s.loadClubForMember(clubID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !operator.IsAdmin() && operatorID != toUserID {
+		return nil, errors.New("只有俱乐部管理员或收款人可以登记还款")
+	}
+	if !isClubMember(clubID, fromUserID) || !isClubMember(clubID, toUserID) {
+		return nil, errors.New("付款人和收款人必须是俱乐部成员")
+	}
+
+	payment := models.ClubPayment{
+		ClubID:     clubID,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		RmbAmount:  roundTo(amount, 2),
+		Note:       note,
+		RecordedBy: operatorID,
+	}
+	if err := models.DB.Create(&payment).Error; err != nil {
+		log.Printf("登记俱乐部还款失败: ClubID=%d, %v", clubID, err)
+		return nil, err
+	}
+
+	log.Printf("登记俱乐部还款: ClubID=%d, From=%d, To=%d, Amount=%.2f", clubID, fromUserID, toUserID, payment.RmbAmount)
+
+	nicknames, err := loadNicknames([]uint{fromUserID, toUserID})
+	if err != nil {
+		return nil, err
+	}
+	return clubPaymentView(payment, nicknames), nil
+}
+
+// loadClubRoomResults 按房间、用户汇总俱乐部房间的结算记录
+func (s *ClubService) loadClubRoomResults(clubID uint, filter StatsFilter) ([]clubRoomResult, error) {
+	query := models.DB.Table("settlements").
+		Select("settlements.room_id AS room_id, rooms.room_type AS room_type, settlements.user_id AS user_id, "+
+			"SUM(settlements.chip_amount) AS chip_amount, SUM(settlements.rmb_amount) AS rmb_amount, "+
+			"MAX(settlements.settled_at) AS last_settled_at").
+		Joins("JOIN rooms ON rooms.id = settlements.room_id").
+		Where("rooms.club_id = ?", clubID)
+
+	if filter.Start != nil {
+		query = query.Where("settlements.settled_at >= ?", storageTime(*filter.Start))
+	}
+	if filter.End != nil {
+		query = query.Where("settlements.settled_at <= ?", storageTime(*filter.End))
+	}
+	if filter.RoomType != "" {
+		query = query.Where("rooms.room_type = ?", filter.RoomType)
+	}
+
+	var results []clubRoomResult
+	if err := query.Group("settlements.room_id, rooms.room_type, settlements.user_id").Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// loadClubForMember 加载俱乐部并确认用户是其成员
+func (s *ClubService) loadClubForMember(clubID, userID uint) (*models.Club, *models.ClubMember, error) {
+	var club models.Club
+	if err := models.DB.First(&club, clubID).Error; err != nil {
+		return nil, nil, errors.New("俱乐部不存在")
+	}
+
+	membership, err := getClubMember(clubID, userID)
+	if err != nil {
+		return nil, nil, errors.New("您不是该俱乐部成员")
+	}
+
+	return &club, membership, nil
+}
+
+// generateInviteCode 生成未被占用的邀请码（最多尝试10次）
+func (s *ClubService) generateInviteCode(db *gorm.DB) (string, error) {
+	for i := 0; i < 10; i++ {
+		code, err := utils.GenerateInviteCode()
+		if err != nil {
+			return "", err
+		}
+
+		var count int64
+		if err := db.Model(&models.Club{}).Where("invite_code = ?", code).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.New("生成邀请码失败，请重试")
+}
+
+func getClubMember(clubID, userID uint) (*models.ClubMember, error) {
+	var member models.ClubMember
+	if err := models.DB.Where("club_id = ? AND user_id = ?", clubID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func isClubMember(clubID, userID uint) bool {
+	_, err := getClubMember(clubID, userID)
+	return err == nil
+}
+
+func clubMemberIDs(members []models.ClubMember) []uint {
+	ids := make([]uint, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.UserID)
+	}
+	return ids
+}
+
+// loadNicknames 批量查询用户昵称
+func loadNicknames(userIDs []uint) (map[uint]string, error) {
+	nicknames := make(map[uint]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return nicknames, nil
+	}
+
+	var users []models.User
+	if err := models.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		nicknames[user.ID] = user.Nickname
+	}
+	return nicknames, nil
+}
+
+func clubPaymentView(payment models.ClubPayment, nicknames map[uint]string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            payment.ID,
+		"from_user_id":  payment.FromUserID,
+		"from_nickname": nicknames[payment.FromUserID],
+		"to_user_id":    payment.ToUserID,
+		"to_nickname":   nicknames[payment.ToUserID],
+		"rmb_amount":    payment.RmbAmount,
+		"note":          payment.Note,
+		"recorded_by":   payment.RecordedBy,
+		"created_at":    payment.CreatedAt,
+	}
+}
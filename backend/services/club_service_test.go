@@ -0,0 +1,182 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClubService_MembershipAndRoles(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol", "Dave"})
+	alice, bob, carol, dave := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	service := NewClubService()
+	club, err := service.CreateClub(alice, "  周五德州  ", "")
+	require.NoError(t, err)
+	require.Equal(t, "周五德州", club.Name)
+	require.Len(t, club.InviteCode, 8)
+
+	_, err = service.JoinClub(bob, club.InviteCode)
+	require.NoError(t, err)
+	_, err = service.JoinClub(carol, club.InviteCode)
+	require.NoError(t, err)
+
+	// 重复加入不会产生重复成员
+	_, err = service.JoinClub(bob, club.InviteCode)
+	require.NoError(t, err)
+	var count int64
+	models.DB.Model(&models.ClubMember{}).Where("club_id = ?", club.ID).Count(&count)
+	require.EqualValues(t, 3, count)
+
+	// 俱乐部角色与全局角色独立：普通成员不能管理俱乐部
+	require.EqualError(t, service.RemoveMember(club.ID, bob, carol), "只有俱乐部管理员可以移除成员")
+	require.EqualError(t, service.SetMemberRole(club.ID, bob, carol, models.ClubRoleAdmin), "只有俱乐部创建者可以设置管理员")
+
+	require.NoError(t, service.SetMemberRole(club.ID, alice, bob, models.ClubRoleAdmin))
+
+	// 管理员可以查看邀请码、移除普通成员，但不能移除其他管理员或创建者
+	details, err := service.GetClubDetails(club.ID, bob)
+	require.NoError(t, err)
+	require.Equal(t, club.InviteCode, details["invite_code"])
+	require.EqualError(t, service.RemoveMember(club.ID, bob, alice), "不能移除俱乐部创建者")
+	require.NoError(t, service.RemoveMember(club.ID, bob, carol))
+
+	details, err = service.GetClubDetails(club.ID, alice)
+	require.NoError(t, err)
+	require.Len(t, details["members"], 2)
+
+	// 重置邀请码后旧邀请码失效
+	newCode, err := service.RegenerateInviteCode(club.ID, bob)
+	require.NoError(t, err)
+	_, err = service.JoinClub(dave, club.InviteCode)
+	require.EqualError(t, err, "邀请码无效")
+	_, err = service.JoinClub(dave, newCode)
+	require.NoError(t, err)
+
+	details, err = service.GetClubDetails(club.ID, dave)
+	require.NoError(t, err)
+	_, visible := details["invite_code"]
+	require.False(t, visible, "普通成员不应看到邀请码")
+
+	require.EqualError(t, service.LeaveClub(club.ID, alice), "创建者不能退出俱乐部")
+	require.NoError(t, service.LeaveClub(club.ID, dave))
+	_, err = service.GetClubDetails(club.ID, dave)
+	require.EqualError(t, err, "您不是该俱乐部成员")
+}
+
+func TestClubService_ClubRoomsRequireMembership(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol"})
+	alice, bob, carol := users[0].ID, users[1].ID, users[2].ID
+
+	service := NewClubService()
+	roomService := NewRoomService(nil, nil)
+
+	club, err := service.CreateClub(alice, "周五德州", "")
+	require.NoError(t, err)
+	_, err = service.JoinClub(bob, club.InviteCode)
+	require.NoError(t, err)
+
+	_, err = roomService.CreateClubRoom(carol, club.ID, "texas", "20:1")
+	require.EqualError(t, err, "您不是该俱乐部成员")
+
+	room, err := roomService.CreateClubRoom(alice, club.ID, "texas", "20:1")
+	require.NoError(t, err)
+	require.NotNil(t, room.ClubID)
+	require.Equal(t, club.ID, *room.ClubID)
+
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(carol, room.ID)
+	require.EqualError(t, err, "该房间仅限俱乐部成员加入")
+
+	rooms, err := service.GetClubRooms(club.ID, bob)
+	require.NoError(t, err)
+	require.Len(t, rooms, 1)
+	require.EqualValues(t, 2, rooms[0]["member_count"])
+}
+
+func TestClubService_LeaderboardAndLedger(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol", "Dave"})
+	alice, bob, carol, dave := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	service := NewClubService()
+	club, err := service.CreateClub(alice, "周五德州", "")
+	require.NoError(t, err)
+	for _, id := range []uint{bob, carol} {
+		_, err = service.JoinClub(id, club.InviteCode)
+		require.NoError(t, err)
+	}
+
+	attach := func(room models.Room) {
+		require.NoError(t, models.DB.Model(&room).Update("club_id", club.ID).Error)
+	}
+
+	// 第一晚两场（第二场在次日凌晨结算，仍属于同一晚），第二晚一场
+	night1 := time.Date(2025, 11, 7, 20, 0, 0, 0, time.Local)
+	attach(seedSettledRoom(t, "texas", night1, night1.Add(3*time.Hour), map[uint]int{alice: 400, bob: -200, carol: -200}))
+	attach(seedSettledRoom(t, "texas", night1.Add(4*time.Hour), night1.Add(6*time.Hour), map[uint]int{alice: -100, bob: 100}))
+	night2 := night1.Add(7 * 24 * time.Hour)
+	attach(seedSettledRoom(t, "niuniu", night2, night2.Add(2*time.Hour), map[uint]int{bob: 300, carol: -300}))
+
+	// 不属于俱乐部的房间不计入
+	seedSettledRoom(t, "texas", night2, night2.Add(time.Hour), map[uint]int{alice: 1000, carol: -1000})
+
+	leaderboard, err := service.GetLeaderboard(club.ID, carol, StatsFilter{})
+	require.NoError(t, err)
+	require.Len(t, leaderboard, 3)
+	require.Equal(t, alice, leaderboard[0]["user_id"])
+	require.Equal(t, 15.0, leaderboard[0]["net_rmb"])
+	require.Equal(t, 300, leaderboard[0]["net_chip"])
+	require.Equal(t, bob, leaderboard[1]["user_id"])
+	require.Equal(t, 10.0, leaderboard[1]["net_rmb"])
+	require.Equal(t, 3, leaderboard[1]["sessions"])
+	require.Equal(t, 2, leaderboard[1]["wins"])
+	require.Equal(t, carol, leaderboard[2]["user_id"])
+	require.Equal(t, -25.0, leaderboard[2]["net_rmb"])
+
+	texasOnly, err := service.GetLeaderboard(club.ID, alice, StatsFilter{RoomType: "texas"})
+	require.NoError(t, err)
+	require.Equal(t, bob, texasOnly[1]["user_id"])
+	require.Equal(t, -5.0, texasOnly[1]["net_rmb"])
+
+	_, err = service.GetLeaderboard(club.ID, dave, StatsFilter{})
+	require.EqualError(t, err, "您不是该俱乐部成员")
+
+	// Carol 把欠 Alice 的钱转了，只有管理员或收款人可以登记
+	_, err = service.RecordPayment(club.ID, carol, carol, alice, 10, "微信转账")
+	require.EqualError(t, err, "只有俱乐部管理员或收款人可以登记还款")
+	_, err = service.RecordPayment(club.ID, alice, carol, alice, 10, "微信转账")
+	require.NoError(t, err)
+
+	ledger, err := service.GetLedger(club.ID, bob)
+	require.NoError(t, err)
+
+	nights := ledger["nights"].([]map[string]interface{})
+	require.Len(t, nights, 2)
+	require.Equal(t, "2025-11-07", nights[0]["date"])
+	require.Equal(t, 2, nights[0]["room_count"])
+	require.Equal(t, "2025-11-14", nights[1]["date"])
+
+	night2Entries := nights[1]["entries"].([]map[string]interface{})
+	for _, entry := range night2Entries {
+		if entry["user_id"] == bob {
+			require.Equal(t, 15.0, entry["rmb_amount"])
+			require.Equal(t, 10.0, entry["cumulative_rmb"])
+		}
+	}
+
+	balances := make(map[uint]float64)
+	for _, entry := range ledger["balances"].([]map[string]interface{}) {
+		balances[entry["user_id"].(uint)] = entry["balance"].(float64)
+	}
+	require.Equal(t, 5.0, balances[alice])
+	require.Equal(t, 10.0, balances[bob])
+	require.Equal(t, -15.0, balances[carol])
+	require.Len(t, ledger["payments"], 1)
+}
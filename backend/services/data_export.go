@@ -0,0 +1,408 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"poker_score_backend/models"
+	"strconv"
+	"time"
+)
+
+// UserDataExport 服务器保存的某个用户的全部个人数据
+type UserDataExport struct {
+	UserID     uint
+	ExportedAt time.Time
+	Tables     []ExportTable
+}
+
+// ExportTable 导出数据中的一张表：JSON 中为对象数组，CSV 压缩包中为一个文件
+type ExportTable struct {
+	Name    string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// utf8BOM 让 Excel 正确识别 CSV 中的中文
+const utf8BOM = "\xEF\xBB\xBF"
+
+// ExportUserData 收集用户的资料、登录设备、单点登录绑定、房间、操作、结算、下注、聊天、俱乐部与成就记录
+// 操作与下注记录包含以该用户为对象的记录；不导出密码哈希、令牌哈希等凭据
+func (s *AccountService) ExportUserData(userID uint) (*UserDataExport, error) {
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	export := &UserDataExport{UserID: userID, ExportedAt: time.Now()}
+	collectors := []func(*models.User) (ExportTable, error){
+		exportProfile,
+		exportSessions,
+		exportAPITokens,
+		exportOIDCIdentities,
+		exportRooms,
+		exportOperations,
+		exportSettlements,
+		exportBetRecords,
+		exportChatMessages,
+		exportClubs,
+		exportClubPayments,
+		exportAchievements,
+	}
+	for _, collect := range collectors {
+		table, err := collect(&user)
+		if err != nil {
+			return nil, err
+		}
+		export.Tables = append(export.Tables, table)
+	}
+	return export, nil
+}
+
+func exportProfile(user *models.User) (ExportTable, error) {
+	var twoFactor int64
+	if err := models.DB.Model(&models.TwoFactor{}).Where("user_id = ? AND enabled = ?", user.ID, true).Count(&twoFactor).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	return ExportTable{
+		Name:    "profile",
+		Columns: []string{"id", "phone", "nickname", "role", "timezone", "day_cutoff_hour", "two_factor_enabled", "created_at", "updated_at"},
+		Rows: [][]interface{}{{
+			user.ID, user.Phone, user.Nickname, user.Role, user.Timezone, user.DayCutoffHour, twoFactor > 0, user.CreatedAt, user.UpdatedAt,
+		}},
+	}, nil
+}
+
+func exportSessions(user *models.User) (ExportTable, error) {
+	var sessions []models.Session
+	if err := models.DB.Where("user_id = ?", user.ID).Order("id ASC").Find(&sessions).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "sessions",
+		Columns: []string{"id", "user_agent", "ip", "two_factor", "created_at", "last_used_at", "expires_at"},
+	}
+	for _, session := range sessions {
+		table.Rows = append(table.Rows, []interface{}{
+			session.ID, session.UserAgent, session.IP, session.TwoFactor, session.CreatedAt, session.LastUsedAt, session.ExpiresAt,
+		})
+	}
+	return table, nil
+}
+
+func exportAPITokens(user *models.User) (ExportTable, error) {
+	var tokens []models.APIToken
+	if err := models.DB.Where("user_id = ?", user.ID).Order("id ASC").Find(&tokens).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "api_tokens",
+		Columns: []string{"id", "name", "prefix", "scopes", "last_used_at", "last_used_ip", "expires_at", "created_at"},
+	}
+	for _, token := range tokens {
+		table.Rows = append(table.Rows, []interface{}{
+			token.ID, token.Name, token.Prefix, token.Scopes, token.LastUsedAt, token.LastUsedIP, token.ExpiresAt, token.CreatedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportOIDCIdentities(user *models.User) (ExportTable, error) {
+	var identities []models.OIDCIdentity
+	if err := models.DB.Where("user_id = ?", user.ID).Order("id ASC").Find(&identities).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "oidc_identities",
+		Columns: []string{"id", "issuer", "subject", "email", "display_name", "last_login_at", "created_at"},
+	}
+	for _, identity := range identities {
+		table.Rows = append(table.Rows, []interface{}{
+			identity.ID, identity.Issuer, identity.Subject, identity.Email, identity.DisplayName, identity.LastLoginAt, identity.CreatedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportRooms(user *models.User) (ExportTable, error) {
+	var rows []struct {
+		RoomID       uint
+		RoomCode     string
+		RoomType     string
+		ChipRate     string
+		Status       string
+		CreatedBy    uint
+		ClubID       *uint
+		JoinedAt     time.Time
+		MemberStatus string
+		Balance      *int
+		CreatedAt    time.Time
+		DissolvedAt  *time.Time
+	}
+	if err := models.DB.Table("room_members").
+		Select("rooms.id AS room_id, rooms.room_code, rooms.room_type, rooms.chip_rate, rooms.status, rooms.created_by, rooms.club_id, "+
+			"room_members.joined_at, room_members.status AS member_status, user_balances.balance, rooms.created_at, rooms.dissolved_at").
+		Joins("JOIN rooms ON rooms.id = room_members.room_id").
+		Joins("LEFT JOIN user_balances ON user_balances.room_id = room_members.room_id AND user_balances.user_id = room_members.user_id").
+		Where("room_members.user_id = ?", user.ID).
+		Order("room_members.joined_at ASC").
+		Scan(&rows).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name: "rooms",
+		Columns: []string{"room_id", "room_code", "room_type", "chip_rate", "status", "is_owner", "club_id",
+			"joined_at", "member_status", "balance", "created_at", "dissolved_at"},
+	}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, []interface{}{
+			row.RoomID, row.RoomCode, row.RoomType, row.ChipRate, row.Status, row.CreatedBy == user.ID, row.ClubID,
+			row.JoinedAt, row.MemberStatus, row.Balance, row.CreatedAt, row.DissolvedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportOperations(user *models.User) (ExportTable, error) {
+	var operations []models.RoomOperation
+	if err := models.DB.Where("user_id = ? OR target_user_id = ?", user.ID, user.ID).
+		Order("created_at ASC, id ASC").Find(&operations).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "operations",
+		Columns: []string{"id", "room_id", "user_id", "operation_type", "amount", "target_user_id", "description", "created_at"},
+	}
+	for _, op := range operations {
+		table.Rows = append(table.Rows, []interface{}{
+			op.ID, op.RoomID, op.UserID, op.OperationType, op.Amount, op.TargetUserID, op.Description, op.CreatedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportSettlements(user *models.User) (ExportTable, error) {
+	var settlements []models.Settlement
+	if err := models.DB.Where("user_id = ?", user.ID).Order("settled_at ASC, id ASC").Find(&settlements).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "settlements",
+		Columns: []string{"id", "room_id", "chip_amount", "rmb_amount", "settlement_batch", "settled_at"},
+	}
+	for _, settlement := range settlements {
+		table.Rows = append(table.Rows, []interface{}{
+			settlement.ID, settlement.RoomID, settlement.ChipAmount, settlement.RmbAmount, settlement.SettlementBatch, settlement.SettledAt,
+		})
+	}
+	return table, nil
+}
+
+func exportBetRecords(user *models.User) (ExportTable, error) {
+	var records []models.BetRecord
+	if err := models.DB.Where("from_user_id = ? OR to_user_id = ?", user.ID, user.ID).
+		Order("created_at ASC, id ASC").Find(&records).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "bet_records",
+		Columns: []string{"id", "room_id", "from_user_id", "to_user_id", "amount", "created_at"},
+	}
+	for _, record := range records {
+		table.Rows = append(table.Rows, []interface{}{
+			record.ID, record.RoomID, record.FromUserID, record.ToUserID, record.Amount, record.CreatedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportChatMessages(user *models.User) (ExportTable, error) {
+	var messages []models.ChatMessage
+	if err := models.DB.Unscoped().Where("user_id = ?", user.ID).Order("created_at ASC, id ASC").Find(&messages).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "chat_messages",
+		Columns: []string{"id", "room_id", "message_type", "content", "operation_id", "created_at", "deleted_at"},
+	}
+	for _, message := range messages {
+		var deletedAt *time.Time
+		if message.DeletedAt.Valid {
+			deletedAt = &message.DeletedAt.Time
+		}
+		table.Rows = append(table.Rows, []interface{}{
+			message.ID, message.RoomID, message.MessageType, message.Content, message.OperationID, message.CreatedAt, deletedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportClubs(user *models.User) (ExportTable, error) {
+	var rows []struct {
+		ClubID   uint
+		Name     string
+		Role     string
+		JoinedAt time.Time
+	}
+	if err := models.DB.Table("club_members").
+		Select("clubs.id AS club_id, clubs.name, club_members.role, club_members.joined_at").
+		Joins("JOIN clubs ON clubs.id = club_members.club_id").
+		Where("club_members.user_id = ?", user.ID).
+		Order("club_members.joined_at ASC").
+		Scan(&rows).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "clubs",
+		Columns: []string{"club_id", "name", "role", "joined_at"},
+	}
+	for _, row := range rows {
+		table.Rows = append(table.Rows, []interface{}{row.ClubID, row.Name, row.Role, row.JoinedAt})
+	}
+	return table, nil
+}
+
+func exportClubPayments(user *models.User) (ExportTable, error) {
+	var payments []models.ClubPayment
+	if err := models.DB.Where("from_user_id = ? OR to_user_id = ?", user.ID, user.ID).
+		Order("created_at ASC, id ASC").Find(&payments).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "club_payments",
+		Columns: []string{"id", "club_id", "from_user_id", "to_user_id", "rmb_amount", "note", "recorded_by", "created_at"},
+	}
+	for _, payment := range payments {
+		table.Rows = append(table.Rows, []interface{}{
+			payment.ID, payment.ClubID, payment.FromUserID, payment.ToUserID, payment.RmbAmount, payment.Note, payment.RecordedBy, payment.CreatedAt,
+		})
+	}
+	return table, nil
+}
+
+func exportAchievements(user *models.User) (ExportTable, error) {
+	var achievements []models.UserAchievement
+	if err := models.DB.Where("user_id = ?", user.ID).Order("earned_at ASC").Find(&achievements).Error; err != nil {
+		return ExportTable{}, err
+	}
+
+	table := ExportTable{
+		Name:    "achievements",
+		Columns: []string{"code", "room_id", "earned_at"},
+	}
+	for _, achievement := range achievements {
+		table.Rows = append(table.Rows, []interface{}{achievement.Code, achievement.RoomID, achievement.EarnedAt})
+	}
+	return table, nil
+}
+
+// RenderExportJSON 渲染为一个 JSON 文档，profile 为对象，其余每张表为对象数组
+func RenderExportJSON(export *UserDataExport) ([]byte, error) {
+	document := map[string]interface{}{
+		"user_id":     export.UserID,
+		"exported_at": export.ExportedAt,
+	}
+	for _, table := range export.Tables {
+		objects := make([]map[string]interface{}, 0, len(table.Rows))
+		for _, row := range table.Rows {
+			object := make(map[string]interface{}, len(table.Columns))
+			for i, column := range table.Columns {
+				object[column] = row[i]
+			}
+			objects = append(objects, object)
+		}
+		if table.Name == "profile" && len(objects) == 1 {
+			document[table.Name] = objects[0]
+			continue
+		}
+		document[table.Name] = objects
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// RenderExportCSVArchive 渲染为 zip 压缩包，每张表一个 CSV 文件
+func RenderExportCSVArchive(export *UserDataExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	for _, table := range export.Tables {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     table.Name + ".csv",
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write([]byte(utf8BOM)); err != nil {
+			return nil, err
+		}
+
+		writer := csv.NewWriter(file)
+		if err := writer.Write(table.Columns); err != nil {
+			return nil, err
+		}
+		for _, row := range table.Rows {
+			record := make([]string, len(row))
+			for i, value := range row {
+				record[i] = formatExportValue(value)
+			}
+			if err := writer.Write(record); err != nil {
+				return nil, err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatExportValue 把导出值格式化为 CSV 单元格，空指针输出为空
+func formatExportValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case *uint:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*v), 10)
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"strings"
+	"time"
+)
+
+// defaultDayCutoffHour 默认以早上7点作为“一晚”的分界
+const defaultDayCutoffHour = 7
+
+// DayBoundary 统计“一晚”使用的时区与分界时刻：分界前的时间算作前一天晚上
+type DayBoundary struct {
+	Location   *time.Location
+	CutoffHour int
+}
+
+// DefaultDayBoundary 服务器时区、早上7点分界
+func DefaultDayBoundary() DayBoundary {
+	return DayBoundary{Location: time.Local, CutoffHour: defaultDayCutoffHour}
+}
+
+// NewDayBoundary 校验并构造分界设置，timezone 为空表示服务器时区
+func NewDayBoundary(timezone string, cutoffHour int) (DayBoundary, error) {
+	if cutoffHour < 0 || cutoffHour > 23 {
+		return DayBoundary{}, errors.New("分界时刻必须在0-23之间")
+	}
+
+	location := time.Local
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err != nil {
+			return DayBoundary{}, errors.New("无效的时区")
+		}
+		location = loaded
+	}
+
+	return DayBoundary{Location: location, CutoffHour: cutoffHour}, nil
+}
+
+// NightRange 返回 t 所在那一晚的起止时间 [start, end)
+// 按日历日期计算而不是加减24小时，夏令时切换的那一晚会是23或25小时
+func (b DayBoundary) NightRange(t time.Time) (time.Time, time.Time) {
+	year, month, day := b.nightDay(t)
+	start := time.Date(year, month, day, b.CutoffHour, 0, 0, 0, b.Location)
+	end := time.Date(year, month, day+1, b.CutoffHour, 0, 0, 0, b.Location)
+	return start, end
+}
+
+// NightDate 返回 t 所属那一晚的日期（YYYY-MM-DD）
+func (b DayBoundary) NightDate(t time.Time) string {
+	year, month, day := b.nightDay(t)
+	return time.Date(year, month, day, 0, 0, 0, 0, b.Location).Format("2006-01-02")
+}
+
+// DateRange 返回日期 date 那一晚的起止时间 [start, end)
+func (b DayBoundary) DateRange(year int, month time.Month, day int) (time.Time, time.Time) {
+	start := time.Date(year, month, day, b.CutoffHour, 0, 0, 0, b.Location)
+	end := time.Date(year, month, day+1, b.CutoffHour, 0, 0, 0, b.Location)
+	return start, end
+}
+
+// storageTime 把查询边界换算到服务器时区
+// SQLite 按字符串比较时间，而记录保存的是服务器时区的时间，其他时区的边界直接比较会错位
+func storageTime(t time.Time) time.Time {
+	return t.In(time.Local)
+}
+
+func (b DayBoundary) nightDay(t time.Time) (int, time.Month, int) {
+	local := t.In(b.Location)
+	year, month, day := local.Date()
+	if local.Hour() < b.CutoffHour {
+		day--
+	}
+	return year, month, day
+}
+
+// dayBoundaryOf 根据保存的偏好构造分界设置，数据异常时退回默认值
+func dayBoundaryOf(timezone string, cutoffHour int) DayBoundary {
+	boundary, err := NewDayBoundary(timezone, cutoffHour)
+	if err != nil {
+		log.Printf("分界设置无效，使用默认值: Timezone=%s, CutoffHour=%d, %v", timezone, cutoffHour, err)
+		return DefaultDayBoundary()
+	}
+	return boundary
+}
+
+// userDayBoundary 加载用户的时区与分界偏好
+func userDayBoundary(userID uint) DayBoundary {
+	var user models.User
+	if err := models.DB.Select("id", "timezone", "day_cutoff_hour").First(&user, userID).Error; err != nil {
+		return DefaultDayBoundary()
+	}
+	return dayBoundaryOf(user.Timezone, user.DayCutoffHour)
+}
+
+// clubDayBoundary 加载俱乐部的时区与分界设置
+func clubDayBoundary(clubID uint) DayBoundary {
+	var club models.Club
+	if err := models.DB.Select("id", "timezone", "day_cutoff_hour").First(&club, clubID).Error; err != nil {
+		return DefaultDayBoundary()
+	}
+	return dayBoundaryOf(club.Timezone, club.DayCutoffHour)
+}
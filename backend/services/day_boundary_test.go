@@ -0,0 +1,159 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDayBoundary_NightRange(t *testing.T) {
+	t.Parallel()
+
+	boundary, err := NewDayBoundary("Asia/Shanghai", 7)
+	require.NoError(t, err)
+	loc := boundary.Location
+
+	// 凌晨3点还算前一天晚上
+	start, end := boundary.NightRange(time.Date(2025, 11, 8, 3, 0, 0, 0, loc))
+	require.Equal(t, time.Date(2025, 11, 7, 7, 0, 0, 0, loc), start)
+	require.Equal(t, time.Date(2025, 11, 8, 7, 0, 0, 0, loc), end)
+	require.Equal(t, "2025-11-07", boundary.NightDate(time.Date(2025, 11, 8, 3, 0, 0, 0, loc)))
+
+	// 到了分界时刻就是新的一晚
+	start, _ = boundary.NightRange(time.Date(2025, 11, 8, 7, 0, 0, 0, loc))
+	require.Equal(t, time.Date(2025, 11, 8, 7, 0, 0, 0, loc), start)
+
+	// 其他时区的时间先换算到设置的时区
+	require.Equal(t, "2025-11-07", boundary.NightDate(time.Date(2025, 11, 7, 22, 30, 0, 0, time.UTC)))
+
+	// 分界为0点时就是自然日
+	midnight, err := NewDayBoundary("Asia/Shanghai", 0)
+	require.NoError(t, err)
+	require.Equal(t, "2025-11-08", midnight.NightDate(time.Date(2025, 11, 8, 3, 0, 0, 0, loc)))
+}
+
+func TestDayBoundary_DSTNights(t *testing.T) {
+	t.Parallel()
+
+	boundary, err := NewDayBoundary("America/New_York", 7)
+	require.NoError(t, err)
+	loc := boundary.Location
+
+	// 2025-03-09 凌晨2点进入夏令时，这一晚只有23小时
+	start, end := boundary.NightRange(time.Date(2025, 3, 9, 1, 30, 0, 0, loc))
+	require.Equal(t, time.Date(2025, 3, 8, 7, 0, 0, 0, loc), start)
+	require.Equal(t, time.Date(2025, 3, 9, 7, 0, 0, 0, loc), end)
+	require.Equal(t, 23*time.Hour, end.Sub(start))
+
+	// 2025-11-02 凌晨2点退出夏令时，这一晚有25小时
+	start, end = boundary.DateRange(2025, time.November, 1)
+	require.Equal(t, 25*time.Hour, end.Sub(start))
+	require.Equal(t, 7, end.Hour())
+	require.Equal(t, "2025-11-01", boundary.NightDate(end.Add(-time.Nanosecond)))
+}
+
+func TestNewDayBoundary_Validation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDayBoundary("Mars/Olympus", 7)
+	require.EqualError(t, err, "无效的时区")
+
+	_, err = NewDayBoundary("", 24)
+	require.EqualError(t, err, "分界时刻必须在0-23之间")
+
+	boundary, err := NewDayBoundary("", 5)
+	require.NoError(t, err)
+	require.Equal(t, time.Local, boundary.Location)
+}
+
+func TestRecordService_GetTonightRecordsUsesUserBoundary(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", alice).
+		Updates(map[string]interface{}{"timezone": "Asia/Tokyo", "day_cutoff_hour": 6}).Error)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	// 东京时间凌晨1点到5点打的一场，属于前一晚
+	joined := time.Date(2025, 11, 8, 1, 0, 0, 0, tokyo).In(time.Local)
+	seedSettledRoom(t, "texas", joined, joined.Add(4*time.Hour), map[uint]int{alice: 100, bob: -100})
+	// 东京时间早上6点半之后的一场属于新的一晚
+	nextNight := time.Date(2025, 11, 8, 6, 30, 0, 0, tokyo).In(time.Local)
+	seedSettledRoom(t, "texas", nextNight, nextNight.Add(time.Hour), map[uint]int{alice: -40, bob: 40})
+
+	service := NewRecordService()
+	service.now = func() time.Time { return time.Date(2025, 11, 8, 5, 30, 0, 0, tokyo) }
+
+	records, err := service.GetTonightRecords(alice, nil, nil)
+	require.NoError(t, err)
+
+	timeRange := records["time_range"].(map[string]interface{})
+	require.True(t, time.Date(2025, 11, 7, 6, 0, 0, 0, tokyo).Equal(timeRange["start"].(time.Time)))
+	require.True(t, time.Date(2025, 11, 8, 6, 0, 0, 0, tokyo).Equal(timeRange["end"].(time.Time)))
+	require.Equal(t, "Asia/Tokyo", timeRange["timezone"])
+	require.Equal(t, 6, timeRange["day_cutoff_hour"])
+
+	settled := make(map[uint]int)
+	for _, record := range records["friends_records"].([]map[string]interface{}) {
+		settled[record["user_id"].(uint)] = record["settled_chip"].(int)
+	}
+	require.Equal(t, map[uint]int{alice: 100, bob: -100}, settled)
+}
+
+func TestRecordService_BoundaryInOtherTimezoneThanServer(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	// 选一个与服务器时区偏移不同的时区
+	timezone := "America/New_York"
+	userLoc, err := time.LoadLocation(timezone)
+	require.NoError(t, err)
+	sample := time.Date(2025, 11, 8, 12, 0, 0, 0, time.UTC)
+	_, userOffset := sample.In(userLoc).Zone()
+	_, serverOffset := sample.In(time.Local).Zone()
+	if userOffset == serverOffset {
+		timezone = "Asia/Tokyo"
+		userLoc, err = time.LoadLocation(timezone)
+		require.NoError(t, err)
+	}
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", alice).
+		Updates(map[string]interface{}{"timezone": timezone, "day_cutoff_hour": 6}).Error)
+
+	// 与线上数据一样，记录以服务器时区保存；两场都在用户时区的同一晚
+	first := time.Date(2025, 11, 7, 6, 30, 0, 0, userLoc).In(time.Local)
+	last := time.Date(2025, 11, 8, 5, 0, 0, 0, userLoc).In(time.Local)
+	seedSettledRoom(t, "texas", first, first.Add(time.Hour), map[uint]int{alice: 100, bob: -100})
+	seedSettledRoom(t, "texas", last, last.Add(30*time.Minute), map[uint]int{alice: -40, bob: 40})
+	// 前一晚的一场不计入
+	before := time.Date(2025, 11, 7, 5, 0, 0, 0, userLoc).In(time.Local)
+	seedSettledRoom(t, "niuniu", before, before.Add(30*time.Minute), map[uint]int{alice: 7, bob: -7})
+
+	service := NewRecordService()
+	service.now = func() time.Time { return time.Date(2025, 11, 8, 5, 45, 0, 0, userLoc) }
+
+	records, err := service.GetTonightRecords(alice, nil, nil)
+	require.NoError(t, err)
+	settled := make(map[uint]int)
+	for _, record := range records["friends_records"].([]map[string]interface{}) {
+		settled[record["user_id"].(uint)] = record["settled_chip"].(int)
+	}
+	require.Equal(t, map[uint]int{alice: 60, bob: -60}, settled)
+
+	timeline, err := service.GetNightTimeline(alice, "2025-11-07", DefaultTimelinePoints)
+	require.NoError(t, err)
+	require.Len(t, timeline["rooms"], 2)
+
+	start, end := service.GetDayBoundary(alice).DateRange(2025, time.November, 7)
+	end = end.Add(-time.Nanosecond)
+	stats, err := service.GetLifetimeStats(alice, StatsFilter{Start: &start, End: &end})
+	require.NoError(t, err)
+	require.Equal(t, 2, stats["total_sessions"])
+	require.Equal(t, 60, stats["net_chip"])
+}
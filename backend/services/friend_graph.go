@@ -0,0 +1,179 @@
+package services
+
+import (
+	"poker_score_backend/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxCachedCoPlayGraphs 最多缓存的时间窗口数量，超出时淘汰最久未使用的
+const maxCachedCoPlayGraphs = 32
+
+// coPlayWindow 同桌关系图对应的时间窗口
+type coPlayWindow struct {
+	Start int64
+	End   int64
+}
+
+// coPlayFingerprint 时间窗口内成员记录的摘要
+// 成员记录的 joined_at 不会修改；合并账户、认领游客会原地改写 user_id，因此除了数量与最大ID还要比较用户ID之和
+type coPlayFingerprint struct {
+	Members   int64
+	MaxID     uint
+	UserIDSum uint64
+}
+
+// coPlayGraph 时间窗口内的同桌关系图：房间与成员构成二部图，按连通分量划分
+// 与我在同一连通分量中的用户就是“今晚一起玩过的好友”（包括好友的好友）
+type coPlayGraph struct {
+	fingerprint   coPlayFingerprint
+	userComponent map[uint]int
+	components    [][]uint
+	lastUsed      time.Time
+}
+
+// coPlayGraphCache 按时间窗口缓存同桌关系图
+type coPlayGraphCache struct {
+	mu     sync.Mutex
+	graphs map[coPlayWindow]*coPlayGraph
+}
+
+func newCoPlayGraphCache() *coPlayGraphCache {
+	return &coPlayGraphCache{
+		graphs: make(map[coPlayWindow]*coPlayGraph),
+	}
+}
+
+// get 获取时间窗口的关系图，成员记录有变化时重新构建
+func (c *coPlayGraphCache) get(start, end time.Time) (*coPlayGraph, error) {
+	fingerprint, err := loadCoPlayFingerprint(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	window := coPlayWindow{Start: start.UnixNano(), End: end.UnixNano()}
+
+	c.mu.Lock()
+	if graph, ok := c.graphs[window]; ok && graph.fingerprint == fingerprint {
+		graph.lastUsed = time.Now()
+		c.mu.Unlock()
+		return graph, nil
+	}
+	c.mu.Unlock()
+
+	graph, err := buildCoPlayGraph(start, end)
+	if err != nil {
+		return nil, err
+	}
+	graph.lastUsed = time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.graphs[window] = graph
+	if len(c.graphs) > maxCachedCoPlayGraphs {
+		var oldest coPlayWindow
+		var oldestUsed time.Time
+		for key, cached := range c.graphs {
+			if oldestUsed.IsZero() || cached.lastUsed.Before(oldestUsed) {
+				oldest, oldestUsed = key, cached.lastUsed
+			}
+		}
+		delete(c.graphs, oldest)
+	}
+
+	return graph, nil
+}
+
+// loadCoPlayFingerprint 查询时间窗口内成员记录的数量、最大ID与用户ID之和
+func loadCoPlayFingerprint(start, end time.Time) (coPlayFingerprint, error) {
+	var fingerprint coPlayFingerprint
+	err := models.DB.Model(&models.RoomMember{}).
+		Select("COUNT(*) AS members, COALESCE(MAX(id), 0) AS max_id, COALESCE(SUM(user_id), 0) AS user_id_sum").
+		Where("joined_at BETWEEN ? AND ?", storageTime(start), storageTime(end)).
+		Scan(&fingerprint).Error
+	return fingerprint, err
+}
+
+// buildCoPlayGraph 一次查询取出时间窗口内的全部房间成员，用并查集求连通分量
+func buildCoPlayGraph(start, end time.Time) (*coPlayGraph, error) {
+	var fingerprint coPlayFingerprint
+	var edges []struct {
+		ID     uint
+		RoomID uint
+		UserID uint
+	}
+	if err := models.DB.Model(&models.RoomMember{}).
+		Select("id, room_id, user_id").
+		Where("joined_at BETWEEN ? AND ?", storageTime(start), storageTime(end)).
+		Scan(&edges).Error; err != nil {
+		return nil, err
+	}
+
+	// 用户与房间放进同一个并查集，房间的键取反以免与用户ID冲突
+	parent := make(map[int64]int64)
+	var find func(x int64) int64
+	find = func(x int64) int64 {
+		p, ok := parent[x]
+		if !ok {
+			parent[x] = x
+			return x
+		}
+		if p == x {
+			return x
+		}
+		root := find(p)
+		parent[x] = root
+		return root
+	}
+
+	for _, edge := range edges {
+		fingerprint.Members++
+		if edge.ID > fingerprint.MaxID {
+			fingerprint.MaxID = edge.ID
+		}
+		fingerprint.UserIDSum += uint64(edge.UserID)
+
+		userRoot := find(int64(edge.UserID))
+		roomRoot := find(-int64(edge.RoomID))
+		if userRoot != roomRoot {
+			parent[roomRoot] = userRoot
+		}
+	}
+
+	graph := &coPlayGraph{
+		fingerprint:   fingerprint,
+		userComponent: make(map[uint]int),
+	}
+	rootComponent := make(map[int64]int)
+	for key := range parent {
+		if key <= 0 {
+			continue
+		}
+		root := find(key)
+		index, ok := rootComponent[root]
+		if !ok {
+			index = len(graph.components)
+			rootComponent[root] = index
+			graph.components = append(graph.components, nil)
+		}
+		graph.userComponent[uint(key)] = index
+		graph.components[index] = append(graph.components[index], uint(key))
+	}
+
+	for _, component := range graph.components {
+		sort.Slice(component, func(i, j int) bool { return component[i] < component[j] })
+	}
+
+	return graph, nil
+}
+
+// friendsOf 返回与用户同一连通分量的所有用户（包括用户本人），用户在窗口内没有加入房间时为空
+func (g *coPlayGraph) friendsOf(userID uint) []uint {
+	index, ok := g.userComponent[userID]
+	if !ok {
+		return []uint{}
+	}
+	return g.components[index]
+}
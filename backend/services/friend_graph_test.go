@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// bfsFriendsReference 改造前逐个房间、逐个成员查询的BFS实现，作为关系图结果的对照
+func bfsFriendsReference(userID uint, start, end time.Time) []uint {
+	var initialRoomIDs []uint
+	models.DB.Model(&models.RoomMember{}).
+		Where("user_id = ? AND joined_at BETWEEN ? AND ?", userID, start, end).
+		Distinct("room_id").
+		Pluck("room_id", &initialRoomIDs)
+
+	visited := make(map[uint]bool)
+	visitedRooms := make(map[uint]bool)
+	queue := initialRoomIDs
+
+	for _, roomID := range initialRoomIDs {
+		visitedRooms[roomID] = true
+	}
+
+	for len(queue) > 0 {
+		roomID := queue[0]
+		queue = queue[1:]
+
+		var members []models.RoomMember
+		models.DB.Where("room_id = ? AND joined_at BETWEEN ? AND ?", roomID, start, end).
+			Find(&members)
+
+		for _, member := range members {
+			if !visited[member.UserID] {
+				visited[member.UserID] = true
+
+				var otherRoomIDs []uint
+				models.DB.Model(&models.RoomMember{}).
+					Where("user_id = ? AND joined_at BETWEEN ? AND ?", member.UserID, start, end).
+					Distinct("room_id").
+					Pluck("room_id", &otherRoomIDs)
+
+				for _, otherRoomID := range otherRoomIDs {
+					if !visitedRooms[otherRoomID] {
+						visitedRooms[otherRoomID] = true
+						queue = append(queue, otherRoomID)
+					}
+				}
+			}
+		}
+	}
+
+	friendIDs := make([]uint, 0, len(visited))
+	for id := range visited {
+		friendIDs = append(friendIDs, id)
+	}
+	sort.Slice(friendIDs, func(i, j int) bool { return friendIDs[i] < friendIDs[j] })
+	return friendIDs
+}
+
+func TestCoPlayGraph_MatchesBFSOnGeneratedData(t *testing.T) {
+	setupSettlementTestDB(t)
+
+	names := make([]string, 40)
+	for i := range names {
+		names[i] = fmt.Sprintf("玩家%d", i+1)
+	}
+	users := seedUsers(t, names)
+
+	rng := rand.New(rand.NewSource(20251107))
+	base := time.Date(2025, 11, 7, 7, 0, 0, 0, time.Local)
+
+	// 三晚共60个房间，每个房间2-6人，加入时间随机分布，部分成员跨晚加入
+	for i := 0; i < 60; i++ {
+		createdAt := base.Add(time.Duration(rng.Intn(72*60)) * time.Minute)
+		room := models.Room{
+			RoomCode:  fmt.Sprintf("%06d", i+1),
+			RoomType:  "texas",
+			ChipRate:  "20:1",
+			Status:    "dissolved",
+			CreatedBy: users[0].ID,
+			CreatedAt: createdAt,
+		}
+		require.NoError(t, models.DB.Create(&room).Error)
+
+		size := 2 + rng.Intn(5)
+		for _, index := range rng.Perm(len(users))[:size] {
+			joinedAt := createdAt.Add(time.Duration(rng.Intn(8*60)) * time.Minute)
+			require.NoError(t, models.DB.Create(&models.RoomMember{
+				RoomID:   room.ID,
+				UserID:   users[index].ID,
+				JoinedAt: joinedAt,
+				Status:   "offline",
+			}).Error)
+		}
+	}
+
+	windows := [][2]time.Time{
+		{base, base.AddDate(0, 0, 1)},
+		{base.AddDate(0, 0, 1), base.AddDate(0, 0, 2)},
+		{base.AddDate(0, 0, 2), base.AddDate(0, 0, 3)},
+		{base.Add(3 * time.Hour), base.Add(9 * time.Hour)},
+		{base, base.AddDate(0, 0, 4)},
+	}
+
+	cache := newCoPlayGraphCache()
+	nonTrivial := 0
+	for _, window := range windows {
+		graph, err := cache.get(window[0], window[1])
+		require.NoError(t, err)
+
+		for _, user := range users {
+			expected := bfsFriendsReference(user.ID, window[0], window[1])
+			require.Equal(t, expected, graph.friendsOf(user.ID), "user %d, window %v", user.ID, window)
+			if len(expected) > 2 {
+				nonTrivial++
+			}
+		}
+	}
+	require.Positive(t, nonTrivial, "生成的数据应包含多跳的好友关系")
+}
+
+func TestCoPlayGraphCache_RebuildsWhenMembersChange(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol"})
+	alice, bob, carol := users[0].ID, users[1].ID, users[2].ID
+
+	start := time.Date(2025, 11, 7, 7, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+	night := start.Add(13 * time.Hour)
+
+	seedSettledRoom(t, "texas", night, night.Add(time.Hour), map[uint]int{alice: 100, bob: -100})
+
+	cache := newCoPlayGraphCache()
+	graph, err := cache.get(start, end)
+	require.NoError(t, err)
+	require.Equal(t, []uint{alice, bob}, graph.friendsOf(alice))
+	require.Empty(t, graph.friendsOf(carol))
+
+	cached, err := cache.get(start, end)
+	require.NoError(t, err)
+	require.Same(t, graph, cached, "成员记录不变时应复用缓存")
+
+	// Carol 加入了 Bob 的另一个房间，Alice 通过 Bob 与 Carol 相连
+	seedSettledRoom(t, "niuniu", night.Add(2*time.Hour), night.Add(3*time.Hour), map[uint]int{bob: 50, carol: -50})
+
+	rebuilt, err := cache.get(start, end)
+	require.NoError(t, err)
+	require.NotSame(t, graph, rebuilt)
+	require.Equal(t, []uint{alice, bob, carol}, rebuilt.friendsOf(alice))
+}
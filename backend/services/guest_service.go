@@ -0,0 +1,310 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"poker_score_backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	guestClaimTTL        = 7 * 24 * time.Hour // 认领码有效期
+	guestClaimCodeLength = 8
+	guestClaimAlphabet   = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+// GuestService 游客：房主为不愿注册的朋友记分，之后可以由本人认领并合并到正式账户
+type GuestService struct {
+	roomService        *RoomService
+	achievementService *AchievementService
+}
+
+// NewGuestService 创建游客服务
+func NewGuestService(roomService *RoomService, achievementService *AchievementService) *GuestService {
+	return &GuestService{
+		roomService:        roomService,
+		achievementService: achievementService,
+	}
+}
+
+// AddGuest 房主向房间添加游客；guestID 不为0时把自己之前添加过的游客加入该房间，否则按昵称新建游客
+func (s *GuestService) AddGuest(roomID, hostID uint, nickname string, guestID uint) (*models.User, error) {
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return nil, errors.New("房间不存在")
+	}
+	if room.Status != "active" {
+		return nil, errors.New("房间已解散")
+	}
+	if room.CreatedBy != hostID {
+		return nil, errors.New("只有房主可以添加游客")
+	}
+
+	var guest models.User
+	if guestID != 0 {
+		found, err := s.findGuest(guestID, hostID)
+		if err != nil {
+			return nil, err
+		}
+		guest = *found
+	} else {
+		nickname = strings.TrimSpace(nickname)
+		if nickname == "" {
+			return nil, errors.New("游客昵称不能为空")
+		}
+
+		phone, err := s.placeholderPhone()
+		if err != nil {
+			return nil, err
+		}
+		guest = models.User{
+			Phone:    phone,
+			Nickname: nickname,
+			Role:     models.RoleGuest,
+			GuestOf:  &hostID,
+		}
+		if err := models.DB.Create(&guest).Error; err != nil {
+			log.Printf("创建游客失败: %v", err)
+			return nil, err
+		}
+		log.Printf("创建游客: ID=%d, Nickname=%s, HostID=%d", guest.ID, guest.Nickname, hostID)
+	}
+
+	if _, err := s.roomService.joinRoom(guest.ID, roomID, "由房主添加为游客", false); err != nil {
+		return nil, err
+	}
+
+	return &guest, nil
+}
+
+// ListGuests 房主添加过的游客，便于在新房间中再次添加
+func (s *GuestService) ListGuests(hostID uint) ([]map[string]interface{}, error) {
+	var guests []models.User
+	if err := models.DB.Where("role = ? AND guest_of = ?", models.RoleGuest, hostID).
+		Order("id DESC").Find(&guests).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(guests))
+	for _, guest := range guests {
+		var roomCount int64
+		models.DB.Model(&models.RoomMember{}).Where("user_id = ?", guest.ID).Count(&roomCount)
+
+		result = append(result, map[string]interface{}{
+			"id":         guest.ID,
+			"nickname":   guest.Nickname,
+			"room_count": roomCount,
+			"created_at": guest.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+// ResolveActor 确定积分操作的实际用户：guestID 为0时是当前用户本人，
+// 否则必须是房主代房间内的游客操作
+func (s *GuestService) ResolveActor(roomID, userID, guestID uint) (uint, error) {
+	if guestID == 0 || guestID == userID {
+		return userID, nil
+	}
+
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return 0, errors.New("房间不存在")
+	}
+	if room.CreatedBy != userID {
+		return 0, errors.New("只有房主可以代游客操作")
+	}
+
+	var guests []models.User
+	if err := models.DB.Where("id = ? AND role = ?", guestID, models.RoleGuest).Limit(1).Find(&guests).Error; err != nil {
+		return 0, err
+	}
+	if len(guests) == 0 {
+		return 0, errors.New("游客不存在")
+	}
+	if err := s.roomService.EnsureActiveMember(roomID, guestID); err != nil {
+		return 0, errors.New("该游客不在房间中")
+	}
+
+	log.Printf("房主代游客操作: RoomID=%d, HostID=%d, GuestID=%d", roomID, userID, guestID)
+	return guestID, nil
+}
+
+// CreateClaimCode 房主为游客生成认领码，重新生成会使之前的认领码失效
+func (s *GuestService) CreateClaimCode(hostID, guestID uint) (*models.GuestClaim, error) {
+	if _, err := s.findGuest(guestID, hostID); err != nil {
+		return nil, err
+	}
+
+	var claim models.GuestClaim
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		code, err := generateGuestClaimCode(tx)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("guest_id = ?", guestID).Delete(&models.GuestClaim{}).Error; err != nil {
+			return err
+		}
+
+		claim = models.GuestClaim{
+			GuestID:   guestID,
+			Code:      code,
+			CreatedBy: hostID,
+			ExpiresAt: time.Now().Add(guestClaimTTL),
+		}
+		return tx.Create(&claim).Error
+	})
+	if err != nil {
+		log.Printf("生成游客认领码失败: GuestID=%d, %v", guestID, err)
+		return nil, err
+	}
+
+	log.Printf("生成游客认领码: GuestID=%d, HostID=%d", guestID, hostID)
+	return &claim, nil
+}
+
+// Claim 用户凭认领码把游客的房间、积分、结算与操作记录合并到自己的账户，游客随后被删除
+func (s *GuestService) Claim(userID uint, code string) (*models.User, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	var guest models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var claims []models.GuestClaim
+		if err := tx.Where("code = ?", code).Limit(1).Find(&claims).Error; err != nil {
+			return err
+		}
+		if len(claims) == 0 || claims[0].IsExpired() {
+			return errors.New("认领码无效或已过期")
+		}
+		claim := claims[0]
+
+		var users []models.User
+		if err := tx.Where("id IN ?", []uint{claim.GuestID, userID}).Find(&users).Error; err != nil {
+			return err
+		}
+		var user *models.User
+		for i := range users {
+			if users[i].ID == claim.GuestID {
+				guest = users[i]
+			}
+			if users[i].ID == userID {
+				user = &users[i]
+			}
+		}
+		if guest.ID == 0 || !guest.IsGuest() {
+			return errors.New("游客不存在")
+		}
+		if user == nil || user.IsGuest() {
+			return errors.New("用户不存在")
+		}
+
+		var shared int64
+		if err := tx.Model(&models.RoomMember{}).
+			Where("user_id = ? AND room_id IN (?)", userID,
+				tx.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", guest.ID)).
+			Count(&shared).Error; err != nil {
+			return err
+		}
+		if shared > 0 {
+			return errors.New("您与该游客在同一房间中都有记录，无法合并")
+		}
+
+		// 进行中的房间里游客还会继续产生积分与操作，需在房间解散后再认领
+		var activeRooms int64
+		if err := tx.Model(&models.RoomMember{}).
+			Joins("JOIN rooms ON rooms.id = room_members.room_id").
+			Where("room_members.user_id = ? AND rooms.status = ?", guest.ID, "active").
+			Count(&activeRooms).Error; err != nil {
+			return err
+		}
+		if activeRooms > 0 {
+			return errors.New("该游客还在进行中的房间里，请在房间解散后再认领")
+		}
+
+		if err := moveUserHistory(tx, guest.ID, userID); err != nil {
+			return err
+		}
+		if err := tx.Where("guest_id = ?", guest.ID).Delete(&models.GuestClaim{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, guest.ID).Error
+	})
+	if err != nil {
+		log.Printf("认领游客失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("游客已合并到用户: GuestID=%d, UserID=%d", guest.ID, userID)
+
+	if s.achievementService != nil {
+		if _, err := s.achievementService.EvaluateUser(userID); err != nil {
+			log.Printf("合并游客后评估成就失败: UserID=%d, %v", userID, err)
+		}
+	}
+	return &guest, nil
+}
+
+// findGuest 查找房主添加的游客
+func (s *GuestService) findGuest(guestID, hostID uint) (*models.User, error) {
+	var guests []models.User
+	if err := models.DB.Where("id = ? AND role = ? AND guest_of = ?", guestID, models.RoleGuest, hostID).
+		Limit(1).Find(&guests).Error; err != nil {
+		return nil, err
+	}
+	if len(guests) == 0 {
+		return nil, errors.New("游客不存在")
+	}
+	return &guests[0], nil
+}
+
+// placeholderPhone 为游客生成占位手机号（g 开头，不是合法手机号，无法用于登录或找回密码）
+func (s *GuestService) placeholderPhone() (string, error) {
+	max := big.NewInt(10_000_000_000)
+	for i := 0; i < 10; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		phone := fmt.Sprintf("g%010d", n.Int64())
+
+		var count int64
+		if err := models.DB.Model(&models.User{}).Where("phone = ?", phone).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return phone, nil
+		}
+	}
+	return "", errors.New("创建游客失败，请重试")
+}
+
+// generateGuestClaimCode 使用安全随机数生成不重复的认领码
+func generateGuestClaimCode(tx *gorm.DB) (string, error) {
+	alphabetSize := big.NewInt(int64(len(guestClaimAlphabet)))
+	for i := 0; i < 10; i++ {
+		code := make([]byte, guestClaimCodeLength)
+		for j := range code {
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return "", err
+			}
+			code[j] = guestClaimAlphabet[n.Int64()]
+		}
+
+		var count int64
+		if err := tx.Model(&models.GuestClaim{}).Where("code = ?", string(code)).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return string(code), nil
+		}
+	}
+	return "", errors.New("生成认领码失败，请重试")
+}
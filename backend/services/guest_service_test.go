@@ -0,0 +1,142 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuest_HostScoresGuestAndUserClaims(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Host", "Friend", "Carol"})
+	host, friend, carol := users[0], users[1], users[2]
+
+	roomService := NewRoomService(nil, nil)
+	operationService := NewOperationService(roomService)
+	settlementService := NewSettlementService(roomService)
+	service := NewGuestService(roomService, nil)
+
+	room, err := roomService.CreateRoom(host.ID, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(friend.ID, room.ID)
+	require.NoError(t, err)
+
+	_, err = service.AddGuest(room.ID, friend.ID, "Guest", 0)
+	require.EqualError(t, err, "只有房主可以添加游客")
+
+	guest, err := service.AddGuest(room.ID, host.ID, " Guest ", 0)
+	require.NoError(t, err)
+	require.True(t, guest.IsGuest())
+	require.Equal(t, "Guest", guest.Nickname)
+	require.Equal(t, host.ID, *guest.GuestOf)
+	require.NoError(t, roomService.EnsureActiveMember(room.ID, guest.ID))
+
+	// 游客不能登录
+	_, err = NewAuthService(time.Hour, time.Hour, time.Minute).Authenticate(guest.Phone, "")
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = service.ResolveActor(room.ID, friend.ID, guest.ID)
+	require.EqualError(t, err, "只有房主可以代游客操作")
+	actorID, err := service.ResolveActor(room.ID, host.ID, guest.ID)
+	require.NoError(t, err)
+	require.Equal(t, guest.ID, actorID)
+	actorID, err = service.ResolveActor(room.ID, friend.ID, 0)
+	require.NoError(t, err)
+	require.Equal(t, friend.ID, actorID)
+
+	_, _, err = operationService.Bet(room.ID, guest.ID, 100)
+	require.NoError(t, err)
+	_, _, _, err = operationService.Withdraw(room.ID, host.ID, 0)
+	require.NoError(t, err)
+	_, _, err = settlementService.ConfirmSettlement(room.ID, host.ID)
+	require.NoError(t, err)
+
+	_, err = service.CreateClaimCode(friend.ID, guest.ID)
+	require.EqualError(t, err, "游客不存在")
+	claim, err := service.CreateClaimCode(host.ID, guest.ID)
+	require.NoError(t, err)
+	require.Len(t, claim.Code, guestClaimCodeLength)
+
+	// 与游客在同一房间中的用户不能认领
+	_, err = service.Claim(friend.ID, claim.Code)
+	require.EqualError(t, err, "您与该游客在同一房间中都有记录，无法合并")
+
+	_, err = service.Claim(carol.ID, "WRONGCODE")
+	require.EqualError(t, err, "认领码无效或已过期")
+
+	// 房间解散前不能认领
+	_, err = service.Claim(carol.ID, claim.Code)
+	require.EqualError(t, err, "该游客还在进行中的房间里，请在房间解散后再认领")
+	_, err = roomService.ManualDissolveRoom(room.ID, host.ID)
+	require.NoError(t, err)
+
+	recordService := NewRecordService()
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	require.ElementsMatch(t, []uint{host.ID, friend.ID, guest.ID}, tonightFriendIDs(t, recordService, host.ID, start, end))
+
+	claimed, err := service.Claim(carol.ID, claim.Code)
+	require.NoError(t, err)
+	require.Equal(t, guest.ID, claimed.ID)
+
+	// 认领原地改写了成员记录，缓存的同桌关系图随之更新
+	require.ElementsMatch(t, []uint{host.ID, friend.ID, carol.ID}, tonightFriendIDs(t, recordService, host.ID, start, end))
+
+	var settlements []models.Settlement
+	require.NoError(t, models.DB.Where("room_id = ? AND user_id = ?", room.ID, carol.ID).Find(&settlements).Error)
+	require.Len(t, settlements, 1)
+	require.Equal(t, -100, settlements[0].ChipAmount)
+	require.NoError(t, roomService.EnsureActiveMember(room.ID, carol.ID))
+
+	var bets int64
+	models.DB.Model(&models.RoomOperation{}).Where("user_id = ? AND operation_type = ?", carol.ID, models.OpTypeBet).Count(&bets)
+	require.Equal(t, int64(1), bets)
+
+	var remaining int64
+	models.DB.Model(&models.User{}).Where("id = ?", guest.ID).Count(&remaining)
+	require.Zero(t, remaining)
+
+	_, err = service.Claim(carol.ID, claim.Code)
+	require.EqualError(t, err, "认领码无效或已过期")
+}
+
+func TestGuest_ReuseAcrossRoomsAndExpiredClaim(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Host", "Other", "Carol"})
+	host, other, carol := users[0], users[1], users[2]
+
+	roomService := NewRoomService(nil, nil)
+	service := NewGuestService(roomService, nil)
+
+	first, err := roomService.CreateRoom(host.ID, "texas", "20:1")
+	require.NoError(t, err)
+	second, err := roomService.CreateRoom(host.ID, "niuniu", "20:1")
+	require.NoError(t, err)
+	otherRoom, err := roomService.CreateRoom(other.ID, "texas", "20:1")
+	require.NoError(t, err)
+
+	guest, err := service.AddGuest(first.ID, host.ID, "Guest", 0)
+	require.NoError(t, err)
+	again, err := service.AddGuest(second.ID, host.ID, "", guest.ID)
+	require.NoError(t, err)
+	require.Equal(t, guest.ID, again.ID)
+
+	// 只能再次添加自己添加过的游客
+	_, err = service.AddGuest(otherRoom.ID, other.ID, "", guest.ID)
+	require.EqualError(t, err, "游客不存在")
+
+	guests, err := service.ListGuests(host.ID)
+	require.NoError(t, err)
+	require.Len(t, guests, 1)
+	require.Equal(t, int64(2), guests[0]["room_count"])
+
+	claim, err := service.CreateClaimCode(host.ID, guest.ID)
+	require.NoError(t, err)
+	require.NoError(t, models.DB.Model(&models.GuestClaim{}).Where("id = ?", claim.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	_, err = service.Claim(carol.ID, claim.Code)
+	require.EqualError(t, err, "认领码无效或已过期")
+}
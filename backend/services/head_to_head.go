@@ -0,0 +1,366 @@
+package services
+
+import (
+	"errors"
+	"poker_score_backend/models"
+	"sort"
+	"time"
+)
+
+// headToHeadMaxDepth 查找两人之间牌友关系链时的最大跳数
+const headToHeadMaxDepth = 4
+
+// ErrHeadToHeadForbidden 查看他人的交手记录时，与其中一方既没有同过房间也不在同一俱乐部
+var ErrHeadToHeadForbidden = errors.New("只能查看与您同过房间或在同一俱乐部的用户的交手记录")
+
+// headToHeadRoom 两人同场的一个房间
+type headToHeadRoom struct {
+	RoomID       uint
+	RoomCode     string
+	RoomType     string
+	PlayedAt     time.Time
+	Settled      bool
+	UserChip     int
+	UserRmb      float64
+	OpponentChip int
+	OpponentRmb  float64
+}
+
+// CheckHeadToHeadAccess 校验 viewerID 能否查看两名用户的交手记录：
+// 查看者是其中一方时可以查看，否则需要与两人都同过房间或在同一俱乐部
+func (s *RecordService) CheckHeadToHeadAccess(viewerID, userID, opponentID uint) error {
+	if viewerID == userID || viewerID == opponentID {
+		return nil
+	}
+
+	for _, otherID := range []uint{userID, opponentID} {
+		related, err := sharesRoomOrClub(viewerID, otherID)
+		if err != nil {
+			return err
+		}
+		if !related {
+			return ErrHeadToHeadForbidden
+		}
+	}
+	return nil
+}
+
+// sharesRoomOrClub 两名用户是否同过房间或在同一俱乐部
+func sharesRoomOrClub(userID, otherID uint) (bool, error) {
+	var count int64
+	if err := models.DB.Model(&models.RoomMember{}).
+		Where("user_id = ? AND room_id IN (?)", userID,
+			models.DB.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", otherID)).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := models.DB.Model(&models.ClubMember{}).
+		Where("user_id = ? AND club_id IN (?)", userID,
+			models.DB.Model(&models.ClubMember{}).Select("club_id").Where("user_id = ?", otherID)).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetHeadToHead 获取两名用户的交手记录：同场的所有房间、各自盈亏以及累计走势
+// 两人从未同场时，返回通过共同牌友连接二人的最短关系链
+func (s *RecordService) GetHeadToHead(userID, opponentID uint, filter StatsFilter) (map[string]interface{}, error) {
+	if userID == opponentID {
+		return nil, errors.New("不能和自己比较")
+	}
+
+	var users []models.User
+	if err := models.DB.Where("id IN ?", []uint{userID, opponentID}).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) != 2 {
+		return nil, errors.New("用户不存在")
+	}
+	nicknames := make(map[uint]string, 2)
+	for _, user := range users {
+		nicknames[user.ID] = user.Nickname
+	}
+
+	rooms, err := s.loadSharedRooms(userID, opponentID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	userWins, opponentWins, draws := 0, 0, 0
+	userNetChip, opponentNetChip := 0, 0
+	userNetRmb, opponentNetRmb := 0.0, 0.0
+
+	roomList := make([]map[string]interface{}, 0, len(rooms))
+	series := make([]map[string]interface{}, 0, len(rooms))
+	for _, room := range rooms {
+		winner := "draw"
+		if room.UserChip > room.OpponentChip {
+			winner = "user"
+		} else if room.UserChip < room.OpponentChip {
+			winner = "opponent"
+		}
+
+		roomList = append(roomList, map[string]interface{}{
+			"room_id":       room.RoomID,
+			"room_code":     room.RoomCode,
+			"room_type":     room.RoomType,
+			"played_at":     room.PlayedAt,
+			"settled":       room.Settled,
+			"user_chip":     room.UserChip,
+			"user_rmb":      room.UserRmb,
+			"opponent_chip": room.OpponentChip,
+			"opponent_rmb":  room.OpponentRmb,
+			"winner":        winner,
+		})
+
+		// 未结算的房间不计入胜负与走势
+		if !room.Settled {
+			continue
+		}
+
+		switch winner {
+		case "user":
+			userWins++
+		case "opponent":
+			opponentWins++
+		default:
+			draws++
+		}
+
+		userNetChip += room.UserChip
+		opponentNetChip += room.OpponentChip
+		userNetRmb += room.UserRmb
+		opponentNetRmb += room.OpponentRmb
+
+		series = append(series, map[string]interface{}{
+			"room_id":                  room.RoomID,
+			"played_at":                room.PlayedAt,
+			"user_cumulative_chip":     userNetChip,
+			"opponent_cumulative_chip": opponentNetChip,
+			"difference_chip":          userNetChip - opponentNetChip,
+		})
+	}
+
+	result := map[string]interface{}{
+		"user":     map[string]interface{}{"user_id": userID, "nickname": nicknames[userID]},
+		"opponent": map[string]interface{}{"user_id": opponentID, "nickname": nicknames[opponentID]},
+		"rooms":    roomList,
+		"summary": map[string]interface{}{
+			"settled_rooms":     len(series),
+			"user_wins":         userWins,
+			"opponent_wins":     opponentWins,
+			"draws":             draws,
+			"user_net_chip":     userNetChip,
+			"user_net_rmb":      roundTo(userNetRmb, 2),
+			"opponent_net_chip": opponentNetChip,
+			"opponent_net_rmb":  roundTo(opponentNetRmb, 2),
+		},
+		"series":          series,
+		"connection_path": nil,
+	}
+
+	if len(rooms) == 0 {
+		path, err := s.findConnectionPath(userID, opponentID, headToHeadMaxDepth)
+		if err != nil {
+			return nil, err
+		}
+		if path != nil {
+			result["connection_path"] = path
+		}
+	}
+
+	return result, nil
+}
+
+// loadSharedRooms 查找两人都有成员记录的房间，并汇总各自在房间内的结算盈亏，按时间升序返回
+func (s *RecordService) loadSharedRooms(userID, opponentID uint, filter StatsFilter) ([]headToHeadRoom, error) {
+	var roomIDs []uint
+	err := models.DB.Model(&models.RoomMember{}).
+		Where("user_id IN ?", []uint{userID, opponentID}).
+		Group("room_id").
+		Having("COUNT(DISTINCT user_id) = 2").
+		Pluck("room_id", &roomIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(roomIDs) == 0 {
+		return []headToHeadRoom{}, nil
+	}
+
+	roomQuery := models.DB.Where("id IN ?", roomIDs)
+	if filter.RoomType != "" {
+		roomQuery = roomQuery.Where("room_type = ?", filter.RoomType)
+	}
+	var rooms []models.Room
+	if err := roomQuery.Find(&rooms).Error; err != nil {
+		return nil, err
+	}
+
+	type settlementRow struct {
+		RoomID        uint
+		UserID        uint
+		ChipAmount    int
+		RmbAmount     float64
+		LastSettledAt string
+	}
+
+	var rows []settlementRow
+	err = models.DB.Model(&models.Settlement{}).
+		Select("room_id, user_id, SUM(chip_amount) AS chip_amount, SUM(rmb_amount) AS rmb_amount, MAX(settled_at) AS last_settled_at").
+		Where("room_id IN ? AND user_id IN ?", roomIDs, []uint{userID, opponentID}).
+		Group("room_id, user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byRoom := make(map[uint]*headToHeadRoom, len(rooms))
+	result := make([]headToHeadRoom, 0, len(rooms))
+	for _, room := range rooms {
+		byRoom[room.ID] = &headToHeadRoom{
+			RoomID:   room.ID,
+			RoomCode: room.RoomCode,
+			RoomType: room.RoomType,
+			PlayedAt: room.CreatedAt,
+		}
+	}
+
+	for _, row := range rows {
+		entry, ok := byRoom[row.RoomID]
+		if !ok {
+			continue
+		}
+		entry.Settled = true
+		if settledAt, ok := parseDBTime(row.LastSettledAt); ok && settledAt.After(entry.PlayedAt) {
+			entry.PlayedAt = settledAt
+		}
+		if row.UserID == userID {
+			entry.UserChip = row.ChipAmount
+			entry.UserRmb = roundTo(row.RmbAmount, 2)
+		} else {
+			entry.OpponentChip = row.ChipAmount
+			entry.OpponentRmb = roundTo(row.RmbAmount, 2)
+		}
+	}
+
+	for _, entry := range byRoom {
+		if filter.Start != nil && entry.PlayedAt.Before(*filter.Start) {
+			continue
+		}
+		if filter.End != nil && entry.PlayedAt.After(*filter.End) {
+			continue
+		}
+		result = append(result, *entry)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].PlayedAt.Equal(result[j].PlayedAt) {
+			return result[i].RoomID < result[j].RoomID
+		}
+		return result[i].PlayedAt.Before(result[j].PlayedAt)
+	})
+
+	return result, nil
+}
+
+// findConnectionPath 与 findFriendsWithBFS 相同，把“同房间”视为牌友关系在用户-房间图上做BFS，
+// 按层扩展（每层两次查询），返回从 fromID 到 toID 的最短用户链；超过 maxDepth 跳或不连通时返回 nil
+func (s *RecordService) findConnectionPath(fromID, toID uint, maxDepth int) ([]map[string]interface{}, error) {
+	parent := map[uint]uint{fromID: 0}
+	visitedRooms := make(map[uint]bool)
+	frontier := []uint{fromID}
+
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var roomIDs []uint
+		if err := models.DB.Model(&models.RoomMember{}).
+			Where("user_id IN ?", frontier).
+			Distinct("room_id").
+			Pluck("room_id", &roomIDs).Error; err != nil {
+			return nil, err
+		}
+
+		newRooms := make([]uint, 0, len(roomIDs))
+		for _, roomID := range roomIDs {
+			if !visitedRooms[roomID] {
+				visitedRooms[roomID] = true
+				newRooms = append(newRooms, roomID)
+			}
+		}
+		if len(newRooms) == 0 {
+			break
+		}
+
+		var members []models.RoomMember
+		if err := models.DB.Where("room_id IN ?", newRooms).Find(&members).Error; err != nil {
+			return nil, err
+		}
+
+		// 记录每个房间里属于上一层的成员，作为新发现用户的前驱
+		frontierSet := make(map[uint]bool, len(frontier))
+		for _, id := range frontier {
+			frontierSet[id] = true
+		}
+		roomParent := make(map[uint]uint)
+		for _, member := range members {
+			if frontierSet[member.UserID] {
+				if _, ok := roomParent[member.RoomID]; !ok {
+					roomParent[member.RoomID] = member.UserID
+				}
+			}
+		}
+
+		next := make([]uint, 0)
+		for _, member := range members {
+			if _, seen := parent[member.UserID]; seen {
+				continue
+			}
+			via, ok := roomParent[member.RoomID]
+			if !ok {
+				continue
+			}
+			parent[member.UserID] = via
+			next = append(next, member.UserID)
+		}
+
+		if _, found := parent[toID]; found {
+			return s.buildConnectionPath(parent, fromID, toID)
+		}
+		frontier = next
+	}
+
+	return nil, nil
+}
+
+func (s *RecordService) buildConnectionPath(parent map[uint]uint, fromID, toID uint) ([]map[string]interface{}, error) {
+	ids := []uint{toID}
+	for current := toID; current != fromID; {
+		current = parent[current]
+		ids = append(ids, current)
+	}
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+
+	var users []models.User
+	if err := models.DB.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	nicknames := make(map[uint]string, len(users))
+	for _, user := range users {
+		nicknames[user.ID] = user.Nickname
+	}
+
+	path := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		path = append(path, map[string]interface{}{
+			"user_id":  id,
+			"nickname": nicknames[id],
+		})
+	}
+	return path, nil
+}
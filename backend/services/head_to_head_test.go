@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordService_GetHeadToHead(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol", "Dave"})
+	alice, bob, carol, dave := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	base := time.Date(2025, 11, 1, 20, 0, 0, 0, time.Local)
+	first := seedSettledRoom(t, "texas", base, base.Add(time.Hour), map[uint]int{alice: 300, bob: -100, carol: -200})
+	second := seedSettledRoom(t, "niuniu", base.Add(24*time.Hour), base.Add(25*time.Hour), map[uint]int{alice: -50, bob: 50})
+	seedSettledRoom(t, "texas", base.Add(48*time.Hour), base.Add(49*time.Hour), map[uint]int{carol: 80, dave: -80})
+
+	// 仍在进行中的同场房间只列出，不计入胜负
+	active := models.Room{RoomCode: "777777", RoomType: "texas", ChipRate: "20:1", Status: "active", CreatedBy: alice, CreatedAt: base.Add(72 * time.Hour)}
+	require.NoError(t, models.DB.Create(&active).Error)
+	for _, userID := range []uint{alice, bob} {
+		require.NoError(t, models.DB.Create(&models.RoomMember{RoomID: active.ID, UserID: userID, JoinedAt: active.CreatedAt, Status: "online"}).Error)
+	}
+
+	service := NewRecordService()
+
+	result, err := service.GetHeadToHead(alice, bob, StatsFilter{})
+	require.NoError(t, err)
+
+	rooms := result["rooms"].([]map[string]interface{})
+	require.Len(t, rooms, 3)
+	require.Equal(t, first.ID, rooms[0]["room_id"])
+	require.Equal(t, "user", rooms[0]["winner"])
+	require.Equal(t, second.ID, rooms[1]["room_id"])
+	require.Equal(t, "opponent", rooms[1]["winner"])
+	require.Equal(t, false, rooms[2]["settled"])
+
+	summary := result["summary"].(map[string]interface{})
+	require.Equal(t, 2, summary["settled_rooms"])
+	require.Equal(t, 1, summary["user_wins"])
+	require.Equal(t, 1, summary["opponent_wins"])
+	require.Equal(t, 250, summary["user_net_chip"])
+	require.Equal(t, -50, summary["opponent_net_chip"])
+
+	series := result["series"].([]map[string]interface{})
+	require.Len(t, series, 2)
+	require.Equal(t, 400, series[0]["difference_chip"])
+	require.Equal(t, 300, series[1]["difference_chip"])
+	require.Nil(t, result["connection_path"])
+
+	// 按游戏类型筛选
+	result, err = service.GetHeadToHead(alice, bob, StatsFilter{RoomType: "niuniu"})
+	require.NoError(t, err)
+	require.Len(t, result["rooms"].([]map[string]interface{}), 1)
+
+	// 从未同场时返回牌友关系链 Alice -> Carol -> Dave
+	result, err = service.GetHeadToHead(alice, dave, StatsFilter{})
+	require.NoError(t, err)
+	require.Empty(t, result["rooms"])
+	path := result["connection_path"].([]map[string]interface{})
+	require.Len(t, path, 3)
+	require.Equal(t, alice, path[0]["user_id"])
+	require.Equal(t, carol, path[1]["user_id"])
+	require.Equal(t, dave, path[2]["user_id"])
+
+	_, err = service.GetHeadToHead(alice, alice, StatsFilter{})
+	require.EqualError(t, err, "不能和自己比较")
+}
+
+func TestRecordService_CheckHeadToHeadAccess(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol", "Dave"})
+	alice, bob, carol, dave := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	base := time.Date(2025, 11, 1, 20, 0, 0, 0, time.Local)
+	seedSettledRoom(t, "texas", base, base.Add(time.Hour), map[uint]int{alice: 100, bob: -100})
+	seedSettledRoom(t, "texas", base.Add(24*time.Hour), base.Add(25*time.Hour), map[uint]int{alice: 30, carol: -30})
+
+	service := NewRecordService()
+
+	// 查看者是其中一方
+	require.NoError(t, service.CheckHeadToHeadAccess(dave, dave, alice))
+	require.NoError(t, service.CheckHeadToHeadAccess(alice, bob, alice))
+
+	// Carol 只和 Alice 同过房间
+	require.ErrorIs(t, service.CheckHeadToHeadAccess(carol, alice, bob), ErrHeadToHeadForbidden)
+
+	// 与 Bob 在同一俱乐部之后可以查看
+	club := models.Club{Name: "Club", InviteCode: "CLUB0001", CreatedBy: bob}
+	require.NoError(t, models.DB.Create(&club).Error)
+	require.NoError(t, models.DB.Create(&[]models.ClubMember{
+		{ClubID: club.ID, UserID: bob, Role: models.ClubRoleOwner, JoinedAt: base},
+		{ClubID: club.ID, UserID: carol, Role: models.ClubRoleMember, JoinedAt: base},
+	}).Error)
+	require.NoError(t, service.CheckHeadToHeadAccess(carol, alice, bob))
+	require.ErrorIs(t, service.CheckHeadToHeadAccess(dave, alice, bob), ErrHeadToHeadForbidden)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"sync"
+	"time"
+)
+
+// LoginGuardService 按手机号记录登录失败并逐级锁定，防止暴力破解密码
+// 连续失败 threshold 次后锁定 baseLockout，之后每次锁定时长翻倍，最长 maxLockout；
+// 距最近一次失败超过 resetAfter 后重新计算
+type LoginGuardService struct {
+	threshold   int
+	baseLockout time.Duration
+	maxLockout  time.Duration
+	resetAfter  time.Duration
+	mu          sync.Mutex
+}
+
+// NewLoginGuardService 创建登录保护服务，threshold 不大于0时不锁定
+func NewLoginGuardService(threshold int, baseLockout, maxLockout, resetAfter time.Duration) *LoginGuardService {
+	return &LoginGuardService{
+		threshold:   threshold,
+		baseLockout: baseLockout,
+		maxLockout:  maxLockout,
+		resetAfter:  resetAfter,
+	}
+}
+
+// LockedFor 返回手机号剩余的锁定时间，未锁定时返回0
+func (s *LoginGuardService) LockedFor(phone string) (time.Duration, error) {
+	lockout, err := s.find(phone)
+	if err != nil || lockout == nil || !lockout.IsLocked() {
+		return 0, err
+	}
+	return time.Until(*lockout.LockedUntil), nil
+}
+
+// RecordFailure 记录一次登录失败，本次失败触发锁定时返回锁定时长
+func (s *LoginGuardService) RecordFailure(phone, ip string) (time.Duration, error) {
+	if s.threshold <= 0 {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lockout, err := s.find(phone)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if lockout == nil {
+		lockout = &models.LoginLockout{Phone: phone}
+	} else if lockout.IsLocked() {
+		return time.Until(*lockout.LockedUntil), nil
+	} else if s.resetAfter > 0 && now.Sub(lockout.LastFailedAt) >= s.resetAfter {
+		lockout.FailedAttempts = 0
+		lockout.LockCount = 0
+	}
+
+	lockout.FailedAttempts++
+	lockout.LastFailedAt = now
+	lockout.LastFailedIP = ip
+
+	var lockedFor time.Duration
+	if lockout.FailedAttempts >= s.threshold {
+		lockout.LockCount++
+		lockout.FailedAttempts = 0
+		lockedFor = s.lockDuration(lockout.LockCount)
+		until := now.Add(lockedFor)
+		lockout.LockedUntil = &until
+	}
+
+	if err := models.DB.Save(lockout).Error; err != nil {
+		log.Printf("保存登录失败记录失败: Phone=%s, %v", phone, err)
+		return 0, err
+	}
+
+	if lockedFor > 0 {
+		log.Printf("登录失败次数过多，手机号已锁定: Phone=%s, IP=%s, 第%d次锁定, 时长=%s", phone, ip, lockout.LockCount, lockedFor)
+	}
+	return lockedFor, nil
+}
+
+// RecordSuccess 登录成功后清除手机号的失败记录
+func (s *LoginGuardService) RecordSuccess(phone string) error {
+	return models.DB.Where("phone = ?", phone).Delete(&models.LoginLockout{}).Error
+}
+
+// ListLockouts 管理员查看登录失败记录，lockedOnly 为 true 时只返回锁定中的手机号
+func (s *LoginGuardService) ListLockouts(lockedOnly bool) ([]map[string]interface{}, error) {
+	query := models.DB.Order("last_failed_at DESC")
+	if lockedOnly {
+		query = query.Where("locked_until > ?", time.Now())
+	}
+
+	var lockouts []models.LoginLockout
+	if err := query.Find(&lockouts).Error; err != nil {
+		return nil, err
+	}
+
+	phones := make([]string, 0, len(lockouts))
+	for _, lockout := range lockouts {
+		phones = append(phones, lockout.Phone)
+	}
+	users := make(map[string]models.User, len(phones))
+	if len(phones) > 0 {
+		var rows []models.User
+		if err := models.DB.Where("phone IN ?", phones).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range rows {
+			users[user.Phone] = user
+		}
+	}
+
+	result := make([]map[string]interface{}, 0, len(lockouts))
+	for _, lockout := range lockouts {
+		view := map[string]interface{}{
+			"phone":           lockout.Phone,
+			"user_id":         nil,
+			"nickname":        nil,
+			"failed_attempts": lockout.FailedAttempts,
+			"lock_count":      lockout.LockCount,
+			"locked":          lockout.IsLocked(),
+			"locked_until":    lockout.LockedUntil,
+			"last_failed_at":  lockout.LastFailedAt,
+			"last_failed_ip":  lockout.LastFailedIP,
+		}
+		if user, ok := users[lockout.Phone]; ok {
+			view["user_id"] = user.ID
+			view["nickname"] = user.Nickname
+		}
+		result = append(result, view)
+	}
+	return result, nil
+}
+
+// Unlock 管理员解除手机号的锁定并清空失败记录
+func (s *LoginGuardService) Unlock(phone string) error {
+	result := models.DB.Where("phone = ?", phone).Delete(&models.LoginLockout{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("该手机号没有登录失败记录")
+	}
+
+	log.Printf("管理员解除登录锁定: Phone=%s", phone)
+	return nil
+}
+
+// lockDuration 第 lockCount 次锁定的时长：baseLockout × 2^(lockCount-1)，不超过 maxLockout
+func (s *LoginGuardService) lockDuration(lockCount int) time.Duration {
+	duration := s.baseLockout
+	for i := 1; i < lockCount; i++ {
+		duration *= 2
+		if s.maxLockout > 0 && duration >= s.maxLockout {
+			return s.maxLockout
+		}
+	}
+	if s.maxLockout > 0 && duration > s.maxLockout {
+		return s.maxLockout
+	}
+	return duration
+}
+
+func (s *LoginGuardService) find(phone string) (*models.LoginLockout, error) {
+	var lockouts []models.LoginLockout
+	if err := models.DB.Where("phone = ?", phone).Limit(1).Find(&lockouts).Error; err != nil {
+		return nil, err
+	}
+	if len(lockouts) == 0 {
+		return nil, nil
+	}
+	return &lockouts[0], nil
+}
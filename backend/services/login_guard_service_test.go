@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func expireLockout(t *testing.T, phone string) {
+	t.Helper()
+	require.NoError(t, models.DB.Model(&models.LoginLockout{}).Where("phone = ?", phone).
+		Update("locked_until", time.Now().Add(-time.Second)).Error)
+}
+
+func TestLoginGuard_ProgressiveLockout(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Alice"})[0]
+
+	guard := NewLoginGuardService(3, time.Minute, 3*time.Minute, 24*time.Hour)
+
+	// 连续失败达到阈值后锁定，时长逐次翻倍直到上限
+	for _, expected := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		for i := 0; i < 2; i++ {
+			lockedFor, err := guard.RecordFailure(user.Phone, "10.0.0.1")
+			require.NoError(t, err)
+			require.Zero(t, lockedFor)
+		}
+		lockedFor, err := guard.RecordFailure(user.Phone, "10.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, expected, lockedFor)
+
+		remaining, err := guard.LockedFor(user.Phone)
+		require.NoError(t, err)
+		require.InDelta(t, expected.Seconds(), remaining.Seconds(), 1)
+
+		expireLockout(t, user.Phone)
+	}
+
+	lockouts, err := guard.ListLockouts(false)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	require.Equal(t, user.ID, lockouts[0]["user_id"])
+	require.Equal(t, 4, lockouts[0]["lock_count"])
+	require.Equal(t, false, lockouts[0]["locked"])
+
+	locked, err := guard.ListLockouts(true)
+	require.NoError(t, err)
+	require.Empty(t, locked)
+
+	// 距最近一次失败足够久后重新计算
+	require.NoError(t, models.DB.Model(&models.LoginLockout{}).Where("phone = ?", user.Phone).
+		Update("last_failed_at", time.Now().Add(-25*time.Hour)).Error)
+	for i := 0; i < 2; i++ {
+		_, err := guard.RecordFailure(user.Phone, "10.0.0.1")
+		require.NoError(t, err)
+	}
+	lockedFor, err := guard.RecordFailure(user.Phone, "10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, lockedFor)
+
+	// 登录成功清除记录
+	require.NoError(t, guard.RecordSuccess(user.Phone))
+	remaining, err := guard.LockedFor(user.Phone)
+	require.NoError(t, err)
+	require.Zero(t, remaining)
+	require.EqualError(t, guard.Unlock(user.Phone), "该手机号没有登录失败记录")
+}
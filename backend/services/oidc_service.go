@@ -0,0 +1,584 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"poker_score_backend/models"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	oidcHTTPTimeout      = 10 * time.Second
+	oidcMaxResponseSize  = 1 << 20     // 身份提供方响应的最大长度
+	jwksRefreshInterval  = time.Minute // 遇到未知 kid 时两次刷新 JWKS 的最小间隔
+	oidcDefaultNickname  = "企业账号用户"
+	oidcRandomTokenBytes = 32
+)
+
+// 单点登录错误
+var (
+	ErrOIDCDisabled      = errors.New("未启用单点登录")
+	ErrOIDCStateInvalid  = errors.New("登录已过期，请重新登录")
+	ErrOIDCProvider      = errors.New("身份提供方暂时不可用，请稍后再试")
+	ErrOIDCNotLinked     = errors.New("该企业账号尚未绑定本站账户，请先使用手机号登录后在个人设置中绑定")
+	ErrOIDCLinkedToOther = errors.New("该企业账号已绑定其他账户")
+)
+
+// OIDCOptions 单点登录配置，由 config.OIDCConfig 转换
+type OIDCOptions struct {
+	Issuer               string
+	ClientID             string
+	ClientSecret         string
+	RedirectURL          string
+	Scopes               []string
+	PhoneClaim           string
+	RequireVerifiedPhone bool
+	AllowSignup          bool
+	StateTTL             time.Duration
+}
+
+// OIDCService OpenID Connect 单点登录服务：授权码模式 + PKCE，
+// 通过 ID Token 中的 iss + sub 识别身份，首次登录时按已验证的手机号匹配已有账户
+type OIDCService struct {
+	options    OIDCOptions
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	provider      *oidcProvider
+	keys          []parsedJWK
+	keysFetchedAt time.Time
+}
+
+// oidcProvider 从发现文档读取的端点
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCResult 回调处理结果
+type OIDCResult struct {
+	User         *models.User
+	Linked       bool   // 本次是已登录用户发起的绑定，不需要创建Session
+	Created      bool   // 本次自动创建了新账户
+	RedirectPath string // 完成后跳转的前端路径
+}
+
+// NewOIDCService 创建单点登录服务
+func NewOIDCService(options OIDCOptions) *OIDCService {
+	return &OIDCService{
+		options:    options,
+		httpClient: &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// Enabled 是否启用单点登录
+func (s *OIDCService) Enabled() bool {
+	return s.options.Issuer != "" && s.options.ClientID != ""
+}
+
+// BeginLogin 生成跳转到身份提供方的授权地址，userID 不为0时表示为该用户绑定身份
+// 返回授权地址与回调时需要校验的 state
+func (s *OIDCService) BeginLogin(userID uint, redirectPath string) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+
+	provider, err := s.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomURLToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomURLToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomURLToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	// 顺便清理过期的登录状态
+	models.DB.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{})
+
+	record := models.OIDCLoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		UserID:       userID,
+		RedirectPath: sanitizeRedirectPath(redirectPath),
+		ExpiresAt:    time.Now().Add(s.options.StateTTL),
+	}
+	if err := models.DB.Create(&record).Error; err != nil {
+		log.Printf("保存单点登录状态失败: %v", err)
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.options.ClientID},
+		"redirect_uri":          {s.options.RedirectURL},
+		"scope":                 {strings.Join(s.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// ConsumeState 取出并删除回调携带的登录状态，每个 state 只能使用一次
+func (s *OIDCService) ConsumeState(state string) (*models.OIDCLoginState, error) {
+	if state == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	var record models.OIDCLoginState
+	if err := models.DB.Where("state = ?", state).Limit(1).Find(&record).Error; err != nil {
+		return nil, err
+	}
+	if record.ID == 0 {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	result := models.DB.Delete(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || record.IsExpired() {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &record, nil
+}
+
+// CompleteLogin 用授权码换取 ID Token 并校验，然后找到（或绑定、创建）对应的账户
+func (s *OIDCService) CompleteLogin(record *models.OIDCLoginState, code string) (*OIDCResult, error) {
+	if !s.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+	if code == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	provider, err := s.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.exchangeCode(provider, code, record.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := s.signingKeys(provider, idTokenKeyID(rawIDToken))
+	if err != nil {
+		return nil, err
+	}
+	claims, err := verifyIDToken(rawIDToken, keys, provider.Issuer, s.options.ClientID, record.Nonce, time.Now())
+	if err != nil {
+		log.Printf("ID Token 校验失败: %v", err)
+		return nil, err
+	}
+
+	result := &OIDCResult{RedirectPath: record.RedirectPath}
+	if record.UserID != 0 {
+		user, err := s.linkIdentity(record.UserID, claims)
+		if err != nil {
+			return nil, err
+		}
+		result.User = user
+		result.Linked = true
+		return result, nil
+	}
+
+	user, created, err := s.resolveUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	result.User = user
+	result.Created = created
+	return result, nil
+}
+
+// ListIdentities 列出用户绑定的单点登录身份
+func (s *OIDCService) ListIdentities(userID uint) ([]models.OIDCIdentity, error) {
+	identities := make([]models.OIDCIdentity, 0)
+	if err := models.DB.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Unlink 解除绑定；账户没有密码时至少保留一个身份，避免无法登录
+func (s *OIDCService) Unlink(userID, identityID uint) error {
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.OIDCIdentity
+		if err := tx.Where("id = ? AND user_id = ?", identityID, userID).Limit(1).Find(&identity).Error; err != nil {
+			return err
+		}
+		if identity.ID == 0 {
+			return errors.New("绑定记录不存在")
+		}
+
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return errors.New("用户不存在")
+		}
+		if user.PasswordHash == "" {
+			var count int64
+			if err := tx.Model(&models.OIDCIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count <= 1 {
+				return errors.New("账户还没有设置密码，解除绑定后将无法登录，请先通过忘记密码设置密码")
+			}
+		}
+
+		if err := tx.Delete(&identity).Error; err != nil {
+			return err
+		}
+		log.Printf("解除单点登录绑定: UserID=%d, Subject=%s", userID, identity.Subject)
+		return nil
+	})
+}
+
+// resolveUser 按 iss + sub 找到已绑定的账户；没有绑定时按已验证的手机号匹配已有账户，
+// 仍然没有且允许注册时创建新账户（没有密码，可通过忘记密码设置）
+func (s *OIDCService) resolveUser(claims *idTokenClaims) (*models.User, bool, error) {
+	var user models.User
+	created := false
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.OIDCIdentity
+		if err := tx.Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).Limit(1).Find(&identity).Error; err != nil {
+			return err
+		}
+		if identity.ID != 0 {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return errors.New("用户不存在")
+			}
+			return touchIdentity(tx, &identity, claims)
+		}
+
+		phone := s.verifiedPhone(claims)
+		if phone != "" {
+			if err := tx.Where("phone = ? AND role NOT IN ?", phone, []string{models.RoleGuest, models.RoleDeleted}).
+				Limit(1).Find(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		if user.ID == 0 {
+			if !s.options.AllowSignup {
+				return ErrOIDCNotLinked
+			}
+			if phone == "" {
+				return errors.New("企业账号没有提供已验证的手机号，无法创建账户")
+			}
+			var count int64
+			if err := tx.Model(&models.User{}).Where("phone = ?", phone).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return errors.New("手机号已注册")
+			}
+
+			user = models.User{
+				Phone:    phone,
+				Nickname: oidcNickname(claims),
+				Role:     "user",
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			created = true
+		}
+
+		identity = models.OIDCIdentity{
+			UserID:  user.ID,
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+		}
+		return touchIdentity(tx, &identity, claims)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		log.Printf("通过单点登录创建账户: ID=%d, Phone=%s, Subject=%s", user.ID, user.Phone, claims.Subject)
+	}
+	return &user, created, nil
+}
+
+// linkIdentity 把身份绑定到已登录的用户
+func (s *OIDCService) linkIdentity(userID uint, claims *idTokenClaims) (*models.User, error) {
+	var user models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil || user.IsGuest() || user.IsDeleted() {
+			return errors.New("用户不存在")
+		}
+
+		var identity models.OIDCIdentity
+		if err := tx.Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).Limit(1).Find(&identity).Error; err != nil {
+			return err
+		}
+		if identity.ID != 0 && identity.UserID != userID {
+			return ErrOIDCLinkedToOther
+		}
+		if identity.ID == 0 {
+			identity = models.OIDCIdentity{
+				UserID:  userID,
+				Issuer:  claims.Issuer,
+				Subject: claims.Subject,
+			}
+		}
+		return touchIdentity(tx, &identity, claims)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("绑定单点登录身份: UserID=%d, Subject=%s", userID, claims.Subject)
+	return &user, nil
+}
+
+// touchIdentity 保存身份并记录最近一次登录的时间、邮箱与名称
+func touchIdentity(tx *gorm.DB, identity *models.OIDCIdentity, claims *idTokenClaims) error {
+	now := time.Now()
+	identity.Email = truncateString(claimString(claims.Raw, "email"), 255)
+	identity.DisplayName = truncateString(oidcDisplayName(claims), 100)
+	identity.LastLoginAt = &now
+	return tx.Save(identity).Error
+}
+
+// verifiedPhone 读取配置的手机号声明并规范为11位手机号，未通过校验时返回空
+func (s *OIDCService) verifiedPhone(claims *idTokenClaims) string {
+	claim := s.options.PhoneClaim
+	if claim == "" {
+		return ""
+	}
+	if s.options.RequireVerifiedPhone && !claimBool(claims.Raw, claim+"_verified") {
+		return ""
+	}
+	return normalizeOIDCPhone(claimString(claims.Raw, claim))
+}
+
+// normalizeOIDCPhone 去掉空格、短横线与 +86 前缀，不是11位数字时返回空
+func normalizeOIDCPhone(value string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+	digits = strings.TrimPrefix(digits, "+86")
+	if len(digits) == 13 && strings.HasPrefix(digits, "86") {
+		digits = digits[2:]
+	}
+	if len(digits) != 11 {
+		return ""
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return digits
+}
+
+func oidcDisplayName(claims *idTokenClaims) string {
+	for _, name := range []string{"name", "preferred_username", "nickname"} {
+		if value := strings.TrimSpace(claimString(claims.Raw, name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// oidcNickname 新账户的昵称，优先使用身份提供方中的名称
+func oidcNickname(claims *idTokenClaims) string {
+	name := oidcDisplayName(claims)
+	if name == "" {
+		return oidcDefaultNickname
+	}
+	if utf8.RuneCountInString(name) > 50 {
+		name = string([]rune(name)[:50])
+	}
+	return name
+}
+
+// sanitizeRedirectPath 只允许跳转到本站的相对路径，防止开放重定向
+func sanitizeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	if len(path) > 255 {
+		return "/"
+	}
+	return path
+}
+
+func (s *OIDCService) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range s.options.Scopes {
+		if scope != "" && scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// discover 读取并缓存发现文档，发现文档中的 issuer 必须与配置一致
+func (s *OIDCService) discover() (*oidcProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider != nil {
+		return s.provider, nil
+	}
+
+	var provider oidcProvider
+	if err := s.getJSON(s.options.Issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		log.Printf("读取单点登录发现文档失败: Issuer=%s, %v", s.options.Issuer, err)
+		return nil, ErrOIDCProvider
+	}
+	if strings.TrimRight(provider.Issuer, "/") != s.options.Issuer ||
+		provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		log.Printf("单点登录发现文档不完整或 issuer 不一致: %+v", provider)
+		return nil, ErrOIDCProvider
+	}
+
+	s.provider = &provider
+	return s.provider, nil
+}
+
+// signingKeys 返回缓存的签名公钥，ID Token 使用了未知的 kid 时刷新（身份提供方轮换了密钥）
+func (s *OIDCService) signingKeys(provider *oidcProvider, kid string) ([]parsedJWK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := s.keys != nil
+	if known && kid != "" {
+		known = false
+		for _, key := range s.keys {
+			if key.kid == kid {
+				known = true
+				break
+			}
+		}
+	}
+	if known || (s.keys != nil && time.Since(s.keysFetchedAt) < jwksRefreshInterval) {
+		return s.keys, nil
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(provider.JWKSURI, &document); err != nil {
+		log.Printf("读取单点登录签名公钥失败: %v", err)
+		return nil, ErrOIDCProvider
+	}
+
+	s.keys = parseJWKS(document.Keys)
+	s.keysFetchedAt = time.Now()
+	return s.keys, nil
+}
+
+// exchangeCode 用授权码与 PKCE code_verifier 换取 ID Token
+func (s *OIDCService) exchangeCode(provider *oidcProvider, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.options.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {s.options.ClientID},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.options.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.options.ClientID), url.QueryEscape(s.options.ClientSecret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("单点登录换取令牌失败: %v", err)
+		return "", ErrOIDCProvider
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return "", ErrOIDCProvider
+	}
+	if err := json.Unmarshal(body, &token); err != nil || resp.StatusCode != http.StatusOK {
+		log.Printf("单点登录换取令牌失败: Status=%d, Error=%s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+		return "", errors.New("身份提供方拒绝了登录请求，请重新登录")
+	}
+	if token.IDToken == "" {
+		return "", ErrInvalidIDToken
+	}
+	return token.IDToken, nil
+}
+
+func (s *OIDCService) getJSON(endpoint string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(out)
+}
+
+// randomURLToken 生成 state、nonce 与 PKCE code_verifier 使用的随机字符串（43个字符）
+func randomURLToken() (string, error) {
+	buf := make([]byte, oidcRandomTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
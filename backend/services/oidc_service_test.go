@@ -0,0 +1,161 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signES256 生成 ES256 签名的测试 ID Token
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDC_VerifyIDToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keys := parseJWKS([]jsonWebKey{{
+		Kty: "EC",
+		Kid: "k1",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}})
+	require.Len(t, keys, 1)
+
+	now := time.Now()
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   "https://sso.example.com",
+			"sub":   "u1",
+			"aud":   "poker",
+			"exp":   now.Add(time.Minute).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "n1",
+		}
+	}
+
+	claims, err := verifyIDToken(signES256(t, key, "k1", validClaims()), keys, "https://sso.example.com", "poker", "n1", now)
+	require.NoError(t, err)
+	require.Equal(t, "u1", claims.Subject)
+
+	cases := map[string]func(map[string]interface{}){
+		"wrong issuer":      func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" },
+		"wrong audience":    func(c map[string]interface{}) { c["aud"] = "other" },
+		"missing azp":       func(c map[string]interface{}) { c["aud"] = []string{"poker", "other"} },
+		"expired":           func(c map[string]interface{}) { c["exp"] = now.Add(-2 * time.Minute).Unix() },
+		"issued in future":  func(c map[string]interface{}) { c["iat"] = now.Add(time.Hour).Unix() },
+		"wrong nonce":       func(c map[string]interface{}) { c["nonce"] = "n2" },
+		"missing subject":   func(c map[string]interface{}) { delete(c, "sub") },
+		"missing exp claim": func(c map[string]interface{}) { delete(c, "exp") },
+	}
+	for name, mutate := range cases {
+		c := validClaims()
+		mutate(c)
+		_, err := verifyIDToken(signES256(t, key, "k1", c), keys, "https://sso.example.com", "poker", "n1", now)
+		require.ErrorIs(t, err, ErrInvalidIDToken, name)
+	}
+
+	// 多个受众且 azp 为本客户端时有效
+	c := validClaims()
+	c["aud"] = []string{"poker", "other"}
+	c["azp"] = "poker"
+	_, err = verifyIDToken(signES256(t, key, "k1", c), keys, "https://sso.example.com", "poker", "n1", now)
+	require.NoError(t, err)
+
+	// 其他密钥签名、篡改内容与 alg=none 都无效
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = verifyIDToken(signES256(t, otherKey, "k1", validClaims()), keys, "https://sso.example.com", "poker", "n1", now)
+	require.ErrorIs(t, err, ErrInvalidIDToken)
+
+	token := signES256(t, key, "k1", validClaims())
+	tampered := validClaims()
+	tampered["sub"] = "admin"
+	payload, err := json.Marshal(tampered)
+	require.NoError(t, err)
+	header := token[:strings.Index(token, ".")]
+	signature := token[strings.LastIndex(token, "."):]
+	_, err = verifyIDToken(header+"."+base64.RawURLEncoding.EncodeToString(payload)+signature, keys, "https://sso.example.com", "poker", "n1", now)
+	require.ErrorIs(t, err, ErrInvalidIDToken)
+
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	_, err = verifyIDToken(noneHeader+"."+base64.RawURLEncoding.EncodeToString(payload)+".", keys, "https://sso.example.com", "poker", "n1", now)
+	require.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestOIDC_ResolveUserSignup(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice"})
+
+	service := NewOIDCService(OIDCOptions{
+		Issuer:               "https://sso.example.com",
+		ClientID:             "poker",
+		PhoneClaim:           "mobile",
+		RequireVerifiedPhone: false,
+		AllowSignup:          true,
+	})
+	identity := func(sub string, raw map[string]interface{}) *idTokenClaims {
+		raw["sub"] = sub
+		return &idTokenClaims{Issuer: "https://sso.example.com", Subject: sub, Raw: raw}
+	}
+
+	// 自定义的手机号声明，不要求 _verified 时直接匹配已有账户
+	user, created, err := service.resolveUser(identity("alice", map[string]interface{}{"mobile": "86" + users[0].Phone}))
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, users[0].ID, user.ID)
+
+	// 没有匹配的账户时创建新账户，没有密码
+	user, created, err = service.resolveUser(identity("bob", map[string]interface{}{"mobile": "138-0000-0001", "name": "Bob"}))
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, "13800000001", user.Phone)
+	require.Equal(t, "Bob", user.Nickname)
+	require.Empty(t, user.PasswordHash)
+
+	again, created, err := service.resolveUser(identity("bob", map[string]interface{}{}))
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, user.ID, again.ID)
+
+	_, _, err = service.resolveUser(identity("carol", map[string]interface{}{}))
+	require.EqualError(t, err, "企业账号没有提供已验证的手机号，无法创建账户")
+
+	// 没有密码的账户不能解除唯一的绑定
+	var bobIdentity models.OIDCIdentity
+	require.NoError(t, models.DB.Where("subject = ?", "bob").First(&bobIdentity).Error)
+	require.Error(t, service.Unlink(user.ID, bobIdentity.ID))
+
+	// 合并账户时身份随之转移
+	_, err = NewAdminService(nil).MergeUsers(user.ID, users[0].ID)
+	require.NoError(t, err)
+	var count int64
+	models.DB.Model(&models.OIDCIdentity{}).Where("user_id = ?", users[0].ID).Count(&count)
+	require.Equal(t, int64(2), count)
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// idTokenClockSkew 校验 ID Token 时间时允许的时钟误差
+const idTokenClockSkew = time.Minute
+
+// ErrInvalidIDToken ID Token 无效（签名、签发方、受众、有效期或 nonce 校验失败）
+var ErrInvalidIDToken = errors.New("身份提供方返回的登录凭证无效")
+
+// jsonWebKey JWKS 中的一个公钥，只使用签名相关的字段
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// idTokenClaims ID Token 中校验与账户绑定用到的声明，其余声明保留在 Raw 中
+type idTokenClaims struct {
+	Issuer   string
+	Subject  string
+	Audience []string
+	AZP      string
+	Nonce    string
+	Expiry   time.Time
+	IssuedAt time.Time
+	Raw      map[string]interface{}
+}
+
+// signingAlgorithms 支持的 ID Token 签名算法
+var signingAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// parseJWKS 解析 JWKS 中用于签名的 RSA 与 EC 公钥，不认识的密钥直接跳过
+func parseJWKS(keys []jsonWebKey) []parsedJWK {
+	parsed := make([]parsedJWK, 0, len(keys))
+	for _, key := range keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, parsedJWK{kid: key.Kid, alg: key.Alg, key: publicKey})
+	}
+	return parsed
+}
+
+// parsedJWK 解析后的公钥
+type parsedJWK struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// idTokenKeyID 读取 ID Token 头部的 kid，用于判断是否需要刷新 JWKS
+func idTokenKeyID(rawToken string) string {
+	header, _, _, _, err := splitJWT(rawToken)
+	if err != nil {
+		return ""
+	}
+	return header.Kid
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func splitJWT(rawToken string) (jwtHeader, []byte, []byte, string, error) {
+	var header jwtHeader
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return header, nil, nil, "", errors.New("malformed JWT")
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, nil, "", err
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return header, nil, nil, "", err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, nil, "", err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, nil, nil, "", err
+	}
+	return header, payload, signature, parts[0] + "." + parts[1], nil
+}
+
+// verifyIDToken 校验 ID Token 的签名与声明：签发方、受众（多个受众时还要求 azp 为本客户端）、
+// 有效期、签发时间与 nonce，返回解析出的声明
+func verifyIDToken(rawToken string, keys []parsedJWK, issuer, clientID, nonce string, now time.Time) (*idTokenClaims, error) {
+	header, payload, signature, signingInput, err := splitJWT(rawToken)
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	hash, ok := signingAlgorithms[header.Alg]
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	verified := false
+	for _, key := range keys {
+		if header.Kid != "" && key.kid != "" && key.kid != header.Kid {
+			continue
+		}
+		if key.alg != "" && key.alg != header.Alg {
+			continue
+		}
+		if verifyJWTSignature(header.Alg, hash, key.key, signingInput, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidIDToken
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	claims := &idTokenClaims{
+		Issuer:   claimString(raw, "iss"),
+		Subject:  claimString(raw, "sub"),
+		AZP:      claimString(raw, "azp"),
+		Nonce:    claimString(raw, "nonce"),
+		Expiry:   claimTime(raw, "exp"),
+		IssuedAt: claimTime(raw, "iat"),
+		Raw:      raw,
+	}
+	switch aud := raw["aud"].(type) {
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, item := range aud {
+			if value, ok := item.(string); ok {
+				claims.Audience = append(claims.Audience, value)
+			}
+		}
+	}
+
+	if claims.Issuer != issuer || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+	if !containsString(claims.Audience, clientID) {
+		return nil, ErrInvalidIDToken
+	}
+	if (len(claims.Audience) > 1 || claims.AZP != "") && claims.AZP != clientID {
+		return nil, ErrInvalidIDToken
+	}
+	if claims.Expiry.IsZero() || !now.Before(claims.Expiry.Add(idTokenClockSkew)) {
+		return nil, ErrInvalidIDToken
+	}
+	if claims.IssuedAt.IsZero() || claims.IssuedAt.After(now.Add(idTokenClockSkew)) {
+		return nil, ErrInvalidIDToken
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+	return claims, nil
+}
+
+func verifyJWTSignature(alg string, hash crypto.Hash, key crypto.PublicKey, signingInput string, signature []byte) bool {
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return false
+		}
+		// JWS 中 ECDSA 签名是定长的 r||s
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(publicKey, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func claimString(raw map[string]interface{}, name string) string {
+	value, _ := raw[name].(string)
+	return value
+}
+
+// claimBool 读取布尔声明，部分身份提供方会把布尔值写成字符串
+func claimBool(raw map[string]interface{}, name string) bool {
+	switch value := raw[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	default:
+		return false
+	}
+}
+
+func claimTime(raw map[string]interface{}, name string) time.Time {
+	value, ok := raw[name].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(value), 0)
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
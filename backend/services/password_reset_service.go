@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetService 通过短信验证码找回密码
+type PasswordResetService struct {
+	authService    *AuthService
+	sender         SMSSender
+	codeTTL        time.Duration // 验证码有效期
+	maxAttempts    int           // 每个验证码允许验证失败的次数
+	resendInterval time.Duration // 同一手机号两次发送验证码的最小间隔
+}
+
+// NewPasswordResetService 创建找回密码服务
+func NewPasswordResetService(authService *AuthService, sender SMSSender, codeTTL time.Duration, maxAttempts int, resendInterval time.Duration) *PasswordResetService {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &PasswordResetService{
+		authService:    authService,
+		sender:         sender,
+		codeTTL:        codeTTL,
+		maxAttempts:    maxAttempts,
+		resendInterval: resendInterval,
+	}
+}
+
+// RequestCode 向手机号发送找回密码的验证码
+// 手机号未注册或处于重发冷却期时不发送短信但同样返回成功，避免通过该接口探测手机号是否注册
+// 重新发送会使之前未使用的验证码作废
+func (s *PasswordResetService) RequestCode(phone string) error {
+	var users []models.User
+	if err := models.DB.Where("phone = ? AND role <> ?", phone, models.RoleGuest).Limit(1).Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		log.Printf("找回密码: 手机号未注册，不发送验证码: Phone=%s", phone)
+		return nil
+	}
+
+	latest, err := latestVerificationCode(phone, models.VerificationPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.resendInterval {
+		log.Printf("找回密码: 验证码发送过于频繁，不重复发送: UserID=%d", users[0].ID)
+		return nil
+	}
+
+	code, record, err := issueVerificationCode(phone, models.VerificationPurposeResetPassword, 0, s.codeTTL)
+	if err != nil {
+		log.Printf("保存验证码失败: Phone=%s, %v", phone, err)
+		return err
+	}
+
+	if err := s.sender.SendCode(phone, code, models.VerificationPurposeResetPassword); err != nil {
+		log.Printf("发送验证码失败: Phone=%s, %v", phone, err)
+		models.DB.Delete(record)
+		return errors.New("验证码发送失败，请稍后再试")
+	}
+
+	log.Printf("找回密码验证码已发送: UserID=%d", users[0].ID)
+	return nil
+}
+
+// ResetPassword 校验验证码并设置新密码，成功后注销该用户的所有Session并解除登录锁定
+func (s *PasswordResetService) ResetPassword(phone, code, newPassword string) error {
+	record, err := latestVerificationCode(phone, models.VerificationPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+	if err := checkVerificationCode(record, phone, code, s.maxAttempts); err != nil {
+		return err
+	}
+
+	passwordHash, err := utils.HashPassword(newPassword)
+	if err != nil {
+		log.Printf("密码加密失败: %v", err)
+		return err
+	}
+
+	var user models.User
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := consumeVerificationCode(tx, record, s.maxAttempts); err != nil {
+			return err
+		}
+
+		var users []models.User
+		if err := tx.Where("phone = ?", phone).Limit(1).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return errors.New("用户不存在")
+		}
+		user = users[0]
+
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("password_hash", passwordHash).Error; err != nil {
+			return err
+		}
+		// 已证明手机号归属，同时解除登录锁定
+		return tx.Where("phone = ?", phone).Delete(&models.LoginLockout{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("通过验证码重置密码成功: UserID=%d", user.ID)
+
+	if _, err := s.authService.LogoutAll(user.ID); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+type capturedSMS struct {
+	Phone   string
+	Code    string
+	Purpose string
+}
+
+type captureSMSSender struct {
+	sent []capturedSMS
+}
+
+func (s *captureSMSSender) SendCode(phone, code, purpose string) error {
+	s.sent = append(s.sent, capturedSMS{Phone: phone, Code: code, Purpose: purpose})
+	return nil
+}
+
+func (s *captureSMSSender) lastCode(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, s.sent)
+	return s.sent[len(s.sent)-1].Code
+}
+
+func TestPasswordReset_RequestAndReset(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Alice"})[0]
+
+	authService := NewAuthService(24*time.Hour, time.Hour, time.Minute)
+	sender := &captureSMSSender{}
+	service := NewPasswordResetService(authService, sender, 10*time.Minute, 5, time.Minute)
+
+	_, err := authService.CreateSession(user.ID, SessionClient{})
+	require.NoError(t, err)
+
+	// 未注册的手机号：同样返回成功，但不发送短信
+	require.NoError(t, service.RequestCode("13700000000"))
+	require.Empty(t, sender.sent)
+
+	require.NoError(t, service.RequestCode(user.Phone))
+	require.Len(t, sender.sent, 1)
+	require.Equal(t, user.Phone, sender.sent[0].Phone)
+	require.Equal(t, models.VerificationPurposeResetPassword, sender.sent[0].Purpose)
+	require.Regexp(t, `^\d{6}$`, sender.sent[0].Code)
+
+	// 数据库中不保存明文
+	var stored models.VerificationCode
+	require.NoError(t, models.DB.Where("phone = ?", user.Phone).First(&stored).Error)
+	require.NotEqual(t, sender.sent[0].Code, stored.CodeHash)
+
+	// 冷却期内不重复发送，但与未注册的手机号一样返回成功
+	require.NoError(t, service.RequestCode(user.Phone))
+	require.Len(t, sender.sent, 1)
+
+	require.NoError(t, service.ResetPassword(user.Phone, sender.lastCode(t), "newpass123"))
+
+	var updated models.User
+	require.NoError(t, models.DB.First(&updated, user.ID).Error)
+	require.True(t, utils.CheckPassword("newpass123", updated.PasswordHash))
+
+	var sessions int64
+	require.NoError(t, models.DB.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&sessions).Error)
+	require.Zero(t, sessions)
+
+	// 验证码只能使用一次
+	require.EqualError(t, service.ResetPassword(user.Phone, sender.lastCode(t), "another123"), "验证码无效或已过期，请重新获取")
+}
+
+func TestPasswordReset_AttemptLimitAndExpiry(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Bob"})[0]
+
+	sender := &captureSMSSender{}
+	service := NewPasswordResetService(NewAuthService(24*time.Hour, time.Hour, time.Minute), sender, 10*time.Minute, 3, 0)
+
+	require.NoError(t, service.RequestCode(user.Phone))
+	code := sender.lastCode(t)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	require.EqualError(t, service.ResetPassword(user.Phone, wrong, "newpass123"), "验证码错误，还可尝试2次")
+	require.EqualError(t, service.ResetPassword(user.Phone, wrong, "newpass123"), "验证码错误，还可尝试1次")
+	require.EqualError(t, service.ResetPassword(user.Phone, wrong, "newpass123"), "验证码错误次数过多，请重新获取")
+	// 达到上限后正确的验证码也不再有效
+	require.EqualError(t, service.ResetPassword(user.Phone, code, "newpass123"), "验证码错误次数过多，请重新获取")
+
+	// 重新获取后旧验证码作废
+	require.NoError(t, service.RequestCode(user.Phone))
+	require.NoError(t, service.RequestCode(user.Phone))
+	require.Len(t, sender.sent, 3)
+	stale := sender.sent[1].Code
+	if stale != sender.lastCode(t) {
+		require.Error(t, service.ResetPassword(user.Phone, stale, "newpass123"))
+	}
+
+	// 过期的验证码无效
+	require.NoError(t, models.DB.Model(&models.VerificationCode{}).Where("phone = ? AND consumed_at IS NULL", user.Phone).
+		Update("expires_at", time.Now().Add(-time.Second)).Error)
+	require.EqualError(t, service.ResetPassword(user.Phone, sender.lastCode(t), "newpass123"), "验证码无效或已过期，请重新获取")
+}
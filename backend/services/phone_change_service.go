@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PhoneChangeService 通过新手机号的短信验证码修改登录手机号
+type PhoneChangeService struct {
+	authService    *AuthService
+	sender         SMSSender
+	codeTTL        time.Duration // 验证码有效期
+	maxAttempts    int           // 每个验证码允许验证失败的次数
+	resendInterval time.Duration // 同一手机号两次发送验证码的最小间隔
+}
+
+// NewPhoneChangeService 创建修改手机号服务，验证码规则与找回密码一致
+func NewPhoneChangeService(authService *AuthService, sender SMSSender, codeTTL time.Duration, maxAttempts int, resendInterval time.Duration) *PhoneChangeService {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &PhoneChangeService{
+		authService:    authService,
+		sender:         sender,
+		codeTTL:        codeTTL,
+		maxAttempts:    maxAttempts,
+		resendInterval: resendInterval,
+	}
+}
+
+// RequestCode 向新手机号发送验证码，验证码只能由申请的用户使用
+func (s *PhoneChangeService) RequestCode(userID uint, newPhone string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkNewPhone(models.DB, user, newPhone); err != nil {
+		return err
+	}
+
+	latest, err := latestVerificationCode(newPhone, models.VerificationPurposeChangePhone)
+	if err != nil {
+		return err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.resendInterval {
+		return errors.New("验证码发送过于频繁，请稍后再试")
+	}
+
+	code, record, err := issueVerificationCode(newPhone, models.VerificationPurposeChangePhone, userID, s.codeTTL)
+	if err != nil {
+		log.Printf("保存验证码失败: Phone=%s, %v", newPhone, err)
+		return err
+	}
+
+	if err := s.sender.SendCode(newPhone, code, models.VerificationPurposeChangePhone); err != nil {
+		log.Printf("发送验证码失败: Phone=%s, %v", newPhone, err)
+		models.DB.Delete(record)
+		return errors.New("验证码发送失败，请稍后再试")
+	}
+
+	log.Printf("修改手机号验证码已发送: UserID=%d", userID)
+	return nil
+}
+
+// ChangePhone 校验当前密码与新手机号的验证码后修改手机号，其他设备的登录随之失效
+func (s *PhoneChangeService) ChangePhone(userID uint, currentSessionID, newPhone, code, password string) (*models.User, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.CheckPassword(password, user.PasswordHash) {
+		return nil, errors.New("当前密码错误")
+	}
+
+	record, err := latestVerificationCode(newPhone, models.VerificationPurposeChangePhone)
+	if err != nil {
+		return nil, err
+	}
+	// 其他用户申请的验证码视同不存在
+	if record != nil && record.UserID != userID {
+		record = nil
+	}
+	if err := checkVerificationCode(record, newPhone, code, s.maxAttempts); err != nil {
+		return nil, err
+	}
+
+	oldPhone := user.Phone
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := consumeVerificationCode(tx, record, s.maxAttempts); err != nil {
+			return err
+		}
+		// 发送验证码后手机号可能已被他人注册
+		if err := s.checkNewPhone(tx, user, newPhone); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("phone", newPhone).Error
+	})
+	if err != nil {
+		log.Printf("修改手机号失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+	user.Phone = newPhone
+
+	log.Printf("手机号修改成功: UserID=%d, %s -> %s", userID, oldPhone, newPhone)
+
+	if _, err := s.authService.revokeSessions(userID, currentSessionID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// findUser 查找可以修改手机号的用户，游客没有手机号
+func (s *PhoneChangeService) findUser(userID uint) (*models.User, error) {
+	var users []models.User
+	if err := models.DB.Where("id = ?", userID).Limit(1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 || users[0].IsGuest() {
+		return nil, errors.New("用户不存在")
+	}
+	return &users[0], nil
+}
+
+// checkNewPhone 新手机号必须与当前不同且未被其他账户使用
+func (s *PhoneChangeService) checkNewPhone(db *gorm.DB, user *models.User, newPhone string) error {
+	if newPhone == user.Phone {
+		return errors.New("新手机号与当前手机号相同")
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("phone = ?", newPhone).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该手机号已注册，如需合并两个账户请联系管理员")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneChange_RequestAndChange(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0], users[1]
+
+	passwordHash, err := utils.HashPassword("secret123")
+	require.NoError(t, err)
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", alice.ID).Update("password_hash", passwordHash).Error)
+
+	authService := NewAuthService(24*time.Hour, time.Hour, time.Minute)
+	sender := &captureSMSSender{}
+	service := NewPhoneChangeService(authService, sender, 10*time.Minute, 5, time.Minute)
+
+	current, err := authService.CreateSession(alice.ID, SessionClient{})
+	require.NoError(t, err)
+	other, err := authService.CreateSession(alice.ID, SessionClient{})
+	require.NoError(t, err)
+
+	require.EqualError(t, service.RequestCode(alice.ID, alice.Phone), "新手机号与当前手机号相同")
+	require.EqualError(t, service.RequestCode(alice.ID, bob.Phone), "该手机号已注册，如需合并两个账户请联系管理员")
+	require.Empty(t, sender.sent)
+
+	const newPhone = "13600000000"
+	require.NoError(t, service.RequestCode(alice.ID, newPhone))
+	require.Len(t, sender.sent, 1)
+	require.Equal(t, newPhone, sender.sent[0].Phone)
+	require.Equal(t, models.VerificationPurposeChangePhone, sender.sent[0].Purpose)
+	code := sender.lastCode(t)
+
+	// 验证码只能由申请的用户使用
+	_, err = service.ChangePhone(bob.ID, "", newPhone, code, "hash")
+	require.EqualError(t, err, "当前密码错误")
+	_, err = service.ChangePhone(alice.ID, current.SessionID, newPhone, code, "wrong")
+	require.EqualError(t, err, "当前密码错误")
+
+	user, err := service.ChangePhone(alice.ID, current.SessionID, newPhone, code, "secret123")
+	require.NoError(t, err)
+	require.Equal(t, newPhone, user.Phone)
+
+	var stored models.User
+	require.NoError(t, models.DB.First(&stored, alice.ID).Error)
+	require.Equal(t, newPhone, stored.Phone)
+
+	// 当前设备保持登录，其他设备失效
+	_, _, err = authService.AuthenticateSession(current.SessionID, SessionClient{})
+	require.NoError(t, err)
+	_, _, err = authService.AuthenticateSession(other.SessionID, SessionClient{})
+	require.Error(t, err)
+
+	// 验证码只能使用一次
+	_, err = service.ChangePhone(alice.ID, current.SessionID, newPhone, code, "secret123")
+	require.EqualError(t, err, "验证码无效或已过期，请重新获取")
+}
+
+func TestPhoneChange_CodeBoundToRequester(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0], users[1]
+
+	passwordHash, err := utils.HashPassword("secret123")
+	require.NoError(t, err)
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id IN ?", []uint{alice.ID, bob.ID}).Update("password_hash", passwordHash).Error)
+
+	sender := &captureSMSSender{}
+	service := NewPhoneChangeService(NewAuthService(24*time.Hour, time.Hour, time.Minute), sender, 10*time.Minute, 5, 0)
+
+	const newPhone = "13600000000"
+	require.NoError(t, service.RequestCode(alice.ID, newPhone))
+	code := sender.lastCode(t)
+
+	_, err = service.ChangePhone(bob.ID, "", newPhone, code, "secret123")
+	require.EqualError(t, err, "验证码无效或已过期，请重新获取")
+
+	// 发送验证码后手机号被他人占用
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", bob.ID).Update("phone", newPhone).Error)
+	_, err = service.ChangePhone(alice.ID, "", newPhone, code, "secret123")
+	require.EqualError(t, err, "该手机号已注册，如需合并两个账户请联系管理员")
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"log"
+	"poker_score_backend/models"
+	ws "poker_score_backend/websocket"
+	"sync"
+	"time"
+)
+
+// 在线状态
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// PresenceService 在线状态服务
+// 按房间和用户统计实时连接（WebSocket/SSE）数量，全部断开后先进入暂离，
+// 超时仍未重连才标记为离线，从而过滤刷新页面、切换网络等短暂断线
+type PresenceService struct {
+	hub          *ws.Hub
+	awayAfter    time.Duration
+	offlineAfter time.Duration
+
+	mu       sync.Mutex
+	entries  map[presenceKey]*presenceEntry
+	stopped  bool           // Stop 之后不再处理连接变化，计时器也不再触发
+	inflight sync.WaitGroup // 正在写数据库或广播的调用，Stop 会等待它们结束
+}
+
+type presenceKey struct {
+	RoomID uint
+	UserID uint
+}
+
+type presenceEntry struct {
+	connections int
+	state       string
+	lastSeen    time.Time
+	generation  uint64
+	timer       *time.Timer
+}
+
+// PresenceInfo 用户在房间内的在线状态
+type PresenceInfo struct {
+	Presence   string
+	LastSeenAt *time.Time
+}
+
+// NewPresenceService 创建在线状态服务
+func NewPresenceService(hub *ws.Hub, awayAfter, offlineAfter time.Duration) *PresenceService {
+	if offlineAfter < awayAfter {
+		offlineAfter = awayAfter
+	}
+
+	return &PresenceService{
+		hub:          hub,
+		awayAfter:    awayAfter,
+		offlineAfter: offlineAfter,
+		entries:      make(map[presenceKey]*presenceEntry),
+	}
+}
+
+// Connect 记录用户建立了一条实时连接
+func (s *PresenceService) Connect(roomID, userID uint) {
+	key := presenceKey{RoomID: roomID, UserID: userID}
+	now := time.Now()
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &presenceEntry{state: PresenceOffline}
+		s.entries[key] = entry
+	}
+
+	entry.connections++
+	entry.generation++
+	entry.lastSeen = now
+	if entry.timer != nil {
+		entry.timer.Stop()
+		entry.timer = nil
+	}
+
+	changed := entry.state != PresenceOnline
+	entry.state = PresenceOnline
+	s.mu.Unlock()
+
+	s.persistLastSeen(roomID, userID, now)
+
+	if changed {
+		s.broadcastPresence(roomID, userID, PresenceOnline, now, now)
+	}
+}
+
+// Disconnect 记录用户断开了一条实时连接
+func (s *PresenceService) Disconnect(roomID, userID uint) {
+	key := presenceKey{RoomID: roomID, UserID: userID}
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	if s.stopped || !ok || entry.connections == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+
+	entry.connections--
+	entry.lastSeen = now
+	if entry.connections == 0 {
+		entry.generation++
+		generation := entry.generation
+		entry.timer = time.AfterFunc(s.awayAfter, func() {
+			s.markAway(key, generation)
+		})
+	}
+	s.mu.Unlock()
+
+	s.persistLastSeen(roomID, userID, now)
+}
+
+// markAway 断线超过暂离阈值后标记为暂离
+func (s *PresenceService) markAway(key presenceKey, generation uint64) {
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	if s.stopped || !ok || entry.generation != generation || entry.connections > 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+
+	entry.state = PresenceAway
+	lastSeen := entry.lastSeen
+	entry.timer = time.AfterFunc(s.offlineAfter-s.awayAfter, func() {
+		s.markOffline(key, generation)
+	})
+	s.mu.Unlock()
+
+	s.broadcastPresence(key.RoomID, key.UserID, PresenceAway, lastSeen, time.Now())
+}
+
+// markOffline 断线超过离线阈值后标记为离线并释放内存中的记录
+func (s *PresenceService) markOffline(key presenceKey, generation uint64) {
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	if s.stopped || !ok || entry.generation != generation || entry.connections > 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.inflight.Add(1)
+	defer s.inflight.Done()
+
+	lastSeen := entry.lastSeen
+	delete(s.entries, key)
+	s.mu.Unlock()
+
+	s.broadcastPresence(key.RoomID, key.UserID, PresenceOffline, lastSeen, time.Now())
+}
+
+// Stop 停止所有暂离、离线计时器并等待进行中的广播结束，之后的连接变化都被忽略
+// 服务器关闭时须在关闭数据库之前调用
+func (s *PresenceService) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	for _, entry := range s.entries {
+		if entry.timer != nil {
+			entry.timer.Stop()
+			entry.timer = nil
+		}
+	}
+	s.mu.Unlock()
+
+	s.inflight.Wait()
+}
+
+// GetPresence 获取用户在房间内的在线状态；内存中无记录时视为离线，最近活跃时间取数据库记录
+func (s *PresenceService) GetPresence(roomID, userID uint, storedLastSeen *time.Time) PresenceInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[presenceKey{RoomID: roomID, UserID: userID}]
+	if !ok {
+		return PresenceInfo{Presence: PresenceOffline, LastSeenAt: storedLastSeen}
+	}
+
+	lastSeen := entry.lastSeen
+	if entry.state == PresenceOnline {
+		lastSeen = time.Now()
+	}
+	return PresenceInfo{Presence: entry.state, LastSeenAt: &lastSeen}
+}
+
+func (s *PresenceService) persistLastSeen(roomID, userID uint, seenAt time.Time) {
+	if err := models.DB.Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Update("last_seen_at", seenAt).Error; err != nil {
+		log.Printf("更新最近活跃时间失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
+	}
+}
+
+func (s *PresenceService) broadcastPresence(roomID, userID uint, presence string, lastSeen, changedAt time.Time) {
+	if s.hub == nil {
+		return
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		log.Printf("广播在线状态时获取用户信息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
+		return
+	}
+
+	payload, err := ws.EncodeEvent(ws.PresenceChangedEvent{
+		UserID:     user.ID,
+		Nickname:   user.Nickname,
+		Presence:   presence,
+		LastSeenAt: lastSeen,
+		ChangedAt:  changedAt,
+	})
+	if err != nil {
+		log.Printf("序列化在线状态消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
+		return
+	}
+
+	log.Printf("广播在线状态: RoomID=%d, UserID=%d, Presence=%s", roomID, userID, presence)
+	s.hub.BroadcastToRoom(roomID, payload)
+}
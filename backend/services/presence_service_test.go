@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	ws "poker_score_backend/websocket"
+
+	"github.com/stretchr/testify/require"
+)
+
+func nextPresenceEvent(t *testing.T, sub *ws.Subscriber, timeout time.Duration) (ws.PresenceChangedEvent, bool) {
+	t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case event := <-sub.Events:
+			require.NoError(t, ws.ValidateMessage(event.Message), string(event.Message))
+
+			var msg struct {
+				Type string                  `json:"type"`
+				Data ws.PresenceChangedEvent `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(event.Message, &msg))
+			if msg.Type == ws.EventPresenceChanged {
+				return msg.Data, true
+			}
+		case <-deadline:
+			return ws.PresenceChangedEvent{}, false
+		}
+	}
+}
+
+func TestPresenceService_AwayThenOffline(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	hub := ws.NewHub()
+	go hub.Run()
+
+	presence := NewPresenceService(hub, 40*time.Millisecond, 120*time.Millisecond)
+	roomService := NewRoomService(hub, presence)
+
+	room, err := roomService.CreateRoom(alice, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+
+	sub, _, _ := hub.Subscribe(room.ID, alice, 0)
+	defer hub.Unsubscribe(sub)
+
+	// 两个连接（如WebSocket和SSE）同时在线，只断开其中一个不影响状态
+	presence.Connect(room.ID, bob)
+	presence.Connect(room.ID, bob)
+
+	event, ok := nextPresenceEvent(t, sub, time.Second)
+	require.True(t, ok)
+	require.Equal(t, bob, event.UserID)
+	require.Equal(t, PresenceOnline, event.Presence)
+
+	presence.Disconnect(room.ID, bob)
+	_, ok = nextPresenceEvent(t, sub, 80*time.Millisecond)
+	require.False(t, ok, "仍有连接时不应变更在线状态")
+	require.Equal(t, PresenceOnline, presence.GetPresence(room.ID, bob, nil).Presence)
+
+	presence.Disconnect(room.ID, bob)
+
+	event, ok = nextPresenceEvent(t, sub, time.Second)
+	require.True(t, ok)
+	require.Equal(t, PresenceAway, event.Presence)
+
+	event, ok = nextPresenceEvent(t, sub, time.Second)
+	require.True(t, ok)
+	require.Equal(t, PresenceOffline, event.Presence)
+
+	// 在线状态与成员身份相互独立
+	var member models.RoomMember
+	require.NoError(t, models.DB.Where("room_id = ? AND user_id = ?", room.ID, bob).First(&member).Error)
+	require.Equal(t, "online", member.Status)
+	require.NotNil(t, member.LastSeenAt)
+
+	members, err := roomService.GetRoomMembers(room.ID)
+	require.NoError(t, err)
+	for _, m := range members {
+		if m["user_id"] == bob {
+			require.Equal(t, PresenceOffline, m["presence"])
+			require.NotNil(t, m["last_seen_at"])
+		}
+	}
+}
+
+func TestPresenceService_ReconnectWithinWindowIsDebounced(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	hub := ws.NewHub()
+	go hub.Run()
+
+	presence := NewPresenceService(hub, 80*time.Millisecond, 200*time.Millisecond)
+	roomService := NewRoomService(hub, presence)
+
+	room, err := roomService.CreateRoom(alice, "texas", "20:1")
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+
+	sub, _, _ := hub.Subscribe(room.ID, alice, 0)
+	defer hub.Unsubscribe(sub)
+
+	presence.Connect(room.ID, bob)
+	_, ok := nextPresenceEvent(t, sub, time.Second)
+	require.True(t, ok)
+
+	// 模拟刷新页面：断开后立即重连，不应产生任何状态广播
+	presence.Disconnect(room.ID, bob)
+	presence.Connect(room.ID, bob)
+
+	_, ok = nextPresenceEvent(t, sub, 250*time.Millisecond)
+	require.False(t, ok, "短暂断线不应广播状态变化")
+	require.Equal(t, PresenceOnline, presence.GetPresence(room.ID, bob, nil).Presence)
+}
+
+func TestPresenceService_StopCancelsTimers(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice"})
+	alice := users[0].ID
+
+	hub := ws.NewHub()
+	go hub.Run()
+
+	presence := NewPresenceService(hub, 20*time.Millisecond, 40*time.Millisecond)
+	room, err := NewRoomService(hub, presence).CreateRoom(alice, "texas", "20:1")
+	require.NoError(t, err)
+
+	sub, _, _ := hub.Subscribe(room.ID, alice, 0)
+	defer hub.Unsubscribe(sub)
+
+	presence.Connect(room.ID, alice)
+	_, ok := nextPresenceEvent(t, sub, time.Second)
+	require.True(t, ok)
+
+	presence.Disconnect(room.ID, alice)
+	presence.Stop()
+
+	// 停止后计时器不再触发，之后的连接变化也被忽略
+	presence.Connect(room.ID, alice)
+	_, ok = nextPresenceEvent(t, sub, 100*time.Millisecond)
+	require.False(t, ok)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// 限流场景
+const (
+	RateScopeLogin         = "login"          // 登录（按IP）
+	RateScopeRegister      = "register"       // 注册（按IP）
+	RateScopeJoin          = "join"           // 通过房间号加入房间（按用户）
+	RateScopeMoney         = "money"          // 下注、收回、强制转移等积分操作（按用户）
+	RateScopePasswordReset = "password_reset" // 发送找回密码验证码与重置密码（按IP）
+)
+
+// rateLimitSweepInterval 内存令牌桶清理已回满的桶的间隔
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitRule 令牌桶规则：桶容量为 Burst，每个 Period 匀速补满；Burst 为0表示不限流
+type RateLimitRule struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Enabled 规则是否生效
+func (r RateLimitRule) Enabled() bool {
+	return r.Burst > 0 && r.Period > 0
+}
+
+// interval 补充一个令牌所需的时间
+func (r RateLimitRule) interval() time.Duration {
+	return r.Period / time.Duration(r.Burst)
+}
+
+// RateLimitStore 令牌桶的存储，默认保存在内存中；多实例部署时可替换为共享存储的实现
+type RateLimitStore interface {
+	// Take 尝试从 key 对应的桶中取出一个令牌，失败时返回需要等待的时间
+	Take(key string, rule RateLimitRule, now time.Time) (bool, time.Duration)
+}
+
+// tokenBucket 令牌桶状态
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration // 桶回满所需时间，超过该时间未使用的桶可以删除
+}
+
+// MemoryRateLimitStore 基于内存的令牌桶存储
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore 创建内存令牌桶存储
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Take 按流逝的时间补充令牌后尝试取出一个
+func (s *MemoryRateLimitStore) Take(key string, rule RateLimitRule, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(rule.Burst), updated: now, period: rule.Period}
+		s.buckets[key] = bucket
+	}
+
+	if elapsed := now.Sub(bucket.updated); elapsed > 0 {
+		bucket.tokens += float64(elapsed) / float64(rule.interval())
+		if bucket.tokens > float64(rule.Burst) {
+			bucket.tokens = float64(rule.Burst)
+		}
+		bucket.updated = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) * float64(rule.interval()))
+	return false, wait
+}
+
+// sweep 定期删除已经回满的桶，避免长时间运行后占用过多内存
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) >= bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimiter 按场景应用令牌桶限流
+type RateLimiter struct {
+	store RateLimitStore
+	rules map[string]RateLimitRule
+}
+
+// NewRateLimiter 创建限流器，rules 以场景为键，未配置或未启用的场景不限流
+func NewRateLimiter(store RateLimitStore, rules map[string]RateLimitRule) *RateLimiter {
+	return &RateLimiter{
+		store: store,
+		rules: rules,
+	}
+}
+
+// Allow 判断场景下的 key（IP或用户）是否还能继续请求，被限流时返回需要等待的时间
+func (l *RateLimiter) Allow(scope, key string) (bool, time.Duration) {
+	rule, ok := l.rules[scope]
+	if !ok || !rule.Enabled() {
+		return true, 0
+	}
+	return l.store.Take(scope+":"+key, rule, time.Now())
+}
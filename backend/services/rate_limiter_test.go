@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStore_TokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	rule := RateLimitRule{Burst: 3, Period: 3 * time.Second}
+	start := time.Date(2025, 11, 7, 20, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := store.Take("login:10.0.0.1", rule, start)
+		require.True(t, allowed)
+	}
+
+	allowed, wait := store.Take("login:10.0.0.1", rule, start)
+	require.False(t, allowed)
+	require.Equal(t, time.Second, wait)
+
+	// 其他key互不影响
+	allowed, _ = store.Take("login:10.0.0.2", rule, start)
+	require.True(t, allowed)
+
+	// 半秒后仍差半个令牌
+	allowed, wait = store.Take("login:10.0.0.1", rule, start.Add(500*time.Millisecond))
+	require.False(t, allowed)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	allowed, _ = store.Take("login:10.0.0.1", rule, start.Add(time.Second))
+	require.True(t, allowed)
+
+	// 长时间未使用后最多回满到桶容量，并在清理时删除
+	later := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		allowed, _ = store.Take("login:10.0.0.1", rule, later)
+		require.True(t, allowed)
+	}
+	allowed, _ = store.Take("login:10.0.0.1", rule, later)
+	require.False(t, allowed)
+	require.NotContains(t, store.buckets, "login:10.0.0.2")
+}
+
+func TestRateLimiter_DisabledScopes(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), map[string]RateLimitRule{
+		RateScopeLogin: {Burst: 1, Period: time.Minute},
+		RateScopeMoney: {},
+	})
+
+	allowed, _ := limiter.Allow(RateScopeLogin, "10.0.0.1")
+	require.True(t, allowed)
+	allowed, wait := limiter.Allow(RateScopeLogin, "10.0.0.1")
+	require.False(t, allowed)
+	require.Greater(t, wait, 59*time.Second)
+
+	for i := 0; i < 100; i++ {
+		allowed, _ = limiter.Allow(RateScopeMoney, "user:1")
+		require.True(t, allowed)
+		allowed, _ = limiter.Allow(RateScopeJoin, "user:1")
+		require.True(t, allowed)
+	}
+}
@@ -7,7 +7,10 @@ import (
 )
 
 // RecordService 战绩统计服务
-type RecordService struct{}
+type RecordService struct {
+	now          func() time.Time
+	coPlayGraphs *coPlayGraphCache
+}
 
 type tonightRecordSummary struct {
 	UserID     uint
@@ -35,32 +38,37 @@ func ensureRecordSummary(cache map[uint]*tonightRecordSummary, userID uint) *ton
 
 // NewRecordService 创建战绩统计服务
 func NewRecordService() *RecordService {
-	return &RecordService{}
+	return &RecordService{
+		now:          time.Now,
+		coPlayGraphs: newCoPlayGraphCache(),
+	}
+}
+
+// GetDayBoundary 获取用户统计战绩使用的时区与“一晚”分界
+func (s *RecordService) GetDayBoundary(userID uint) DayBoundary {
+	return userDayBoundary(userID)
 }
 
 // GetTonightRecords 获取今晚战绩
 func (s *RecordService) GetTonightRecords(userID uint, startTime, endTime *time.Time) (map[string]interface{}, error) {
-	// 如果没有提供时间，自动计算
+	boundary := userDayBoundary(userID)
+
+	// 如果没有提供时间，按用户的时区与分界时刻自动计算
 	var start, end time.Time
 	if startTime == nil || endTime == nil {
-		start, end = s.calculateDefaultTimeRange()
+		start, end = s.calculateDefaultTimeRange(boundary)
 	} else {
 		start = *startTime
 		end = *endTime
 	}
 
-	// 查找用户在时间段内加入过的房间
-	var roomIDs []uint
-	err := models.DB.Model(&models.RoomMember{}).
-		Where("user_id = ? AND joined_at BETWEEN ? AND ?", userID, start, end).
-		Distinct("room_id").
-		Pluck("room_id", &roomIDs).Error
+	// 按时间窗口的同桌关系图查找"今晚一起玩过的好友"（包括好友的好友）
+	graph, err := s.coPlayGraphs.get(start, end)
 	if err != nil {
 		return nil, err
 	}
 
-	// 使用BFS查找"今晚一起玩过的好友"
-	friendIDs := s.findFriendsWithBFS(roomIDs, start, end)
+	friendIDs := graph.friendsOf(userID)
 	friendSet := make(map[uint]struct{})
 	friendSet[userID] = struct{}{}
 	for _, fid := range friendIDs {
@@ -80,7 +88,7 @@ func (s *RecordService) GetTonightRecords(userID uint, startTime, endTime *time.
 	settlementQueryIDs := buildIDList(friendSet)
 	if len(settlementQueryIDs) > 0 {
 		var settlements []models.Settlement
-		if err := models.DB.Where("user_id IN ? AND settled_at BETWEEN ? AND ?", settlementQueryIDs, start, end).
+		if err := models.DB.Where("user_id IN ? AND settled_at BETWEEN ? AND ?", settlementQueryIDs, storageTime(start), storageTime(end)).
 			Find(&settlements).Error; err != nil {
 			return nil, err
 		}
@@ -100,8 +108,10 @@ func (s *RecordService) GetTonightRecords(userID uint, startTime, endTime *time.
 
 	activeQueryIDs := buildIDList(friendSet)
 	if len(activeQueryIDs) > 0 {
+		// 只取进行中房间的成员记录，历史房间的成员不参与计算
 		var activeMembers []models.RoomMember
-		if err := models.DB.Where("user_id IN ?", activeQueryIDs).
+		if err := models.DB.Joins("JOIN rooms ON rooms.id = room_members.room_id AND rooms.status = ?", "active").
+			Where("room_members.user_id IN ?", activeQueryIDs).
 			Find(&activeMembers).Error; err != nil {
 			return nil, err
 		}
@@ -219,25 +229,27 @@ func (s *RecordService) GetTonightRecords(userID uint, startTime, endTime *time.
 	}
 
 	// 查询用户当前在的房间
-	var currentRoomMembers []models.RoomMember
-	models.DB.Where("user_id = ?", userID).Find(&currentRoomMembers)
-
-	currentRooms := make([]map[string]interface{}, 0, len(currentRoomMembers))
-	for _, member := range currentRoomMembers {
-		var room models.Room
-		if err := models.DB.First(&room, member.RoomID).Error; err == nil && room.Status == "active" {
-			currentRooms = append(currentRooms, map[string]interface{}{
-				"room_id":   room.ID,
-				"room_code": room.RoomCode,
-				"room_type": room.RoomType,
-			})
-		}
+	var myActiveRooms []models.Room
+	models.DB.Joins("JOIN room_members ON room_members.room_id = rooms.id AND room_members.user_id = ?", userID).
+		Where("rooms.status = ?", "active").
+		Order("room_members.id ASC").
+		Find(&myActiveRooms)
+
+	currentRooms := make([]map[string]interface{}, 0, len(myActiveRooms))
+	for _, room := range myActiveRooms {
+		currentRooms = append(currentRooms, map[string]interface{}{
+			"room_id":   room.ID,
+			"room_code": room.RoomCode,
+			"room_type": room.RoomType,
+		})
 	}
 
 	return map[string]interface{}{
 		"time_range": map[string]interface{}{
-			"start": start,
-			"end":   end,
+			"start":           start,
+			"end":             end,
+			"timezone":        boundary.Location.String(),
+			"day_cutoff_hour": boundary.CutoffHour,
 		},
 		"current_rooms":   currentRooms,
 		"friends_records": friendsRecords,
@@ -245,70 +257,8 @@ func (s *RecordService) GetTonightRecords(userID uint, startTime, endTime *time.
 	}, nil
 }
 
-// calculateDefaultTimeRange 计算默认时间段
-func (s *RecordService) calculateDefaultTimeRange() (time.Time, time.Time) {
-	now := time.Now()
-	today7am := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
-
-	var start, end time.Time
-	if now.Before(today7am) {
-		// 当前时间在7:00am之前，统计昨天7:00am到今天7:00am
-		start = today7am.Add(-24 * time.Hour)
-		end = today7am
-	} else {
-		// 当前时间在7:00am之后，统计今天7:00am到明天7:00am
-		start = today7am
-		end = today7am.Add(24 * time.Hour)
-	}
-
-	return start, end
-}
-
-// findFriendsWithBFS 使用BFS查找"今晚一起玩过的好友"
-func (s *RecordService) findFriendsWithBFS(initialRoomIDs []uint, start, end time.Time) []uint {
-	visited := make(map[uint]bool)
-	visitedRooms := make(map[uint]bool)
-	queue := initialRoomIDs
-
-	for _, roomID := range initialRoomIDs {
-		visitedRooms[roomID] = true
-	}
-
-	for len(queue) > 0 {
-		roomID := queue[0]
-		queue = queue[1:]
-
-		// 查找该房间在时间段内的所有成员
-		var members []models.RoomMember
-		models.DB.Where("room_id = ? AND joined_at BETWEEN ? AND ?", roomID, start, end).
-			Find(&members)
-
-		for _, member := range members {
-			if !visited[member.UserID] {
-				visited[member.UserID] = true
-
-				// 查找这个用户在时间段内加入的其他房间
-				var otherRoomIDs []uint
-				models.DB.Model(&models.RoomMember{}).
-					Where("user_id = ? AND joined_at BETWEEN ? AND ?", member.UserID, start, end).
-					Distinct("room_id").
-					Pluck("room_id", &otherRoomIDs)
-
-				for _, otherRoomID := range otherRoomIDs {
-					if !visitedRooms[otherRoomID] {
-						visitedRooms[otherRoomID] = true
-						queue = append(queue, otherRoomID)
-					}
-				}
-			}
-		}
-	}
-
-	// 转换为slice
-	friendIDs := make([]uint, 0, len(visited))
-	for userID := range visited {
-		friendIDs = append(friendIDs, userID)
-	}
-
-	return friendIDs
+// calculateDefaultTimeRange 计算默认时间段：当前时刻所在的“一晚”
+// 例如分界为7点时，7:00之前统计昨天7:00到今天7:00，之后统计今天7:00到明天7:00
+func (s *RecordService) calculateDefaultTimeRange(boundary DayBoundary) (time.Time, time.Time) {
+	return boundary.NightRange(s.now())
 }
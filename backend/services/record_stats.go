@@ -0,0 +1,364 @@
+package services
+
+import (
+	"math"
+	"poker_score_backend/models"
+	"sort"
+	"time"
+)
+
+// StatsFilter 战绩统计筛选条件，字段为空表示不限制
+type StatsFilter struct {
+	Start    *time.Time
+	End      *time.Time
+	RoomType string
+}
+
+// playerSession 用户在一个房间内的一场对局（按房间汇总所有结算批次）
+type playerSession struct {
+	RoomID     uint
+	RoomCode   string
+	RoomType   string
+	ChipAmount int
+	RmbAmount  float64
+	StartedAt  time.Time
+	EndedAt    time.Time
+}
+
+// streakSummary 连胜/连败统计
+type streakSummary struct {
+	CurrentType   string // win/loss/none
+	CurrentLength int
+	LongestWin    int
+	LongestLoss   int
+}
+
+// sqliteTimeLayouts SQLite聚合函数返回的时间是字符串，按GORM写入的格式解析
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+// GetLifetimeStats 获取用户在指定时间段、游戏类型下的生涯战绩
+func (s *RecordService) GetLifetimeStats(userID uint, filter StatsFilter) (map[string]interface{}, error) {
+	sessions, err := s.loadPlayerSessions(userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	totalSessions := len(sessions)
+	wins, losses, draws := 0, 0, 0
+	netChip := 0
+	netRmb := 0.0
+	var totalDuration time.Duration
+	var biggestWin, biggestLoss *playerSession
+
+	for i := range sessions {
+		session := &sessions[i]
+		netChip += session.ChipAmount
+		netRmb += session.RmbAmount
+		totalDuration += session.EndedAt.Sub(session.StartedAt)
+
+		switch {
+		case session.ChipAmount > 0:
+			wins++
+			if biggestWin == nil || session.ChipAmount > biggestWin.ChipAmount {
+				biggestWin = session
+			}
+		case session.ChipAmount < 0:
+			losses++
+			if biggestLoss == nil || session.ChipAmount < biggestLoss.ChipAmount {
+				biggestLoss = session
+			}
+		default:
+			draws++
+		}
+	}
+
+	winRate := 0.0
+	averageMinutes := 0.0
+	if totalSessions > 0 {
+		winRate = roundTo(float64(wins)/float64(totalSessions), 4)
+		averageMinutes = roundTo(totalDuration.Minutes()/float64(totalSessions), 1)
+	}
+
+	streaks := computeStreaks(sessions)
+
+	return map[string]interface{}{
+		"filter": map[string]interface{}{
+			"start":     filter.Start,
+			"end":       filter.End,
+			"room_type": filter.RoomType,
+		},
+		"total_sessions":          totalSessions,
+		"wins":                    wins,
+		"losses":                  losses,
+		"draws":                   draws,
+		"win_rate":                winRate,
+		"net_chip":                netChip,
+		"net_rmb":                 roundTo(netRmb, 2),
+		"biggest_win":             sessionView(biggestWin),
+		"biggest_loss":            sessionView(biggestLoss),
+		"average_session_minutes": averageMinutes,
+		"monthly":                 monthlyBreakdown(sessions, userDayBoundary(userID)),
+		"current_streak":          map[string]interface{}{"type": streaks.CurrentType, "length": streaks.CurrentLength},
+		"longest_win_streak":      streaks.LongestWin,
+		"longest_loss_streak":     streaks.LongestLoss,
+	}, nil
+}
+
+// loadPlayerSessions 使用聚合查询按房间汇总用户的结算记录，并补全每场的开始与结束时间
+// 结果按结束时间升序排列
+func (s *RecordService) loadPlayerSessions(userID uint, filter StatsFilter) ([]playerSession, error) {
+	type sessionRow struct {
+		RoomID        uint
+		RoomCode      string
+		RoomType      string
+		ChipAmount    int
+		RmbAmount     float64
+		FirstSettled  string
+		LastSettledAt string
+	}
+
+	query := models.DB.Table("settlements").
+		Select("settlements.room_id AS room_id, rooms.room_code AS room_code, rooms.room_type AS room_type, "+
+			"SUM(settlements.chip_amount) AS chip_amount, SUM(settlements.rmb_amount) AS rmb_amount, "+
+			"MIN(settlements.settled_at) AS first_settled, MAX(settlements.settled_at) AS last_settled_at").
+		Joins("JOIN rooms ON rooms.id = settlements.room_id").
+		Where("settlements.user_id = ?", userID)
+
+	if filter.Start != nil {
+		query = query.Where("settlements.settled_at >= ?", storageTime(*filter.Start))
+	}
+	if filter.End != nil {
+		query = query.Where("settlements.settled_at <= ?", storageTime(*filter.End))
+	}
+	if filter.RoomType != "" {
+		query = query.Where("rooms.room_type = ?", filter.RoomType)
+	}
+
+	var rows []sessionRow
+	if err := query.Group("settlements.room_id, rooms.room_code, rooms.room_type").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return []playerSession{}, nil
+	}
+
+	roomIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		roomIDs = append(roomIDs, row.RoomID)
+	}
+
+	type boundRow struct {
+		RoomID uint
+		At     string
+	}
+
+	// 开始时间：首次加入房间的时间
+	var joinedRows []boundRow
+	if err := models.DB.Model(&models.RoomMember{}).
+		Select("room_id, MIN(joined_at) AS at").
+		Where("user_id = ? AND room_id IN ?", userID, roomIDs).
+		Group("room_id").
+		Scan(&joinedRows).Error; err != nil {
+		return nil, err
+	}
+
+	// 结束时间：最后一次操作与最后一次结算中较晚的一个
+	var lastOpRows []boundRow
+	if err := models.DB.Model(&models.RoomOperation{}).
+		Select("room_id, MAX(created_at) AS at").
+		Where("user_id = ? AND room_id IN ?", userID, roomIDs).
+		Group("room_id").
+		Scan(&lastOpRows).Error; err != nil {
+		return nil, err
+	}
+
+	joinedAt := make(map[uint]time.Time, len(joinedRows))
+	for _, row := range joinedRows {
+		if t, ok := parseDBTime(row.At); ok {
+			joinedAt[row.RoomID] = t
+		}
+	}
+	lastOpAt := make(map[uint]time.Time, len(lastOpRows))
+	for _, row := range lastOpRows {
+		if t, ok := parseDBTime(row.At); ok {
+			lastOpAt[row.RoomID] = t
+		}
+	}
+
+	sessions := make([]playerSession, 0, len(rows))
+	for _, row := range rows {
+		firstSettled, _ := parseDBTime(row.FirstSettled)
+		lastSettled, _ := parseDBTime(row.LastSettledAt)
+
+		started, ok := joinedAt[row.RoomID]
+		if !ok || started.After(firstSettled) {
+			started = firstSettled
+		}
+		ended := lastSettled
+		if lastOp, ok := lastOpAt[row.RoomID]; ok && lastOp.After(ended) {
+			ended = lastOp
+		}
+
+		sessions = append(sessions, playerSession{
+			RoomID:     row.RoomID,
+			RoomCode:   row.RoomCode,
+			RoomType:   row.RoomType,
+			ChipAmount: row.ChipAmount,
+			RmbAmount:  roundTo(row.RmbAmount, 2),
+			StartedAt:  started,
+			EndedAt:    ended,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].EndedAt.Equal(sessions[j].EndedAt) {
+			return sessions[i].RoomID < sessions[j].RoomID
+		}
+		return sessions[i].EndedAt.Before(sessions[j].EndedAt)
+	})
+
+	return sessions, nil
+}
+
+// computeStreaks 按时间顺序统计连胜/连败，平局会中断连胜和连败
+func computeStreaks(sessions []playerSession) streakSummary {
+	var summary streakSummary
+	summary.CurrentType = "none"
+
+	for _, session := range sessions {
+		result := "none"
+		if session.ChipAmount > 0 {
+			result = "win"
+		} else if session.ChipAmount < 0 {
+			result = "loss"
+		}
+
+		if result == "none" {
+			summary.CurrentType = "none"
+			summary.CurrentLength = 0
+			continue
+		}
+
+		if result == summary.CurrentType {
+			summary.CurrentLength++
+		} else {
+			summary.CurrentType = result
+			summary.CurrentLength = 1
+		}
+
+		if result == "win" && summary.CurrentLength > summary.LongestWin {
+			summary.LongestWin = summary.CurrentLength
+		}
+		if result == "loss" && summary.CurrentLength > summary.LongestLoss {
+			summary.LongestLoss = summary.CurrentLength
+		}
+	}
+
+	return summary
+}
+
+// monthlyBreakdown 按对局结束时间所属那一晚的月份汇总（凌晨结束的对局算作前一晚）
+func monthlyBreakdown(sessions []playerSession, boundary DayBoundary) []map[string]interface{} {
+	type monthSummary struct {
+		Month    string
+		Sessions int
+		Wins     int
+		NetChip  int
+		NetRmb   float64
+	}
+
+	months := make([]*monthSummary, 0)
+	index := make(map[string]*monthSummary)
+	for _, session := range sessions {
+		key := boundary.NightDate(session.EndedAt)[:7]
+		summary, ok := index[key]
+		if !ok {
+			summary = &monthSummary{Month: key}
+			index[key] = summary
+			months = append(months, summary)
+		}
+		summary.Sessions++
+		summary.NetChip += session.ChipAmount
+		summary.NetRmb += session.RmbAmount
+		if session.ChipAmount > 0 {
+			summary.Wins++
+		}
+	}
+
+	sort.Slice(months, func(i, j int) bool { return months[i].Month < months[j].Month })
+
+	result := make([]map[string]interface{}, 0, len(months))
+	for _, month := range months {
+		result = append(result, map[string]interface{}{
+			"month":    month.Month,
+			"sessions": month.Sessions,
+			"wins":     month.Wins,
+			"win_rate": roundTo(float64(month.Wins)/float64(month.Sessions), 4),
+			"net_chip": month.NetChip,
+			"net_rmb":  roundTo(month.NetRmb, 2),
+		})
+	}
+	return result
+}
+
+// nightResult 用户一晚的汇总结果
+type nightResult struct {
+	Date       string
+	Sessions   int
+	RmbAmount  float64
+	EndedAt    time.Time
+	LastRoomID uint
+}
+
+// groupNights 按对局结束时间所属的那一晚汇总，sessions 需按结束时间升序，结果同样按时间先后排列
+func groupNights(sessions []playerSession, boundary DayBoundary) []nightResult {
+	nights := make([]nightResult, 0)
+	for _, session := range sessions {
+		date := boundary.NightDate(session.EndedAt)
+		if len(nights) == 0 || nights[len(nights)-1].Date != date {
+			nights = append(nights, nightResult{Date: date})
+		}
+		night := &nights[len(nights)-1]
+		night.Sessions++
+		night.RmbAmount = roundTo(night.RmbAmount+session.RmbAmount, 2)
+		night.EndedAt = session.EndedAt
+		night.LastRoomID = session.RoomID
+	}
+	return nights
+}
+
+func sessionView(session *playerSession) map[string]interface{} {
+	if session == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"room_id":     session.RoomID,
+		"room_code":   session.RoomCode,
+		"room_type":   session.RoomType,
+		"chip_amount": session.ChipAmount,
+		"rmb_amount":  session.RmbAmount,
+		"started_at":  session.StartedAt,
+		"ended_at":    session.EndedAt,
+	}
+}
+
+func parseDBTime(value string) (time.Time, bool) {
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+// seedSettledRoom 创建一个已结算的房间，results 为各用户的积分盈亏
+func seedSettledRoom(t *testing.T, roomType string, joinedAt, settledAt time.Time, results map[uint]int) models.Room {
+	t.Helper()
+
+	room := models.Room{
+		RoomCode:  fmt.Sprintf("%06d", joinedAt.Unix()%1000000),
+		RoomType:  roomType,
+		ChipRate:  "20:1",
+		Status:    "dissolved",
+		CreatedAt: joinedAt,
+	}
+	for userID := range results {
+		room.CreatedBy = userID
+		break
+	}
+	require.NoError(t, models.DB.Create(&room).Error)
+
+	batch := fmt.Sprintf("batch-%d", room.ID)
+	for userID, chip := range results {
+		require.NoError(t, models.DB.Create(&models.RoomMember{
+			RoomID:   room.ID,
+			UserID:   userID,
+			JoinedAt: joinedAt,
+			Status:   "offline",
+		}).Error)
+		require.NoError(t, models.DB.Create(&models.Settlement{
+			RoomID:          room.ID,
+			UserID:          userID,
+			ChipAmount:      chip,
+			RmbAmount:       calculateRmbAmount(chip, room.ChipRate),
+			SettledAt:       settledAt,
+			SettlementBatch: batch,
+		}).Error)
+	}
+
+	return room
+}
+
+func TestRecordService_GetLifetimeStats(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	base := time.Date(2025, 10, 30, 20, 0, 0, 0, time.Local)
+	seedSettledRoom(t, "texas", base, base.Add(2*time.Hour), map[uint]int{alice: 200, bob: -200})
+	seedSettledRoom(t, "texas", base.Add(24*time.Hour), base.Add(25*time.Hour), map[uint]int{alice: 400, bob: -400})
+	seedSettledRoom(t, "niuniu", base.Add(72*time.Hour), base.Add(75*time.Hour), map[uint]int{alice: -100, bob: 100})
+	seedSettledRoom(t, "texas", base.Add(96*time.Hour), base.Add(97*time.Hour), map[uint]int{alice: 0, bob: 0})
+	seedSettledRoom(t, "texas", base.Add(120*time.Hour), base.Add(121*time.Hour), map[uint]int{alice: -60, bob: 60})
+
+	service := NewRecordService()
+
+	stats, err := service.GetLifetimeStats(alice, StatsFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 5, stats["total_sessions"])
+	require.Equal(t, 2, stats["wins"])
+	require.Equal(t, 2, stats["losses"])
+	require.Equal(t, 1, stats["draws"])
+	require.Equal(t, 0.4, stats["win_rate"])
+	require.Equal(t, 440, stats["net_chip"])
+	require.Equal(t, 22.0, stats["net_rmb"])
+	require.Equal(t, 400, stats["biggest_win"].(map[string]interface{})["chip_amount"])
+	require.Equal(t, -100, stats["biggest_loss"].(map[string]interface{})["chip_amount"])
+	require.Equal(t, 96.0, stats["average_session_minutes"])
+	require.Equal(t, 2, stats["longest_win_streak"])
+	require.Equal(t, 1, stats["longest_loss_streak"])
+	require.Equal(t, map[string]interface{}{"type": "loss", "length": 1}, stats["current_streak"])
+
+	monthly := stats["monthly"].([]map[string]interface{})
+	require.Len(t, monthly, 2)
+	require.Equal(t, "2025-10", monthly[0]["month"])
+	require.Equal(t, 2, monthly[0]["sessions"])
+	require.Equal(t, 600, monthly[0]["net_chip"])
+	require.Equal(t, "2025-11", monthly[1]["month"])
+	require.Equal(t, 3, monthly[1]["sessions"])
+
+	// 按游戏类型与时间段筛选
+	stats, err = service.GetLifetimeStats(alice, StatsFilter{RoomType: "niuniu"})
+	require.NoError(t, err)
+	require.Equal(t, 1, stats["total_sessions"])
+	require.Equal(t, -100, stats["net_chip"])
+
+	start := base.Add(12 * time.Hour)
+	end := base.Add(80 * time.Hour)
+	stats, err = service.GetLifetimeStats(bob, StatsFilter{Start: &start, End: &end})
+	require.NoError(t, err)
+	require.Equal(t, 2, stats["total_sessions"])
+	require.Equal(t, -300, stats["net_chip"])
+	require.Equal(t, 100, stats["biggest_win"].(map[string]interface{})["chip_amount"])
+}
+
+func TestComputeStreaks(t *testing.T) {
+	chips := func(values ...int) []playerSession {
+		sessions := make([]playerSession, len(values))
+		for i, v := range values {
+			sessions[i] = playerSession{ChipAmount: v}
+		}
+		return sessions
+	}
+
+	cases := []struct {
+		name     string
+		sessions []playerSession
+		expected streakSummary
+	}{
+		{"空", nil, streakSummary{CurrentType: "none"}},
+		{"连胜", chips(10, 20, 30), streakSummary{CurrentType: "win", CurrentLength: 3, LongestWin: 3}},
+		{"平局中断", chips(10, 20, 0, 5), streakSummary{CurrentType: "win", CurrentLength: 1, LongestWin: 2}},
+		{"输赢交替", chips(-1, -2, 3, -4, -5, -6), streakSummary{CurrentType: "loss", CurrentLength: 3, LongestWin: 1, LongestLoss: 3}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, computeStreaks(tc.sessions))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	ws "poker_score_backend/websocket"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoomService_EmittedEventsMatchSchema(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol"})
+	alice, bob, carol := users[0].ID, users[1].ID, users[2].ID
+
+	hub := ws.NewHub()
+	go hub.Run()
+
+	roomService := NewRoomService(hub, nil)
+	operationService := NewOperationService(roomService)
+	settlementService := NewSettlementService(roomService)
+
+	room, err := roomService.CreateRoom(alice, "niuniu", "20:1")
+	require.NoError(t, err)
+
+	sub, _, _ := hub.Subscribe(room.ID, alice, 0)
+	defer hub.Unsubscribe(sub)
+
+	_, err = roomService.JoinRoom(bob, room.ID)
+	require.NoError(t, err)
+	_, err = roomService.JoinRoom(carol, room.ID)
+	require.NoError(t, err)
+
+	_, _, err = operationService.Bet(room.ID, alice, 100)
+	require.NoError(t, err)
+	_, _, err = operationService.NiuniuBet(room.ID, bob, []NiuniuBetItem{{ToUserID: carol, Amount: 50}})
+	require.NoError(t, err)
+	_, _, _, err = operationService.Withdraw(room.ID, carol, 30)
+	require.NoError(t, err)
+	_, _, _, _, err = operationService.ForceTransfer(room.ID, alice, carol)
+	require.NoError(t, err)
+
+	require.NoError(t, roomService.KickUser(room.ID, alice, bob))
+	_, err = roomService.ReturnToRoom(room.ID, bob)
+	require.NoError(t, err)
+	require.NoError(t, roomService.LeaveRoom(carol, room.ID))
+
+	chatService := NewChatService(roomService, 10, time.Minute, 500)
+	message, err := chatService.SendMessage(room.ID, bob, "转账了")
+	require.NoError(t, err)
+	var lastOp models.RoomOperation
+	require.NoError(t, models.DB.Where("room_id = ?", room.ID).Order("id DESC").First(&lastOp).Error)
+	_, err = chatService.React(room.ID, alice, lastOp.ID, "👍")
+	require.NoError(t, err)
+	require.NoError(t, chatService.DeleteMessage(room.ID, message["id"].(uint), alice))
+
+	_, _, _, err = settlementService.InitiateSettlement(room.ID, alice)
+	require.NoError(t, err)
+	_, _, err = settlementService.ConfirmSettlement(room.ID, alice)
+	require.NoError(t, err)
+	_, err = roomService.ManualDissolveRoom(room.ID, alice)
+	require.NoError(t, err)
+
+	seen := make(map[string]int)
+	for {
+		var event ws.RoomEvent
+		select {
+		case event = <-sub.Events:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待房间事件超时，已收到: %v", seen)
+		}
+
+		require.NoError(t, ws.ValidateMessage(event.Message), string(event.Message))
+
+		var envelope struct {
+			Type string `json:"type"`
+		}
+		require.NoError(t, json.Unmarshal(event.Message, &envelope))
+		seen[envelope.Type]++
+
+		if envelope.Type == ws.EventRoomDissolved {
+			break
+		}
+	}
+
+	for _, eventType := range []string{
+		ws.EventUserJoined,
+		ws.EventUserReturned,
+		ws.EventUserLeft,
+		ws.EventUserKicked,
+		ws.EventBet,
+		ws.EventNiuniuBet,
+		ws.EventWithdraw,
+		ws.EventForceTransfer,
+		ws.EventSettlementInitiated,
+		ws.EventSettlementConfirmed,
+		ws.EventRoomDissolved,
+		ws.EventChatMessage,
+		ws.EventChatMessageDeleted,
+	} {
+		require.Positive(t, seen[eventType], "未收到事件 %s", eventType)
+	}
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// RecapReasonManual 房间被成员手动解散
+	RecapReasonManual = "manual"
+	// RecapReasonInactive 房间因长时间无操作自动解散
+	RecapReasonInactive = "inactive"
+
+	// recapBiggestPots 复盘中列出的最大收回笔数
+	recapBiggestPots = 3
+)
+
+// RoomRecap 房间复盘报告
+type RoomRecap struct {
+	RoomID          uint       `json:"room_id"`
+	RoomCode        string     `json:"room_code"`
+	RoomType        string     `json:"room_type"`
+	ChipRate        string     `json:"chip_rate"`
+	ClubID          *uint      `json:"club_id,omitempty"`
+	CreatedBy       RecapUser  `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DissolvedAt     time.Time  `json:"dissolved_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Reason          string     `json:"reason"`                 // manual/inactive
+	DissolvedBy     *RecapUser `json:"dissolved_by,omitempty"` // 手动解散的成员
+
+	TotalBet       int `json:"total_bet"`       // 全场下注积分（含牛牛下注）
+	OperationCount int `json:"operation_count"` // 下注、收回、转移的操作次数
+
+	Participants     []RecapParticipant `json:"participants"`
+	BiggestPots      []RecapPot         `json:"biggest_pots"`
+	MostActiveBettor *RecapBettor       `json:"most_active_bettor"`
+	NiuniuFlows      []RecapBetFlow     `json:"niuniu_flows"`
+	SettlementPlan   []SettlementPlan   `json:"settlement_plan"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// RecapUser 复盘中引用的用户
+type RecapUser struct {
+	UserID   uint   `json:"user_id"`
+	Nickname string `json:"nickname"`
+}
+
+// RecapParticipant 参与者的下注、收回与最终结果，结果为该房间所有结算批次之和
+type RecapParticipant struct {
+	RecapUser
+	JoinedAt      time.Time `json:"joined_at"`
+	BetCount      int       `json:"bet_count"`
+	BetTotal      int       `json:"bet_total"`
+	WithdrawTotal int       `json:"withdraw_total"`
+	ChipAmount    int       `json:"chip_amount"`
+	RmbAmount     float64   `json:"rmb_amount"`
+}
+
+// RecapPot 一次收回（或被转移）的积分
+type RecapPot struct {
+	RecapUser
+	Amount        int       `json:"amount"`
+	OperationType string    `json:"operation_type"`
+	At            time.Time `json:"at"`
+}
+
+// RecapBettor 下注最活跃的成员
+type RecapBettor struct {
+	RecapUser
+	BetCount int `json:"bet_count"`
+	BetTotal int `json:"bet_total"`
+}
+
+// RecapBetFlow 牛牛下注中一名成员给另一名成员下注的汇总
+type RecapBetFlow struct {
+	From   RecapUser `json:"from"`
+	To     RecapUser `json:"to"`
+	Count  int       `json:"count"`
+	Amount int       `json:"amount"`
+}
+
+// RecapService 房间复盘服务
+type RecapService struct {
+	settlementService *SettlementService
+}
+
+// NewRecapService 创建房间复盘服务
+func NewRecapService(settlementService *SettlementService) *RecapService {
+	return &RecapService{
+		settlementService: settlementService,
+	}
+}
+
+// OnRoomDissolved 房间解散后生成并保存复盘
+func (s *RecapService) OnRoomDissolved(roomID uint) {
+	if _, err := s.ensureRecap(roomID); err != nil {
+		log.Printf("生成房间复盘失败: RoomID=%d, %v", roomID, err)
+		return
+	}
+	log.Printf("生成房间复盘: RoomID=%d", roomID)
+}
+
+// GetRoomRecap 获取房间复盘，仅房间成员可查看，时间按查看者偏好的时区展示；功能上线前解散的房间在首次查询时补生成
+func (s *RecapService) GetRoomRecap(roomID, userID uint) (*RoomRecap, error) {
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return nil, errors.New("房间不存在")
+	}
+
+	var members []models.RoomMember
+	if err := models.DB.Where("room_id = ? AND user_id = ?", roomID, userID).Limit(1).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, errors.New("您不在该房间中")
+	}
+
+	if room.Status != "dissolved" {
+		return nil, errors.New("房间尚未解散")
+	}
+
+	recap, err := s.ensureRecap(roomID)
+	if err != nil {
+		return nil, err
+	}
+	return recap.inLocation(userDayBoundary(userID).Location), nil
+}
+
+// ensureRecap 读取已保存的复盘，不存在时生成；并发生成时以先写入的为准
+func (s *RecapService) ensureRecap(roomID uint) (*RoomRecap, error) {
+	var saved []models.RoomRecap
+	if err := models.DB.Where("room_id = ?", roomID).Limit(1).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	if len(saved) > 0 {
+		return decodeRecap(saved[0])
+	}
+
+	recap, err := s.buildRecap(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(recap)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RoomRecap{
+		RoomID:      roomID,
+		Content:     string(content),
+		GeneratedAt: recap.GeneratedAt,
+	}
+	res := models.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if err := models.DB.Where("room_id = ?", roomID).First(&record).Error; err != nil {
+			return nil, err
+		}
+		return decodeRecap(record)
+	}
+
+	return recap, nil
+}
+
+func decodeRecap(record models.RoomRecap) (*RoomRecap, error) {
+	var recap RoomRecap
+	if err := json.Unmarshal([]byte(record.Content), &recap); err != nil {
+		return nil, err
+	}
+	return &recap, nil
+}
+
+// buildRecap 根据房间的成员、操作、牛牛下注与结算记录汇总复盘
+func (s *RecapService) buildRecap(roomID uint) (*RoomRecap, error) {
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return nil, errors.New("房间不存在")
+	}
+
+	var members []models.RoomMember
+	if err := models.DB.Where("room_id = ?", roomID).Order("joined_at ASC, id ASC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	var operations []models.RoomOperation
+	if err := models.DB.Where("room_id = ?", roomID).Order("created_at ASC, id ASC").Find(&operations).Error; err != nil {
+		return nil, err
+	}
+
+	var betRecords []models.BetRecord
+	if err := models.DB.Where("room_id = ?", roomID).Order("created_at ASC, id ASC").Find(&betRecords).Error; err != nil {
+		return nil, err
+	}
+
+	type resultRow struct {
+		UserID     uint
+		ChipAmount int
+	}
+	var results []resultRow
+	if err := models.DB.Model(&models.Settlement{}).
+		Select("user_id, SUM(chip_amount) AS chip_amount").
+		Where("room_id = ?", roomID).
+		Group("user_id").
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	userIDs := []uint{room.CreatedBy}
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	nicknames, err := loadNicknames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	recapUser := func(id uint) RecapUser {
+		return RecapUser{UserID: id, Nickname: nicknames[id]}
+	}
+
+	dissolvedAt := time.Now()
+	if room.DissolvedAt != nil {
+		dissolvedAt = *room.DissolvedAt
+	}
+
+	recap := &RoomRecap{
+		RoomID:          room.ID,
+		RoomCode:        room.RoomCode,
+		RoomType:        room.RoomType,
+		ChipRate:        room.ChipRate,
+		ClubID:          room.ClubID,
+		CreatedBy:       recapUser(room.CreatedBy),
+		CreatedAt:       room.CreatedAt,
+		DissolvedAt:     dissolvedAt,
+		DurationMinutes: int(dissolvedAt.Sub(room.CreatedAt).Minutes()),
+		Reason:          RecapReasonInactive,
+		Participants:    make([]RecapParticipant, 0, len(members)),
+		BiggestPots:     make([]RecapPot, 0, recapBiggestPots),
+		NiuniuFlows:     make([]RecapBetFlow, 0),
+		GeneratedAt:     time.Now(),
+	}
+
+	participants := make(map[uint]*RecapParticipant, len(members))
+	order := make([]uint, 0, len(members))
+	for _, member := range members {
+		if _, ok := participants[member.UserID]; ok {
+			continue
+		}
+		participants[member.UserID] = &RecapParticipant{RecapUser: recapUser(member.UserID), JoinedAt: member.JoinedAt}
+		order = append(order, member.UserID)
+	}
+	participant := func(id uint) *RecapParticipant {
+		if p, ok := participants[id]; ok {
+			return p
+		}
+		// 操作记录中出现但没有成员记录的用户（理论上不会发生）
+		p := &RecapParticipant{RecapUser: recapUser(id)}
+		participants[id] = p
+		order = append(order, id)
+		return p
+	}
+
+	pots := make([]RecapPot, 0)
+	for _, op := range operations {
+		amount := 0
+		if op.Amount != nil {
+			amount = *op.Amount
+		}
+
+		switch op.OperationType {
+		case models.OpTypeBet, models.OpTypeNiuniuBet:
+			p := participant(op.UserID)
+			p.BetCount++
+			p.BetTotal += amount
+			recap.TotalBet += amount
+			recap.OperationCount++
+		case models.OpTypeWithdraw, models.OpTypeForceTransfer:
+			receiverID := op.UserID
+			if op.OperationType == models.OpTypeForceTransfer {
+				if op.TargetUserID == nil {
+					continue
+				}
+				receiverID = *op.TargetUserID
+			}
+			p := participant(receiverID)
+			p.WithdrawTotal += amount
+			pots = append(pots, RecapPot{RecapUser: p.RecapUser, Amount: amount, OperationType: op.OperationType, At: op.CreatedAt})
+			recap.OperationCount++
+		case models.OpTypeRoomDissolved:
+			recap.Reason = RecapReasonManual
+			dissolvedBy := participant(op.UserID).RecapUser
+			recap.DissolvedBy = &dissolvedBy
+		}
+	}
+
+	// 最大的几笔收回：金额相同时先发生的在前
+	sort.SliceStable(pots, func(i, j int) bool { return pots[i].Amount > pots[j].Amount })
+	if len(pots) > recapBiggestPots {
+		pots = pots[:recapBiggestPots]
+	}
+	recap.BiggestPots = append(recap.BiggestPots, pots...)
+
+	balances := make([]models.UserBalance, 0, len(results))
+	for _, result := range results {
+		p := participant(result.UserID)
+		p.ChipAmount = result.ChipAmount
+		p.RmbAmount = roundTo(calculateRmbAmount(result.ChipAmount, room.ChipRate), 2)
+		balances = append(balances, models.UserBalance{RoomID: roomID, UserID: result.UserID, Balance: result.ChipAmount})
+	}
+
+	for _, id := range order {
+		p := participants[id]
+		recap.Participants = append(recap.Participants, *p)
+
+		// 下注次数最多的成员，次数相同时比较下注总额
+		if p.BetCount == 0 {
+			continue
+		}
+		best := recap.MostActiveBettor
+		if best == nil || p.BetCount > best.BetCount || (p.BetCount == best.BetCount && p.BetTotal > best.BetTotal) {
+			recap.MostActiveBettor = &RecapBettor{RecapUser: p.RecapUser, BetCount: p.BetCount, BetTotal: p.BetTotal}
+		}
+	}
+
+	// 最终结果从高到低
+	sort.SliceStable(recap.Participants, func(i, j int) bool {
+		return recap.Participants[i].ChipAmount > recap.Participants[j].ChipAmount
+	})
+
+	type flowKey struct{ from, to uint }
+	flows := make(map[flowKey]*RecapBetFlow)
+	for _, record := range betRecords {
+		key := flowKey{record.FromUserID, record.ToUserID}
+		flow, ok := flows[key]
+		if !ok {
+			flow = &RecapBetFlow{From: participant(record.FromUserID).RecapUser, To: participant(record.ToUserID).RecapUser}
+			flows[key] = flow
+		}
+		flow.Count++
+		flow.Amount += record.Amount
+	}
+	for _, flow := range flows {
+		recap.NiuniuFlows = append(recap.NiuniuFlows, *flow)
+	}
+	sort.Slice(recap.NiuniuFlows, func(i, j int) bool {
+		a, b := recap.NiuniuFlows[i], recap.NiuniuFlows[j]
+		if a.Amount != b.Amount {
+			return a.Amount > b.Amount
+		}
+		if a.From.UserID != b.From.UserID {
+			return a.From.UserID < b.From.UserID
+		}
+		return a.To.UserID < b.To.UserID
+	})
+
+	recap.SettlementPlan = s.settlementService.generateSettlementPlan(balances, room.ChipRate)
+
+	return recap, nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"poker_score_backend/models"
+	"strings"
+	"time"
+)
+
+const recapTimeLayout = "2006-01-02 15:04"
+
+// recapRoomTypeNames 房间类型的展示名称
+var recapRoomTypeNames = map[string]string{
+	"texas":  "德州扑克",
+	"niuniu": "牛牛",
+}
+
+// recapView 渲染 Markdown 与 HTML 时共用的展示数据
+type recapView struct {
+	Title    string
+	Summary  []string
+	Results  [][]string
+	Pots     [][]string
+	Bettor   string
+	Flows    [][]string
+	Plan     []string
+	Footnote string
+}
+
+func newRecapView(recap *RoomRecap) recapView {
+	roomType := recapRoomTypeNames[recap.RoomType]
+	if roomType == "" {
+		roomType = recap.RoomType
+	}
+
+	ending := "12小时无操作，自动结算并解散"
+	if recap.Reason == RecapReasonManual && recap.DissolvedBy != nil {
+		ending = fmt.Sprintf("由 %s 手动解散", recap.DissolvedBy.Nickname)
+	}
+
+	view := recapView{
+		Title: fmt.Sprintf("房间 %s 复盘", recap.RoomCode),
+		Summary: []string{
+			fmt.Sprintf("类型：%s，积分比例 %s", roomType, recap.ChipRate),
+			fmt.Sprintf("时间：%s 至 %s，共 %s", recap.CreatedAt.Format(recapTimeLayout), recap.DissolvedAt.Format(recapTimeLayout), formatRecapDuration(recap.DurationMinutes)),
+			fmt.Sprintf("参与：%d 人，全场下注 %d 积分，共 %d 次操作", len(recap.Participants), recap.TotalBet, recap.OperationCount),
+			"结束：" + ending,
+		},
+		Footnote: fmt.Sprintf("生成于 %s", recap.GeneratedAt.Format(recapTimeLayout)),
+	}
+
+	for i, p := range recap.Participants {
+		view.Results = append(view.Results, []string{
+			fmt.Sprintf("%d", i+1),
+			p.Nickname,
+			fmt.Sprintf("%+d", p.ChipAmount),
+			fmt.Sprintf("%+.2f", p.RmbAmount),
+			fmt.Sprintf("%d（%d次）", p.BetTotal, p.BetCount),
+			fmt.Sprintf("%d", p.WithdrawTotal),
+		})
+	}
+
+	for _, pot := range recap.BiggestPots {
+		source := "收回"
+		if pot.OperationType == models.OpTypeForceTransfer {
+			source = "被转移"
+		}
+		view.Pots = append(view.Pots, []string{pot.Nickname, fmt.Sprintf("%d", pot.Amount), source, pot.At.Format("15:04")})
+	}
+
+	if recap.MostActiveBettor != nil {
+		view.Bettor = fmt.Sprintf("%s：下注 %d 次，共 %d 积分", recap.MostActiveBettor.Nickname, recap.MostActiveBettor.BetCount, recap.MostActiveBettor.BetTotal)
+	}
+
+	for _, flow := range recap.NiuniuFlows {
+		view.Flows = append(view.Flows, []string{flow.From.Nickname, flow.To.Nickname, fmt.Sprintf("%d", flow.Amount), fmt.Sprintf("%d", flow.Count)})
+	}
+
+	for _, plan := range recap.SettlementPlan {
+		view.Plan = append(view.Plan, plan.Description)
+	}
+
+	return view
+}
+
+func formatRecapDuration(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%d分钟", minutes)
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%d小时", minutes/60)
+	}
+	return fmt.Sprintf("%d小时%d分钟", minutes/60, minutes%60)
+}
+
+// RenderRecapMarkdown 把复盘渲染为可直接分享的 Markdown 文本
+func RenderRecapMarkdown(recap *RoomRecap) string {
+	view := newRecapView(recap)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", view.Title)
+	for _, line := range view.Summary {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+
+	b.WriteString("\n## 最终结果\n\n")
+	writeMarkdownTable(&b, []string{"名次", "玩家", "积分", "金额（元）", "下注", "收回"}, view.Results)
+
+	if len(view.Pots) > 0 {
+		b.WriteString("\n## 最大收回\n\n")
+		writeMarkdownTable(&b, []string{"玩家", "积分", "方式", "时间"}, view.Pots)
+	}
+
+	if view.Bettor != "" {
+		fmt.Fprintf(&b, "\n## 最活跃下注\n\n%s\n", view.Bettor)
+	}
+
+	if len(view.Flows) > 0 {
+		b.WriteString("\n## 牛牛下注流向\n\n")
+		writeMarkdownTable(&b, []string{"下注者", "被下注者", "积分", "次数"}, view.Flows)
+	}
+
+	b.WriteString("\n## 结算方案\n\n")
+	if len(view.Plan) == 0 {
+		b.WriteString("无需转账\n")
+	}
+	for _, line := range view.Plan {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+
+	fmt.Fprintf(&b, "\n_%s_\n", view.Footnote)
+	return b.String()
+}
+
+func writeMarkdownTable(b *strings.Builder, header []string, rows [][]string) {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, cell := range cells {
+			fmt.Fprintf(b, " %s |", escape.Replace(cell))
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(header)
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
+
+// recapHTMLTemplate 单文件 HTML，只使用内联样式，不引用图片或外部资源
+var recapHTMLTemplate = template.Must(template.New("recap").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,"PingFang SC","Microsoft YaHei",sans-serif;max-width:640px;margin:24px auto;padding:0 16px;color:#222}
+h1{font-size:22px}h2{font-size:17px;margin-top:28px;border-bottom:1px solid #eee;padding-bottom:4px}
+table{border-collapse:collapse;width:100%}th,td{border:1px solid #ddd;padding:6px 8px;text-align:left}th{background:#f6f6f6}
+ul{padding-left:20px}.note{color:#888;font-size:12px;margin-top:28px}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>{{range .Summary}}<li>{{.}}</li>{{end}}</ul>
+<h2>最终结果</h2>
+<table><tr><th>名次</th><th>玩家</th><th>积分</th><th>金额（元）</th><th>下注</th><th>收回</th></tr>
+{{range .Results}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if .Pots}}<h2>最大收回</h2>
+<table><tr><th>玩家</th><th>积分</th><th>方式</th><th>时间</th></tr>
+{{range .Pots}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{if .Bettor}}<h2>最活跃下注</h2>
+<p>{{.Bettor}}</p>
+{{end}}{{if .Flows}}<h2>牛牛下注流向</h2>
+<table><tr><th>下注者</th><th>被下注者</th><th>积分</th><th>次数</th></tr>
+{{range .Flows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}<h2>结算方案</h2>
+{{if .Plan}}<ul>{{range .Plan}}<li>{{.}}</li>{{end}}</ul>{{else}}<p>无需转账</p>{{end}}
+<p class="note">{{.Footnote}}</p>
+</body>
+</html>
+`))
+
+// RenderRecapHTML 把复盘渲染为单文件 HTML 页面，昵称等内容会被转义
+func RenderRecapHTML(recap *RoomRecap) (string, error) {
+	var buf bytes.Buffer
+	if err := recapHTMLTemplate.Execute(&buf, newRecapView(recap)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// inLocation 返回时间换算到指定时区后的副本，用于按查看者的时区展示
+func (r *RoomRecap) inLocation(loc *time.Location) *RoomRecap {
+	copied := *r
+	copied.CreatedAt = r.CreatedAt.In(loc)
+	copied.DissolvedAt = r.DissolvedAt.In(loc)
+	copied.GeneratedAt = r.GeneratedAt.In(loc)
+	copied.Participants = make([]RecapParticipant, len(r.Participants))
+	for i, p := range r.Participants {
+		p.JoinedAt = p.JoinedAt.In(loc)
+		copied.Participants[i] = p
+	}
+	copied.BiggestPots = make([]RecapPot, len(r.BiggestPots))
+	for i, pot := range r.BiggestPots {
+		pot.At = pot.At.In(loc)
+		copied.BiggestPots[i] = pot
+	}
+	return &copied
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecapService_GeneratesAndPersistsRecap(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "<Carol>", "Dave"})
+	alice, bob, carol, dave := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	createdAt := time.Date(2025, 11, 7, 20, 0, 0, 0, time.Local)
+	dissolvedAt := createdAt.Add(150 * time.Minute)
+	room := models.Room{RoomCode: "246810", RoomType: "niuniu", ChipRate: "20:1", Status: "active", CreatedBy: alice, CreatedAt: createdAt}
+	require.NoError(t, models.DB.Create(&room).Error)
+	for i, id := range []uint{alice, bob, carol} {
+		require.NoError(t, models.DB.Create(&models.RoomMember{RoomID: room.ID, UserID: id, JoinedAt: createdAt.Add(time.Duration(i) * time.Minute), Status: "offline"}).Error)
+	}
+
+	at := createdAt
+	record := func(userID uint, opType string, amount int, target *uint) {
+		at = at.Add(10 * time.Minute)
+		require.NoError(t, models.DB.Create(&models.RoomOperation{
+			RoomID: room.ID, UserID: userID, OperationType: opType, Amount: &amount, TargetUserID: target, CreatedAt: at,
+		}).Error)
+	}
+	betOn := func(from, to uint, amount int) {
+		require.NoError(t, models.DB.Create(&models.BetRecord{RoomID: room.ID, FromUserID: from, ToUserID: to, Amount: amount, CreatedAt: at}).Error)
+	}
+
+	record(bob, models.OpTypeNiuniuBet, 300, nil)
+	betOn(bob, alice, 200)
+	betOn(bob, carol, 100)
+	record(carol, models.OpTypeNiuniuBet, 100, nil)
+	betOn(carol, alice, 100)
+	record(bob, models.OpTypeBet, 50, nil)
+	record(alice, models.OpTypeWithdraw, 400, nil)
+	record(alice, models.OpTypeForceTransfer, 50, &carol)
+
+	for userID, chip := range map[uint]int{alice: 400, bob: -350, carol: -50} {
+		require.NoError(t, models.DB.Create(&models.Settlement{
+			RoomID: room.ID, UserID: userID, ChipAmount: chip, RmbAmount: calculateRmbAmount(chip, room.ChipRate), SettledAt: at, SettlementBatch: "batch",
+		}).Error)
+	}
+
+	service := NewRecapService(NewSettlementService(nil))
+
+	_, err := service.GetRoomRecap(room.ID, alice)
+	require.EqualError(t, err, "房间尚未解散")
+
+	require.NoError(t, models.DB.Model(&room).Updates(map[string]interface{}{"status": "dissolved", "dissolved_at": dissolvedAt}).Error)
+	require.NoError(t, models.DB.Create(&models.RoomOperation{
+		RoomID: room.ID, UserID: alice, OperationType: models.OpTypeRoomDissolved, CreatedAt: dissolvedAt,
+	}).Error)
+
+	service.OnRoomDissolved(room.ID)
+
+	var count int64
+	require.NoError(t, models.DB.Model(&models.RoomRecap{}).Where("room_id = ?", room.ID).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+
+	recap, err := service.GetRoomRecap(room.ID, bob)
+	require.NoError(t, err)
+	require.Equal(t, 150, recap.DurationMinutes)
+	require.Equal(t, RecapReasonManual, recap.Reason)
+	require.Equal(t, alice, recap.DissolvedBy.UserID)
+	require.Equal(t, 450, recap.TotalBet)
+	require.Equal(t, 5, recap.OperationCount)
+
+	require.Len(t, recap.Participants, 3)
+	require.Equal(t, []uint{alice, carol, bob}, []uint{recap.Participants[0].UserID, recap.Participants[1].UserID, recap.Participants[2].UserID})
+	require.Equal(t, 20.0, recap.Participants[0].RmbAmount)
+	require.Equal(t, 50, recap.Participants[1].WithdrawTotal)
+	require.Equal(t, 350, recap.Participants[2].BetTotal)
+
+	require.Len(t, recap.BiggestPots, 2)
+	require.Equal(t, alice, recap.BiggestPots[0].UserID)
+	require.Equal(t, 400, recap.BiggestPots[0].Amount)
+	require.Equal(t, carol, recap.BiggestPots[1].UserID)
+	require.Equal(t, models.OpTypeForceTransfer, recap.BiggestPots[1].OperationType)
+
+	require.Equal(t, bob, recap.MostActiveBettor.UserID)
+	require.Equal(t, 2, recap.MostActiveBettor.BetCount)
+
+	require.Len(t, recap.NiuniuFlows, 3)
+	require.Equal(t, RecapBetFlow{From: RecapUser{bob, "Bob"}, To: RecapUser{alice, "Alice"}, Count: 1, Amount: 200}, recap.NiuniuFlows[0])
+	require.Equal(t, bob, recap.NiuniuFlows[1].From.UserID)
+	require.Equal(t, carol, recap.NiuniuFlows[2].From.UserID)
+
+	require.Len(t, recap.SettlementPlan, 2)
+	require.Equal(t, bob, recap.SettlementPlan[0].FromUserID)
+	require.Equal(t, alice, recap.SettlementPlan[0].ToUserID)
+	require.Equal(t, 350, recap.SettlementPlan[0].ChipAmount)
+
+	// 已保存的复盘不会被重新生成
+	again, err := service.GetRoomRecap(room.ID, alice)
+	require.NoError(t, err)
+	require.True(t, recap.GeneratedAt.Equal(again.GeneratedAt))
+
+	_, err = service.GetRoomRecap(room.ID, dave)
+	require.EqualError(t, err, "您不在该房间中")
+
+	markdown := RenderRecapMarkdown(recap)
+	require.True(t, strings.HasPrefix(markdown, "# 房间 246810 复盘\n"))
+	require.Contains(t, markdown, "共 2小时30分钟")
+	require.Contains(t, markdown, "结束：由 Alice 手动解散")
+	require.Contains(t, markdown, "| 1 | Alice | +400 | +20.00 | 0（0次） | 400 |")
+	require.Contains(t, markdown, "Bob：下注 2 次，共 350 积分")
+	require.Contains(t, markdown, "- Bob → Alice 350积分（¥17.50）")
+
+	page, err := RenderRecapHTML(recap)
+	require.NoError(t, err)
+	require.Contains(t, page, "<title>房间 246810 复盘</title>")
+	require.Contains(t, page, "&lt;Carol&gt;")
+	require.NotContains(t, page, "<Carol>")
+	require.NotContains(t, page, "<img")
+}
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -21,13 +20,28 @@ const (
 
 // RoomService 房间服务
 type RoomService struct {
-	hub *ws.Hub
+	hub      *ws.Hub
+	presence *PresenceService
+
+	settledListeners   []RoomSettledListener
+	dissolvedListeners []RoomDissolvedListener
+}
+
+// RoomSettledListener 房间产生新的结算记录（确认结算或自动结算）后的回调
+type RoomSettledListener interface {
+	OnRoomSettled(roomID uint)
+}
+
+// RoomDissolvedListener 房间解散（手动解散或超时自动解散）后的回调
+type RoomDissolvedListener interface {
+	OnRoomDissolved(roomID uint)
 }
 
 // NewRoomService 创建房间服务
-func NewRoomService(hub *ws.Hub) *RoomService {
+func NewRoomService(hub *ws.Hub, presence *PresenceService) *RoomService {
 	service := &RoomService{
-		hub: hub,
+		hub:      hub,
+		presence: presence,
 	}
 
 	go service.runInactivityWatcher()
@@ -60,6 +74,18 @@ func (s *RoomService) cleanupInactiveRooms() {
 
 // CreateRoom 创建房间
 func (s *RoomService) CreateRoom(userID uint, roomType, chipRate string) (*models.Room, error) {
+	return s.createRoom(userID, roomType, chipRate, nil)
+}
+
+// CreateClubRoom 在俱乐部下创建房间，只有俱乐部成员可以创建
+func (s *RoomService) CreateClubRoom(userID, clubID uint, roomType, chipRate string) (*models.Room, error) {
+	if !isClubMember(clubID, userID) {
+		return nil, errors.New("您不是该俱乐部成员")
+	}
+	return s.createRoom(userID, roomType, chipRate, &clubID)
+}
+
+func (s *RoomService) createRoom(userID uint, roomType, chipRate string, clubID *uint) (*models.Room, error) {
 	// 生成唯一的房间号（最多尝试10次）
 	var roomCode string
 	for i := 0; i < 10; i++ {
@@ -88,6 +114,7 @@ func (s *RoomService) CreateRoom(userID uint, roomType, chipRate string) (*model
 		ChipRate:  chipRate,
 		Status:    "active",
 		CreatedBy: userID,
+		ClubID:    clubID,
 	}
 
 	err := models.DB.Create(&room).Error
@@ -115,6 +142,11 @@ func (s *RoomService) CreateRoom(userID uint, roomType, chipRate string) (*model
 
 // JoinRoom 加入房间
 func (s *RoomService) JoinRoom(userID, roomID uint) (*models.RoomMember, error) {
+	return s.joinRoom(userID, roomID, "加入了房间", true)
+}
+
+// joinRoom 将用户加入房间，checkClub 为 false 时不检查俱乐部成员身份（房主添加的游客）
+func (s *RoomService) joinRoom(userID, roomID uint, description string, checkClub bool) (*models.RoomMember, error) {
 	// 检查房间是否存在且活跃
 	var room models.Room
 	err := models.DB.First(&room, roomID).Error
@@ -134,6 +166,11 @@ func (s *RoomService) JoinRoom(userID, roomID uint) (*models.RoomMember, error)
 		return &existingMember, nil
 	}
 
+	// 俱乐部房间只对俱乐部成员开放
+	if checkClub && room.ClubID != nil && !isClubMember(*room.ClubID, userID) {
+		return nil, errors.New("该房间仅限俱乐部成员加入")
+	}
+
 	// 创建房间成员记录
 	member := models.RoomMember{
 		RoomID:   roomID,
@@ -155,7 +192,7 @@ func (s *RoomService) JoinRoom(userID, roomID uint) (*models.RoomMember, error)
 	}
 
 	// 记录操作
-	s.recordOperation(roomID, userID, models.OpTypeJoin, nil, nil, "加入了房间")
+	s.recordOperation(roomID, userID, models.OpTypeJoin, nil, nil, description)
 
 	log.Printf("用户加入房间成功: RoomID=%d, UserID=%d", roomID, userID)
 
@@ -320,6 +357,8 @@ func (s *RoomService) ManualDissolveRoom(roomID, userID uint) (time.Time, error)
 	}
 
 	log.Printf("房间由用户手动解散: RoomID=%d, UserID=%d", roomID, userID)
+	// 先通知解散回调（生成复盘等），客户端收到解散事件后即可查询
+	s.notifyRoomDissolved(roomID)
 	s.broadcastRoomDissolved(roomID, dissolvedAt)
 
 	return dissolvedAt, nil
@@ -390,11 +429,19 @@ func (s *RoomService) GetRoomMembers(roomID uint) ([]map[string]interface{}, err
 
 		balance, _ := s.GetUserBalance(roomID, member.UserID)
 
+		presence := PresenceInfo{Presence: PresenceOffline, LastSeenAt: member.LastSeenAt}
+		if s.presence != nil {
+			presence = s.presence.GetPresence(roomID, member.UserID, member.LastSeenAt)
+		}
+
 		result = append(result, map[string]interface{}{
-			"user_id":  user.ID,
-			"nickname": user.Nickname,
-			"balance":  balance,
-			"status":   member.Status,
+			"user_id":      user.ID,
+			"nickname":     user.Nickname,
+			"is_guest":     user.IsGuest(),
+			"balance":      balance,
+			"status":       member.Status,
+			"presence":     presence.Presence,
+			"last_seen_at": presence.LastSeenAt,
 		})
 	}
 
@@ -696,7 +743,33 @@ func (s *RoomService) checkAndDissolveRoom(roomID uint) {
 	}
 
 	log.Printf("房间因12小时无操作已解散: RoomID=%d", roomID)
+	s.notifyRoomDissolved(roomID)
 	s.broadcastRoomDissolved(roomID, now)
+	s.notifyRoomSettled(roomID)
+}
+
+// AddSettledListener 注册结算回调，需在开始服务前调用
+func (s *RoomService) AddSettledListener(listener RoomSettledListener) {
+	s.settledListeners = append(s.settledListeners, listener)
+}
+
+// notifyRoomSettled 通知所有结算回调
+func (s *RoomService) notifyRoomSettled(roomID uint) {
+	for _, listener := range s.settledListeners {
+		listener.OnRoomSettled(roomID)
+	}
+}
+
+// AddDissolvedListener 注册解散回调，需在开始服务前调用
+func (s *RoomService) AddDissolvedListener(listener RoomDissolvedListener) {
+	s.dissolvedListeners = append(s.dissolvedListeners, listener)
+}
+
+// notifyRoomDissolved 通知所有解散回调
+func (s *RoomService) notifyRoomDissolved(roomID uint) {
+	for _, listener := range s.dissolvedListeners {
+		listener.OnRoomDissolved(roomID)
+	}
 }
 
 func (s *RoomService) autoSettleRoomWithDB(tx *gorm.DB, room *models.Room, settledAt time.Time) error {
@@ -784,6 +857,17 @@ func (s *RoomService) recordOperationWithDB(db *gorm.DB, roomID, userID uint, op
 	return &operation, nil
 }
 
+// publishEvent 编码事件并广播到房间
+func (s *RoomService) publishEvent(roomID uint, event ws.Event) error {
+	payload, err := ws.EncodeEvent(event)
+	if err != nil {
+		return err
+	}
+
+	s.hub.BroadcastToRoom(roomID, payload)
+	return nil
+}
+
 func (s *RoomService) broadcastUserJoined(roomID, userID uint, joinedAt time.Time) {
 	if s.hub == nil {
 		return
@@ -801,25 +885,16 @@ func (s *RoomService) broadcastUserJoined(roomID, userID uint, joinedAt time.Tim
 		balance = 0
 	}
 
-	message := ws.Message{
-		Type: "user_joined",
-		Data: map[string]interface{}{
-			"user_id":   user.ID,
-			"nickname":  user.Nickname,
-			"balance":   balance,
-			"status":    "online",
-			"joined_at": joinedAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播用户加入: RoomID=%d, UserID=%d", roomID, userID)
+	if err := s.publishEvent(roomID, ws.UserJoinedEvent{
+		UserID:   user.ID,
+		Nickname: user.Nickname,
+		Balance:  balance,
+		Status:   "online",
+		JoinedAt: joinedAt,
+	}); err != nil {
 		log.Printf("序列化用户加入消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播用户加入: RoomID=%d, UserID=%d", roomID, userID)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastUserReturned(roomID, userID uint, returnedAt time.Time) {
@@ -839,25 +914,16 @@ func (s *RoomService) broadcastUserReturned(roomID, userID uint, returnedAt time
 		balance = 0
 	}
 
-	message := ws.Message{
-		Type: "user_returned",
-		Data: map[string]interface{}{
-			"user_id":     user.ID,
-			"nickname":    user.Nickname,
-			"balance":     balance,
-			"status":      "online",
-			"returned_at": returnedAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播用户返回: RoomID=%d, UserID=%d", roomID, userID)
+	if err := s.publishEvent(roomID, ws.UserReturnedEvent{
+		UserID:     user.ID,
+		Nickname:   user.Nickname,
+		Balance:    balance,
+		Status:     "online",
+		ReturnedAt: returnedAt,
+	}); err != nil {
 		log.Printf("序列化用户返回消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播用户返回: RoomID=%d, UserID=%d", roomID, userID)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastBet(roomID, userID uint, amount, myBalance, tableBalance int, createdAt time.Time) {
@@ -871,26 +937,17 @@ func (s *RoomService) broadcastBet(roomID, userID uint, amount, myBalance, table
 		return
 	}
 
-	message := ws.Message{
-		Type: "bet",
-		Data: map[string]interface{}{
-			"user_id":       user.ID,
-			"nickname":      user.Nickname,
-			"amount":        amount,
-			"balance":       myBalance,
-			"table_balance": tableBalance,
-			"created_at":    createdAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播下注: RoomID=%d, UserID=%d, Amount=%d", roomID, userID, amount)
+	if err := s.publishEvent(roomID, ws.BetEvent{
+		UserID:       user.ID,
+		Nickname:     user.Nickname,
+		Amount:       amount,
+		Balance:      myBalance,
+		TableBalance: tableBalance,
+		CreatedAt:    createdAt,
+	}); err != nil {
 		log.Printf("序列化下注消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播下注: RoomID=%d, UserID=%d, Amount=%d", roomID, userID, amount)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastNiuniuBet(roomID, userID uint, betDetails []NiuniuBetDetail, totalAmount, myBalance, tableBalance int, createdAt time.Time) {
@@ -904,27 +961,27 @@ func (s *RoomService) broadcastNiuniuBet(roomID, userID uint, betDetails []Niuni
 		return
 	}
 
-	message := ws.Message{
-		Type: "niuniu_bet",
-		Data: map[string]interface{}{
-			"user_id":       user.ID,
-			"nickname":      user.Nickname,
-			"total_amount":  totalAmount,
-			"balance":       myBalance,
-			"table_balance": tableBalance,
-			"bets":          betDetails,
-			"created_at":    createdAt.Format(time.RFC3339),
-		},
+	bets := make([]ws.NiuniuBetEntry, 0, len(betDetails))
+	for _, detail := range betDetails {
+		bets = append(bets, ws.NiuniuBetEntry{
+			ToUserID:   detail.ToUserID,
+			ToNickname: detail.ToNickname,
+			Amount:     detail.Amount,
+		})
 	}
 
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播牛牛下注: RoomID=%d, UserID=%d, TotalAmount=%d", roomID, userID, totalAmount)
+	if err := s.publishEvent(roomID, ws.NiuniuBetEvent{
+		UserID:       user.ID,
+		Nickname:     user.Nickname,
+		TotalAmount:  totalAmount,
+		Balance:      myBalance,
+		TableBalance: tableBalance,
+		Bets:         bets,
+		CreatedAt:    createdAt,
+	}); err != nil {
 		log.Printf("序列化牛牛下注消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播牛牛下注: RoomID=%d, UserID=%d, TotalAmount=%d", roomID, userID, totalAmount)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastWithdraw(roomID, userID uint, amount, myBalance, tableBalance int, createdAt time.Time) {
@@ -938,26 +995,17 @@ func (s *RoomService) broadcastWithdraw(roomID, userID uint, amount, myBalance,
 		return
 	}
 
-	message := ws.Message{
-		Type: "withdraw",
-		Data: map[string]interface{}{
-			"user_id":       user.ID,
-			"nickname":      user.Nickname,
-			"amount":        amount,
-			"balance":       myBalance,
-			"table_balance": tableBalance,
-			"created_at":    createdAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播收回: RoomID=%d, UserID=%d, Amount=%d", roomID, userID, amount)
+	if err := s.publishEvent(roomID, ws.WithdrawEvent{
+		UserID:       user.ID,
+		Nickname:     user.Nickname,
+		Amount:       amount,
+		Balance:      myBalance,
+		TableBalance: tableBalance,
+		CreatedAt:    createdAt,
+	}); err != nil {
 		log.Printf("序列化收回消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播收回: RoomID=%d, UserID=%d, Amount=%d", roomID, userID, amount)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastForceTransfer(roomID, userID, targetUserID uint, amount, actorBalance, targetBalance, tableBalance int, createdAt time.Time) {
@@ -977,29 +1025,20 @@ func (s *RoomService) broadcastForceTransfer(roomID, userID, targetUserID uint,
 		return
 	}
 
-	message := ws.Message{
-		Type: "force_transfer",
-		Data: map[string]interface{}{
-			"user_id":         actor.ID,
-			"nickname":        actor.Nickname,
-			"target_user_id":  target.ID,
-			"target_nickname": target.Nickname,
-			"amount":          amount,
-			"actor_balance":   actorBalance,
-			"target_balance":  targetBalance,
-			"table_balance":   tableBalance,
-			"created_at":      createdAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播积分强制转移: RoomID=%d, UserID=%d, TargetUserID=%d, Amount=%d", roomID, userID, targetUserID, amount)
+	if err := s.publishEvent(roomID, ws.ForceTransferEvent{
+		UserID:         actor.ID,
+		Nickname:       actor.Nickname,
+		TargetUserID:   target.ID,
+		TargetNickname: target.Nickname,
+		Amount:         amount,
+		ActorBalance:   actorBalance,
+		TargetBalance:  targetBalance,
+		TableBalance:   tableBalance,
+		CreatedAt:      createdAt,
+	}); err != nil {
 		log.Printf("序列化积分强制转移消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播积分强制转移: RoomID=%d, UserID=%d, TargetUserID=%d, Amount=%d", roomID, userID, targetUserID, amount)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastUserLeft(roomID, userID uint, status string, occurredAt time.Time) {
@@ -1013,24 +1052,15 @@ func (s *RoomService) broadcastUserLeft(roomID, userID uint, status string, occu
 		return
 	}
 
-	message := ws.Message{
-		Type: "user_left",
-		Data: map[string]interface{}{
-			"user_id":     user.ID,
-			"nickname":    user.Nickname,
-			"status":      status,
-			"occurred_at": occurredAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播用户离开: RoomID=%d, UserID=%d", roomID, userID)
+	if err := s.publishEvent(roomID, ws.UserLeftEvent{
+		UserID:     user.ID,
+		Nickname:   user.Nickname,
+		Status:     status,
+		OccurredAt: occurredAt,
+	}); err != nil {
 		log.Printf("序列化用户离开消息失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
-		return
 	}
-
-	log.Printf("广播用户离开: RoomID=%d, UserID=%d", roomID, userID)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastUserKicked(roomID, kickedUserID, kickedBy uint, kickedAt time.Time) {
@@ -1050,26 +1080,17 @@ func (s *RoomService) broadcastUserKicked(roomID, kickedUserID, kickedBy uint, k
 		return
 	}
 
-	message := ws.Message{
-		Type: "user_kicked",
-		Data: map[string]interface{}{
-			"user_id":            targetUser.ID,
-			"nickname":           targetUser.Nickname,
-			"kicked_by":          kicker.ID,
-			"kicked_by_nickname": kicker.Nickname,
-			"status":             "offline",
-			"kicked_at":          kickedAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播踢出: RoomID=%d, UserID=%d, KickedBy=%d", roomID, kickedUserID, kickedBy)
+	if err := s.publishEvent(roomID, ws.UserKickedEvent{
+		UserID:           targetUser.ID,
+		Nickname:         targetUser.Nickname,
+		KickedBy:         kicker.ID,
+		KickedByNickname: kicker.Nickname,
+		Status:           "offline",
+		KickedAt:         kickedAt,
+	}); err != nil {
 		log.Printf("序列化踢出消息失败: RoomID=%d, UserID=%d, %v", roomID, kickedUserID, err)
-		return
 	}
-
-	log.Printf("广播踢出: RoomID=%d, UserID=%d, KickedBy=%d", roomID, kickedUserID, kickedBy)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastSettlementInitiated(roomID, initiatedBy uint, initiatedAt time.Time, plan []SettlementPlan, tableBalance int) {
@@ -1083,28 +1104,24 @@ func (s *RoomService) broadcastSettlementInitiated(roomID, initiatedBy uint, ini
 		return
 	}
 
-	message := ws.Message{
-		Type: "settlement_initiated",
-		Data: map[string]interface{}{
-			"initiated_by":          user.ID,
-			"initiated_by_nickname": user.Nickname,
-			"initiated_at":          initiatedAt.Format(time.RFC3339),
-			"settlement_plan":       plan,
-			"table_balance":         tableBalance,
-		},
+	transfers := make([]ws.SettlementTransfer, 0, len(plan))
+	for _, item := range plan {
+		transfers = append(transfers, ws.SettlementTransfer(item))
 	}
 
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播结算发起: RoomID=%d, UserID=%d", roomID, initiatedBy)
+	if err := s.publishEvent(roomID, ws.SettlementInitiatedEvent{
+		InitiatedBy:         user.ID,
+		InitiatedByNickname: user.Nickname,
+		InitiatedAt:         initiatedAt,
+		SettlementPlan:      transfers,
+		TableBalance:        tableBalance,
+	}); err != nil {
 		log.Printf("序列化结算发起消息失败: RoomID=%d, UserID=%d, %v", roomID, initiatedBy, err)
-		return
 	}
-
-	log.Printf("广播结算发起: RoomID=%d, UserID=%d", roomID, initiatedBy)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
-func (s *RoomService) broadcastSettlementConfirmed(roomID, confirmedBy uint, settlementBatch string, settledAt time.Time, summary map[string]interface{}) {
+func (s *RoomService) broadcastSettlementConfirmed(roomID, confirmedBy uint, settlementBatch string, settledAt time.Time, summary ws.SettlementSummary) {
 	if s.hub == nil {
 		return
 	}
@@ -1115,25 +1132,16 @@ func (s *RoomService) broadcastSettlementConfirmed(roomID, confirmedBy uint, set
 		return
 	}
 
-	message := ws.Message{
-		Type: "settlement_confirmed",
-		Data: map[string]interface{}{
-			"confirmed_by":          user.ID,
-			"confirmed_by_nickname": user.Nickname,
-			"settlement_batch":      settlementBatch,
-			"settled_at":            settledAt.Format(time.RFC3339),
-			"settlement_summary":    summary,
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播结算确认: RoomID=%d, UserID=%d, Batch=%s", roomID, confirmedBy, settlementBatch)
+	if err := s.publishEvent(roomID, ws.SettlementConfirmedEvent{
+		ConfirmedBy:         user.ID,
+		ConfirmedByNickname: user.Nickname,
+		SettlementBatch:     settlementBatch,
+		SettledAt:           settledAt,
+		SettlementSummary:   summary,
+	}); err != nil {
 		log.Printf("序列化结算确认消息失败: RoomID=%d, UserID=%d, %v", roomID, confirmedBy, err)
-		return
 	}
-
-	log.Printf("广播结算确认: RoomID=%d, UserID=%d, Batch=%s", roomID, confirmedBy, settlementBatch)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
 
 func (s *RoomService) broadcastRoomDissolved(roomID uint, dissolvedAt time.Time) {
@@ -1141,20 +1149,11 @@ func (s *RoomService) broadcastRoomDissolved(roomID uint, dissolvedAt time.Time)
 		return
 	}
 
-	message := ws.Message{
-		Type: "room_dissolved",
-		Data: map[string]interface{}{
-			"room_id":      roomID,
-			"dissolved_at": dissolvedAt.Format(time.RFC3339),
-		},
-	}
-
-	payload, err := json.Marshal(message)
-	if err != nil {
+	log.Printf("广播房间解散: RoomID=%d", roomID)
+	if err := s.publishEvent(roomID, ws.RoomDissolvedEvent{
+		RoomID:      roomID,
+		DissolvedAt: dissolvedAt,
+	}); err != nil {
 		log.Printf("序列化房间解散消息失败: RoomID=%d, %v", roomID, err)
-		return
 	}
-
-	log.Printf("广播房间解散: RoomID=%d", roomID)
-	s.hub.BroadcastToRoom(roomID, payload)
 }
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 赛季状态
+const (
+	SeasonStatusUpcoming  = "upcoming"  // 未开始
+	SeasonStatusActive    = "active"    // 进行中
+	SeasonStatusEnded     = "ended"     // 已结束，等待定时任务固化排名
+	SeasonStatusFinalized = "finalized" // 排名已固化
+)
+
+// maxPlacementPoints 名次积分最多配置的名次数
+const maxPlacementPoints = 20
+
+// SeasonService 赛季服务
+type SeasonService struct {
+	snapshotHour int
+
+	stop     chan struct{} // 关闭后定时快照任务退出
+	stopOnce sync.Once
+	done     chan struct{} // 定时快照任务退出后关闭
+}
+
+// SeasonInput 创建赛季的参数
+type SeasonInput struct {
+	Name            string
+	ClubID          *uint
+	StartAt         time.Time
+	EndAt           time.Time
+	RmbWeight       float64
+	PlacementPoints []float64
+	AttendanceBonus float64
+}
+
+// seasonRoomResult 用户在赛季内某个房间的结果，没有结算记录的成员盈亏为0
+type seasonRoomResult struct {
+	RoomID     uint
+	UserID     uint
+	ChipAmount int
+	RmbAmount  float64
+}
+
+// NewSeasonService 创建赛季服务，并在每天 snapshotHour 点（服务器时区）为进行中的赛季保存排名快照
+func NewSeasonService(snapshotHour int) *SeasonService {
+	service := &SeasonService{
+		snapshotHour: snapshotHour,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	go service.runSnapshotScheduler()
+
+	return service
+}
+
+func (s *SeasonService) runSnapshotScheduler() {
+	defer close(s.done)
+
+	for {
+		now := time.Now()
+		timer := time.NewTimer(nextSnapshotAt(now, s.snapshotHour).Sub(now))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.SnapshotStandings(time.Now()); err != nil {
+			log.Printf("保存赛季排名快照失败: %v", err)
+		}
+	}
+}
+
+// Stop 停止定时快照任务并等待进行中的快照结束，服务器关闭时须在关闭数据库之前调用
+func (s *SeasonService) Stop() {
+	if s.stop == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+// nextSnapshotAt 计算下一次快照时间；按日历日期递增，夏令时切换当天同样在当地的 hour 点执行
+func nextSnapshotAt(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, hour, 0, 0, 0, now.Location())
+	}
+	return next
+}
+
+// CreateSeason 创建赛季：俱乐部赛季由俱乐部管理员创建，全服赛季由系统管理员创建
+func (s *SeasonService) CreateSeason(userID uint, input SeasonInput) (*models.Season, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return nil, errors.New("赛季名称不能为空")
+	}
+	if utf8.RuneCountInString(input.Name) > 50 {
+		return nil, errors.New("赛季名称不能超过50个字符")
+	}
+	if !input.EndAt.After(input.StartAt) {
+		return nil, errors.New("结束时间必须晚于开始时间")
+	}
+	if input.RmbWeight < 0 || input.AttendanceBonus < 0 {
+		return nil, errors.New("积分配置不能为负数")
+	}
+	if len(input.PlacementPoints) > maxPlacementPoints {
+		return nil, errors.New("名次积分最多配置20个名次")
+	}
+	for _, points := range input.PlacementPoints {
+		if points < 0 {
+			return nil, errors.New("积分配置不能为负数")
+		}
+	}
+
+	if input.ClubID != nil {
+		member, err := getClubMember(*input.ClubID, userID)
+		if err != nil {
+			return nil, errors.New("您不是该俱乐部成员")
+		}
+		if !member.IsAdmin() {
+			return nil, errors.New("只有俱乐部管理员可以创建赛季")
+		}
+	} else {
+		var user models.User
+		if err := models.DB.First(&user, userID).Error; err != nil || user.Role != "admin" {
+			return nil, errors.New("只有管理员可以创建全服赛季")
+		}
+	}
+
+	placementPoints := input.PlacementPoints
+	if placementPoints == nil {
+		placementPoints = []float64{}
+	}
+
+	// 统一按服务器时区保存，SQLite 按字符串比较时间
+	season := models.Season{
+		Name:            input.Name,
+		ClubID:          input.ClubID,
+		StartAt:         storageTime(input.StartAt),
+		EndAt:           storageTime(input.EndAt),
+		RmbWeight:       input.RmbWeight,
+		PlacementPoints: placementPoints,
+		AttendanceBonus: input.AttendanceBonus,
+		CreatedBy:       userID,
+	}
+	if err := models.DB.Create(&season).Error; err != nil {
+		log.Printf("创建赛季失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("赛季创建成功: SeasonID=%d, Name=%s, CreatedBy=%d", season.ID, season.Name, userID)
+	return &season, nil
+}
+
+// ListSeasons 获取赛季列表；clubID 为空时返回全服赛季
+func (s *SeasonService) ListSeasons(userID uint, clubID *uint) ([]map[string]interface{}, error) {
+	query := models.DB.Order("start_at DESC")
+	if clubID != nil {
+		if !isClubMember(*clubID, userID) {
+			return nil, errors.New("您不是该俱乐部成员")
+		}
+		query = query.Where("club_id = ?", *clubID)
+	} else {
+		query = query.Where("club_id IS NULL")
+	}
+
+	var seasons []models.Season
+	if err := query.Find(&seasons).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]map[string]interface{}, 0, len(seasons))
+	for _, season := range seasons {
+		result = append(result, seasonView(season, now))
+	}
+	return result, nil
+}
+
+// GetLeaderboard 获取赛季排行榜
+// date 非空时返回当天的排名快照；排名已固化的赛季始终返回最终快照；其余情况实时计算
+func (s *SeasonService) GetLeaderboard(seasonID, userID uint, date string) (map[string]interface{}, error) {
+	var season models.Season
+	if err := models.DB.First(&season, seasonID).Error; err != nil {
+		return nil, errors.New("赛季不存在")
+	}
+	if season.ClubID != nil && !isClubMember(*season.ClubID, userID) {
+		return nil, errors.New("您不是该俱乐部成员")
+	}
+
+	var snapshotDates []string
+	if err := models.DB.Model(&models.SeasonStanding{}).
+		Where("season_id = ?", seasonID).
+		Distinct("snapshot_date").
+		Order("snapshot_date ASC").
+		Pluck("snapshot_date", &snapshotDates).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := map[string]interface{}{
+		"season":         seasonView(season, now),
+		"snapshot_dates": snapshotDates,
+	}
+
+	if date == "" && season.FinalizedAt != nil && len(snapshotDates) > 0 {
+		date = snapshotDates[len(snapshotDates)-1]
+	}
+
+	if date != "" {
+		var standings []models.SeasonStanding
+		if err := models.DB.Where("season_id = ? AND snapshot_date = ?", seasonID, date).
+			Order("rank ASC").Find(&standings).Error; err != nil {
+			return nil, err
+		}
+		if len(standings) == 0 {
+			return nil, errors.New("该日期没有排名快照")
+		}
+		result["source"] = "snapshot"
+		result["snapshot_date"] = date
+		result["standings"] = standings
+		return result, nil
+	}
+
+	standings, err := s.computeStandings(season, now)
+	if err != nil {
+		return nil, err
+	}
+	result["source"] = "live"
+	result["snapshot_date"] = nil
+	result["standings"] = standings
+	return result, nil
+}
+
+// SnapshotStandings 为所有已开始且尚未固化的赛季保存 now 时刻的排名快照，
+// 同一天重复执行会覆盖当天的快照；赛季结束后的首次快照即为最终排名，此后不再变化
+func (s *SeasonService) SnapshotStandings(now time.Time) error {
+	var seasons []models.Season
+	if err := models.DB.Where("start_at <= ? AND finalized_at IS NULL", storageTime(now)).Find(&seasons).Error; err != nil {
+		return err
+	}
+
+	snapshotDate := now.In(time.Local).Format("2006-01-02")
+	for _, season := range seasons {
+		standings, err := s.computeStandings(season, now)
+		if err != nil {
+			return err
+		}
+		for i := range standings {
+			standings[i].SnapshotDate = snapshotDate
+		}
+
+		finalize := !now.Before(season.EndAt)
+		err = models.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("season_id = ? AND snapshot_date = ?", season.ID, snapshotDate).
+				Delete(&models.SeasonStanding{}).Error; err != nil {
+				return err
+			}
+			if len(standings) > 0 {
+				if err := tx.Create(&standings).Error; err != nil {
+					return err
+				}
+			}
+			if finalize {
+				return tx.Model(&models.Season{}).Where("id = ?", season.ID).Update("finalized_at", storageTime(now)).Error
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("保存赛季排名快照失败: SeasonID=%d, %v", season.ID, err)
+			return err
+		}
+
+		log.Printf("赛季排名快照已保存: SeasonID=%d, Date=%s, Players=%d, Finalized=%v", season.ID, snapshotDate, len(standings), finalize)
+	}
+
+	return nil
+}
+
+// computeStandings 统计赛季开始到 until（不超过赛季结束时间）之间的排名
+// 积分 = 净盈亏(元) × rmb_weight + 各场名次积分 + 参加场次 × attendance_bonus
+// 并列时依次比较：净盈亏、单场第一次数、参加场次，最后按用户ID
+func (s *SeasonService) computeStandings(season models.Season, until time.Time) ([]models.SeasonStanding, error) {
+	end := season.EndAt
+	if until.Before(end) {
+		end = until
+	}
+
+	results, err := s.loadSeasonRoomResults(season, end)
+	if err != nil {
+		return nil, err
+	}
+
+	byRoom := make(map[uint][]seasonRoomResult)
+	for _, result := range results {
+		byRoom[result.RoomID] = append(byRoom[result.RoomID], result)
+	}
+
+	standings := make(map[uint]*models.SeasonStanding)
+	ensure := func(userID uint) *models.SeasonStanding {
+		standing, ok := standings[userID]
+		if !ok {
+			standing = &models.SeasonStanding{SeasonID: season.ID, UserID: userID}
+			standings[userID] = standing
+		}
+		return standing
+	}
+
+	for _, roomResults := range byRoom {
+		places := placeRoomResults(roomResults)
+		for i, result := range roomResults {
+			standing := ensure(result.UserID)
+			standing.Sessions++
+			standing.NetChip += result.ChipAmount
+			standing.NetRmb += result.RmbAmount
+			if places[i] == 1 {
+				standing.Wins++
+			}
+			if places[i] <= len(season.PlacementPoints) {
+				standing.PlacementPoints += season.PlacementPoints[places[i]-1]
+			}
+		}
+	}
+
+	userIDs := make([]uint, 0, len(standings))
+	sorted := make([]models.SeasonStanding, 0, len(standings))
+	for _, standing := range standings {
+		standing.NetRmb = roundTo(standing.NetRmb, 2)
+		standing.RmbPoints = roundTo(standing.NetRmb*season.RmbWeight, 2)
+		standing.PlacementPoints = roundTo(standing.PlacementPoints, 2)
+		standing.AttendancePoints = roundTo(float64(standing.Sessions)*season.AttendanceBonus, 2)
+		standing.Points = roundTo(standing.RmbPoints+standing.PlacementPoints+standing.AttendancePoints, 2)
+		sorted = append(sorted, *standing)
+		userIDs = append(userIDs, standing.UserID)
+	}
+
+	sortStandings(sorted)
+
+	nicknames, err := loadNicknames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sorted {
+		sorted[i].Rank = i + 1
+		sorted[i].Nickname = nicknames[sorted[i].UserID]
+	}
+
+	return sorted, nil
+}
+
+// sortStandings 按积分从高到低排序，并列时依次比较净盈亏、单场第一次数、参加场次，最后按用户ID
+func sortStandings(standings []models.SeasonStanding) {
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.NetRmb != b.NetRmb {
+			return a.NetRmb > b.NetRmb
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		return a.UserID < b.UserID
+	})
+}
+
+// loadSeasonRoomResults 加载赛季范围内有结算记录的房间，以及这些房间所有成员的盈亏
+func (s *SeasonService) loadSeasonRoomResults(season models.Season, end time.Time) ([]seasonRoomResult, error) {
+	query := models.DB.Table("settlements").
+		Select("settlements.room_id AS room_id, settlements.user_id AS user_id, "+
+			"SUM(settlements.chip_amount) AS chip_amount, SUM(settlements.rmb_amount) AS rmb_amount").
+		Joins("JOIN rooms ON rooms.id = settlements.room_id").
+		Where("settlements.settled_at >= ? AND settlements.settled_at < ?", storageTime(season.StartAt), storageTime(end))
+	if season.ClubID != nil {
+		query = query.Where("rooms.club_id = ?", *season.ClubID)
+	}
+
+	var settled []seasonRoomResult
+	if err := query.Group("settlements.room_id, settlements.user_id").Scan(&settled).Error; err != nil {
+		return nil, err
+	}
+	if len(settled) == 0 {
+		return settled, nil
+	}
+
+	roomIDSet := make(map[uint]bool)
+	seen := make(map[[2]uint]bool, len(settled))
+	for _, result := range settled {
+		roomIDSet[result.RoomID] = true
+		seen[[2]uint{result.RoomID, result.UserID}] = true
+	}
+	roomIDs := make([]uint, 0, len(roomIDSet))
+	for id := range roomIDSet {
+		roomIDs = append(roomIDs, id)
+	}
+
+	// 结算时积分为0的成员没有结算记录，但同样计入出勤和名次
+	var members []models.RoomMember
+	if err := models.DB.Where("room_id IN ?", roomIDs).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		key := [2]uint{member.RoomID, member.UserID}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		settled = append(settled, seasonRoomResult{RoomID: member.RoomID, UserID: member.UserID})
+	}
+
+	return settled, nil
+}
+
+// placeRoomResults 按积分盈亏从高到低计算单场名次，盈亏相同的名次相同（1,1,3…）
+func placeRoomResults(results []seasonRoomResult) []int {
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return results[order[i]].ChipAmount > results[order[j]].ChipAmount
+	})
+
+	places := make([]int, len(results))
+	for position, index := range order {
+		if position > 0 && results[index].ChipAmount == results[order[position-1]].ChipAmount {
+			places[index] = places[order[position-1]]
+		} else {
+			places[index] = position + 1
+		}
+	}
+	return places
+}
+
+func seasonStatus(season models.Season, now time.Time) string {
+	switch {
+	case season.FinalizedAt != nil:
+		return SeasonStatusFinalized
+	case now.Before(season.StartAt):
+		return SeasonStatusUpcoming
+	case now.Before(season.EndAt):
+		return SeasonStatusActive
+	default:
+		return SeasonStatusEnded
+	}
+}
+
+func seasonView(season models.Season, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               season.ID,
+		"name":             season.Name,
+		"club_id":          season.ClubID,
+		"start_at":         season.StartAt,
+		"end_at":           season.EndAt,
+		"rmb_weight":       season.RmbWeight,
+		"placement_points": season.PlacementPoints,
+		"attendance_bonus": season.AttendanceBonus,
+		"status":           seasonStatus(season, now),
+		"created_by":       season.CreatedBy,
+		"created_at":       season.CreatedAt,
+		"finalized_at":     season.FinalizedAt,
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceRoomResults_TiesShareBetterPlace(t *testing.T) {
+	t.Parallel()
+
+	places := placeRoomResults([]seasonRoomResult{
+		{UserID: 1, ChipAmount: -200},
+		{UserID: 2, ChipAmount: 400},
+		{UserID: 3, ChipAmount: -200},
+		{UserID: 4, ChipAmount: 0},
+	})
+	require.Equal(t, []int{3, 1, 3, 2}, places)
+}
+
+func TestNextSnapshotAt_KeepsLocalHourAcrossDST(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 2025-03-09 凌晨2点进入夏令时，这一天只有23小时
+	now := time.Date(2025, 3, 8, 8, 0, 0, 0, loc)
+	next := nextSnapshotAt(now, 7)
+	require.Equal(t, time.Date(2025, 3, 9, 7, 0, 0, 0, loc), next)
+	require.Equal(t, 22*time.Hour, next.Sub(now))
+
+	// 当天还没到快照时间
+	now = time.Date(2025, 3, 8, 6, 59, 0, 0, loc)
+	require.Equal(t, time.Date(2025, 3, 8, 7, 0, 0, 0, loc), nextSnapshotAt(now, 7))
+}
+
+func TestSeasonService_PermissionsAndValidation(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob"})
+	alice, bob := users[0].ID, users[1].ID
+
+	clubService := NewClubService()
+	club, err := clubService.CreateClub(alice, "周五德州", "")
+	require.NoError(t, err)
+	_, err = clubService.JoinClub(bob, club.InviteCode)
+	require.NoError(t, err)
+
+	service := &SeasonService{snapshotHour: 7}
+	start := time.Date(2025, 11, 1, 0, 0, 0, 0, time.Local)
+	input := SeasonInput{Name: "十一月联赛", ClubID: &club.ID, StartAt: start, EndAt: start.AddDate(0, 1, 0), RmbWeight: 1}
+
+	_, err = service.CreateSeason(bob, input)
+	require.EqualError(t, err, "只有俱乐部管理员可以创建赛季")
+
+	serverInput := input
+	serverInput.ClubID = nil
+	_, err = service.CreateSeason(alice, serverInput)
+	require.EqualError(t, err, "只有管理员可以创建全服赛季")
+
+	invalid := input
+	invalid.EndAt = start
+	_, err = service.CreateSeason(alice, invalid)
+	require.EqualError(t, err, "结束时间必须晚于开始时间")
+
+	season, err := service.CreateSeason(alice, input)
+	require.NoError(t, err)
+	require.Equal(t, []float64{}, season.PlacementPoints)
+
+	seasons, err := service.ListSeasons(bob, &club.ID)
+	require.NoError(t, err)
+	require.Len(t, seasons, 1)
+
+	seasons, err = service.ListSeasons(bob, nil)
+	require.NoError(t, err)
+	require.Empty(t, seasons)
+}
+
+func TestSeasonService_StandingsSnapshotsAndFinalization(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol", "Dave"})
+	alice, bob, carol, dave := users[0].ID, users[1].ID, users[2].ID, users[3].ID
+
+	clubService := NewClubService()
+	club, err := clubService.CreateClub(alice, "周五德州", "")
+	require.NoError(t, err)
+	for _, id := range []uint{bob, carol, dave} {
+		_, err = clubService.JoinClub(id, club.InviteCode)
+		require.NoError(t, err)
+	}
+
+	attach := func(room models.Room) models.Room {
+		require.NoError(t, models.DB.Model(&room).Update("club_id", club.ID).Error)
+		return room
+	}
+
+	nov7 := time.Date(2025, 11, 7, 20, 0, 0, 0, time.Local)
+	room1 := attach(seedSettledRoom(t, "texas", nov7, nov7.Add(3*time.Hour), map[uint]int{alice: 400, bob: -200, carol: -200}))
+	// Dave 坐下但没有输赢，没有结算记录，仍计入出勤与名次
+	require.NoError(t, models.DB.Create(&models.RoomMember{RoomID: room1.ID, UserID: dave, JoinedAt: nov7, Status: "offline"}).Error)
+
+	nov14 := nov7.AddDate(0, 0, 7)
+	attach(seedSettledRoom(t, "niuniu", nov14, nov14.Add(2*time.Hour), map[uint]int{bob: 300, carol: -300}))
+
+	// 非俱乐部房间、赛季结束后的俱乐部房间都不计入
+	seedSettledRoom(t, "texas", nov14, nov14.Add(time.Hour), map[uint]int{alice: 1000, carol: -1000})
+	dec2 := time.Date(2025, 12, 2, 20, 0, 0, 0, time.Local)
+	attach(seedSettledRoom(t, "texas", dec2, dec2.Add(time.Hour), map[uint]int{carol: 2000, alice: -2000}))
+
+	service := &SeasonService{snapshotHour: 7}
+	start := time.Date(2025, 11, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 1, 0)
+
+	season, err := service.CreateSeason(alice, SeasonInput{
+		Name:            "十一月联赛",
+		ClubID:          &club.ID,
+		StartAt:         start,
+		EndAt:           end,
+		RmbWeight:       1,
+		PlacementPoints: []float64{10, 5, 2},
+		AttendanceBonus: 1,
+	})
+	require.NoError(t, err)
+
+	// 只看出勤的赛季，用来验证并列时的排序
+	attendanceOnly, err := service.CreateSeason(alice, SeasonInput{
+		Name:            "全勤奖",
+		ClubID:          &club.ID,
+		StartAt:         start,
+		EndAt:           end,
+		AttendanceBonus: 1,
+	})
+	require.NoError(t, err)
+
+	standings, err := service.computeStandings(*season, end)
+	require.NoError(t, err)
+	require.Len(t, standings, 4)
+
+	expected := []struct {
+		userID    uint
+		points    float64
+		placement float64
+		sessions  int
+		wins      int
+	}{
+		{alice, 31, 10, 1, 1}, // 20元 + 第一名10分 + 出勤1分
+		{bob, 19, 12, 2, 1},   // 5元 + 2分 + 10分 + 出勤2分
+		{dave, 6, 5, 1, 0},    // 0元 + 第二名5分 + 出勤1分
+		{carol, -16, 7, 2, 0}, // -25元 + 2分 + 5分 + 出勤2分
+	}
+	for i, want := range expected {
+		require.Equal(t, want.userID, standings[i].UserID, "rank %d", i+1)
+		require.Equal(t, i+1, standings[i].Rank)
+		require.Equal(t, want.points, standings[i].Points)
+		require.Equal(t, want.placement, standings[i].PlacementPoints)
+		require.Equal(t, want.sessions, standings[i].Sessions)
+		require.Equal(t, want.wins, standings[i].Wins)
+	}
+
+	// 积分相同时先比较净盈亏
+	tied, err := service.computeStandings(*attendanceOnly, end)
+	require.NoError(t, err)
+	order := []uint{tied[0].UserID, tied[1].UserID, tied[2].UserID, tied[3].UserID}
+	require.Equal(t, []uint{bob, carol, alice, dave}, order)
+
+	// 赛季进行中：保存当天快照，排名未固化
+	midSeason := time.Date(2025, 11, 20, 7, 0, 0, 0, time.Local)
+	require.NoError(t, service.SnapshotStandings(midSeason))
+	require.NoError(t, service.SnapshotStandings(midSeason), "同一天重复执行应覆盖快照")
+
+	var count int64
+	models.DB.Model(&models.SeasonStanding{}).Where("season_id = ? AND snapshot_date = ?", season.ID, "2025-11-20").Count(&count)
+	require.EqualValues(t, 4, count)
+
+	live, err := service.GetLeaderboard(season.ID, carol, "")
+	require.NoError(t, err)
+	require.Equal(t, "live", live["source"])
+	// 赛季时间已过（相对真实时间），但尚未固化时仍实时计算
+	require.Equal(t, SeasonStatusEnded, live["season"].(map[string]interface{})["status"].(string))
+
+	// 赛季结束后的第一次快照固化最终排名
+	require.NoError(t, service.SnapshotStandings(time.Date(2025, 12, 1, 7, 0, 0, 0, time.Local)))
+	require.NoError(t, models.DB.First(season, season.ID).Error)
+	require.NotNil(t, season.FinalizedAt)
+
+	// 之后补录的结算和昵称修改都不会影响历史赛季
+	nov25 := time.Date(2025, 11, 25, 20, 0, 0, 0, time.Local)
+	attach(seedSettledRoom(t, "texas", nov25, nov25.Add(time.Hour), map[uint]int{carol: 5000, alice: -5000}))
+	require.NoError(t, models.DB.Model(&models.User{}).Where("id = ?", alice).Update("nickname", "Alice改名").Error)
+	require.NoError(t, service.SnapshotStandings(time.Date(2025, 12, 2, 7, 0, 0, 0, time.Local)))
+
+	final, err := service.GetLeaderboard(season.ID, bob, "")
+	require.NoError(t, err)
+	require.Equal(t, "snapshot", final["source"])
+	require.Equal(t, "2025-12-01", final["snapshot_date"])
+	require.Equal(t, []string{"2025-11-20", "2025-12-01"}, final["snapshot_dates"])
+
+	finalStandings := final["standings"].([]models.SeasonStanding)
+	require.Equal(t, alice, finalStandings[0].UserID)
+	require.Equal(t, "Alice", finalStandings[0].Nickname)
+	require.Equal(t, carol, finalStandings[3].UserID)
+	require.Equal(t, -16.0, finalStandings[3].Points)
+
+	history, err := service.GetLeaderboard(season.ID, bob, "2025-11-20")
+	require.NoError(t, err)
+	require.Len(t, history["standings"], 4)
+
+	_, err = service.GetLeaderboard(season.ID, bob, "2025-11-21")
+	require.EqualError(t, err, "该日期没有排名快照")
+}
+
+func TestSeasonService_NormalizesClientOffset(t *testing.T) {
+	setupSettlementTestDB(t)
+	alice := seedUsers(t, []string{"Alice"})[0].ID
+
+	club, err := NewClubService().CreateClub(alice, "周五德州", "")
+	require.NoError(t, err)
+
+	// 客户端时区比服务器时区快9小时，原样保存会让时间字符串比实际晚9小时
+	start := time.Date(2025, 11, 1, 20, 0, 0, 0, time.Local)
+	_, localOffset := start.Zone()
+	client := time.FixedZone("client", localOffset+9*3600)
+
+	service := &SeasonService{snapshotHour: 7}
+	season, err := service.CreateSeason(alice, SeasonInput{
+		Name:      "周末赛",
+		ClubID:    &club.ID,
+		StartAt:   start.In(client),
+		EndAt:     start.Add(time.Hour).In(client),
+		RmbWeight: 1,
+	})
+	require.NoError(t, err)
+
+	var stored models.Season
+	require.NoError(t, models.DB.First(&stored, season.ID).Error)
+	require.True(t, stored.StartAt.Equal(start))
+	_, storedOffset := stored.StartAt.Zone()
+	require.Equal(t, localOffset, storedOffset)
+
+	// 赛季结束后的第一次快照即固化排名
+	require.NoError(t, service.SnapshotStandings(start.Add(2*time.Hour).In(client)))
+	require.NoError(t, models.DB.First(&stored, season.ID).Error)
+	require.NotNil(t, stored.FinalizedAt)
+}
+
+func TestSeasonService_StopEndsScheduler(t *testing.T) {
+	service := NewSeasonService(time.Now().Add(2 * time.Hour).Hour())
+
+	stopped := make(chan struct{})
+	go func() {
+		service.Stop()
+		service.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stop 未能结束定时快照任务")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"poker_score_backend/models"
+	ws "poker_score_backend/websocket"
 	"sort"
 	"time"
 
@@ -141,13 +142,6 @@ func (s *SettlementService) ConfirmSettlement(roomID, userID uint) (string, time
 	}
 
 	// 收集结算详情
-	type settlementDetail struct {
-		UserID     uint    `json:"user_id"`
-		Nickname   string  `json:"nickname"`
-		ChipAmount int     `json:"chip_amount"`
-		RmbAmount  float64 `json:"rmb_amount"`
-	}
-
 	userIDSet := make(map[uint]struct{})
 	for _, balance := range balances {
 		if balance.Balance == 0 {
@@ -173,7 +167,7 @@ func (s *SettlementService) ConfirmSettlement(roomID, userID uint) (string, time
 		}
 	}
 
-	details := make([]settlementDetail, 0, len(userIDs))
+	details := make([]ws.SettlementResult, 0, len(userIDs))
 	for _, balance := range balances {
 		if balance.Balance == 0 {
 			continue
@@ -184,7 +178,7 @@ func (s *SettlementService) ConfirmSettlement(roomID, userID uint) (string, time
 			nickname = user.Nickname
 		}
 
-		details = append(details, settlementDetail{
+		details = append(details, ws.SettlementResult{
 			UserID:     balance.UserID,
 			Nickname:   nickname,
 			ChipAmount: balance.Balance,
@@ -192,11 +186,11 @@ func (s *SettlementService) ConfirmSettlement(roomID, userID uint) (string, time
 		})
 	}
 
-	descPayload := map[string]interface{}{
-		"batch":      settlementBatch,
-		"settled_at": settledAt.Format(time.RFC3339),
-		"chip_rate":  room.ChipRate,
-		"details":    details,
+	descPayload := ws.SettlementSummary{
+		Batch:     settlementBatch,
+		SettledAt: settledAt,
+		ChipRate:  room.ChipRate,
+		Details:   details,
 	}
 
 	descBytes, err := json.Marshal(descPayload)
@@ -212,6 +206,7 @@ func (s *SettlementService) ConfirmSettlement(roomID, userID uint) (string, time
 
 	log.Printf("确认结算成功: RoomID=%d, UserID=%d, Batch=%s", roomID, userID, settlementBatch)
 	s.roomService.broadcastSettlementConfirmed(roomID, userID, settlementBatch, settledAt, descPayload)
+	s.roomService.notifyRoomSettled(roomID)
 
 	return settlementBatch, settledAt, nil
 }
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMSSender 短信发送接口，接入短信服务商时实现该接口即可
+type SMSSender interface {
+	// SendCode 向手机号发送验证码，purpose 为验证码用途
+	SendCode(phone, code, purpose string) error
+}
+
+// LogSMSSender 默认的短信发送实现：不真正发送短信，而是把验证码写入日志文件或控制台，便于本地开发和测试
+type LogSMSSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogSMSSender 创建日志短信发送器，path 为空时输出到控制台
+func NewLogSMSSender(path string) *LogSMSSender {
+	return &LogSMSSender{path: path}
+}
+
+// SendCode 记录一条验证码短信
+func (s *LogSMSSender) SendCode(phone, code, purpose string) error {
+	if s.path == "" {
+		log.Printf("[短信] 手机号=%s 用途=%s 验证码=%s", phone, purpose, code)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("打开短信日志文件失败: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s\t%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phone, purpose, code); err != nil {
+		return fmt.Errorf("写入短信日志文件失败: %w", err)
+	}
+	return nil
+}
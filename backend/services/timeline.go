@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"poker_score_backend/models"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultTimelinePoints 积分走势默认返回的点数
+	DefaultTimelinePoints = 200
+	// MaxTimelinePoints 积分走势最多返回的点数
+	MaxTimelinePoints = 1000
+)
+
+// timelinePoint 回放到某条操作记录后的积分状态
+type timelinePoint struct {
+	At          time.Time
+	OperationID uint
+	Table       int
+	Balances    map[uint]int
+}
+
+// GetRoomTimeline 回放房间操作记录，返回各成员积分与桌面积分随时间的变化
+func (s *OperationService) GetRoomTimeline(roomID, userID uint, maxPoints int) (map[string]interface{}, error) {
+	var room models.Room
+	if err := models.DB.First(&room, roomID).Error; err != nil {
+		return nil, errors.New("房间不存在")
+	}
+
+	var member models.RoomMember
+	if err := models.DB.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error; err != nil {
+		return nil, errors.New("您不在该房间中")
+	}
+
+	timeline, err := buildTimeline([]models.Room{room}, maxPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline["room"] = timelineRoomView(room)
+	return timeline, nil
+}
+
+// GetNightTimeline 汇总用户某一晚加入过的所有房间的积分走势，date 为空表示当前这一晚
+func (s *RecordService) GetNightTimeline(userID uint, date string, maxPoints int) (map[string]interface{}, error) {
+	boundary := userDayBoundary(userID)
+
+	var start, end time.Time
+	if date == "" {
+		start, end = boundary.NightRange(s.now())
+	} else {
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, errors.New("日期格式错误")
+		}
+		start, end = boundary.DateRange(day.Year(), day.Month(), day.Day())
+	}
+
+	var roomIDs []uint
+	if err := models.DB.Model(&models.RoomMember{}).
+		Where("user_id = ? AND joined_at >= ? AND joined_at < ?", userID, storageTime(start), storageTime(end)).
+		Distinct("room_id").
+		Pluck("room_id", &roomIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var rooms []models.Room
+	if len(roomIDs) > 0 {
+		if err := models.DB.Where("id IN ?", roomIDs).Order("created_at ASC").Find(&rooms).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	timeline, err := buildTimeline(rooms, maxPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	roomViews := make([]map[string]interface{}, 0, len(rooms))
+	for _, room := range rooms {
+		roomViews = append(roomViews, timelineRoomView(room))
+	}
+
+	timeline["rooms"] = roomViews
+	timeline["time_range"] = map[string]interface{}{
+		"date":            boundary.NightDate(start),
+		"start":           start,
+		"end":             end,
+		"timezone":        boundary.Location.String(),
+		"day_cutoff_hour": boundary.CutoffHour,
+	}
+	return timeline, nil
+}
+
+// buildTimeline 按时间顺序回放房间的操作记录，多个房间时成员积分与桌面积分分别求和
+// 结算不会清零曲线：成员积分是从开局起的累计盈亏，结算与解散以 markers 标出
+func buildTimeline(rooms []models.Room, maxPoints int) (map[string]interface{}, error) {
+	if maxPoints < 2 || maxPoints > MaxTimelinePoints {
+		maxPoints = DefaultTimelinePoints
+	}
+
+	roomIDs := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	var memberIDs []uint
+	var operations []models.RoomOperation
+	if len(roomIDs) > 0 {
+		if err := models.DB.Model(&models.RoomMember{}).
+			Where("room_id IN ?", roomIDs).
+			Distinct("user_id").
+			Pluck("user_id", &memberIDs).Error; err != nil {
+			return nil, err
+		}
+
+		if err := models.DB.Where("room_id IN ?", roomIDs).
+			Order("created_at ASC, id ASC").
+			Find(&operations).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	balances := make(map[uint]int, len(memberIDs))
+	for _, id := range memberIDs {
+		balances[id] = 0
+	}
+
+	points := make([]timelinePoint, 0, len(operations)+1)
+	if len(rooms) > 0 {
+		points = append(points, timelinePoint{At: rooms[0].CreatedAt, Balances: copyBalances(balances)})
+	}
+
+	markers := make([]map[string]interface{}, 0)
+	table := 0
+	for _, op := range operations {
+		amount := 0
+		if op.Amount != nil {
+			amount = *op.Amount
+		}
+
+		switch op.OperationType {
+		case models.OpTypeBet, models.OpTypeNiuniuBet:
+			balances[op.UserID] -= amount
+			table += amount
+		case models.OpTypeWithdraw:
+			balances[op.UserID] += amount
+			table -= amount
+		case models.OpTypeForceTransfer:
+			if op.TargetUserID == nil {
+				continue
+			}
+			balances[*op.TargetUserID] += amount
+			table -= amount
+		case models.OpTypeSettlementConfirmed, models.OpTypeRoomDissolved:
+			markers = append(markers, map[string]interface{}{
+				"at":             op.CreatedAt,
+				"room_id":        op.RoomID,
+				"operation_id":   op.ID,
+				"operation_type": op.OperationType,
+			})
+			continue
+		default:
+			continue
+		}
+
+		points = append(points, timelinePoint{
+			At:          op.CreatedAt,
+			OperationID: op.ID,
+			Table:       table,
+			Balances:    copyBalances(balances),
+		})
+	}
+
+	totalPoints := len(points)
+	points = downsampleTimeline(points, maxPoints)
+
+	userIDs := make([]uint, 0, len(balances))
+	for id := range balances {
+		userIDs = append(userIDs, id)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	nicknames, err := loadNicknames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]time.Time, len(points))
+	operationIDs := make([]uint, len(points))
+	tableBalances := make([]int, len(points))
+	for i, point := range points {
+		times[i] = point.At
+		operationIDs[i] = point.OperationID
+		tableBalances[i] = point.Table
+	}
+
+	series := make([]map[string]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		values := make([]int, len(points))
+		for i, point := range points {
+			values[i] = point.Balances[id]
+		}
+		series = append(series, map[string]interface{}{
+			"user_id":       id,
+			"nickname":      nicknames[id],
+			"balances":      values,
+			"final_balance": balances[id],
+		})
+	}
+
+	return map[string]interface{}{
+		"times":         times,
+		"operation_ids": operationIDs,
+		"table_balance": tableBalances,
+		"series":        series,
+		"markers":       markers,
+		"total_points":  totalPoints,
+		"downsampled":   len(points) < totalPoints,
+	}, nil
+}
+
+// downsampleTimeline 点数过多时按顺序分桶，每个桶只保留最后一个点
+// 每个点都是回放后的完整状态，保留桶末尾的点就不会丢失累计值；首尾两个点始终保留
+func downsampleTimeline(points []timelinePoint, maxPoints int) []timelinePoint {
+	if len(points) <= maxPoints {
+		return points
+	}
+
+	sampled := make([]timelinePoint, 0, maxPoints)
+	sampled = append(sampled, points[0])
+
+	rest := points[1:]
+	buckets := maxPoints - 1
+	for i := 1; i <= buckets; i++ {
+		last := i*len(rest)/buckets - 1
+		sampled = append(sampled, rest[last])
+	}
+
+	return sampled
+}
+
+func copyBalances(balances map[uint]int) map[uint]int {
+	copied := make(map[uint]int, len(balances))
+	for id, balance := range balances {
+		copied[id] = balance
+	}
+	return copied
+}
+
+func timelineRoomView(room models.Room) map[string]interface{} {
+	return map[string]interface{}{
+		"room_id":    room.ID,
+		"room_code":  room.RoomCode,
+		"room_type":  room.RoomType,
+		"chip_rate":  room.ChipRate,
+		"status":     room.Status,
+		"created_at": room.CreatedAt,
+	}
+}
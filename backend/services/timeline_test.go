@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownsampleTimeline_KeepsEndpointsAndBucketEnds(t *testing.T) {
+	t.Parallel()
+
+	points := make([]timelinePoint, 11)
+	for i := range points {
+		points[i] = timelinePoint{OperationID: uint(i)}
+	}
+
+	require.Len(t, downsampleTimeline(points, 20), 11)
+
+	sampled := downsampleTimeline(points, 3)
+	ids := make([]uint, len(sampled))
+	for i, point := range sampled {
+		ids[i] = point.OperationID
+	}
+	require.Equal(t, []uint{0, 5, 10}, ids)
+
+	sampled = downsampleTimeline(points, 6)
+	require.Len(t, sampled, 6)
+	require.Equal(t, uint(0), sampled[0].OperationID)
+	require.Equal(t, uint(10), sampled[5].OperationID)
+}
+
+func TestTimeline_ReplaysRoomAndNight(t *testing.T) {
+	setupSettlementTestDB(t)
+	users := seedUsers(t, []string{"Alice", "Bob", "Carol"})
+	alice, bob, carol := users[0].ID, users[1].ID, users[2].ID
+
+	night := time.Date(2025, 11, 7, 20, 0, 0, 0, time.Local)
+
+	createRoom := func(code string, createdAt time.Time, members ...uint) models.Room {
+		room := models.Room{RoomCode: code, RoomType: "texas", ChipRate: "20:1", Status: "dissolved", CreatedBy: members[0], CreatedAt: createdAt}
+		require.NoError(t, models.DB.Create(&room).Error)
+		for _, id := range members {
+			require.NoError(t, models.DB.Create(&models.RoomMember{RoomID: room.ID, UserID: id, JoinedAt: createdAt, Status: "offline"}).Error)
+		}
+		return room
+	}
+	record := func(room models.Room, userID uint, opType string, amount int, target *uint, at time.Time) {
+		require.NoError(t, models.DB.Create(&models.RoomOperation{
+			RoomID: room.ID, UserID: userID, OperationType: opType, Amount: &amount, TargetUserID: target, CreatedAt: at,
+		}).Error)
+	}
+
+	room1 := createRoom("100001", night, alice, bob)
+	record(room1, alice, models.OpTypeBet, 100, nil, night.Add(time.Minute))
+	record(room1, bob, models.OpTypeBet, 50, nil, night.Add(2*time.Minute))
+	record(room1, bob, models.OpTypeWithdraw, 120, nil, night.Add(3*time.Minute))
+	record(room1, alice, models.OpTypeForceTransfer, 30, &alice, night.Add(4*time.Minute))
+	require.NoError(t, models.DB.Create(&models.RoomOperation{
+		RoomID: room1.ID, UserID: alice, OperationType: models.OpTypeSettlementConfirmed, CreatedAt: night.Add(5 * time.Minute),
+	}).Error)
+
+	room2 := createRoom("100002", night.Add(2*time.Hour), alice, carol)
+	record(room2, carol, models.OpTypeBet, 40, nil, night.Add(2*time.Hour+time.Minute))
+	record(room2, alice, models.OpTypeWithdraw, 40, nil, night.Add(2*time.Hour+2*time.Minute))
+
+	operationService := &OperationService{}
+	timeline, err := operationService.GetRoomTimeline(room1.ID, bob, 0)
+	require.NoError(t, err)
+	require.Equal(t, 5, timeline["total_points"])
+	require.Equal(t, false, timeline["downsampled"])
+	require.Equal(t, []int{0, 100, 150, 30, 0}, timeline["table_balance"])
+	require.Len(t, timeline["markers"], 1)
+
+	series := timeline["series"].([]map[string]interface{})
+	require.Len(t, series, 2)
+	require.Equal(t, alice, series[0]["user_id"])
+	require.Equal(t, []int{0, -100, -100, -100, -70}, series[0]["balances"])
+	require.Equal(t, []int{0, 0, -50, 70, 70}, series[1]["balances"])
+
+	_, err = operationService.GetRoomTimeline(room1.ID, carol, 0)
+	require.EqualError(t, err, "您不在该房间中")
+
+	downsampled, err := operationService.GetRoomTimeline(room1.ID, bob, 3)
+	require.NoError(t, err)
+	require.Equal(t, true, downsampled["downsampled"])
+	require.Equal(t, []int{0, 150, 0}, downsampled["table_balance"])
+
+	// 整晚：两个房间按时间合并，成员积分与桌面积分分别求和
+	recordService := NewRecordService()
+	nightTimeline, err := recordService.GetNightTimeline(alice, "2025-11-07", 0)
+	require.NoError(t, err)
+	require.Len(t, nightTimeline["rooms"], 2)
+	require.Equal(t, []int{0, 100, 150, 30, 0, 40, 0}, nightTimeline["table_balance"])
+
+	nightSeries := nightTimeline["series"].([]map[string]interface{})
+	require.Len(t, nightSeries, 3)
+	require.Equal(t, -30, nightSeries[0]["final_balance"])
+	require.Equal(t, 70, nightSeries[1]["final_balance"])
+	require.Equal(t, -40, nightSeries[2]["final_balance"])
+
+	// Bob 没有参加第二个房间
+	bobNight, err := recordService.GetNightTimeline(bob, "2025-11-07", 0)
+	require.NoError(t, err)
+	require.Len(t, bobNight["rooms"], 1)
+
+	_, err = recordService.GetNightTimeline(alice, "11/07", 0)
+	require.EqualError(t, err, "日期格式错误")
+}
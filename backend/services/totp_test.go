@@ -0,0 +1,69 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret RFC 6238 附录B测试向量使用的 SHA1 密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// RFC 给出的是8位验证码，6位验证码取其后6位
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, expected := range vectors {
+		code, err := totpCode(rfc6238Secret, totpStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, expected, code, "T=%d", unix)
+	}
+}
+
+func TestMatchTOTP_SkewAndReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := totpStep(now)
+
+	previous, err := totpCode(rfc6238Secret, step-1)
+	require.NoError(t, err)
+	matched, ok := matchTOTP(rfc6238Secret, previous, now, 0)
+	require.True(t, ok)
+	require.Equal(t, step-1, matched)
+
+	// 超出允许偏差
+	tooOld, err := totpCode(rfc6238Secret, step-2)
+	require.NoError(t, err)
+	_, ok = matchTOTP(rfc6238Secret, tooOld, now, 0)
+	require.False(t, ok)
+
+	// 已使用过的时间窗口不能再次通过
+	current, err := totpCode(rfc6238Secret, step)
+	require.NoError(t, err)
+	_, ok = matchTOTP(rfc6238Secret, current, now, step)
+	require.False(t, ok)
+
+	_, ok = matchTOTP(rfc6238Secret, "12345", now, 0)
+	require.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("PokerScore", "13800138000", rfc6238Secret)
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", parsed.Scheme)
+	require.Equal(t, "totp", parsed.Host)
+	require.Equal(t, "/PokerScore:13800138000", parsed.Path)
+	require.Equal(t, rfc6238Secret, parsed.Query().Get("secret"))
+	require.Equal(t, "PokerScore", parsed.Query().Get("issuer"))
+	require.Equal(t, "6", parsed.Query().Get("digits"))
+	require.Equal(t, "30", parsed.Query().Get("period"))
+}
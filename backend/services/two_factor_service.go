@@ -0,0 +1,390 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount         = 10 // 每次生成的恢复码数量
+	loginChallengeMaxAttempts = 5  // 每次登录第二步允许输错验证码的次数
+)
+
+// 两步验证错误
+var (
+	ErrInvalidTwoFactorCode     = errors.New("验证码错误")
+	ErrTwoFactorTooManyAttempts = errors.New("验证码错误次数过多，请重新登录")
+)
+
+// TwoFactorService TOTP两步验证服务
+type TwoFactorService struct {
+	authService  *AuthService
+	issuer       string        // 验证器应用中显示的服务名称
+	challengeTTL time.Duration // 登录第二步的有效期
+}
+
+// NewTwoFactorService 创建两步验证服务
+func NewTwoFactorService(authService *AuthService, issuer string, challengeTTL time.Duration) *TwoFactorService {
+	return &TwoFactorService{
+		authService:  authService,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+	}
+}
+
+// GetStatus 获取用户的两步验证状态
+func (s *TwoFactorService) GetStatus(userID uint) (map[string]interface{}, error) {
+	twoFactor, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := map[string]interface{}{
+		"enabled":                  false,
+		"enabled_at":               nil,
+		"recovery_codes_remaining": 0,
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		return status, nil
+	}
+
+	var remaining int64
+	if err := models.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&remaining).Error; err != nil {
+		return nil, err
+	}
+
+	status["enabled"] = true
+	status["enabled_at"] = twoFactor.EnabledAt
+	status["recovery_codes_remaining"] = remaining
+	return status, nil
+}
+
+// IsEnabled 用户是否已启用两步验证
+func (s *TwoFactorService) IsEnabled(userID uint) (bool, error) {
+	twoFactor, err := s.find(userID)
+	if err != nil {
+		return false, err
+	}
+	return twoFactor != nil && twoFactor.Enabled, nil
+}
+
+// BeginSetup 生成新的密钥并返回供验证器应用扫码的地址，需调用 Enable 确认后才生效
+// 重复调用会替换尚未确认的密钥
+func (s *TwoFactorService) BeginSetup(userID uint) (map[string]interface{}, error) {
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	twoFactor, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		return nil, errors.New("两步验证已开启")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor == nil {
+		twoFactor = &models.TwoFactor{UserID: userID}
+	}
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = 0
+	if err := models.DB.Save(twoFactor).Error; err != nil {
+		log.Printf("保存两步验证密钥失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"secret":           secret,
+		"provisioning_uri": totpProvisioningURI(s.issuer, user.Phone, secret),
+	}, nil
+}
+
+// Enable 校验验证器中的验证码后启用两步验证，返回只展示一次的恢复码
+// 当前Session视为已通过两步验证
+func (s *TwoFactorService) Enable(userID uint, currentSessionID, code string) ([]string, error) {
+	twoFactor, err := s.find(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil {
+		return nil, errors.New("请先获取两步验证密钥")
+	}
+	if twoFactor.Enabled {
+		return nil, errors.New("两步验证已开启")
+	}
+
+	step, ok := matchTOTP(twoFactor.Secret, code, time.Now(), twoFactor.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(twoFactor).Updates(map[string]interface{}{
+			"enabled":        true,
+			"enabled_at":     now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if codes, err = replaceRecoveryCodes(tx, userID); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).Where("user_id = ? AND session_id = ?", userID, currentSessionID).
+			Update("two_factor", true).Error
+	})
+	if err != nil {
+		log.Printf("启用两步验证失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("两步验证已启用: UserID=%d", userID)
+	return codes, nil
+}
+
+// Disable 校验密码与验证码（或恢复码）后关闭两步验证
+func (s *TwoFactorService) Disable(userID uint, password, code string) error {
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if !utils.CheckPassword(password, user.PasswordHash) {
+		return errors.New("密码错误")
+	}
+
+	if err := s.verify(userID, code); err != nil {
+		return err
+	}
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.LoginChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).Where("user_id = ?", userID).Update("two_factor", false).Error
+	})
+	if err != nil {
+		log.Printf("关闭两步验证失败: UserID=%d, %v", userID, err)
+		return err
+	}
+
+	log.Printf("两步验证已关闭: UserID=%d", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，旧的恢复码全部作废
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.verify(userID, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CreateChallenge 密码校验通过后创建登录第二步的令牌
+func (s *TwoFactorService) CreateChallenge(userID uint) (*models.LoginChallenge, error) {
+	challenge := models.LoginChallenge{
+		Token:     uuid.New().String(),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	}
+	if err := models.DB.Create(&challenge).Error; err != nil {
+		log.Printf("创建登录验证失败: UserID=%d, %v", userID, err)
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ChallengeUser 返回登录第二步令牌所属的用户
+func (s *TwoFactorService) ChallengeUser(token string) (*models.User, error) {
+	challenge, err := s.findChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, challenge.UserID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return &user, nil
+}
+
+// CompleteLogin 校验验证码（或恢复码）完成登录，创建已通过两步验证的Session
+func (s *TwoFactorService) CompleteLogin(token, code string, client SessionClient) (*models.User, *models.Session, error) {
+	challenge, err := s.findChallenge(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.verify(challenge.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if challenge.Attempts+1 >= loginChallengeMaxAttempts {
+				models.DB.Delete(challenge)
+				return nil, nil, ErrTwoFactorTooManyAttempts
+			}
+			models.DB.Model(challenge).Update("attempts", gorm.Expr("attempts + 1"))
+		}
+		return nil, nil, err
+	}
+
+	// 令牌只能使用一次，并发提交时只有一个请求能删除成功
+	result := models.DB.Delete(challenge)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, errors.New("登录验证已过期，请重新登录")
+	}
+
+	var user models.User
+	if err := models.DB.First(&user, challenge.UserID).Error; err != nil {
+		return nil, nil, errors.New("用户不存在")
+	}
+
+	session, err := s.authService.createSession(user.ID, client, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("用户通过两步验证登录: ID=%d", user.ID)
+	return &user, session, nil
+}
+
+// verify 校验已启用两步验证的用户提交的验证码或恢复码，验证码不能重复使用
+func (s *TwoFactorService) verify(userID uint, code string) error {
+	twoFactor, err := s.find(userID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		return errors.New("未开启两步验证")
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := matchTOTP(twoFactor.Secret, code, time.Now(), twoFactor.LastUsedStep); ok {
+		result := models.DB.Model(&models.TwoFactor{}).
+			Where("id = ? AND last_used_step < ?", twoFactor.ID, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil
+		}
+		return ErrInvalidTwoFactorCode
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+	result := models.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+
+	log.Printf("使用两步验证恢复码: UserID=%d", userID)
+	return nil
+}
+
+func (s *TwoFactorService) find(userID uint) (*models.TwoFactor, error) {
+	var records []models.TwoFactor
+	if err := models.DB.Where("user_id = ?", userID).Limit(1).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+func (s *TwoFactorService) findChallenge(token string) (*models.LoginChallenge, error) {
+	var challenges []models.LoginChallenge
+	if err := models.DB.Where("token = ?", token).Limit(1).Find(&challenges).Error; err != nil {
+		return nil, err
+	}
+	if len(challenges) == 0 {
+		return nil, errors.New("登录验证已过期，请重新登录")
+	}
+	challenge := &challenges[0]
+	if challenge.IsExpired() {
+		models.DB.Delete(challenge)
+		return nil, errors.New("登录验证已过期，请重新登录")
+	}
+	return challenge, nil
+}
+
+// replaceRecoveryCodes 删除用户原有的恢复码并生成一组新的，返回明文
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(raw)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写、空格与连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func hashRecoveryCode(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"poker_score_backend/models"
+	"poker_score_backend/utils"
+
+	"github.com/stretchr/testify/require"
+)
+
+func currentTOTP(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := totpCode(secret, totpStep(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+func TestTwoFactor_EnrollLoginAndRecovery(t *testing.T) {
+	setupSettlementTestDB(t)
+	user := seedUsers(t, []string{"Alice"})[0]
+	passwordHash, err := utils.HashPassword("password")
+	require.NoError(t, err)
+	require.NoError(t, models.DB.Model(&user).Update("password_hash", passwordHash).Error)
+
+	authService := NewAuthService(24*time.Hour, time.Hour, time.Minute)
+	service := NewTwoFactorService(authService, "PokerScore", 5*time.Minute)
+
+	current, err := authService.CreateSession(user.ID, SessionClient{})
+	require.NoError(t, err)
+
+	_, err = service.Enable(user.ID, current.SessionID, "123456")
+	require.EqualError(t, err, "请先获取两步验证密钥")
+
+	setup, err := service.BeginSetup(user.ID)
+	require.NoError(t, err)
+	secret := setup["secret"].(string)
+	require.Contains(t, setup["provisioning_uri"], "secret="+secret)
+
+	enabled, err := service.IsEnabled(user.ID)
+	require.NoError(t, err)
+	require.False(t, enabled)
+
+	codes, err := service.Enable(user.ID, current.SessionID, currentTOTP(t, secret, 0))
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	require.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, codes[0])
+
+	var stored models.Session
+	require.NoError(t, models.DB.First(&stored, current.ID).Error)
+	require.True(t, stored.TwoFactor)
+
+	_, err = service.BeginSetup(user.ID)
+	require.EqualError(t, err, "两步验证已开启")
+
+	// 登录第二步：错误的验证码计入次数，达到上限后令牌作废
+	challenge, err := service.CreateChallenge(user.ID)
+	require.NoError(t, err)
+	pending, err := service.ChallengeUser(challenge.Token)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, pending.ID)
+
+	for i := 0; i < loginChallengeMaxAttempts-1; i++ {
+		_, _, err = service.CompleteLogin(challenge.Token, "00000a", SessionClient{})
+		require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	}
+	_, _, err = service.CompleteLogin(challenge.Token, "00000a", SessionClient{})
+	require.ErrorIs(t, err, ErrTwoFactorTooManyAttempts)
+	_, _, err = service.CompleteLogin(challenge.Token, currentTOTP(t, secret, 1), SessionClient{})
+	require.EqualError(t, err, "登录验证已过期，请重新登录")
+
+	// 恢复码可以代替验证码登录，且只能使用一次（忽略大小写与连字符）
+	challenge, err = service.CreateChallenge(user.ID)
+	require.NoError(t, err)
+	_, session, err := service.CompleteLogin(challenge.Token, " "+codes[0][:5]+codes[0][6:]+" ", SessionClient{IP: "10.0.0.1"})
+	require.NoError(t, err)
+	require.True(t, session.TwoFactor)
+
+	challenge, err = service.CreateChallenge(user.ID)
+	require.NoError(t, err)
+	_, _, err = service.CompleteLogin(challenge.Token, codes[0], SessionClient{})
+	require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+	status, err := service.GetStatus(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, true, status["enabled"])
+	require.EqualValues(t, recoveryCodeCount-1, status["recovery_codes_remaining"])
+
+	// 验证码不能重放：第二步使用过的时间窗口不能再用于关闭
+	_, session, err = service.CompleteLogin(challenge.Token, currentTOTP(t, secret, 1), SessionClient{})
+	require.NoError(t, err)
+	require.ErrorIs(t, service.Disable(user.ID, "password", currentTOTP(t, secret, 1)), ErrInvalidTwoFactorCode)
+
+	require.EqualError(t, service.Disable(user.ID, "wrong", codes[1]), "密码错误")
+	require.NoError(t, service.Disable(user.ID, "password", codes[1]))
+
+	var revoked models.Session
+	require.NoError(t, models.DB.First(&revoked, session.ID).Error)
+	require.False(t, revoked.TwoFactor)
+	enabled, err = service.IsEnabled(user.ID)
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
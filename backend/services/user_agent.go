@@ -0,0 +1,67 @@
+package services
+
+import (
+	"strings"
+)
+
+// userAgentApps 按优先级识别的客户端，内置浏览器优先于其内核
+var userAgentApps = []struct {
+	keyword string
+	name    string
+}{
+	{"micromessenger", "微信"},
+	{"dingtalk", "钉钉"},
+	{"alipay", "支付宝"},
+	{"qq/", "QQ"},
+	{"quark", "夸克"},
+	{"ucbrowser", "UC浏览器"},
+	{"edg/", "Edge"},
+	{"firefox/", "Firefox"},
+	{"chrome/", "Chrome"},
+	{"safari/", "Safari"},
+}
+
+// userAgentPlatforms 按优先级识别的系统，iPhone/iPad 的UA中也包含 Mac OS X
+var userAgentPlatforms = []struct {
+	keyword string
+	name    string
+}{
+	{"iphone", "iPhone"},
+	{"ipad", "iPad"},
+	{"android", "Android"},
+	{"windows", "Windows"},
+	{"mac os x", "Mac"},
+	{"linux", "Linux"},
+}
+
+// describeDevice 根据User-Agent粗略识别客户端与系统，便于用户在会话列表中辨认设备
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	parts := make([]string, 0, 2)
+	for _, app := range userAgentApps {
+		if strings.Contains(ua, app.keyword) {
+			parts = append(parts, app.name)
+			break
+		}
+	}
+	for _, platform := range userAgentPlatforms {
+		if strings.Contains(ua, platform.keyword) {
+			parts = append(parts, platform.name)
+			break
+		}
+	}
+
+	if len(parts) == 0 {
+		return "未知设备"
+	}
+	return strings.Join(parts, " · ")
+}
+
+// truncateString 按字节截断字符串，且不会截断多字节字符
+func truncateString(value string, maxBytes int) string {
+	if len(value) <= maxBytes {
+		return value
+	}
+	return strings.ToValidUTF8(value[:maxBytes], "")
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"poker_score_backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// verificationCodeDigits 验证码位数
+const verificationCodeDigits = 6
+
+// issueVerificationCode 生成验证码并保存其哈希，同一手机号同一用途之前未使用的验证码随之作废
+// userID 为申请验证码的用户，找回密码时为0
+func issueVerificationCode(phone, purpose string, userID uint, ttl time.Duration) (string, *models.VerificationCode, error) {
+	code, err := generateVerificationCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	record := models.VerificationCode{
+		Phone:     phone,
+		Purpose:   purpose,
+		UserID:    userID,
+		CodeHash:  hashVerificationCode(phone, code),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("phone = ? AND purpose = ? AND consumed_at IS NULL", phone, purpose).
+			Delete(&models.VerificationCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return code, &record, nil
+}
+
+// latestVerificationCode 获取手机号最近一次发送的指定用途验证码，不存在时返回 nil
+func latestVerificationCode(phone, purpose string) (*models.VerificationCode, error) {
+	var codes []models.VerificationCode
+	if err := models.DB.Where("phone = ? AND purpose = ?", phone, purpose).
+		Order("id DESC").Limit(1).Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	return &codes[0], nil
+}
+
+// checkVerificationCode 校验验证码，错误时累加失败次数并提示剩余次数
+func checkVerificationCode(record *models.VerificationCode, phone, code string, maxAttempts int) error {
+	if record == nil || record.ConsumedAt != nil || record.IsExpired() {
+		return errors.New("验证码无效或已过期，请重新获取")
+	}
+	if record.Attempts >= maxAttempts {
+		return errors.New("验证码错误次数过多，请重新获取")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashVerificationCode(phone, code)), []byte(record.CodeHash)) != 1 {
+		// 条件更新，避免并发请求绕过次数限制
+		result := models.DB.Model(&models.VerificationCode{}).
+			Where("id = ? AND attempts < ?", record.ID, maxAttempts).
+			Update("attempts", gorm.Expr("attempts + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		remaining := maxAttempts - record.Attempts - 1
+		if result.RowsAffected == 0 || remaining <= 0 {
+			return errors.New("验证码错误次数过多，请重新获取")
+		}
+		return fmt.Errorf("验证码错误，还可尝试%d次", remaining)
+	}
+	return nil
+}
+
+// consumeVerificationCode 在事务中把验证码标记为已使用，并发请求只有一个能成功
+func consumeVerificationCode(tx *gorm.DB, record *models.VerificationCode, maxAttempts int) error {
+	result := tx.Model(&models.VerificationCode{}).
+		Where("id = ? AND consumed_at IS NULL AND attempts < ?", record.ID, maxAttempts).
+		Update("consumed_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("验证码无效或已过期，请重新获取")
+	}
+	return nil
+}
+
+// generateVerificationCode 使用安全随机数生成定长数字验证码
+func generateVerificationCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < verificationCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", verificationCodeDigits, n.Int64()), nil
+}
+
+// hashVerificationCode 以手机号作为盐计算验证码的哈希，数据库中不保存明文
+func hashVerificationCode(phone, code string) string {
+	sum := sha256.Sum256([]byte(phone + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
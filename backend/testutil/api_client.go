@@ -17,6 +17,7 @@ type APIClient struct {
 	engine        *gin.Engine
 	cookies       map[string]*http.Cookie
 	authorization string
+	userAgent     string
 }
 
 // NewAPIClient 创建新的测试客户端。
@@ -32,6 +33,11 @@ func (c *APIClient) SetAuthorization(value string) {
 	c.authorization = value
 }
 
+// SetUserAgent 设置 User-Agent 头。
+func (c *APIClient) SetUserAgent(value string) {
+	c.userAgent = value
+}
+
 // Authorization 获取当前 Authorization。
 func (c *APIClient) Authorization() string {
 	return c.authorization
@@ -86,6 +92,10 @@ func (c *APIClient) Do(method, path string, body interface{}) (*httptest.Respons
 		req.Header.Set("Authorization", c.authorization)
 	}
 
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
 	for _, cookie := range c.cookies {
 		req.AddCookie(cookie)
 	}
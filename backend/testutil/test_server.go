@@ -16,6 +16,7 @@ func TestConfig() *config.Config {
 	dbDSN := fmt.Sprintf("file:test-%s.db?mode=memory&cache=shared&_fk=1", uuid.NewString())
 
 	return &config.Config{
+		Env: "test",
 		Server: config.ServerConfig{
 			Port:           ":0",
 			ReadTimeout:    5 * time.Second,
@@ -24,6 +25,7 @@ func TestConfig() *config.Config {
 			CookieDomain:   "",
 			CookieSecure:   false,
 			CookieSameSite: "Lax",
+			TrustedProxies: nil,
 		},
 		Database: config.DatabaseConfig{
 			Path:            dbDSN,
@@ -32,8 +34,49 @@ func TestConfig() *config.Config {
 			ConnMaxLifetime: time.Minute,
 		},
 		Session: config.SessionConfig{
-			CookieName: "poker_test_session",
-			MaxAge:     24 * time.Hour,
+			CookieName:  "poker_test_session",
+			MaxAge:      24 * time.Hour,
+			IdleTimeout: time.Hour,
+			WSTicketTTL: 5 * time.Second,
+		},
+		Presence: config.PresenceConfig{
+			AwayAfter:    50 * time.Millisecond,
+			OfflineAfter: 200 * time.Millisecond,
+		},
+		Chat: config.ChatConfig{
+			RateLimit:  5,
+			RateWindow: 10 * time.Second,
+			MaxLength:  500,
+		},
+		Season: config.SeasonConfig{
+			SnapshotHour: 7,
+		},
+		SMS: config.SMSConfig{
+			CodeTTL:        10 * time.Minute,
+			MaxAttempts:    5,
+			ResendInterval: time.Minute,
+			LogFile:        "",
+		},
+		// 测试中大量请求来自同一个IP，默认不限流，需要时由用例单独开启
+		RateLimit: config.RateLimitConfig{
+			LockoutThreshold: 5,
+			LockoutBase:      time.Minute,
+			LockoutMax:       24 * time.Hour,
+			LockoutReset:     24 * time.Hour,
+		},
+		TwoFactor: config.TwoFactorConfig{
+			Issuer:           "PokerScoreTest",
+			RequireForAdmins: false,
+			ChallengeTTL:     5 * time.Minute,
+		},
+		Admin: config.AdminConfig{},
+		// 默认不启用单点登录，需要时由用例指向模拟的身份提供方
+		OIDC: config.OIDCConfig{
+			Scopes:               []string{"openid", "profile", "phone"},
+			ProviderName:         "测试企业账号",
+			PhoneClaim:           "phone_number",
+			RequireVerifiedPhone: true,
+			StateTTL:             10 * time.Minute,
 		},
 	}
 }
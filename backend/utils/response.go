@@ -2,7 +2,10 @@ package utils
 
 import (
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // Response 统一响应结构
@@ -73,6 +76,18 @@ func Conflict(c *gin.Context, message string) {
 	Error(c, http.StatusConflict, 409, message)
 }
 
+// TooManyRequests 429错误，通过 Retry-After 头与 data.retry_after 返回需要等待的秒数（至少1秒）
+func TooManyRequests(c *gin.Context, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	ErrorWithData(c, http.StatusTooManyRequests, 429, message, gin.H{
+		"retry_after": seconds,
+	})
+}
+
 // InternalServerError 500错误
 func InternalServerError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, 500, message)
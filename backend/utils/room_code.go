@@ -1,6 +1,8 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
+	"math/big"
 	"math/rand"
 	"time"
 )
@@ -21,3 +23,20 @@ func GenerateRoomCode() string {
 		string(rune(code%10+'0'))
 }
 
+
+// inviteCodeAlphabet 邀请码字符集，去掉了容易混淆的 0/O、1/I/L
+const inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// GenerateInviteCode 使用安全随机数生成8位俱乐部邀请码，邀请码可以直接加入俱乐部，不能被猜出
+func GenerateInviteCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(inviteCodeAlphabet)))
+	code := make([]byte, 8)
+	for i := range code {
+		n, err := cryptorand.Int(cryptorand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -3,7 +3,6 @@ package websocket
 import (
 	"encoding/json"
 	"log"
-	"poker_score_backend/models"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,8 +18,8 @@ const (
 	// Ping周期（必须小于pongWait）
 	pingPeriod = (pongWait * 9) / 10
 
-	// 最大消息大小
-	maxMessageSize = 512
+	// 最大消息大小（需容纳聊天消息）
+	maxMessageSize = 4096
 )
 
 // Client WebSocket客户端
@@ -38,12 +37,9 @@ type Client struct {
 
 	// 房间ID
 	RoomID uint
-}
 
-// Message WebSocket消息
-type Message struct {
-	Type string                 `json:"type"`
-	Data map[string]interface{} `json:"data,omitempty"`
+	// 协商得到的协议版本
+	Version int
 }
 
 // readPump 从WebSocket读取消息
@@ -52,16 +48,10 @@ func (c *Client) readPump() {
 		c.hub.unregister <- c
 		c.conn.Close()
 
-		// 将用户标记为离线状态，但保留房间成员身份
-		res := models.DB.Model(&models.RoomMember{}).
-			Where("room_id = ? AND user_id = ?", c.RoomID, c.UserID).
-			Update("status", "offline")
-
-		if res.Error != nil {
-			log.Printf("用户离线标记失败: RoomID=%d, UserID=%d, %v", c.RoomID, c.UserID, res.Error)
-		}
+		// 仅减少连接计数，成员身份与房间状态不受影响
+		c.hub.MarkDisconnected(c.RoomID, c.UserID)
 
-		log.Printf("用户WebSocket连接断开，状态已设为离线: RoomID=%d, UserID=%d", c.RoomID, c.UserID)
+		log.Printf("用户WebSocket连接断开: RoomID=%d, UserID=%d", c.RoomID, c.UserID)
 	}()
 
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
@@ -79,19 +69,41 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// 处理客户端消息（目前只处理ping）
-		var msg Message
-		if err := json.Unmarshal(message, &msg); err == nil {
-			if msg.Type == "ping" {
-				// 回复pong
-				pongMsg := Message{Type: "pong"}
-				pongBytes, _ := json.Marshal(pongMsg)
-				c.send <- pongBytes
-			}
+		var msg ClientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			continue
+		}
+		c.handleMessage(msg)
+	}
+}
+
+// handleMessage 处理客户端上行消息，未知类型直接忽略
+func (c *Client) handleMessage(msg ClientMessage) {
+	switch msg.Type {
+	case ClientMessagePing:
+		// 回复pong
+		pongBytes, _ := EncodeEvent(PongEvent{})
+		c.reply(pongBytes)
+	case ClientMessageChat, ClientMessageReaction:
+		if c.hub.handler == nil {
+			return
+		}
+		if err := c.hub.handler.HandleClientMessage(c.RoomID, c.UserID, msg.Type, msg.Data); err != nil {
+			errBytes, _ := EncodeEvent(ErrorEvent{RequestType: msg.Type, Message: err.Error()})
+			c.reply(errBytes)
 		}
 	}
 }
 
+// reply 仅向当前连接发送消息，缓冲区已满时丢弃
+func (c *Client) reply(message []byte) {
+	select {
+	case c.send <- message:
+	default:
+		log.Printf("WebSocket发送缓冲已满，丢弃回复: RoomID=%d, UserID=%d", c.RoomID, c.UserID)
+	}
+}
+
 // writePump 向WebSocket写入消息
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -137,23 +149,26 @@ func (c *Client) writePump() {
 }
 
 // ServeWs 处理WebSocket请求
-func ServeWs(hub *Hub, conn *websocket.Conn, userID, roomID uint) {
-	// 确保成员状态为在线
-	if err := models.DB.Model(&models.RoomMember{}).
-		Where("room_id = ? AND user_id = ?", roomID, userID).
-		Update("status", "online").Error; err != nil {
-		log.Printf("更新成员在线状态失败: RoomID=%d, UserID=%d, %v", roomID, userID, err)
+func ServeWs(hub *Hub, conn *websocket.Conn, userID, roomID uint, version int) {
+	client := &Client{
+		hub:     hub,
+		conn:    conn,
+		send:    make(chan []byte, 256),
+		UserID:  userID,
+		RoomID:  roomID,
+		Version: version,
 	}
 
-	client := &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		UserID: userID,
-		RoomID: roomID,
+	// 首条消息告知客户端协商结果
+	if hello, err := EncodeEvent(HelloEvent{
+		ProtocolVersion:   version,
+		SupportedVersions: SupportedVersions,
+	}); err == nil {
+		client.send <- hello
 	}
 
 	client.hub.register <- client
+	client.hub.MarkConnected(roomID, userID)
 
 	// 在新的goroutine中启动读写
 	go client.writePump()
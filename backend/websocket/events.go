@@ -0,0 +1,306 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 房间事件类型
+const (
+	EventHello               = "hello"
+	EventConnected           = "connected"
+	EventResync              = "resync"
+	EventPong                = "pong"
+	EventUserJoined          = "user_joined"
+	EventUserReturned        = "user_returned"
+	EventUserLeft            = "user_left"
+	EventUserKicked          = "user_kicked"
+	EventBet                 = "bet"
+	EventNiuniuBet           = "niuniu_bet"
+	EventWithdraw            = "withdraw"
+	EventForceTransfer       = "force_transfer"
+	EventSettlementInitiated = "settlement_initiated"
+	EventSettlementConfirmed = "settlement_confirmed"
+	EventRoomDissolved       = "room_dissolved"
+	EventPresenceChanged     = "presence_changed"
+	EventChatMessage         = "chat_message"
+	EventChatMessageDeleted  = "chat_message_deleted"
+	EventAchievementEarned   = "achievement_earned"
+	EventError               = "error"
+)
+
+// Event 房间事件，每种事件对应一个结构体
+type Event interface {
+	EventType() string
+}
+
+// HelloEvent 连接建立后下发的协议协商结果
+type HelloEvent struct {
+	ProtocolVersion   int   `json:"protocol_version"`
+	SupportedVersions []int `json:"supported_versions"`
+}
+
+// ConnectedEvent SSE首次连接
+type ConnectedEvent struct {
+	RoomID          uint `json:"room_id"`
+	ProtocolVersion int  `json:"protocol_version"`
+}
+
+// ResyncEvent SSE无法续传，客户端需重新拉取房间详情
+type ResyncEvent struct {
+	RoomID          uint `json:"room_id"`
+	ProtocolVersion int  `json:"protocol_version"`
+}
+
+// PongEvent 心跳回复
+type PongEvent struct{}
+
+// UserJoinedEvent 用户加入房间
+type UserJoinedEvent struct {
+	UserID   uint      `json:"user_id"`
+	Nickname string    `json:"nickname"`
+	Balance  int       `json:"balance"`
+	Status   string    `json:"status"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// UserReturnedEvent 用户返回房间
+type UserReturnedEvent struct {
+	UserID     uint      `json:"user_id"`
+	Nickname   string    `json:"nickname"`
+	Balance    int       `json:"balance"`
+	Status     string    `json:"status"`
+	ReturnedAt time.Time `json:"returned_at"`
+}
+
+// UserLeftEvent 用户离开房间
+type UserLeftEvent struct {
+	UserID     uint      `json:"user_id"`
+	Nickname   string    `json:"nickname"`
+	Status     string    `json:"status"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// UserKickedEvent 用户被踢出
+type UserKickedEvent struct {
+	UserID           uint      `json:"user_id"`
+	Nickname         string    `json:"nickname"`
+	KickedBy         uint      `json:"kicked_by"`
+	KickedByNickname string    `json:"kicked_by_nickname"`
+	Status           string    `json:"status"`
+	KickedAt         time.Time `json:"kicked_at"`
+}
+
+// BetEvent 德扑下注
+type BetEvent struct {
+	UserID       uint      `json:"user_id"`
+	Nickname     string    `json:"nickname"`
+	Amount       int       `json:"amount"`
+	Balance      int       `json:"balance"`
+	TableBalance int       `json:"table_balance"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NiuniuBetEntry 牛牛下注明细
+type NiuniuBetEntry struct {
+	ToUserID   uint   `json:"to_user_id"`
+	ToNickname string `json:"to_nickname,omitempty"`
+	Amount     int    `json:"amount"`
+}
+
+// NiuniuBetEvent 牛牛下注
+type NiuniuBetEvent struct {
+	UserID       uint             `json:"user_id"`
+	Nickname     string           `json:"nickname"`
+	TotalAmount  int              `json:"total_amount"`
+	Balance      int              `json:"balance"`
+	TableBalance int              `json:"table_balance"`
+	Bets         []NiuniuBetEntry `json:"bets"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// WithdrawEvent 收回积分
+type WithdrawEvent struct {
+	UserID       uint      `json:"user_id"`
+	Nickname     string    `json:"nickname"`
+	Amount       int       `json:"amount"`
+	Balance      int       `json:"balance"`
+	TableBalance int       `json:"table_balance"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ForceTransferEvent 积分强制转移
+type ForceTransferEvent struct {
+	UserID         uint      `json:"user_id"`
+	Nickname       string    `json:"nickname"`
+	TargetUserID   uint      `json:"target_user_id"`
+	TargetNickname string    `json:"target_nickname"`
+	Amount         int       `json:"amount"`
+	ActorBalance   int       `json:"actor_balance"`
+	TargetBalance  int       `json:"target_balance"`
+	TableBalance   int       `json:"table_balance"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SettlementTransfer 结算方案中的一笔转账
+type SettlementTransfer struct {
+	FromUserID   uint    `json:"from_user_id"`
+	FromNickname string  `json:"from_nickname"`
+	ToUserID     uint    `json:"to_user_id"`
+	ToNickname   string  `json:"to_nickname"`
+	ChipAmount   int     `json:"chip_amount"`
+	RmbAmount    float64 `json:"rmb_amount"`
+	Description  string  `json:"description"`
+}
+
+// SettlementInitiatedEvent 发起结算
+type SettlementInitiatedEvent struct {
+	InitiatedBy         uint                 `json:"initiated_by"`
+	InitiatedByNickname string               `json:"initiated_by_nickname"`
+	InitiatedAt         time.Time            `json:"initiated_at"`
+	SettlementPlan      []SettlementTransfer `json:"settlement_plan"`
+	TableBalance        int                  `json:"table_balance"`
+}
+
+// SettlementResult 单个用户的结算结果
+type SettlementResult struct {
+	UserID     uint    `json:"user_id"`
+	Nickname   string  `json:"nickname"`
+	ChipAmount int     `json:"chip_amount"`
+	RmbAmount  float64 `json:"rmb_amount"`
+}
+
+// SettlementSummary 结算汇总
+type SettlementSummary struct {
+	Batch     string             `json:"batch"`
+	SettledAt time.Time          `json:"settled_at"`
+	ChipRate  string             `json:"chip_rate"`
+	Details   []SettlementResult `json:"details"`
+}
+
+// SettlementConfirmedEvent 确认结算
+type SettlementConfirmedEvent struct {
+	ConfirmedBy         uint              `json:"confirmed_by"`
+	ConfirmedByNickname string            `json:"confirmed_by_nickname"`
+	SettlementBatch     string            `json:"settlement_batch"`
+	SettledAt           time.Time         `json:"settled_at"`
+	SettlementSummary   SettlementSummary `json:"settlement_summary"`
+}
+
+// RoomDissolvedEvent 房间解散
+type RoomDissolvedEvent struct {
+	RoomID      uint      `json:"room_id"`
+	DissolvedAt time.Time `json:"dissolved_at"`
+}
+
+// PresenceChangedEvent 用户在线状态变化（online/away/offline），与房间成员身份无关
+type PresenceChangedEvent struct {
+	UserID     uint      `json:"user_id"`
+	Nickname   string    `json:"nickname"`
+	Presence   string    `json:"presence"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// ChatMessageEvent 聊天消息或表情回应
+type ChatMessageEvent struct {
+	MessageID   uint      `json:"message_id"`
+	UserID      uint      `json:"user_id"`
+	Nickname    string    `json:"nickname"`
+	MessageType string    `json:"message_type"`
+	Content     string    `json:"content"`
+	OperationID *uint     `json:"operation_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ChatMessageDeletedEvent 聊天消息被删除
+type ChatMessageDeletedEvent struct {
+	MessageID         uint      `json:"message_id"`
+	DeletedBy         uint      `json:"deleted_by"`
+	DeletedByNickname string    `json:"deleted_by_nickname"`
+	DeletedAt         time.Time `json:"deleted_at"`
+}
+
+// AchievementEarnedEvent 房间成员获得了新的成就徽章
+type AchievementEarnedEvent struct {
+	UserID      uint      `json:"user_id"`
+	Nickname    string    `json:"nickname"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+// ErrorEvent 客户端上行消息处理失败，仅发送给该连接
+type ErrorEvent struct {
+	RequestType string `json:"request_type"`
+	Message     string `json:"message"`
+}
+
+func (HelloEvent) EventType() string               { return EventHello }
+func (ConnectedEvent) EventType() string           { return EventConnected }
+func (ResyncEvent) EventType() string              { return EventResync }
+func (PongEvent) EventType() string                { return EventPong }
+func (UserJoinedEvent) EventType() string          { return EventUserJoined }
+func (UserReturnedEvent) EventType() string        { return EventUserReturned }
+func (UserLeftEvent) EventType() string            { return EventUserLeft }
+func (UserKickedEvent) EventType() string          { return EventUserKicked }
+func (BetEvent) EventType() string                 { return EventBet }
+func (NiuniuBetEvent) EventType() string           { return EventNiuniuBet }
+func (WithdrawEvent) EventType() string            { return EventWithdraw }
+func (ForceTransferEvent) EventType() string       { return EventForceTransfer }
+func (SettlementInitiatedEvent) EventType() string { return EventSettlementInitiated }
+func (SettlementConfirmedEvent) EventType() string { return EventSettlementConfirmed }
+func (RoomDissolvedEvent) EventType() string       { return EventRoomDissolved }
+func (PresenceChangedEvent) EventType() string     { return EventPresenceChanged }
+func (ChatMessageEvent) EventType() string         { return EventChatMessage }
+func (ChatMessageDeletedEvent) EventType() string  { return EventChatMessageDeleted }
+func (AchievementEarnedEvent) EventType() string   { return EventAchievementEarned }
+func (ErrorEvent) EventType() string               { return EventError }
+
+// registeredEvents 所有服务端可能下发的事件，用于生成JSON Schema
+var registeredEvents = []Event{
+	HelloEvent{},
+	ConnectedEvent{},
+	ResyncEvent{},
+	PongEvent{},
+	UserJoinedEvent{},
+	UserReturnedEvent{},
+	UserLeftEvent{},
+	UserKickedEvent{},
+	BetEvent{},
+	NiuniuBetEvent{},
+	WithdrawEvent{},
+	ForceTransferEvent{},
+	SettlementInitiatedEvent{},
+	SettlementConfirmedEvent{},
+	RoomDissolvedEvent{},
+	PresenceChangedEvent{},
+	ChatMessageEvent{},
+	ChatMessageDeletedEvent{},
+	AchievementEarnedEvent{},
+	ErrorEvent{},
+}
+
+// RegisteredEventTypes 返回所有已注册的事件类型
+func RegisteredEventTypes() []string {
+	types := make([]string, 0, len(registeredEvents))
+	for _, event := range registeredEvents {
+		types = append(types, event.EventType())
+	}
+	return types
+}
+
+// NewMessage 将事件包装为当前协议版本的消息
+func NewMessage(event Event) Message {
+	return Message{
+		Type:    event.EventType(),
+		Version: ProtocolVersion,
+		Data:    event,
+	}
+}
+
+// EncodeEvent 将事件编码为下发给客户端的JSON
+func EncodeEvent(event Event) ([]byte, error) {
+	return json.Marshal(NewMessage(event))
+}
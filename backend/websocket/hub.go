@@ -1,8 +1,24 @@
 package websocket
 
 import (
+	"encoding/json"
 	"log"
 	"sync"
+	"time"
+)
+
+const (
+	// 每个房间保留的历史事件数量（用于SSE断线续传）
+	roomHistorySize = 200
+
+	// 无人订阅的房间历史保留时长
+	roomHistoryRetention = 30 * time.Minute
+
+	// 历史事件清理周期
+	roomHistoryPrunePeriod = 5 * time.Minute
+
+	// SSE订阅者的发送缓冲
+	subscriberBufferSize = 256
 )
 
 // Hub WebSocket连接管理中心
@@ -10,6 +26,12 @@ type Hub struct {
 	// 房间ID -> 客户端集合的映射
 	rooms map[uint]map[*Client]bool
 
+	// 房间ID -> SSE订阅者集合的映射
+	subscribers map[uint]map[*Subscriber]bool
+
+	// 房间ID -> 最近广播的事件（用于Last-Event-ID续传）
+	history map[uint]*roomHistory
+
 	// 注册请求
 	register chan *Client
 
@@ -19,28 +41,80 @@ type Hub struct {
 	// 广播消息
 	broadcast chan *BroadcastMessage
 
+	// 在线状态跟踪（可为空）
+	presence PresenceTracker
+
+	// 客户端上行消息处理（可为空）
+	handler MessageHandler
+
 	// 互斥锁
 	mu sync.RWMutex
 }
 
+// PresenceTracker 跟踪用户在房间内的实时连接，用于计算在线状态
+// 方法在连接所在的goroutine中调用，实现方不得在调用中同步等待Hub
+type PresenceTracker interface {
+	Connect(roomID, userID uint)
+	Disconnect(roomID, userID uint)
+}
+
+// MessageHandler 处理客户端通过WebSocket发送的业务消息（如聊天）
+// 方法在连接的读goroutine中调用，返回的错误会以 error 事件回复给该连接
+type MessageHandler interface {
+	HandleClientMessage(roomID, userID uint, msgType string, data json.RawMessage) error
+}
+
 // BroadcastMessage 广播消息
 type BroadcastMessage struct {
 	RoomID  uint
 	Message []byte
 }
 
+// RoomEvent 带序号的房间事件
+type RoomEvent struct {
+	ID      uint64
+	Message []byte
+}
+
+// roomHistory 房间事件历史
+type roomHistory struct {
+	lastID    uint64
+	events    []RoomEvent
+	updatedAt time.Time
+}
+
+// Subscriber SSE订阅者
+type Subscriber struct {
+	// 接收事件的通道，Hub关闭通道表示订阅结束
+	Events chan RoomEvent
+
+	// 用户ID
+	UserID uint
+
+	// 房间ID
+	RoomID uint
+
+	// 订阅时房间最新的事件序号
+	StartID uint64
+}
+
 // NewHub 创建Hub
 func NewHub() *Hub {
 	return &Hub{
-		rooms:      make(map[uint]map[*Client]bool),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan *BroadcastMessage),
+		rooms:       make(map[uint]map[*Client]bool),
+		subscribers: make(map[uint]map[*Subscriber]bool),
+		history:     make(map[uint]*roomHistory),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		broadcast:   make(chan *BroadcastMessage),
 	}
 }
 
 // Run 运行Hub
 func (h *Hub) Run() {
+	ticker := time.NewTicker(roomHistoryPrunePeriod)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -70,7 +144,9 @@ func (h *Hub) Run() {
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
+			event := h.appendHistory(message.RoomID, message.Message)
+
 			if clients, ok := h.rooms[message.RoomID]; ok {
 				for client := range clients {
 					select {
@@ -82,8 +158,96 @@ func (h *Hub) Run() {
 					}
 				}
 			}
-			h.mu.RUnlock()
+
+			if subs, ok := h.subscribers[message.RoomID]; ok {
+				for sub := range subs {
+					select {
+					case sub.Events <- event:
+					default:
+						// 订阅者消费过慢，关闭后由客户端携带Last-Event-ID重连
+						close(sub.Events)
+						delete(subs, sub)
+					}
+				}
+				if len(subs) == 0 {
+					delete(h.subscribers, message.RoomID)
+				}
+			}
+			h.mu.Unlock()
+
+		case <-ticker.C:
+			h.pruneHistory(time.Now())
+		}
+	}
+}
+
+// appendHistory 为消息分配序号并写入房间历史，调用方需持有写锁
+func (h *Hub) appendHistory(roomID uint, message []byte) RoomEvent {
+	history := h.ensureHistory(roomID)
+
+	history.lastID++
+	event := RoomEvent{ID: history.lastID, Message: message}
+
+	history.events = append(history.events, event)
+	if len(history.events) > roomHistorySize {
+		history.events = append([]RoomEvent(nil), history.events[len(history.events)-roomHistorySize:]...)
+	}
+	history.updatedAt = time.Now()
+
+	return event
+}
+
+// ensureHistory 获取房间历史，不存在时创建，调用方需持有写锁
+func (h *Hub) ensureHistory(roomID uint) *roomHistory {
+	history, ok := h.history[roomID]
+	if !ok {
+		// 以当前毫秒时间作为序号基数，保证服务重启或历史清理后序号仍单调递增，
+		// 旧的Last-Event-ID必然落在历史之外从而触发重新同步
+		history = &roomHistory{
+			lastID:    uint64(time.Now().UnixMilli()) * 1000,
+			updatedAt: time.Now(),
 		}
+		h.history[roomID] = history
+	}
+	return history
+}
+
+// pruneHistory 清理长时间无人订阅且无新事件的房间历史
+func (h *Hub) pruneHistory(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for roomID, history := range h.history {
+		if len(h.rooms[roomID]) > 0 || len(h.subscribers[roomID]) > 0 {
+			continue
+		}
+		if now.Sub(history.updatedAt) >= roomHistoryRetention {
+			delete(h.history, roomID)
+		}
+	}
+}
+
+// SetPresenceTracker 设置在线状态跟踪器，需在Hub开始服务前调用
+func (h *Hub) SetPresenceTracker(tracker PresenceTracker) {
+	h.presence = tracker
+}
+
+// SetMessageHandler 设置客户端上行消息处理器，需在Hub开始服务前调用
+func (h *Hub) SetMessageHandler(handler MessageHandler) {
+	h.handler = handler
+}
+
+// MarkConnected 记录用户建立了一条实时连接（WebSocket或SSE）
+func (h *Hub) MarkConnected(roomID, userID uint) {
+	if h.presence != nil {
+		h.presence.Connect(roomID, userID)
+	}
+}
+
+// MarkDisconnected 记录用户断开了一条实时连接
+func (h *Hub) MarkDisconnected(roomID, userID uint) {
+	if h.presence != nil {
+		h.presence.Disconnect(roomID, userID)
 	}
 }
 
@@ -95,6 +259,87 @@ func (h *Hub) BroadcastToRoom(roomID uint, message []byte) {
 	}
 }
 
+// Subscribe 订阅房间事件
+// lastEventID 为客户端最后收到的事件序号（0表示不续传），返回需要补发的事件；
+// 若所需事件已不在历史中，resync 为 true，客户端应重新拉取房间详情
+func (h *Hub) Subscribe(roomID, userID uint, lastEventID uint64) (sub *Subscriber, missed []RoomEvent, resync bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventID > 0 {
+		missed, resync = h.eventsAfter(roomID, lastEventID)
+	}
+
+	sub = &Subscriber{
+		Events:  make(chan RoomEvent, subscriberBufferSize),
+		UserID:  userID,
+		RoomID:  roomID,
+		StartID: h.ensureHistory(roomID).lastID,
+	}
+
+	if h.subscribers[roomID] == nil {
+		h.subscribers[roomID] = make(map[*Subscriber]bool)
+	}
+	h.subscribers[roomID][sub] = true
+
+	log.Printf("SSE订阅者注册: RoomID=%d, UserID=%d, LastEventID=%d", roomID, userID, lastEventID)
+	return sub, missed, resync
+}
+
+// eventsAfter 返回序号大于 lastEventID 的历史事件，调用方需持有锁
+func (h *Hub) eventsAfter(roomID uint, lastEventID uint64) ([]RoomEvent, bool) {
+	history, ok := h.history[roomID]
+	if !ok || lastEventID > history.lastID {
+		// 历史已被清理或服务重启导致序号重置
+		return nil, true
+	}
+
+	if lastEventID == history.lastID {
+		return nil, false
+	}
+
+	if len(history.events) == 0 || history.events[0].ID > lastEventID+1 {
+		return nil, true
+	}
+
+	start := int(lastEventID + 1 - history.events[0].ID)
+	missed := make([]RoomEvent, len(history.events)-start)
+	copy(missed, history.events[start:])
+	return missed, false
+}
+
+// Unsubscribe 取消订阅房间事件
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.subscribers[sub.RoomID]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[sub]; ok {
+		delete(subs, sub)
+		close(sub.Events)
+		log.Printf("SSE订阅者注销: RoomID=%d, UserID=%d", sub.RoomID, sub.UserID)
+	}
+
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.RoomID)
+	}
+}
+
+// LastEventID 获取房间最新的事件序号
+func (h *Hub) LastEventID(roomID uint) uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if history, ok := h.history[roomID]; ok {
+		return history.lastID
+	}
+	return 0
+}
+
 // GetRoomClientCount 获取房间在线客户端数量
 func (h *Hub) GetRoomClientCount(roomID uint) int {
 	h.mu.RLock()
@@ -105,4 +350,3 @@ func (h *Hub) GetRoomClientCount(roomID uint) int {
 	}
 	return 0
 }
-
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func startTestHub(t *testing.T) *Hub {
+	t.Helper()
+
+	hub := NewHub()
+	go hub.Run()
+	return hub
+}
+
+func receiveEvent(t *testing.T, sub *Subscriber) RoomEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-sub.Events:
+		require.True(t, ok, "订阅通道被意外关闭")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("等待房间事件超时")
+		return RoomEvent{}
+	}
+}
+
+func TestHubSubscribe_ReceivesBroadcast(t *testing.T) {
+	hub := startTestHub(t)
+
+	sub, missed, resync := hub.Subscribe(1, 10, 0)
+	defer hub.Unsubscribe(sub)
+	require.Empty(t, missed)
+	require.False(t, resync)
+	require.NotZero(t, sub.StartID)
+
+	hub.BroadcastToRoom(1, []byte(`{"type":"bet"}`))
+	hub.BroadcastToRoom(2, []byte(`{"type":"other_room"}`))
+	hub.BroadcastToRoom(1, []byte(`{"type":"withdraw"}`))
+
+	first := receiveEvent(t, sub)
+	second := receiveEvent(t, sub)
+	require.Equal(t, sub.StartID+1, first.ID)
+	require.Equal(t, sub.StartID+2, second.ID)
+	require.JSONEq(t, `{"type":"bet"}`, string(first.Message))
+	require.JSONEq(t, `{"type":"withdraw"}`, string(second.Message))
+}
+
+func TestHubSubscribe_ResumesFromLastEventID(t *testing.T) {
+	hub := startTestHub(t)
+
+	first, _, _ := hub.Subscribe(1, 10, 0)
+	for _, msg := range []string{`{"type":"a"}`, `{"type":"b"}`, `{"type":"c"}`} {
+		hub.BroadcastToRoom(1, []byte(msg))
+	}
+	seen := receiveEvent(t, first)
+	receiveEvent(t, first)
+	receiveEvent(t, first)
+	hub.Unsubscribe(first)
+
+	resumed, missed, resync := hub.Subscribe(1, 10, seen.ID)
+	defer hub.Unsubscribe(resumed)
+	require.False(t, resync)
+	require.Len(t, missed, 2)
+	require.Equal(t, seen.ID+1, missed[0].ID)
+	require.JSONEq(t, `{"type":"b"}`, string(missed[0].Message))
+	require.JSONEq(t, `{"type":"c"}`, string(missed[1].Message))
+	require.Equal(t, missed[1].ID, resumed.StartID)
+}
+
+func TestHubSubscribe_RequestsResyncWhenHistoryMissing(t *testing.T) {
+	hub := startTestHub(t)
+
+	sub, missed, resync := hub.Subscribe(1, 10, 42)
+	defer hub.Unsubscribe(sub)
+	require.True(t, resync)
+	require.Empty(t, missed)
+
+	for i := 0; i < roomHistorySize+5; i++ {
+		hub.BroadcastToRoom(1, []byte(`{"type":"bet"}`))
+	}
+	first := receiveEvent(t, sub)
+	for i := 1; i < roomHistorySize+5; i++ {
+		receiveEvent(t, sub)
+	}
+
+	stale, missed, resync := hub.Subscribe(1, 11, first.ID)
+	defer hub.Unsubscribe(stale)
+	require.True(t, resync)
+	require.Empty(t, missed)
+}
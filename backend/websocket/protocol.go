@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ProtocolVersion 服务端当前使用的消息协议版本
+	ProtocolVersion = 1
+
+	// SubprotocolPrefix WebSocket子协议前缀，例如 "poker-score.v1"
+	SubprotocolPrefix = "poker-score.v"
+)
+
+// SupportedVersions 服务端支持的协议版本（升序）
+var SupportedVersions = []int{1}
+
+// ErrUnsupportedProtocol 客户端请求的协议版本均不受支持
+var ErrUnsupportedProtocol = errors.New("不支持的协议版本")
+
+// Message WebSocket消息
+type Message struct {
+	Type    string      `json:"type"`
+	Version int         `json:"v,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// 客户端上行消息类型
+const (
+	ClientMessagePing     = "ping"         // 心跳
+	ClientMessageChat     = "chat_message" // 发送聊天消息
+	ClientMessageReaction = "reaction"     // 对操作记录发送表情回应
+)
+
+// ClientMessage 客户端上行消息，data 由对应的处理器解析
+type ClientMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Subprotocol 返回指定版本对应的WebSocket子协议名
+func Subprotocol(version int) string {
+	return SubprotocolPrefix + strconv.Itoa(version)
+}
+
+// NegotiateVersion 根据客户端请求的版本选择协议版本
+// requested 可以是子协议名（"poker-score.v1"）或纯数字（"1"）；
+// 未请求任何版本时视为旧客户端，使用当前版本
+func NegotiateVersion(requested []string) (int, error) {
+	if len(requested) == 0 {
+		return ProtocolVersion, nil
+	}
+
+	best := 0
+	for _, raw := range requested {
+		value := strings.TrimPrefix(strings.TrimSpace(raw), SubprotocolPrefix)
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		if isSupportedVersion(version) && version > best {
+			best = version
+		}
+	}
+
+	if best == 0 {
+		return 0, ErrUnsupportedProtocol
+	}
+	return best, nil
+}
+
+func isSupportedVersion(version int) bool {
+	for _, supported := range SupportedVersions {
+		if supported == version {
+			return true
+		}
+	}
+	return false
+}
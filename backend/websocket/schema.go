@@ -0,0 +1,259 @@
+package websocket
+
+//go:generate go run ../cmd/wsschema -o ../../docs/websocket-schema.json
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaID JSON Schema文档标识
+const SchemaID = "https://poker.iamwsll.cn/schemas/room-events.json"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// JSONSchema 根据已注册的事件结构体生成房间消息的JSON Schema（draft-07）
+// 每条消息都必须匹配 oneOf 中的某个分支，分支以 type 字段区分
+func JSONSchema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+	branches := make([]interface{}, 0, len(registeredEvents))
+
+	for _, event := range registeredEvents {
+		eventType := event.EventType()
+		dataSchema := schemaForType(reflect.TypeOf(event))
+		definitions[eventType] = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type": map[string]interface{}{"const": eventType},
+				"v":    map[string]interface{}{"type": "integer", "enum": intsToInterfaces(SupportedVersions)},
+				"data": dataSchema,
+			},
+			"required":             []interface{}{"type", "v", "data"},
+			"additionalProperties": false,
+		}
+		branches = append(branches, map[string]interface{}{"$ref": "#/definitions/" + eventType})
+	}
+
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         SchemaID,
+		"title":       "RoomEventMessage",
+		"description": fmt.Sprintf("房间实时事件消息（协议版本 %d）", ProtocolVersion),
+		"oneOf":       branches,
+		"definitions": definitions,
+	}
+}
+
+// JSONSchemaDocument 返回格式化后的JSON Schema文档
+func JSONSchemaDocument() ([]byte, error) {
+	data, err := json.MarshalIndent(JSONSchema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitEmpty := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+			properties[name] = schemaForType(field.Type)
+			if !omitEmpty {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = stringsToInterfaces(required)
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+func intsToInterfaces(values []int) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// ValidateMessage 校验一条下发消息是否符合JSON Schema
+func ValidateMessage(raw []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("消息不是合法JSON: %w", err)
+	}
+
+	message, ok := doc.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("消息必须是JSON对象")
+	}
+
+	eventType, _ := message["type"].(string)
+	schema := JSONSchema()
+	definition, ok := schema["definitions"].(map[string]interface{})[eventType]
+	if !ok {
+		return fmt.Errorf("未知的事件类型: %q", eventType)
+	}
+
+	return validateValue(definition.(map[string]interface{}), doc, "$")
+}
+
+// validateValue 按生成器使用到的Schema子集（type/const/enum/properties/required/additionalProperties/items/minimum/format）进行校验
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if expected, ok := schema["const"]; ok && value != expected {
+		return fmt.Errorf("%s: 期望 %v，实际 %v", path, expected, value)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, candidate := range enum {
+			if number, isNumber := value.(float64); isNumber {
+				if candidateInt, isInt := candidate.(int); isInt && number == float64(candidateInt) {
+					matched = true
+				}
+			} else if candidate == value {
+				matched = true
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: %v 不在允许的取值 %v 中", path, value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: 期望对象", path)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, exists := obj[name.(string)]; !exists {
+					return fmt.Errorf("%s: 缺少字段 %s", path, name)
+				}
+			}
+		}
+		for name, fieldValue := range obj {
+			fieldSchema, known := properties[name]
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: 未定义的字段 %s", path, name)
+				}
+				continue
+			}
+			if err := validateValue(fieldSchema.(map[string]interface{}), fieldValue, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: 期望数组", path)
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for i, item := range items {
+			if err := validateValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: 期望字符串", path)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fmt.Errorf("%s: 时间格式错误: %v", path, err)
+			}
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			return fmt.Errorf("%s: 期望整数", path)
+		}
+		if minimum, ok := schema["minimum"].(int); ok && number < float64(minimum) {
+			return fmt.Errorf("%s: 不能小于 %d", path, minimum)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: 期望数字", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: 期望布尔值", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchemaDocument_UpToDate(t *testing.T) {
+	expected, err := JSONSchemaDocument()
+	require.NoError(t, err)
+
+	committed, err := os.ReadFile("../../docs/websocket-schema.json")
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(committed), "JSON Schema已过期，请在websocket目录执行 go generate")
+}
+
+func TestValidateMessage_ControlEvents(t *testing.T) {
+	events := []Event{
+		HelloEvent{ProtocolVersion: ProtocolVersion, SupportedVersions: SupportedVersions},
+		ConnectedEvent{RoomID: 1, ProtocolVersion: ProtocolVersion},
+		ResyncEvent{RoomID: 1, ProtocolVersion: ProtocolVersion},
+		PongEvent{},
+	}
+
+	for _, event := range events {
+		payload, err := EncodeEvent(event)
+		require.NoError(t, err)
+		require.NoError(t, ValidateMessage(payload), event.EventType())
+	}
+}
+
+func TestValidateMessage_RejectsDrift(t *testing.T) {
+	valid, err := EncodeEvent(BetEvent{UserID: 1, Nickname: "A", Amount: 10, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	require.NoError(t, ValidateMessage(valid))
+
+	testCases := map[string]string{
+		"unknown type":     `{"type":"mystery","v":1,"data":{}}`,
+		"missing version":  `{"type":"pong","data":{}}`,
+		"bad version":      `{"type":"pong","v":99,"data":{}}`,
+		"renamed field":    `{"type":"withdraw","v":1,"data":{"user_id":1,"nickname":"A","amount":1,"my_balance":0,"table_balance":0,"created_at":"2025-11-07T05:52:40Z"}}`,
+		"wrong field type": `{"type":"room_dissolved","v":1,"data":{"room_id":"6","dissolved_at":"2025-11-07T11:52:00Z"}}`,
+		"bad timestamp":    `{"type":"room_dissolved","v":1,"data":{"room_id":6,"dissolved_at":"yesterday"}}`,
+	}
+
+	for name, raw := range testCases {
+		require.Error(t, ValidateMessage([]byte(raw)), name)
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	version, err := NegotiateVersion(nil)
+	require.NoError(t, err)
+	require.Equal(t, ProtocolVersion, version)
+
+	version, err = NegotiateVersion([]string{"poker-score.v1"})
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	version, err = NegotiateVersion([]string{"poker-score.v9", "1"})
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	_, err = NegotiateVersion([]string{"poker-score.v9", "chat"})
+	require.ErrorIs(t, err, ErrUnsupportedProtocol)
+}
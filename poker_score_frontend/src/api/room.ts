@@ -35,6 +35,11 @@ export function dissolveRoom(roomId: number) {
   return post(`/rooms/${roomId}/dissolve`)
 }
 
+// 获取一次性WebSocket连接票据
+export function createWsTicket(roomId: number) {
+  return post('/ws/ticket', { room_id: roomId })
+}
+
 // 踢出用户
 export function kickUser(roomId: number, userId: number) {
   return post(`/rooms/${roomId}/kick`, { user_id: userId })
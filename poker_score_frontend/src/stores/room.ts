@@ -4,6 +4,7 @@ import { ref } from 'vue'
 import { message as antdMessage } from 'ant-design-vue'
 import router from '@/router'
 import { useUserStore } from '@/stores/user'
+import { createWsTicket } from '@/api/room'
 
 export interface RoomMember {
   user_id: number
@@ -374,7 +375,7 @@ export const useRoomStore = defineStore('room', () => {
   }
 
   // 连接WebSocket
-  function connectWebSocket(roomId: number) {
+  async function connectWebSocket(roomId: number) {
     if (ws.value) {
       const state = ws.value.readyState
       if (currentRoomId.value === roomId && (state === WebSocket.OPEN || state === WebSocket.CONNECTING)) {
@@ -390,14 +391,21 @@ export const useRoomStore = defineStore('room', () => {
     const wsUrl = new URL(`/api/ws/room/${roomId}`, baseOrigin)
     wsUrl.protocol = wsUrl.protocol === 'https:' ? 'wss:' : 'ws:'
 
-    // 添加session_id到查询参数（用于移动端浏览器）
-    const sessionId = localStorage.getItem('session_id')
-    if (sessionId) {
-      wsUrl.searchParams.set('session_id', sessionId)
+    // 浏览器无法为WebSocket设置Authorization头，使用一次性票据认证（用于移动端浏览器）
+    currentRoomId.value = roomId
+    try {
+      const res = await createWsTicket(roomId)
+      wsUrl.searchParams.set('ticket', res.data.ticket)
+    } catch (error) {
+      console.error('获取WebSocket票据失败:', error)
+    }
+
+    // 等待票据期间已切换或断开房间
+    if (currentRoomId.value !== roomId || ws.value) {
+      return
     }
 
     ws.value = new WebSocket(wsUrl.toString())
-    currentRoomId.value = roomId
 
     ws.value.onopen = () => {
       console.log('WebSocket连接成功')